@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/importer"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// importUser is recorded as the author of price changes made by the import
+// subcommand
+const importUser = "import-cli"
+
+// storeConfigFromEnv reads the storage settings shared by the server and
+// its subcommands
+func storeConfigFromEnv() repository.Config {
+	return repository.Config{
+		Backend:    getEnv("STORAGE_BACKEND", repository.BackendMemory),
+		DataPath:   getEnv("DATA_PATH", "/app/data/seed"),
+		SQLitePath: getEnv("SQLITE_PATH", "/app/data/inventory.db"),
+		JournalDir: getEnv("JOURNAL_DIR", ""),
+	}
+}
+
+// runImport imports a CSV or JSON file into the store configured by the
+// environment, exactly as POST /api/v1/vehicles/import does, and prints the
+// report. It returns the exit status: 1 when nothing was imported because
+// of invalid records or an error, 2 for bad usage.
+//
+// The server should not be running against the same store: it would not
+// see the imported vehicles.
+func runImport(args []string, logger *logrus.Logger) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "csv or json (default from the file extension)")
+	mapping := flags.String("mapping", "", "CSV column mapping as a JSON object, or a file holding one")
+	dryRun := flags.Bool("dry-run", false, "validate without storing anything")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: server import [flags] FILE")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	path := flags.Arg(0)
+
+	opts := importer.Options{
+		Format: *format,
+		DryRun: *dryRun,
+		By:     importUser,
+	}
+	if opts.Format == "" {
+		opts.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if *mapping != "" {
+		raw := []byte(*mapping)
+		if !strings.HasPrefix(strings.TrimSpace(*mapping), "{") {
+			var err error
+			if raw, err = os.ReadFile(*mapping); err != nil {
+				logger.WithError(err).Error("Failed to read column mapping")
+				return 2
+			}
+		}
+		if err := json.Unmarshal(raw, &opts.Mapping); err != nil {
+			logger.WithError(err).Error("Column mapping must be a JSON object of field names to column names")
+			return 2
+		}
+	}
+
+	config := storeConfigFromEnv()
+	if !opts.DryRun && config.Backend != repository.BackendSQLite && config.JournalDir == "" {
+		logger.Error("The memory backend keeps nothing without JOURNAL_DIR; set it or use STORAGE_BACKEND=sqlite")
+		return 2
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		logger.WithError(err).Error("Failed to open import file")
+		return 1
+	}
+	defer file.Close()
+
+	store, err := repository.NewStore(config, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to open the store")
+		return 1
+	}
+	defer store.Close()
+
+	report, err := importer.New(store, &sync.Mutex{}).Import(file, opts)
+	if report != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	}
+	if err != nil {
+		logger.WithError(err).Error("Import failed")
+		return 1
+	}
+	if len(report.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -4,12 +4,21 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/alerts"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/auth"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/blob"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/handlers"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/images"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/importer"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/recommend"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/reservations"
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
 	"github.com/sirupsen/logrus"
@@ -21,30 +30,131 @@ func main() {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetLevel(logrus.InfoLevel)
 
+	// Subcommands run offline against the configured store
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			os.Exit(runImport(os.Args[2:], logger))
+		case "validate-data":
+			os.Exit(runValidateData(os.Args[2:], logger))
+		}
+	}
+
 	// Get configuration from environment
 	dataPath := getEnv("DATA_PATH", "/app/data/seed")
 	jwtSecret := getEnv("JWT_SECRET", "dev-jwt-secret-change-in-production")
 	port := getEnv("PORT", "8001")
+	storeConfig := storeConfigFromEnv()
+	// The seed rates are only read; updates go to EXCHANGE_RATES_PATH, next
+	// to the journal by default, or stay in memory without either
+	ratesPath := getEnv("EXCHANGE_RATES_PATH", "")
+	if ratesPath == "" && storeConfig.JournalDir != "" {
+		ratesPath = filepath.Join(storeConfig.JournalDir, "exchange_rates.json")
+	}
+	alertsPath := getEnv("ALERTS_PATH", "")
+	webhookURL := getEnv("NOTIFY_WEBHOOK_URL", "")
+	smtpAddr := getEnv("NOTIFY_SMTP_ADDR", "")
+	smtpFrom := getEnv("NOTIFY_SMTP_FROM", "alerts@autostack.local")
+	snapshotInterval, err := time.ParseDuration(getEnv("SNAPSHOT_INTERVAL", "5m"))
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid SNAPSHOT_INTERVAL")
+	}
+	watchInterval, err := time.ParseDuration(getEnv("WATCH_INTERVAL", "0"))
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid WATCH_INTERVAL")
+	}
+	reservationHold, err := time.ParseDuration(getEnv("RESERVATION_HOLD", "48h"))
+	if err != nil || reservationHold <= 0 {
+		logger.WithError(err).Fatal("Invalid RESERVATION_HOLD")
+	}
+	sweepInterval, err := time.ParseDuration(getEnv("RESERVATION_SWEEP_INTERVAL", "1m"))
+	if err != nil || sweepInterval <= 0 {
+		logger.WithError(err).Fatal("Invalid RESERVATION_SWEEP_INTERVAL")
+	}
+	imageMaxSize, err := strconv.ParseInt(getEnv("IMAGE_MAX_BYTES", strconv.Itoa(images.DefaultMaxSize)), 10, 64)
+	if err != nil || imageMaxSize <= 0 {
+		logger.WithError(err).Fatal("Invalid IMAGE_MAX_BYTES")
+	}
+	strictData, err := strconv.ParseBool(getEnv("STRICT_DATA", "false"))
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid STRICT_DATA")
+	}
 
 	logger.Info("Starting API Inventory service...")
 	logger.WithFields(logrus.Fields{
-		"data_path": dataPath,
-		"port":      port,
+		"data_path":       dataPath,
+		"port":            port,
+		"storage_backend": storeConfig.Backend,
+		"journal_dir":     storeConfig.JournalDir,
+		"rates_path":      ratesPath,
 	}).Info("Configuration loaded")
 
+	// In strict mode, refuse to start on seed data validate-data rejects
+	if strictData {
+		checkDataStrict(dataPath, logger)
+	}
+
 	// Initialize repository
-	repo, err := repository.NewRepository(dataPath, logger)
+	storeConfig.SnapshotInterval = snapshotInterval
+	storeConfig.WatchInterval = watchInterval
+	repo, err := repository.NewStore(storeConfig, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize repository")
 	}
 
+	// Load exchange rates
+	rates, err := exchange.OpenWithSeed(filepath.Join(dataPath, "exchange_rates.json"), ratesPath)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load exchange rates")
+	}
+
+	// Saved searches are matched against every vehicle written through repo,
+	// reloaded or rerated by a review. Take the reloader first, as the
+	// observing wrapper only exposes Store.
+	var notifiers []alerts.Notifier
+	if webhookURL != "" {
+		notifiers = append(notifiers, alerts.NewWebhookNotifier(webhookURL))
+	}
+	if smtpAddr != "" {
+		notifiers = append(notifiers, alerts.NewSMTPNotifier(smtpAddr, smtpFrom))
+	}
+	alertsManager, err := alerts.Open(alertsPath, repo, rates, logger, notifiers...)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to load saved searches")
+	}
+	reloader, _ := repo.(repository.Reloader)
+	repo = repository.Observe(repo, alertsManager)
+
+	// Uploaded vehicle images
+	blobs, err := blob.New(blob.Config{
+		Backend: getEnv("BLOB_BACKEND", blob.BackendLocal),
+		Dir:     getEnv("BLOB_DIR", "/app/data/images"),
+	})
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to initialize image storage")
+	}
+
 	// Initialize JWT manager
 	jwtManager := auth.NewJWTManager(jwtSecret, 24*time.Hour)
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler(logger)
 	authHandler := handlers.NewAuthHandler(repo, jwtManager, logger)
-	vehicleHandler := handlers.NewVehicleHandler(repo, logger)
+	vehicleHandler := handlers.NewVehicleHandler(repo, rates, logger)
+	reservationManager := reservations.NewManager(repo, vehicleHandler.UpdateLock(), reservationHold, logger)
+	reservationManager.Start(sweepInterval)
+	reservationHandler := handlers.NewReservationHandler(reservationManager, logger)
+	savedSearchHandler := handlers.NewSavedSearchHandler(alertsManager, repo, logger)
+	favoriteHandler := handlers.NewFavoriteHandler(repo, logger)
+	dealerHandler := handlers.NewDealerHandler(repo, logger)
+	imageManager := images.NewManager(repo, blobs, vehicleHandler.UpdateLock(), imageMaxSize, logger)
+	vehicleHandler.SetImages(imageManager)
+	imageHandler := handlers.NewImageHandler(imageManager, logger)
+	importHandler := handlers.NewImportHandler(importer.New(repo, vehicleHandler.UpdateLock()), logger)
+	recommendationHandler := handlers.NewRecommendationHandler(repo, recommend.NewViews(), rates, logger)
+	vinHandler := handlers.NewVINHandler(logger)
+	adminHandler := handlers.NewAdminHandler(reloader, logger)
+	ratesHandler := handlers.NewExchangeRateHandler(rates, logger)
 
 	// Setup router
 	r := mux.NewRouter()
@@ -52,14 +162,61 @@ func main() {
 	// Public routes
 	r.HandleFunc("/health", healthHandler.HandleHealth).Methods("GET")
 	r.HandleFunc("/api/v1/auth/login", authHandler.HandleLogin).Methods("POST")
+	r.HandleFunc("/api/v1/vehicles/{id}/images/{file}", imageHandler.HandleServeImage).Methods("GET")
 
 	// Protected routes
 	api := r.PathPrefix("/api/v1").Subrouter()
 	api.Use(middleware.AuthMiddleware(jwtManager, logger))
 
 	api.HandleFunc("/vehicles", vehicleHandler.HandleListVehicles).Methods("GET")
-	api.HandleFunc("/vehicles/{id}", vehicleHandler.HandleGetVehicle).Methods("GET")
+	api.HandleFunc("/vehicles/compare", vehicleHandler.HandleCompareVehicles).Methods("GET")
+	api.HandleFunc("/vehicles/export", vehicleHandler.HandleExportVehicles).Methods("GET")
+	api.HandleFunc("/vehicles/{id}", recommendationHandler.TrackViews(vehicleHandler.HandleGetVehicle)).Methods("GET")
 	api.HandleFunc("/vehicles/search", vehicleHandler.HandleSearchVehicles).Methods("POST")
+	api.HandleFunc("/vin/{vin}", vinHandler.HandleDecodeVIN).Methods("GET")
+	api.HandleFunc("/vehicles/{id}/reservation", reservationHandler.HandleReserveVehicle).Methods("POST")
+	api.HandleFunc("/vehicles/{id}/reservation", reservationHandler.HandleCancelReservation).Methods("DELETE")
+	api.HandleFunc("/reservations", reservationHandler.HandleListReservations).Methods("GET")
+	api.HandleFunc("/me/saved-searches", savedSearchHandler.HandleListSavedSearches).Methods("GET")
+	api.HandleFunc("/me/saved-searches", savedSearchHandler.HandleCreateSavedSearch).Methods("POST")
+	api.HandleFunc("/me/saved-searches/{id}", savedSearchHandler.HandleGetSavedSearch).Methods("GET")
+	api.HandleFunc("/me/saved-searches/{id}", savedSearchHandler.HandleDeleteSavedSearch).Methods("DELETE")
+	api.HandleFunc("/me/notifications", savedSearchHandler.HandleListNotifications).Methods("GET")
+	api.HandleFunc("/me/notifications/{id}/read", savedSearchHandler.HandleMarkNotificationRead).Methods("POST")
+	api.HandleFunc("/me/favorites", favoriteHandler.HandleListFavorites).Methods("GET")
+	api.HandleFunc("/me/favorites/{vehicleId}", favoriteHandler.HandleAddFavorite).Methods("PUT")
+	api.HandleFunc("/me/favorites/{vehicleId}", favoriteHandler.HandleRemoveFavorite).Methods("DELETE")
+	api.HandleFunc("/me/recommendations", recommendationHandler.HandleRecommendations).Methods("GET")
+	api.HandleFunc("/dealers", dealerHandler.HandleListDealers).Methods("GET")
+	api.HandleFunc("/dealers/{id}", dealerHandler.HandleGetDealer).Methods("GET")
+	api.HandleFunc("/dealers/{id}/vehicles", vehicleHandler.HandleDealerVehicles).Methods("GET")
+	api.HandleFunc("/dealers/{id}/reviews", dealerHandler.HandleListReviews).Methods("GET")
+	api.HandleFunc("/dealers/{id}/reviews", dealerHandler.HandlePostReview).Methods("POST")
+
+	// Admin-only inventory mutations
+	requireAdmin := middleware.RequireRole(repo, "admin", logger)
+	api.Handle("/vehicles", requireAdmin(http.HandlerFunc(vehicleHandler.HandleCreateVehicle))).Methods("POST")
+	api.Handle("/vehicles/import", requireAdmin(http.HandlerFunc(importHandler.HandleImportVehicles))).Methods("POST")
+	api.Handle("/vehicles/{id}", requireAdmin(http.HandlerFunc(vehicleHandler.HandleUpdateVehicle))).Methods("PUT")
+	api.Handle("/vehicles/{id}", requireAdmin(http.HandlerFunc(vehicleHandler.HandlePatchVehicle))).Methods("PATCH")
+	api.Handle("/vehicles/{id}", requireAdmin(http.HandlerFunc(vehicleHandler.HandleDeleteVehicle))).Methods("DELETE")
+	api.Handle("/vehicles/{id}/transitions", requireAdmin(http.HandlerFunc(vehicleHandler.HandleTransitionVehicle))).Methods("POST")
+	api.Handle("/vehicles/{id}/transitions", requireAdmin(http.HandlerFunc(vehicleHandler.HandleListTransitions))).Methods("GET")
+	api.HandleFunc("/vehicles/{id}/price-history", vehicleHandler.HandlePriceHistory).Methods("GET")
+	api.HandleFunc("/vehicles/{id}/images", imageHandler.HandleListImages).Methods("GET")
+	api.Handle("/vehicles/{id}/images", requireAdmin(http.HandlerFunc(imageHandler.HandleUploadImage))).Methods("POST")
+	api.Handle("/vehicles/{id}/images", requireAdmin(http.HandlerFunc(imageHandler.HandleReorderImages))).Methods("PUT")
+	api.Handle("/vehicles/{id}/images/{imageId}", requireAdmin(http.HandlerFunc(imageHandler.HandleDeleteImage))).Methods("DELETE")
+
+	// Admin routes
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AuthMiddleware(jwtManager, logger))
+	admin.Use(middleware.RequireRole(repo, "admin", logger))
+
+	admin.HandleFunc("/reload", adminHandler.HandleReload).Methods("POST")
+	admin.HandleFunc("/reload", adminHandler.HandleReloadStatus).Methods("GET")
+	admin.HandleFunc("/exchange-rates", ratesHandler.HandleListRates).Methods("GET")
+	admin.HandleFunc("/exchange-rates", ratesHandler.HandleUpdateRates).Methods("PUT")
 
 	// Add logging middleware to all routes
 	r.Use(middleware.LoggingMiddleware(logger))
@@ -67,7 +224,7 @@ func main() {
 	// Setup CORS
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Content-Type", "Authorization"},
 		AllowCredentials: true,
 	}).Handler(r)
@@ -0,0 +1,387 @@
+// Package alerts keeps users' saved searches and notifies them when a new
+// or changed vehicle matches one.
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/jsonfile"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// Limits on what a user can keep
+const (
+	MaxSavedSearches = 20
+	// MaxNotifications is how many notifications an inbox keeps; the oldest
+	// are dropped first
+	MaxNotifications = 200
+)
+
+var (
+	// ErrSearchNotFound is returned when the user has no saved search with
+	// the ID
+	ErrSearchNotFound = errors.New("saved search not found")
+	// ErrNotificationNotFound is returned when the user has no notification
+	// with the ID
+	ErrNotificationNotFound = errors.New("notification not found")
+	// ErrTooManySearches is returned when the user already has
+	// MaxSavedSearches saved searches
+	ErrTooManySearches = fmt.Errorf("at most %d saved searches are allowed", MaxSavedSearches)
+)
+
+// UserLookup finds users by ID
+type UserLookup interface {
+	GetUserByID(userID string) (*models.User, error)
+}
+
+// state is the content of the alerts file
+type state struct {
+	SearchSeq       int                    `json:"searchSeq"`
+	NotificationSeq int                    `json:"notificationSeq"`
+	Searches        []*models.SavedSearch  `json:"searches"`
+	Notifications   []*models.Notification `json:"notifications"`
+}
+
+// Manager stores saved searches and notification inboxes, optionally
+// backed by a JSON file, and matches vehicle writes against the searches.
+// It implements repository.VehicleObserver and is safe for concurrent use.
+type Manager struct {
+	mu    sync.Mutex
+	path  string
+	state state
+
+	users  UserLookup
+	rates  models.PriceConverter
+	logger *logrus.Logger
+
+	// notifiers deliver notifications beyond the inbox, from a single
+	// worker so slow deliveries never hold up vehicle writes
+	notifiers  []Notifier
+	deliveries chan delivery
+	stop       chan struct{}
+	stopOnce   sync.Once
+	wg         sync.WaitGroup
+}
+
+var _ repository.VehicleObserver = (*Manager)(nil)
+
+// delivery is a notification waiting for the notifiers
+type delivery struct {
+	user         *models.User
+	notification models.Notification
+}
+
+// Open loads the saved searches and notifications stored at path. An empty
+// path keeps them in memory only; a missing file is created by the first
+// change. rates converts prices for searches with a price currency and may
+// be nil.
+func Open(path string, users UserLookup, rates models.PriceConverter, logger *logrus.Logger, notifiers ...Notifier) (*Manager, error) {
+	m := &Manager{
+		path:       path,
+		users:      users,
+		rates:      rates,
+		logger:     logger,
+		notifiers:  notifiers,
+		deliveries: make(chan delivery, 100),
+		stop:       make(chan struct{}),
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(data, &m.state); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+			}
+		}
+	}
+
+	if len(m.notifiers) > 0 {
+		m.wg.Add(1)
+		go m.deliverLoop()
+	}
+
+	return m, nil
+}
+
+// SavedSearches returns the user's saved searches, oldest first
+func (m *Manager) SavedSearches(userID string) []*models.SavedSearch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	searches := []*models.SavedSearch{}
+	for _, search := range m.state.Searches {
+		if search.UserID == userID {
+			copied := *search
+			searches = append(searches, &copied)
+		}
+	}
+	return searches
+}
+
+// SavedSearch returns one of the user's saved searches
+func (m *Manager) SavedSearch(userID, searchID string) (*models.SavedSearch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, search := range m.state.Searches {
+		if search.ID == searchID && search.UserID == userID {
+			copied := *search
+			return &copied, nil
+		}
+	}
+	return nil, ErrSearchNotFound
+}
+
+// CreateSavedSearch assigns an ID and creation time to the search and
+// stores it
+func (m *Manager) CreateSavedSearch(search *models.SavedSearch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, existing := range m.state.Searches {
+		if existing.UserID == search.UserID {
+			count++
+		}
+	}
+	if count >= MaxSavedSearches {
+		return ErrTooManySearches
+	}
+
+	next := m.state
+	next.SearchSeq++
+	stored := *search
+	stored.ID = fmt.Sprintf("search-%03d", next.SearchSeq)
+	stored.CreatedAt = time.Now().UTC()
+	next.Searches = append(append([]*models.SavedSearch(nil), m.state.Searches...), &stored)
+	if err := m.save(next); err != nil {
+		return err
+	}
+
+	*search = stored
+	return nil
+}
+
+// DeleteSavedSearch removes one of the user's saved searches. Notifications
+// it produced stay in the inbox.
+func (m *Manager) DeleteSavedSearch(userID, searchID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := m.state
+	next.Searches = make([]*models.SavedSearch, 0, len(m.state.Searches))
+	for _, search := range m.state.Searches {
+		if search.ID != searchID || search.UserID != userID {
+			next.Searches = append(next.Searches, search)
+		}
+	}
+	if len(next.Searches) == len(m.state.Searches) {
+		return ErrSearchNotFound
+	}
+
+	return m.save(next)
+}
+
+// Notifications returns the user's notifications, newest first
+func (m *Manager) Notifications(userID string, unreadOnly bool) []*models.Notification {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	notifications := []*models.Notification{}
+	for i := len(m.state.Notifications) - 1; i >= 0; i-- {
+		notification := m.state.Notifications[i]
+		if notification.UserID != userID || (unreadOnly && notification.Read) {
+			continue
+		}
+		copied := *notification
+		notifications = append(notifications, &copied)
+	}
+	return notifications
+}
+
+// MarkRead marks one of the user's notifications as read
+func (m *Manager) MarkRead(userID, notificationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := m.state
+	next.Notifications = append([]*models.Notification(nil), m.state.Notifications...)
+	for i, notification := range next.Notifications {
+		if notification.ID != notificationID || notification.UserID != userID {
+			continue
+		}
+		if notification.Read {
+			return nil
+		}
+		read := *notification
+		read.Read = true
+		next.Notifications[i] = &read
+		return m.save(next)
+	}
+	return ErrNotificationNotFound
+}
+
+// VehicleSaved records a notification for every saved search the vehicle
+// matches, unless it already matched before the change
+func (m *Manager) VehicleSaved(vehicle, previous *models.Vehicle) {
+	event := models.EventListed
+	if previous != nil {
+		event = models.EventUpdated
+	}
+	now := time.Now().UTC()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	next := m.state
+	next.Notifications = append([]*models.Notification(nil), m.state.Notifications...)
+	var deliveries []delivery
+	for _, search := range m.state.Searches {
+		if !m.matches(vehicle, search) || (previous != nil && m.matches(previous, search)) {
+			continue
+		}
+
+		next.NotificationSeq++
+		notification := &models.Notification{
+			ID:         fmt.Sprintf("notification-%06d", next.NotificationSeq),
+			UserID:     search.UserID,
+			SearchID:   search.ID,
+			SearchName: search.Name,
+			Event:      event,
+			VehicleID:  vehicle.ID,
+			Title:      strings.TrimSpace(fmt.Sprintf("%d %s %s %s", vehicle.Year, vehicle.Make, vehicle.Model, vehicle.Trim)),
+			Price:      models.Money{Amount: vehicle.Price, Currency: vehicle.Currency},
+			CreatedAt:  now,
+		}
+		next.Notifications = append(next.Notifications, notification)
+		deliveries = append(deliveries, delivery{notification: *notification})
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+	next.Notifications = trimInboxes(next.Notifications)
+
+	if err := m.save(next); err != nil {
+		m.logger.WithError(err).WithField("vehicle_id", vehicle.ID).Error("Failed to record saved search matches")
+		return
+	}
+	m.logger.WithFields(logrus.Fields{
+		"vehicle_id": vehicle.ID,
+		"matches":    len(deliveries),
+	}).Info("Vehicle matched saved searches")
+
+	if len(m.notifiers) == 0 {
+		return
+	}
+	for _, d := range deliveries {
+		user, err := m.users.GetUserByID(d.notification.UserID)
+		if err != nil {
+			continue
+		}
+		d.user = user
+		select {
+		case m.deliveries <- d:
+		default:
+			m.logger.WithField("notification_id", d.notification.ID).Warn("Notification queue full; delivery skipped")
+		}
+	}
+}
+
+// matches reports whether the vehicle passes the saved search. Like vehicle
+// searches, a search without statuses only matches public listings.
+func (m *Manager) matches(vehicle *models.Vehicle, search *models.SavedSearch) bool {
+	filter := search.Filter
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = models.PublicStatuses
+	}
+	if filter.PriceCurrency != "" && m.rates != nil {
+		filter.Rates = m.rates
+	}
+	return repository.MatchesFilter(vehicle, &filter)
+}
+
+// save writes the next state to the file, if there is one, and makes it
+// current. Callers must hold mu.
+func (m *Manager) save(next state) error {
+	if m.path != "" {
+		if err := jsonfile.WriteAtomic(m.path, next); err != nil {
+			return fmt.Errorf("failed to save alerts: %w", err)
+		}
+	}
+	m.state = next
+	return nil
+}
+
+// trimInboxes drops each user's oldest notifications beyond
+// MaxNotifications
+func trimInboxes(notifications []*models.Notification) []*models.Notification {
+	counts := make(map[string]int)
+	for _, notification := range notifications {
+		counts[notification.UserID]++
+	}
+
+	kept := notifications[:0]
+	for _, notification := range notifications {
+		if counts[notification.UserID] > MaxNotifications {
+			counts[notification.UserID]--
+			continue
+		}
+		kept = append(kept, notification)
+	}
+	return kept
+}
+
+// deliverLoop hands queued notifications to the notifiers until Close is
+// called
+func (m *Manager) deliverLoop() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case d := <-m.deliveries:
+			m.deliver(d)
+		case <-m.stop:
+			// Deliver what is already queued before stopping
+			for {
+				select {
+				case d := <-m.deliveries:
+					m.deliver(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver passes a notification to every notifier, logging failures
+func (m *Manager) deliver(d delivery) {
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(d.user, &d.notification); err != nil {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"notifier":        notifier.Name(),
+				"notification_id": d.notification.ID,
+			}).Warn("Notification delivery failed")
+		}
+	}
+}
+
+// Close stops delivering notifications once the queue is drained
+func (m *Manager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.wg.Wait()
+	return nil
+}
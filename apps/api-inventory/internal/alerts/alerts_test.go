@@ -0,0 +1,207 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestStore(t *testing.T) (repository.Store, *logrus.Logger) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	repo, err := repository.NewRepository(filepath.Join("..", "..", "..", "..", "data", "seed"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return repo, logger
+}
+
+// newAccord returns a listing that is not in the seed data
+func newAccord(price float64) *models.Vehicle {
+	return &models.Vehicle{
+		VIN:      "1HGCM82633A004352",
+		Year:     2003,
+		Make:     "Honda",
+		Model:    "Accord",
+		Type:     "sedan",
+		Price:    price,
+		Currency: "USD",
+		Status:   models.StatusAvailable,
+	}
+}
+
+func TestVehicleSavedNotifies(t *testing.T) {
+	repo, logger := newTestStore(t)
+	path := filepath.Join(t.TempDir(), "alerts.json")
+
+	m, err := Open(path, repo, nil, logger)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	store := repository.Observe(repo, m)
+
+	search := &models.SavedSearch{
+		UserID: "user-003",
+		Name:   "Cheap sedans",
+		Filter: models.VehicleFilter{VehicleTypes: []string{"sedan"}, MaxPrice: 10000},
+	}
+	if err := m.CreateSavedSearch(search); err != nil {
+		t.Fatalf("CreateSavedSearch failed: %v", err)
+	}
+	other := &models.SavedSearch{UserID: "user-004", Name: "Trucks", Filter: models.VehicleFilter{Type: "truck"}}
+	if err := m.CreateSavedSearch(other); err != nil {
+		t.Fatalf("CreateSavedSearch failed: %v", err)
+	}
+
+	// Too expensive at first, then reduced into the search
+	vehicle := newAccord(12000)
+	if err := store.CreateVehicle(vehicle); err != nil {
+		t.Fatalf("CreateVehicle failed: %v", err)
+	}
+	if got := m.Notifications("user-003", false); len(got) != 0 {
+		t.Fatalf("Expected no notifications yet, got %+v", got)
+	}
+
+	reduced := vehicle.Clone()
+	reduced.Price = 9500
+	if err := store.UpdateVehicle(reduced); err != nil {
+		t.Fatalf("UpdateVehicle failed: %v", err)
+	}
+	// Still matching after another edit, so no second notification
+	edited := reduced.Clone()
+	edited.Mileage = 150000
+	if err := store.UpdateVehicle(edited); err != nil {
+		t.Fatalf("UpdateVehicle failed: %v", err)
+	}
+
+	notifications := m.Notifications("user-003", false)
+	if len(notifications) != 1 {
+		t.Fatalf("Expected one notification, got %+v", notifications)
+	}
+	n := notifications[0]
+	if n.SearchID != search.ID || n.Event != models.EventUpdated || n.VehicleID != vehicle.ID || n.Title != "2003 Honda Accord" || n.Price.Amount != 9500 {
+		t.Errorf("Unexpected notification %+v", n)
+	}
+	if got := m.Notifications("user-004", false); len(got) != 0 {
+		t.Errorf("Expected no notifications for another search, got %+v", got)
+	}
+
+	if err := m.MarkRead("user-004", n.ID); err != ErrNotificationNotFound {
+		t.Errorf("Expected ErrNotificationNotFound for another user, got %v", err)
+	}
+	if err := m.MarkRead("user-003", n.ID); err != nil {
+		t.Fatalf("MarkRead failed: %v", err)
+	}
+	if got := m.Notifications("user-003", true); len(got) != 0 {
+		t.Errorf("Expected no unread notifications, got %+v", got)
+	}
+
+	// Searches and notifications survive a restart
+	reopened, err := Open(path, repo, nil, logger)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if got := reopened.SavedSearches("user-003"); len(got) != 1 || got[0].Name != "Cheap sedans" {
+		t.Errorf("Expected the saved search after reopening, got %+v", got)
+	}
+	if got := reopened.Notifications("user-003", false); len(got) != 1 || !got[0].Read {
+		t.Errorf("Expected the read notification after reopening, got %+v", got)
+	}
+
+	if err := m.DeleteSavedSearch("user-004", search.ID); err != ErrSearchNotFound {
+		t.Errorf("Expected ErrSearchNotFound deleting another user's search, got %v", err)
+	}
+	if err := m.DeleteSavedSearch("user-003", search.ID); err != nil {
+		t.Errorf("DeleteSavedSearch failed: %v", err)
+	}
+}
+
+func TestSavedSearchesHideDrafts(t *testing.T) {
+	repo, logger := newTestStore(t)
+	m, _ := Open("", repo, nil, logger)
+	store := repository.Observe(repo, m)
+
+	m.CreateSavedSearch(&models.SavedSearch{UserID: "user-003", Name: "Hondas", Filter: models.VehicleFilter{Make: "honda"}})
+
+	vehicle := newAccord(9000)
+	vehicle.Status = models.StatusDraft
+	if err := store.CreateVehicle(vehicle); err != nil {
+		t.Fatalf("CreateVehicle failed: %v", err)
+	}
+	if got := m.Notifications("user-003", false); len(got) != 0 {
+		t.Fatalf("Expected no notification for a draft, got %+v", got)
+	}
+
+	published := vehicle.Clone()
+	if err := published.Transition(models.StatusAvailable, "user-002", "", vehicle.ListingDate); err != nil {
+		t.Fatalf("Transition failed: %v", err)
+	}
+	store.UpdateVehicle(published)
+	if got := m.Notifications("user-003", false); len(got) != 1 {
+		t.Errorf("Expected a notification once published, got %+v", got)
+	}
+}
+
+func TestTooManySavedSearches(t *testing.T) {
+	repo, logger := newTestStore(t)
+	m, _ := Open("", repo, nil, logger)
+
+	for i := 0; i < MaxSavedSearches; i++ {
+		if err := m.CreateSavedSearch(&models.SavedSearch{UserID: "user-003", Name: "search"}); err != nil {
+			t.Fatalf("CreateSavedSearch failed: %v", err)
+		}
+	}
+	if err := m.CreateSavedSearch(&models.SavedSearch{UserID: "user-003", Name: "one more"}); err != ErrTooManySearches {
+		t.Errorf("Expected ErrTooManySearches, got %v", err)
+	}
+	if err := m.CreateSavedSearch(&models.SavedSearch{UserID: "user-004", Name: "search"}); err != nil {
+		t.Errorf("Expected other users to be unaffected, got %v", err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		received = append(received, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	repo, logger := newTestStore(t)
+	m, _ := Open("", repo, nil, logger, NewWebhookNotifier(server.URL))
+	store := repository.Observe(repo, m)
+
+	m.CreateSavedSearch(&models.SavedSearch{UserID: "user-003", Name: "Hondas", Filter: models.VehicleFilter{Make: "Honda"}})
+	if err := store.CreateVehicle(newAccord(9000)); err != nil {
+		t.Fatalf("CreateVehicle failed: %v", err)
+	}
+
+	// Close delivers everything queued
+	m.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("Expected one webhook call, got %d", len(received))
+	}
+	user, _ := received[0]["user"].(map[string]interface{})
+	notification, _ := received[0]["notification"].(map[string]interface{})
+	if user["email"] != "james.smith@autostack.co.uk" || notification["event"] != models.EventListed {
+		t.Errorf("Unexpected webhook body %+v", received[0])
+	}
+}
@@ -0,0 +1,118 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// Notifier delivers notifications outside the in-app inbox, which always
+// receives them
+type Notifier interface {
+	// Name identifies the notifier in logs
+	Name() string
+	Notify(user *models.User, notification *models.Notification) error
+}
+
+// WebhookNotifier posts each notification as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name implements Notifier
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify posts {"user": {...}, "notification": {...}} and expects a 2xx
+// response
+func (n *WebhookNotifier) Notify(user *models.User, notification *models.Notification) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"user": map[string]string{
+			"id":    user.ID,
+			"email": user.Email,
+			"name":  user.Name,
+		},
+		"notification": notification,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier emails each notification through an SMTP server without
+// authentication, such as a local mail sink
+type SMTPNotifier struct {
+	addr string
+	from string
+	// send is smtp.SendMail, replaced in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPNotifier creates a notifier sending mail from the given address
+// through the server at addr (host:port)
+func NewSMTPNotifier(addr, from string) *SMTPNotifier {
+	return &SMTPNotifier{addr: addr, from: from, send: smtp.SendMail}
+}
+
+// Name implements Notifier
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Notify emails the user about the notification
+func (n *SMTPNotifier) Notify(user *models.User, notification *models.Notification) error {
+	if user.Email == "" {
+		return fmt.Errorf("user %s has no email address", user.ID)
+	}
+	return n.send(n.addr, nil, n.from, []string{user.Email}, n.message(user, notification))
+}
+
+// message formats the email for a notification
+func (n *SMTPNotifier) message(user *models.User, notification *models.Notification) []byte {
+	verb := "A new listing matches"
+	if notification.Event == models.EventUpdated {
+		verb = "An updated listing now matches"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.from)
+	fmt.Fprintf(&b, "To: %s\r\n", user.Email)
+	// The subject is built from user input, so encode it rather than let
+	// a line break start another header
+	subject := fmt.Sprintf("%s matches \"%s\"", notification.Title, notification.SearchName)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", notification.CreatedAt.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "Hello %s,\r\n\r\n", user.Name)
+	fmt.Fprintf(&b, "%s your saved search \"%s\":\r\n\r\n", verb, notification.SearchName)
+	fmt.Fprintf(&b, "%s - %.2f %s (vehicle %s)\r\n", notification.Title, notification.Price.Amount, notification.Price.Currency, notification.VehicleID)
+	return []byte(b.String())
+}
@@ -0,0 +1,74 @@
+package alerts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+func TestSMTPNotifier(t *testing.T) {
+	var sentTo []string
+	var sent string
+	n := NewSMTPNotifier("localhost:1025", "alerts@autostack.local")
+	n.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sentTo, sent = to, string(msg)
+		return nil
+	}
+
+	user := &models.User{ID: "user-003", Email: "james.smith@autostack.co.uk", Name: "James Smith"}
+	notification := &models.Notification{
+		SearchName: "Cheap sedans",
+		Event:      models.EventListed,
+		VehicleID:  "veh-052",
+		Title:      "2003 Honda Accord",
+		Price:      models.Money{Amount: 9000, Currency: "USD"},
+		CreatedAt:  time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+	}
+	if err := n.Notify(user, notification); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	if len(sentTo) != 1 || sentTo[0] != user.Email {
+		t.Errorf("Unexpected recipients %v", sentTo)
+	}
+	for _, want := range []string{
+		"To: james.smith@autostack.co.uk\r\n",
+		"Subject: 2003 Honda Accord matches \"Cheap sedans\"\r\n",
+		"2003 Honda Accord - 9000.00 USD (vehicle veh-052)",
+	} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("Expected the message to contain %q, got:\n%s", want, sent)
+		}
+	}
+
+	if err := n.Notify(&models.User{ID: "user-009"}, notification); err == nil {
+		t.Error("Expected an error for a user without an email address")
+	}
+
+	// A line break in the name cannot add headers
+	notification.SearchName = "Sedans\r\nBcc: everyone@example.com"
+	if err := n.Notify(user, notification); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	header, _, _ := strings.Cut(sent, "\r\n\r\n")
+	if strings.Contains(header, "\r\nBcc:") || !strings.Contains(header, "Subject: =?utf-8?q?") {
+		t.Errorf("Expected an encoded subject, got:\n%s", header)
+	}
+}
+
+func TestWebhookNotifierStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhookNotifier(server.URL).Notify(&models.User{ID: "user-003"}, &models.Notification{})
+	if err == nil {
+		t.Error("Expected an error for a failing webhook")
+	}
+}
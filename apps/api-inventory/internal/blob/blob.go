@@ -0,0 +1,70 @@
+// Package blob stores opaque files, such as uploaded images, by key. Keys
+// are slash-separated relative paths like "vehicles/veh-001/img-1a2b.jpg".
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Blob backends supported by New
+const (
+	BackendLocal = "local"
+)
+
+var (
+	// ErrNotFound is returned when no blob is stored under the key
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for a key that is empty, absolute or
+	// escapes the store with ".."
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Info describes a stored blob
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Store keeps blobs by key. Writes replace any blob stored under the key
+// and are atomic: readers see the old blob or the new one, never part of it.
+type Store interface {
+	// Put stores the content read from r under key
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the blob stored under key. The caller closes it.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error)
+	// Delete removes the blob stored under key
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures the blob backend
+type Config struct {
+	// Backend is BackendLocal, the default
+	Backend string
+	// Dir is the directory the local backend stores blobs in
+	Dir string
+}
+
+// New creates the store selected by the configuration
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocal(cfg.Dir)
+	default:
+		return nil, fmt.Errorf("unknown blob backend %q", cfg.Backend)
+	}
+}
+
+// checkKey rejects keys that do not name a file inside the store
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) ||
+		path.Clean(key) != key || key == "." || key == ".." || strings.HasPrefix(key, "../") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := New(Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	key := "vehicles/veh-001/img-1.jpg"
+	if err := store.Put(ctx, key, strings.NewReader("first")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := store.Put(ctx, key, strings.NewReader("second")); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	r, info, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "second" || info.Size != 6 || info.ModTime.IsZero() {
+		t.Errorf("Expected the replaced blob, got %q %+v", data, info)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	if _, _, err := store.Open(ctx, "vehicles/veh-001"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a directory not to open as a blob, got %v", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocal(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a/../../outside", "a//b", `a\b`, "."} {
+		if err := store.Put(ctx, key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected %q to be rejected, got %v", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside")); err == nil {
+		t.Error("Expected nothing to be written outside the store")
+	}
+}
+
+func TestNewRejectsUnknownBackend(t *testing.T) {
+	if _, err := New(Config{Backend: "s3", Dir: t.TempDir()}); err == nil {
+		t.Error("Expected an unknown backend to be rejected")
+	}
+}
@@ -0,0 +1,335 @@
+package datacheck
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Seed files checked by Check
+const (
+	UsersFile     = "users.json"
+	VehiclesFile  = "vehicles.json"
+	FavoritesFile = "favorites.json"
+	DealersFile   = "dealers.json"
+	ReviewsFile   = "dealer_reviews.json"
+	RatesFile     = "exchange_rates.json"
+)
+
+// KnownRoles are the roles the services grant access by
+var KnownRoles = []string{"user", "admin"}
+
+// countryPattern matches an ISO 3166-1 alpha-2 country code
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Check loads the seed files in dataPath and reports every problem found.
+// Unreadable files are reported too, so the report is always complete.
+func Check(dataPath string) *Report {
+	report := &Report{
+		DataPath: dataPath,
+		Records:  make(map[string]int),
+		Issues:   []Issue{},
+	}
+
+	users := checkUsers(report, dataPath)
+	dealers := checkDealers(report, dataPath)
+	vehicles := checkVehicles(report, dataPath, users, dealers)
+	checkFavorites(report, dataPath, users, vehicles)
+	checkReviews(report, dataPath, users, dealers, vehicles)
+	checkRates(report, dataPath)
+
+	report.sortIssues()
+	return report
+}
+
+// checkUsers checks users.json and returns the IDs of the users read
+func checkUsers(report *Report, dataPath string) map[string]bool {
+	ids := make(map[string]bool)
+	records, ok := readRecords[models.User](report, dataPath, UsersFile, true)
+	if !ok {
+		return ids
+	}
+
+	c := &fileChecker{report: report, file: UsersFile}
+	uniqueID := newUniqueIDs(c, "id")
+	uniqueEmail := newUniqueIDs(c, "email")
+	for _, rec := range records {
+		user, line := rec.value, rec.line
+		uniqueID.check(line, user.ID, user.ID, user.ID)
+		ids[user.ID] = true
+
+		uniqueEmail.check(line, user.ID, user.Email, strings.ToLower(strings.TrimSpace(user.Email)))
+		if user.Email != "" && !strings.Contains(user.Email, "@") {
+			c.errorf(line, user.ID, "email", "%q is not an email address", user.Email)
+		}
+
+		if _, err := bcrypt.Cost([]byte(user.Password)); err != nil {
+			c.errorf(line, user.ID, "password", "is not a bcrypt hash: %v", err)
+		}
+
+		if len(user.Roles) == 0 {
+			c.errorf(line, user.ID, "roles", "user has no roles")
+		}
+		for _, role := range user.Roles {
+			if !contains(KnownRoles, role) {
+				c.warnf(line, user.ID, "roles", "unknown role %q", role)
+			}
+		}
+
+		if !models.IsSupportedCurrency(user.PreferredCurrency) {
+			c.errorf(line, user.ID, "preferredCurrency", "%q must be one of %s",
+				user.PreferredCurrency, strings.Join(models.SupportedCurrencies, ", "))
+		}
+		if !countryPattern.MatchString(user.Country) {
+			c.warnf(line, user.ID, "country", "%q is not a two-letter country code", user.Country)
+		}
+		if user.CreatedAt.IsZero() {
+			c.warnf(line, user.ID, "createdAt", "is missing")
+		}
+
+		if prefs := user.Preferences; prefs != nil && len(prefs.PriceRange) > 0 {
+			r := prefs.PriceRange
+			if len(r) != 2 || r[0] < 0 || r[0] > r[1] {
+				c.errorf(line, user.ID, "preferences.priceRange", "must be [min, max] with 0 <= min <= max, got %v", r)
+			}
+		}
+	}
+	return ids
+}
+
+// checkDealers checks the optional dealers.json and returns the IDs of the
+// dealers read
+func checkDealers(report *Report, dataPath string) map[string]bool {
+	ids := make(map[string]bool)
+	records, ok := readRecords[models.Dealer](report, dataPath, DealersFile, false)
+	if !ok {
+		return ids
+	}
+
+	c := &fileChecker{report: report, file: DealersFile}
+	uniqueID := newUniqueIDs(c, "id")
+	for _, rec := range records {
+		dealer, line := rec.value, rec.line
+		uniqueID.check(line, dealer.ID, dealer.ID, dealer.ID)
+		ids[dealer.ID] = true
+
+		if strings.TrimSpace(dealer.Name) == "" {
+			c.errorf(line, dealer.ID, "name", "is required")
+		}
+		if email := dealer.Contact.Email; email != "" && !strings.Contains(email, "@") {
+			c.errorf(line, dealer.ID, "contact.email", "%q is not an email address", email)
+		}
+		if dealer.Rating != nil {
+			c.warnf(line, dealer.ID, "rating", "is computed from the reviews; the stored value is ignored")
+		}
+
+		if len(dealer.Locations) == 0 {
+			c.warnf(line, dealer.ID, "locations", "dealer has no locations")
+		}
+		for _, location := range dealer.Locations {
+			if strings.TrimSpace(location.City) == "" {
+				c.errorf(line, dealer.ID, "locations.city", "is required")
+			}
+			if !countryPattern.MatchString(location.Country) {
+				c.warnf(line, dealer.ID, "locations.country", "%q is not a two-letter country code", location.Country)
+			}
+		}
+	}
+	return ids
+}
+
+// checkVehicles checks vehicles.json against the listing rules and the
+// users and dealers read, and returns the vehicles read by ID
+func checkVehicles(report *Report, dataPath string, users, dealers map[string]bool) map[string]*models.Vehicle {
+	vehicles := make(map[string]*models.Vehicle)
+	records, ok := readRecords[models.Vehicle](report, dataPath, VehiclesFile, true)
+	if !ok {
+		return vehicles
+	}
+
+	c := &fileChecker{report: report, file: VehiclesFile}
+	uniqueID := newUniqueIDs(c, "id")
+	uniqueVIN := newUniqueIDs(c, "vin")
+	for _, rec := range records {
+		vehicle, line := rec.value, rec.line
+		uniqueID.check(line, vehicle.ID, vehicle.ID, vehicle.ID)
+		vehicles[vehicle.ID] = vehicle
+		if vehicle.VIN != "" {
+			uniqueVIN.check(line, vehicle.ID, vehicle.VIN, strings.ToUpper(strings.TrimSpace(vehicle.VIN)))
+		}
+
+		// Listing rules are errors; a VIN that does not match the make or
+		// year is only flagged, as on load
+		checkVIN := true
+		var verr *models.ValidationError
+		if err := vehicle.Validate(); errors.As(err, &verr) {
+			for _, fe := range verr.Errors {
+				c.errorf(line, vehicle.ID, fe.Field, "%s", fe.Message)
+				if fe.Field == "vin" {
+					checkVIN = false
+				}
+			}
+		}
+		if checkVIN {
+			if err := vehicle.CheckVIN(); errors.As(err, &verr) {
+				for _, fe := range verr.Errors {
+					c.warnf(line, vehicle.ID, fe.Field, "%s", fe.Message)
+				}
+			}
+		}
+
+		if vehicle.Status == "" {
+			c.warnf(line, vehicle.ID, "status", "is missing")
+		}
+		if vehicle.ListingDate.IsZero() {
+			c.warnf(line, vehicle.ID, "listingDate", "is missing")
+		}
+
+		// The dealer rating is computed from the dealer's reviews
+		switch {
+		case vehicle.DealerID == "":
+			c.warnf(line, vehicle.ID, "dealerId", "is missing, so the vehicle has no dealer rating")
+		case !dealers[vehicle.DealerID]:
+			c.errorf(line, vehicle.ID, "dealerId", "unknown dealer %q", vehicle.DealerID)
+		}
+		if vehicle.DealerRating != 0 {
+			c.warnf(line, vehicle.ID, "dealerRating", "is computed from the dealer's reviews; the stored value is ignored")
+		}
+
+		if res := vehicle.Reservation; res != nil {
+			if vehicle.Status != models.StatusReserved {
+				c.errorf(line, vehicle.ID, "reservation", "is set on a vehicle that is %s", vehicle.Status)
+			}
+			if res.VehicleID != vehicle.ID {
+				c.errorf(line, vehicle.ID, "reservation.vehicleId", "%q does not match the vehicle", res.VehicleID)
+			}
+			if !users[res.UserID] {
+				c.errorf(line, vehicle.ID, "reservation.userId", "unknown user %q", res.UserID)
+			}
+		}
+	}
+	return vehicles
+}
+
+// checkFavorites checks the optional favorites.json against the users and
+// vehicles read
+func checkFavorites(report *Report, dataPath string, users map[string]bool, vehicles map[string]*models.Vehicle) {
+	records, ok := readRecords[models.Favorite](report, dataPath, FavoritesFile, false)
+	if !ok {
+		return
+	}
+
+	c := &fileChecker{report: report, file: FavoritesFile}
+	seen := make(map[string]int)
+	for _, rec := range records {
+		favorite, line := rec.value, rec.line
+		key := favorite.UserID + "/" + favorite.VehicleID
+		if first, dup := seen[key]; dup {
+			c.warnf(line, key, "", "duplicates the favorite on line %d, which it replaces", first)
+		}
+		seen[key] = line
+
+		if !users[favorite.UserID] {
+			c.errorf(line, key, "userId", "unknown user %q", favorite.UserID)
+		}
+		// Favorites outlive the vehicles they refer to
+		if vehicles[favorite.VehicleID] == nil {
+			c.warnf(line, key, "vehicleId", "vehicle %q is not listed", favorite.VehicleID)
+		}
+		if !models.IsSupportedCurrency(favorite.Price.Currency) {
+			c.errorf(line, key, "price.currency", "%q is not a supported currency", favorite.Price.Currency)
+		}
+	}
+}
+
+// checkReviews checks the optional dealer_reviews.json against the users,
+// dealers and vehicles read
+func checkReviews(report *Report, dataPath string, users, dealers map[string]bool, vehicles map[string]*models.Vehicle) {
+	records, ok := readRecords[models.DealerReview](report, dataPath, ReviewsFile, false)
+	if !ok {
+		return
+	}
+
+	c := &fileChecker{report: report, file: ReviewsFile}
+	seen := make(map[string]int)
+	for _, rec := range records {
+		review, line := rec.value, rec.line
+		key := review.DealerID + "/" + review.UserID
+		if first, dup := seen[key]; dup {
+			c.warnf(line, key, "", "duplicates the review on line %d, which it replaces", first)
+		}
+		seen[key] = line
+
+		if !dealers[review.DealerID] {
+			c.errorf(line, key, "dealerId", "unknown dealer %q", review.DealerID)
+		}
+		if !users[review.UserID] {
+			c.errorf(line, key, "userId", "unknown user %q", review.UserID)
+		}
+
+		var verr *models.ValidationError
+		if err := review.Validate(); errors.As(err, &verr) {
+			for _, fe := range verr.Errors {
+				c.errorf(line, key, fe.Field, "%s", fe.Message)
+			}
+		}
+
+		// The verifying vehicle may since have been deleted
+		switch vehicle := vehicles[review.VehicleID]; {
+		case review.VehicleID == "":
+			c.warnf(line, key, "vehicleId", "is missing, so the review is unverified")
+		case vehicle == nil:
+			c.warnf(line, key, "vehicleId", "vehicle %q is not listed", review.VehicleID)
+		case vehicle.DealerID != review.DealerID:
+			c.errorf(line, key, "vehicleId", "vehicle %q is listed by %q, not this dealer", review.VehicleID, vehicle.DealerID)
+		}
+		if review.CreatedAt.IsZero() {
+			c.warnf(line, key, "createdAt", "is missing")
+		}
+	}
+}
+
+// checkRates checks that exchange_rates.json loads and covers every
+// supported currency
+func checkRates(report *Report, dataPath string) {
+	c := &fileChecker{report: report, file: RatesFile}
+
+	rates, err := exchange.Open(filepath.Join(dataPath, RatesFile))
+	if err != nil {
+		c.errorf(0, "", "", "%v", err)
+		return
+	}
+
+	quoted := make(map[string]bool)
+	all := rates.Rates()
+	for _, rate := range all {
+		if quoted[rate.Currency] {
+			continue
+		}
+		quoted[rate.Currency] = true
+		if !models.IsSupportedCurrency(rate.Currency) {
+			c.warnf(0, "", "currency", "%s is not a supported currency", rate.Currency)
+		}
+	}
+	report.Records[RatesFile] = len(all)
+
+	for _, currency := range models.SupportedCurrencies {
+		if currency != exchange.BaseCurrency && !quoted[currency] {
+			c.warnf(0, "", "currency", "%s has no rate, so %s prices are never converted", currency, currency)
+		}
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package datacheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// validHash is a bcrypt hash of "password"
+const validHash = "$2a$10$eLTE.2EIttvwXEG3cNc9c.fZmt5km2cO5JmYvUAll.DuobqcS.hQW"
+
+func writeSeed(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestCheckShippedSeed(t *testing.T) {
+	report := Check(filepath.Join("..", "..", "..", "..", "data", "seed"))
+
+	if !report.OK() {
+		t.Errorf("Expected the shipped seed data to pass, got %+v", report.Issues)
+	}
+	if report.Records[UsersFile] == 0 || report.Records[VehiclesFile] == 0 {
+		t.Errorf("Expected records to be counted, got %v", report.Records)
+	}
+}
+
+func TestCheckFindsProblems(t *testing.T) {
+	dir := writeSeed(t, map[string]string{
+		UsersFile: `[
+  {"id": "user-001", "email": "a@example.com", "password": "` + validHash + `", "country": "US",
+   "preferredCurrency": "USD", "roles": ["user"], "createdAt": "2024-01-01T00:00:00Z"},
+  {"id": "user-001", "email": "A@example.com", "password": "secret", "country": "US",
+   "preferredCurrency": "XYZ", "roles": [], "createdAt": "2024-01-01T00:00:00Z",
+   "preferences": {"priceRange": [5000, 1000]}}
+]`,
+		VehiclesFile: `[
+  {"id": "veh-001", "vin": "WAUZZZ8V8NA123456", "year": 2023, "make": "Audi", "model": "Q7",
+   "price": 61990, "currency": "USD", "status": "available", "listingDate": "2024-01-01T00:00:00Z", "dealerId": "dlr-001"},
+  {"id": "veh-002", "vin": "wauzzz8v8na123456", "year": 2023, "make": "Audi", "model": "Q7",
+   "mileage": -10, "price": 100, "currency": "ZZZ", "status": "available", "listingDate": "2024-01-01T00:00:00Z",
+   "reservation": {"vehicleId": "veh-002", "userId": "user-999"}},
+  {"id": "veh-003", "year": "2020"},
+  {"id": "veh-004", "vin": "WAUZZZ8V8NA123457", "year": 2023, "make": "Audi", "model": "Q7",
+   "currency": "USD", "status": "available", "listingDate": "2024-01-01T00:00:00Z", "colour": "red",
+   "dealerId": "dlr-404"}
+]`,
+		FavoritesFile: `[{"userId": "user-404", "vehicleId": "veh-001", "price": {"amount": 1, "currency": "USD"}}]`,
+		DealersFile: `[
+  {"id": "dlr-001", "name": "Bay Motors", "locations": [{"city": "Oakland", "country": "US"}]},
+  {"id": "dlr-002", "name": " ", "locations": []}
+]`,
+		ReviewsFile: `[
+  {"dealerId": "dlr-001", "userId": "user-001", "vehicleId": "veh-001", "rating": 5, "createdAt": "2024-02-01T00:00:00Z"},
+  {"dealerId": "dlr-002", "userId": "user-404", "vehicleId": "veh-001", "rating": 9, "createdAt": "2024-02-01T00:00:00Z"}
+]`,
+		RatesFile: `[{"currency": "GBP", "date": "2024-01-01", "rate": 0.79}]`,
+	})
+
+	report := Check(dir)
+	if report.OK() {
+		t.Fatal("Expected the check to fail")
+	}
+
+	type key struct {
+		file     string
+		line     int
+		field    string
+		severity string
+	}
+	found := make(map[key]bool)
+	for _, issue := range report.Issues {
+		found[key{issue.File, issue.Line, issue.Field, issue.Severity}] = true
+	}
+
+	want := []key{
+		{UsersFile, 4, "id", SeverityError},
+		{UsersFile, 4, "email", SeverityError},
+		{UsersFile, 4, "password", SeverityError},
+		{UsersFile, 4, "roles", SeverityError},
+		{UsersFile, 4, "preferredCurrency", SeverityError},
+		{UsersFile, 4, "preferences.priceRange", SeverityError},
+		{VehiclesFile, 4, "vin", SeverityError},
+		{VehiclesFile, 4, "mileage", SeverityError},
+		{VehiclesFile, 4, "currency", SeverityError},
+		{VehiclesFile, 4, "reservation", SeverityError},
+		{VehiclesFile, 4, "reservation.userId", SeverityError},
+		{VehiclesFile, 7, "year", SeverityError},
+		{VehiclesFile, 8, "", SeverityWarning},
+		{VehiclesFile, 8, "dealerId", SeverityError},
+		{VehiclesFile, 4, "dealerId", SeverityWarning},
+		{FavoritesFile, 1, "userId", SeverityError},
+		{DealersFile, 3, "name", SeverityError},
+		{DealersFile, 3, "locations", SeverityWarning},
+		{ReviewsFile, 3, "userId", SeverityError},
+		{ReviewsFile, 3, "rating", SeverityError},
+		{ReviewsFile, 3, "vehicleId", SeverityError},
+		{RatesFile, 0, "currency", SeverityWarning},
+	}
+	for _, k := range want {
+		if !found[k] {
+			t.Errorf("Expected a %s on %s line %d field %q, got %+v", k.severity, k.file, k.line, k.field, report.Issues)
+		}
+	}
+	if found[key{UsersFile, 2, "id", SeverityError}] || found[key{VehiclesFile, 2, "vin", SeverityError}] {
+		t.Error("Expected the first of two duplicates to pass")
+	}
+	if found[key{ReviewsFile, 2, "vehicleId", SeverityError}] || found[key{VehiclesFile, 2, "dealerId", SeverityWarning}] {
+		t.Error("Expected the vehicle's own dealer to pass")
+	}
+	if report.Records[VehiclesFile] != 3 {
+		t.Errorf("Expected the undecodable vehicle to be left out, got %d", report.Records[VehiclesFile])
+	}
+}
+
+func TestCheckReportsUnreadableFiles(t *testing.T) {
+	dir := writeSeed(t, map[string]string{
+		UsersFile: `{"id": "user-001"}`,
+		RatesFile: `[{"currency": "GBP", "date": "yesterday", "rate": 0.79}]`,
+	})
+
+	report := Check(dir)
+	files := make(map[string]bool)
+	for _, issue := range report.Issues {
+		if issue.Severity == SeverityError && issue.Line == 0 {
+			files[issue.File] = true
+		}
+	}
+	for _, file := range []string{UsersFile, VehiclesFile, RatesFile} {
+		if !files[file] {
+			t.Errorf("Expected %s to be reported, got %+v", file, report.Issues)
+		}
+	}
+	if files[FavoritesFile] {
+		t.Error("Expected a missing favorites file to be fine")
+	}
+}
@@ -0,0 +1,200 @@
+// Package datacheck lints the JSON seed files in DATA_PATH. It loads them
+// with the model types and checks the rules the service relies on, such as
+// unique IDs and VINs, supported currencies, bcrypt password hashes and
+// references between files.
+package datacheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Issue severities. Only errors fail a check; warnings flag data the
+// service loads but that is probably wrong.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found in a seed file
+type Issue struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	// Line is the line the record starts on, or 0 for the file as a whole
+	Line int `json:"line,omitempty"`
+	// Record is the ID of the record, when it has one
+	Record  string `json:"record,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of a check
+type Report struct {
+	DataPath string `json:"dataPath"`
+	// Records counts the records read from each file
+	Records  map[string]int `json:"records"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+	Issues   []Issue        `json:"issues"`
+}
+
+// OK reports whether the check found no errors
+func (r *Report) OK() bool {
+	return r.Errors == 0
+}
+
+// Summary describes the report in one line
+func (r *Report) Summary() string {
+	return fmt.Sprintf("%d errors and %d warnings in %s", r.Errors, r.Warnings, r.DataPath)
+}
+
+// add records an issue
+func (r *Report) add(issue Issue) {
+	if issue.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// sortIssues orders the issues by file and line
+func (r *Report) sortIssues() {
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		if r.Issues[i].File != r.Issues[j].File {
+			return r.Issues[i].File < r.Issues[j].File
+		}
+		return r.Issues[i].Line < r.Issues[j].Line
+	})
+}
+
+// record is a decoded record of a seed file
+type record[T any] struct {
+	line  int
+	value *T
+}
+
+// fileChecker reports the issues of one seed file
+type fileChecker struct {
+	report *Report
+	file   string
+}
+
+// issue records a problem with the record on the given line
+func (c *fileChecker) issue(severity string, line int, id, field, message string) {
+	c.report.add(Issue{
+		Severity: severity,
+		File:     c.file,
+		Line:     line,
+		Record:   id,
+		Field:    field,
+		Message:  message,
+	})
+}
+
+// errorf records an error with the record on the given line
+func (c *fileChecker) errorf(line int, id, field, format string, args ...interface{}) {
+	c.issue(SeverityError, line, id, field, fmt.Sprintf(format, args...))
+}
+
+// warnf records a warning about the record on the given line
+func (c *fileChecker) warnf(line int, id, field, format string, args ...interface{}) {
+	c.issue(SeverityWarning, line, id, field, fmt.Sprintf(format, args...))
+}
+
+// uniqueIDs reports records whose ID is missing or already used
+type uniqueIDs struct {
+	checker *fileChecker
+	field   string
+	seen    map[string]int
+}
+
+func newUniqueIDs(c *fileChecker, field string) *uniqueIDs {
+	return &uniqueIDs{checker: c, field: field, seen: make(map[string]int)}
+}
+
+// check reports the value on the given line when it is empty or was seen
+// before. key is the value compared; it may be folded to ignore case.
+func (u *uniqueIDs) check(line int, id, value, key string) {
+	if strings.TrimSpace(value) == "" {
+		u.checker.errorf(line, id, u.field, "is required")
+		return
+	}
+	if first, ok := u.seen[key]; ok {
+		u.checker.errorf(line, id, u.field, "%q duplicates the record on line %d", value, first)
+		return
+	}
+	u.seen[key] = line
+}
+
+// readRecords decodes a seed file holding a JSON array of T. Records that
+// do not decode are reported and left out; unknown fields are warned about.
+// A missing file is reported as an error when required.
+func readRecords[T any](report *Report, dataPath, file string, required bool) ([]record[T], bool) {
+	c := &fileChecker{report: report, file: file}
+
+	data, err := os.ReadFile(filepath.Join(dataPath, file))
+	if errors.Is(err, os.ErrNotExist) {
+		if required {
+			c.errorf(0, "", "", "file is missing")
+		}
+		return nil, false
+	}
+	if err != nil {
+		c.errorf(0, "", "", "cannot be read: %v", err)
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		c.errorf(0, "", "", "must hold a JSON array")
+		return nil, false
+	}
+
+	var records []record[T]
+	for dec.More() {
+		start := int(dec.InputOffset())
+		for start < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[start])) {
+			start++
+		}
+		line := bytes.Count(data[:start], []byte("\n")) + 1
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			c.errorf(line, "", "", "is not valid JSON: %v", err)
+			report.Records[file] = len(records)
+			return records, true
+		}
+
+		value := new(T)
+		if err := json.Unmarshal(raw, value); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				c.errorf(line, "", typeErr.Field, "must be a %s, got %s", typeErr.Type, typeErr.Value)
+			} else {
+				c.errorf(line, "", "", "cannot be decoded: %v", err)
+			}
+			continue
+		}
+
+		strict := json.NewDecoder(bytes.NewReader(raw))
+		strict.DisallowUnknownFields()
+		if err := strict.Decode(new(T)); err != nil && strings.HasPrefix(err.Error(), "json: unknown field") {
+			c.warnf(line, "", "", "has an %s, which is ignored", strings.TrimPrefix(err.Error(), "json: "))
+		}
+
+		records = append(records, record[T]{line: line, value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		c.errorf(0, "", "", "is not valid JSON: %v", err)
+	}
+
+	report.Records[file] = len(records)
+	return records, true
+}
@@ -0,0 +1,236 @@
+// Package exchange holds dated currency exchange rates and converts prices
+// between currencies.
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/jsonfile"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// BaseCurrency is the currency every rate is quoted against
+const BaseCurrency = "USD"
+
+// dateLayout is the format of rate dates
+const dateLayout = "2006-01-02"
+
+// ErrInvalidRate is returned when an updated rate is malformed
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate is the value of one BaseCurrency unit in Currency from Date onwards
+type Rate struct {
+	Currency string `json:"currency"`
+	// Date is the day the rate takes effect, formatted YYYY-MM-DD
+	Date string  `json:"date"`
+	Rate float64 `json:"rate"`
+}
+
+// datedRate is a Rate with its date parsed
+type datedRate struct {
+	date time.Time
+	rate float64
+}
+
+// Table is a set of dated exchange rates, optionally backed by a JSON file
+// that updates are written to. It is safe for concurrent use.
+type Table struct {
+	mu   sync.RWMutex
+	path string
+	// rates holds each currency's rates, oldest first
+	rates map[string][]datedRate
+}
+
+// NewTable creates an empty table that is not backed by a file
+func NewTable() *Table {
+	return &Table{rates: make(map[string][]datedRate)}
+}
+
+// Open loads the rates stored at path. A missing file gives an empty table;
+// it is created by the first update.
+func Open(path string) (*Table, error) {
+	return OpenWithSeed("", path)
+}
+
+// OpenWithSeed loads the rates at seedPath, which is only ever read, and
+// the rates stored at path on top of them. Updates are written to path, so
+// the seed file can sit on a read-only volume; with an empty path they are
+// only kept in memory. Missing files give no rates.
+func OpenWithSeed(seedPath, path string) (*Table, error) {
+	t := NewTable()
+	t.path = path
+
+	for _, file := range []string{seedPath, path} {
+		if file == "" {
+			continue
+		}
+		if err := t.load(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// load merges the rates stored in a file into the table
+func (t *Table) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var rates []Rate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	merged, err := merge(t.rates, rates)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", filepath.Base(path), err)
+	}
+	t.rates = merged
+
+	return nil
+}
+
+// Rates returns every rate ordered by currency and date
+func (t *Table) Rates() []Rate {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return flatten(t.rates)
+}
+
+// Update adds rates, replacing any existing rate for the same currency and
+// date. When the table is backed by a file the result is saved first and
+// the table is left unchanged if saving fails.
+func (t *Table) Update(rates []Rate) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	merged, err := merge(t.rates, rates)
+	if err != nil {
+		return err
+	}
+	if t.path != "" {
+		if err := jsonfile.WriteAtomic(t.path, flatten(merged)); err != nil {
+			return fmt.Errorf("failed to save exchange rates: %w", err)
+		}
+	}
+	t.rates = merged
+
+	return nil
+}
+
+// Convert converts amount between currencies at today's rates. It reports
+// false when either currency has no rate.
+func (t *Table) Convert(amount float64, from, to string) (float64, bool) {
+	return t.ConvertAt(amount, from, to, time.Now())
+}
+
+// ConvertAt converts amount between currencies at the rates in effect at
+// the given time. Before a currency's first rate the earliest rate is used.
+func (t *Table) ConvertAt(amount float64, from, to string, at time.Time) (float64, bool) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, true
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	fromRate, ok := t.rateAt(from, at)
+	if !ok {
+		return 0, false
+	}
+	toRate, ok := t.rateAt(to, at)
+	if !ok {
+		return 0, false
+	}
+
+	return amount / fromRate * toRate, true
+}
+
+// rateAt returns the value of one BaseCurrency unit in currency at the
+// given time
+func (t *Table) rateAt(currency string, at time.Time) (float64, bool) {
+	if currency == BaseCurrency {
+		return 1, true
+	}
+
+	rates := t.rates[currency]
+	if len(rates) == 0 {
+		return 0, false
+	}
+	// The first rate taking effect after at, so the one before is in effect
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].date.After(at) })
+	if i == 0 {
+		return rates[0].rate, true
+	}
+	return rates[i-1].rate, true
+}
+
+// merge returns a copy of existing with rates added, validating each one
+func merge(existing map[string][]datedRate, rates []Rate) (map[string][]datedRate, error) {
+	merged := make(map[string][]datedRate, len(existing))
+	for currency, list := range existing {
+		merged[currency] = append([]datedRate(nil), list...)
+	}
+
+	for _, rate := range rates {
+		currency := strings.ToUpper(strings.TrimSpace(rate.Currency))
+		if currency == BaseCurrency {
+			return nil, fmt.Errorf("%w: rates are quoted against %s and cannot be set for it", ErrInvalidRate, BaseCurrency)
+		}
+		if !models.IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("%w: unsupported currency %q", ErrInvalidRate, rate.Currency)
+		}
+		date, err := time.Parse(dateLayout, rate.Date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date %q must be YYYY-MM-DD", ErrInvalidRate, rate.Date)
+		}
+		if rate.Rate <= 0 {
+			return nil, fmt.Errorf("%w: rate for %s on %s must be positive", ErrInvalidRate, currency, rate.Date)
+		}
+
+		list := merged[currency]
+		i := sort.Search(len(list), func(i int) bool { return !list[i].date.Before(date) })
+		if i < len(list) && list[i].date.Equal(date) {
+			list[i].rate = rate.Rate
+			continue
+		}
+		list = append(list, datedRate{})
+		copy(list[i+1:], list[i:])
+		list[i] = datedRate{date: date, rate: rate.Rate}
+		merged[currency] = list
+	}
+
+	return merged, nil
+}
+
+// flatten lists the rates ordered by currency and date
+func flatten(rates map[string][]datedRate) []Rate {
+	currencies := make([]string, 0, len(rates))
+	for currency := range rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	list := []Rate{}
+	for _, currency := range currencies {
+		for _, r := range rates[currency] {
+			list = append(list, Rate{Currency: currency, Date: r.date.Format(dateLayout), Rate: r.rate})
+		}
+	}
+	return list
+}
@@ -0,0 +1,169 @@
+package exchange
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
+
+func TestConvertAt(t *testing.T) {
+	table := NewTable()
+	if err := table.Update([]Rate{
+		{Currency: "GBP", Date: "2024-01-01", Rate: 0.8},
+		{Currency: "GBP", Date: "2024-07-01", Rate: 0.75},
+		{Currency: "EUR", Date: "2024-01-01", Rate: 0.9},
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	tests := []struct {
+		amount   float64
+		from, to string
+		at       string
+		expected float64
+	}{
+		{100, "USD", "GBP", "2024-03-01", 80},
+		{100, "USD", "GBP", "2024-07-01", 75},
+		// Before the first rate the earliest one applies
+		{100, "USD", "GBP", "2023-01-01", 80},
+		{80, "gbp", "usd", "2024-03-01", 100},
+		{80, "GBP", "EUR", "2024-03-01", 90},
+		{42, "EUR", "EUR", "2024-03-01", 42},
+	}
+	for _, tt := range tests {
+		got, ok := table.ConvertAt(tt.amount, tt.from, tt.to, date(tt.at))
+		if !ok || math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("ConvertAt(%v, %s, %s, %s) = %v, %v; expected %v", tt.amount, tt.from, tt.to, tt.at, got, ok, tt.expected)
+		}
+	}
+
+	if _, ok := table.Convert(100, "USD", "AUD"); ok {
+		t.Error("Expected no conversion without an AUD rate")
+	}
+}
+
+func TestUpdateValidates(t *testing.T) {
+	table := NewTable()
+	invalid := [][]Rate{
+		{{Currency: "USD", Date: "2024-01-01", Rate: 1}},
+		{{Currency: "JPY", Date: "2024-01-01", Rate: 150}},
+		{{Currency: "GBP", Date: "01/01/2024", Rate: 0.8}},
+		{{Currency: "GBP", Date: "2024-01-01", Rate: 0}},
+		// One bad rate rejects the whole update
+		{{Currency: "EUR", Date: "2024-01-01", Rate: 0.9}, {Currency: "GBP", Date: "2024-01-01", Rate: -1}},
+	}
+	for _, rates := range invalid {
+		if err := table.Update(rates); !errors.Is(err, ErrInvalidRate) {
+			t.Errorf("Update(%+v) error = %v, expected ErrInvalidRate", rates, err)
+		}
+	}
+	if rates := table.Rates(); len(rates) != 0 {
+		t.Errorf("Expected no rates after rejected updates, got %+v", rates)
+	}
+}
+
+func TestUpdatePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exchange_rates.json")
+
+	table, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if len(table.Rates()) != 0 {
+		t.Fatal("Expected an empty table for a missing file")
+	}
+
+	if err := table.Update([]Rate{
+		{Currency: "gbp", Date: "2024-07-01", Rate: 0.75},
+		{Currency: "GBP", Date: "2024-01-01", Rate: 0.8},
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	// Replaces the rate of the same day
+	if err := table.Update([]Rate{{Currency: "GBP", Date: "2024-01-01", Rate: 0.79}}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	rates := reopened.Rates()
+	if len(rates) != 2 || rates[0] != (Rate{Currency: "GBP", Date: "2024-01-01", Rate: 0.79}) || rates[1].Date != "2024-07-01" {
+		t.Errorf("Unexpected rates after reopening: %+v", rates)
+	}
+
+	// A failed save leaves the table unchanged
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Update([]Rate{{Currency: "EUR", Date: "2024-01-01", Rate: 0.9}}); err == nil {
+		t.Error("Expected the save to fail")
+	}
+	if len(reopened.Rates()) != 2 {
+		t.Error("Expected the failed update to be discarded")
+	}
+}
+
+func TestOpenWithSeedLeavesSeedAlone(t *testing.T) {
+	seedPath := filepath.Join(t.TempDir(), "exchange_rates.json")
+	seed := []byte(`[{"currency": "GBP", "date": "2024-01-01", "rate": 0.8}]`)
+	if err := os.WriteFile(seedPath, seed, 0o444); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "exchange_rates.json")
+
+	table, err := OpenWithSeed(seedPath, path)
+	if err != nil {
+		t.Fatalf("OpenWithSeed failed: %v", err)
+	}
+	if err := table.Update([]Rate{{Currency: "GBP", Date: "2024-01-01", Rate: 0.79}}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if data, _ := os.ReadFile(seedPath); string(data) != string(seed) {
+		t.Errorf("Expected the seed file to be left alone, got %s", data)
+	}
+
+	// Stored rates win over the seed rates of the same day
+	reopened, err := OpenWithSeed(seedPath, path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	if rates := reopened.Rates(); len(rates) != 1 || rates[0].Rate != 0.79 {
+		t.Errorf("Unexpected rates after reopening: %+v", rates)
+	}
+
+	// Without a path updates are only kept in memory
+	inMemory, err := OpenWithSeed(seedPath, "")
+	if err != nil {
+		t.Fatalf("OpenWithSeed failed: %v", err)
+	}
+	if err := inMemory.Update([]Rate{{Currency: "EUR", Date: "2024-01-01", Rate: 0.9}}); err != nil {
+		t.Errorf("Expected an in-memory update to succeed, got %v", err)
+	}
+}
+
+func TestSeedRates(t *testing.T) {
+	table, err := Open(filepath.Join("..", "..", "..", "..", "data", "seed", "exchange_rates.json"))
+	if err != nil {
+		t.Fatalf("Failed to load seed rates: %v", err)
+	}
+	for _, currency := range models.SupportedCurrencies {
+		if _, ok := table.Convert(1, currency, BaseCurrency); !ok {
+			t.Errorf("Expected a rate for %s", currency)
+		}
+	}
+}
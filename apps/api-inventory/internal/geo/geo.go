@@ -0,0 +1,83 @@
+// Package geo geocodes vehicle locations with an offline gazetteer and
+// measures distances between coordinates.
+package geo
+
+import (
+	_ "embed"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+//go:embed gazetteer.json
+var gazetteerJSON []byte
+
+// place is a gazetteer entry
+type place struct {
+	City    string  `json:"city"`
+	Region  string  `json:"region"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+}
+
+// gazetteer indexes the places by country and city. A city name can occur
+// in several regions of a country, so each key holds every match.
+var gazetteer = loadGazetteer()
+
+func loadGazetteer() map[string][]place {
+	var places []place
+	if err := json.Unmarshal(gazetteerJSON, &places); err != nil {
+		panic("geo: invalid gazetteer: " + err.Error())
+	}
+
+	index := make(map[string][]place, len(places))
+	for _, p := range places {
+		key := placeKey(p.Country, p.City)
+		index[key] = append(index[key], p)
+	}
+	return index
+}
+
+func placeKey(country, city string) string {
+	return strings.ToLower(strings.TrimSpace(country)) + "|" + strings.ToLower(strings.TrimSpace(city))
+}
+
+// Geocode resolves a location string such as "San Francisco, CA" in the
+// given country. The region after the comma picks between cities with the
+// same name; if it does not match any of them the city must be unambiguous.
+func Geocode(location, country string) (models.GeoPoint, bool) {
+	city, region, _ := strings.Cut(location, ",")
+	candidates := gazetteer[placeKey(country, city)]
+
+	region = strings.TrimSpace(region)
+	for _, p := range candidates {
+		if strings.EqualFold(p.Region, region) {
+			return models.GeoPoint{Lat: p.Lat, Lng: p.Lng}, true
+		}
+	}
+	if len(candidates) == 1 {
+		return models.GeoPoint{Lat: candidates[0].Lat, Lng: candidates[0].Lng}, true
+	}
+
+	return models.GeoPoint{}, false
+}
+
+// DistanceKm returns the great-circle distance between two points
+func DistanceKm(a, b models.GeoPoint) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat := lat2 - lat1
+	dLng := radians(b.Lng - a.Lng)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
@@ -0,0 +1,53 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+func TestGeocode(t *testing.T) {
+	tests := []struct {
+		location string
+		country  string
+		lat, lng float64
+		found    bool
+	}{
+		{"San Francisco, CA", "US", 37.7749, -122.4194, true},
+		{"Perth, WA", "AU", -31.9505, 115.8605, true},
+		{"Seattle, WA", "US", 47.6062, -122.3321, true},
+		{"  london , England", "gb", 51.5074, -0.1278, true},
+		// The region is ignored when the city name is unambiguous
+		{"Munich", "DE", 48.1351, 11.5820, true},
+		// Perth is not in the United States
+		{"Perth, WA", "US", 0, 0, false},
+		{"Atlantis", "US", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		point, found := Geocode(tt.location, tt.country)
+		if found != tt.found {
+			t.Errorf("Geocode(%q, %q) found = %v, expected %v", tt.location, tt.country, found, tt.found)
+			continue
+		}
+		if found && (point.Lat != tt.lat || point.Lng != tt.lng) {
+			t.Errorf("Geocode(%q, %q) = %+v, expected %v,%v", tt.location, tt.country, point, tt.lat, tt.lng)
+		}
+	}
+}
+
+func TestDistanceKm(t *testing.T) {
+	sf := models.GeoPoint{Lat: 37.7749, Lng: -122.4194}
+	la := models.GeoPoint{Lat: 34.0522, Lng: -118.2437}
+
+	if d := DistanceKm(sf, la); math.Abs(d-559) > 2 {
+		t.Errorf("Expected about 559 km from San Francisco to Los Angeles, got %.1f", d)
+	}
+	if d := DistanceKm(sf, sf); d != 0 {
+		t.Errorf("Expected no distance to the same point, got %f", d)
+	}
+	if d := DistanceKm(sf, la); d != DistanceKm(la, sf) {
+		t.Error("Expected distance to be symmetric")
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminHandler handles operational requests for administrators
+type AdminHandler struct {
+	reloader repository.Reloader
+	logger   *logrus.Logger
+}
+
+// NewAdminHandler creates a new admin handler. reloader may be nil when the
+// storage backend cannot reload seed data.
+func NewAdminHandler(reloader repository.Reloader, logger *logrus.Logger) *AdminHandler {
+	return &AdminHandler{
+		reloader: reloader,
+		logger:   logger,
+	}
+}
+
+// HandleReload reloads the seed data files and returns the reload status
+func (h *AdminHandler) HandleReload(w http.ResponseWriter, r *http.Request) {
+	if h.reloader == nil {
+		http.Error(w, "Reload is not supported by the storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	status := http.StatusOK
+	response := map[string]interface{}{}
+	if err := h.reloader.Reload(); err != nil {
+		// The previous data is still being served
+		status = http.StatusUnprocessableEntity
+		response["error"] = err.Error()
+	} else {
+		h.logger.Info("Seed data reloaded by admin request")
+	}
+	response["data"] = h.reloader.ReloadStatus()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleReloadStatus returns the reload counters
+func (h *AdminHandler) HandleReloadStatus(w http.ResponseWriter, r *http.Request) {
+	if h.reloader == nil {
+		http.Error(w, "Reload is not supported by the storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": h.reloader.ReloadStatus(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
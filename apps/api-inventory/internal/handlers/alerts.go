@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/alerts"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxSearchNameLength is the longest saved search name accepted
+const maxSearchNameLength = 100
+
+// SavedSearchHandler serves the caller's saved searches and notifications
+type SavedSearchHandler struct {
+	alerts *alerts.Manager
+	users  middleware.UserLookup
+	logger *logrus.Logger
+}
+
+// NewSavedSearchHandler creates a new saved search handler
+func NewSavedSearchHandler(manager *alerts.Manager, users middleware.UserLookup, logger *logrus.Logger) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		alerts: manager,
+		users:  users,
+		logger: logger,
+	}
+}
+
+// HandleListSavedSearches returns the caller's saved searches
+func (h *SavedSearchHandler) HandleListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": h.alerts.SavedSearches(userID),
+	})
+}
+
+// HandleGetSavedSearch returns one of the caller's saved searches
+func (h *SavedSearchHandler) HandleGetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	search, err := h.alerts.SavedSearch(userID, mux.Vars(r)["id"])
+	if err != nil {
+		h.writeAlertsError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": search,
+	})
+}
+
+// HandleCreateSavedSearch saves a named search for the caller. Without a
+// filter the search is built from the caller's preferences. Prices are in
+// the caller's preferred currency unless the filter sets priceCurrency.
+func (h *SavedSearchHandler) HandleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	user, err := h.users.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized: unknown user", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Name   string                `json:"name"`
+		Filter *models.VehicleFilter `json:"filter"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxSearchNameLength {
+		writeFieldError(w, "name", "must be between 1 and "+strconv.Itoa(maxSearchNameLength)+" characters")
+		return
+	}
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		writeFieldError(w, "name", "must not contain control characters")
+		return
+	}
+
+	filter := user.SearchFromPreferences()
+	if req.Filter != nil {
+		filter = *req.Filter
+	}
+	filter.PriceCurrency = strings.ToUpper(strings.TrimSpace(filter.PriceCurrency))
+	if filter.PriceCurrency == "" && (filter.MinPrice > 0 || filter.MaxPrice > 0) && models.IsSupportedCurrency(user.PreferredCurrency) {
+		filter.PriceCurrency = user.PreferredCurrency
+	}
+	if filter.PriceCurrency != "" && !models.IsSupportedCurrency(filter.PriceCurrency) {
+		writeFieldError(w, "filter.priceCurrency", "must be one of "+strings.Join(models.SupportedCurrencies, ", "))
+		return
+	}
+	for _, status := range filter.Statuses {
+		if !models.IsValidStatus(strings.ToLower(status)) {
+			writeFieldError(w, "filter.statuses", "must be one of "+strings.Join(models.Statuses, ", "))
+			return
+		}
+	}
+
+	search := &models.SavedSearch{
+		UserID: userID,
+		Name:   name,
+		Filter: filter,
+	}
+	if err := h.alerts.CreateSavedSearch(search); err != nil {
+		h.writeAlertsError(w, err)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"search_id": search.ID,
+	}).Info("Saved search created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": search,
+	})
+}
+
+// HandleDeleteSavedSearch removes one of the caller's saved searches
+func (h *SavedSearchHandler) HandleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	if err := h.alerts.DeleteSavedSearch(userID, mux.Vars(r)["id"]); err != nil {
+		h.writeAlertsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListNotifications returns the caller's notifications, newest first.
+// unread=true leaves out those already read.
+func (h *SavedSearchHandler) HandleListNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	unreadOnly, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	notifications := h.alerts.Notifications(userID, unreadOnly)
+	unread := 0
+	for _, notification := range notifications {
+		if !notification.Read {
+			unread++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":   notifications,
+		"count":  len(notifications),
+		"unread": unread,
+	})
+}
+
+// HandleMarkNotificationRead marks one of the caller's notifications as read
+func (h *SavedSearchHandler) HandleMarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	if err := h.alerts.MarkRead(userID, mux.Vars(r)["id"]); err != nil {
+		h.writeAlertsError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAlertsError maps alerts errors to HTTP responses
+func (h *SavedSearchHandler) writeAlertsError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, alerts.ErrSearchNotFound), errors.Is(err, alerts.ErrNotificationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, alerts.ErrTooManySearches):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.WithError(err).Error("Failed to update saved searches")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/alerts"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestSavedSearchRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+
+	repo, err := repository.NewRepository(filepath.Join("..", "..", "..", "..", "data", "seed"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	manager, err := alerts.Open("", repo, nil, logger)
+	if err != nil {
+		t.Fatalf("Failed to open alerts: %v", err)
+	}
+	store := repository.Observe(repo, manager)
+
+	vehicles := NewVehicleHandler(store, nil, logger)
+	handler := NewSavedSearchHandler(manager, store, logger)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/vehicles", vehicles.HandleCreateVehicle).Methods("POST")
+	r.HandleFunc("/me/saved-searches", handler.HandleListSavedSearches).Methods("GET")
+	r.HandleFunc("/me/saved-searches", handler.HandleCreateSavedSearch).Methods("POST")
+	r.HandleFunc("/me/saved-searches/{id}", handler.HandleGetSavedSearch).Methods("GET")
+	r.HandleFunc("/me/saved-searches/{id}", handler.HandleDeleteSavedSearch).Methods("DELETE")
+	r.HandleFunc("/me/notifications", handler.HandleListNotifications).Methods("GET")
+	r.HandleFunc("/me/notifications/{id}/read", handler.HandleMarkNotificationRead).Methods("POST")
+
+	return r
+}
+
+func TestSavedSearches(t *testing.T) {
+	r := newTestSavedSearchRouter(t)
+	buyer := asUser(r, "user-001")
+	other := asUser(r, "user-003")
+
+	// Without a filter the search comes from the buyer's preferences
+	rec := doRequest(buyer, "POST", "/me/saved-searches", map[string]interface{}{"name": "My usual"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data struct {
+			ID     string `json:"id"`
+			Filter struct {
+				MinPrice      float64  `json:"minPrice"`
+				MaxPrice      float64  `json:"maxPrice"`
+				PriceCurrency string   `json:"priceCurrency"`
+				VehicleTypes  []string `json:"vehicleTypes"`
+			} `json:"filter"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	filter := created.Data.Filter
+	if filter.MinPrice != 20000 || filter.MaxPrice != 60000 || filter.PriceCurrency != "USD" || len(filter.VehicleTypes) != 2 {
+		t.Errorf("Expected the search from the preferences, got %+v", filter)
+	}
+
+	rec = doRequest(buyer, "POST", "/me/saved-searches", map[string]interface{}{
+		"name":   "Old Hondas",
+		"filter": map[string]interface{}{"make": "Honda", "maxYear": 2005},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, body := range []map[string]interface{}{
+		{"name": ""},
+		{"name": "Sedans\r\nBcc: everyone@example.com"},
+		{"name": "Bad", "filter": map[string]interface{}{"statuses": []string{"parked"}}},
+		{"name": "Bad", "filter": map[string]interface{}{"priceCurrency": "JPY"}},
+	} {
+		if rec := doRequest(buyer, "POST", "/me/saved-searches", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %v, got %d", body, rec.Code)
+		}
+	}
+
+	// Searches are private to their owner
+	if rec := doRequest(other, "GET", "/me/saved-searches/"+created.Data.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's search, got %d", rec.Code)
+	}
+	rec = doRequest(buyer, "GET", "/me/saved-searches", nil)
+	var list struct {
+		Data []struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Data) != 2 {
+		t.Errorf("Expected two saved searches, got %+v", list.Data)
+	}
+
+	// A new matching listing lands in the inbox
+	rec = doRequest(asUser(r, "user-002"), "POST", "/vehicles", map[string]interface{}{
+		"vin":      "1HGCM82633A004352",
+		"year":     2003,
+		"make":     "Honda",
+		"model":    "Accord",
+		"type":     "sedan",
+		"price":    9000,
+		"currency": "USD",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(buyer, "GET", "/me/notifications", nil)
+	var inbox struct {
+		Data []struct {
+			ID         string `json:"id"`
+			SearchName string `json:"searchName"`
+			Event      string `json:"event"`
+		} `json:"data"`
+		Unread int `json:"unread"`
+	}
+	json.NewDecoder(rec.Body).Decode(&inbox)
+	if len(inbox.Data) != 1 || inbox.Data[0].SearchName != "Old Hondas" || inbox.Data[0].Event != "listed" || inbox.Unread != 1 {
+		t.Fatalf("Unexpected inbox %+v", inbox)
+	}
+
+	if rec := doRequest(other, "POST", "/me/notifications/"+inbox.Data[0].ID+"/read", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's notification, got %d", rec.Code)
+	}
+	if rec := doRequest(buyer, "POST", "/me/notifications/"+inbox.Data[0].ID+"/read", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	rec = doRequest(buyer, "GET", "/me/notifications?unread=true", nil)
+	json.NewDecoder(rec.Body).Decode(&inbox)
+	if len(inbox.Data) != 0 || inbox.Unread != 0 {
+		t.Errorf("Expected no unread notifications, got %+v", inbox)
+	}
+
+	if rec := doRequest(buyer, "DELETE", "/me/saved-searches/"+created.Data.ID, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := doRequest(buyer, "GET", "/me/saved-searches/"+created.Data.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deleting, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// HandleCompareVehicles compares two to five vehicles side by side, e.g.
+// ?ids=veh-001,veh-004. Prices are normalized to priceCurrency, else the
+// caller's preferred currency, else the currency of the first vehicle.
+func (h *VehicleHandler) HandleCompareVehicles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	ids := listParam(query, "ids")
+	if len(ids) < models.MinCompared || len(ids) > models.MaxCompared {
+		writeFieldError(w, "ids", fmt.Sprintf("must list between %d and %d vehicle IDs", models.MinCompared, models.MaxCompared))
+		return
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			writeFieldError(w, "ids", "must not repeat a vehicle")
+			return
+		}
+		seen[id] = true
+	}
+
+	var filter models.VehicleFilter
+	if err := h.setPriceCurrency(r, &filter, query.Get("priceCurrency")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	vehicles := make([]*models.Vehicle, 0, len(ids))
+	var missing []string
+	for _, id := range ids {
+		vehicle, err := h.repo.GetVehicleByID(id)
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		vehicles = append(vehicles, vehicle)
+	}
+	if len(missing) > 0 {
+		http.Error(w, "Vehicle not found: "+strings.Join(missing, ", "), http.StatusNotFound)
+		return
+	}
+
+	currency := filter.PriceCurrency
+	if currency == "" {
+		currency = strings.ToUpper(vehicles[0].Currency)
+	}
+	var rates models.PriceConverter
+	if h.rates != nil {
+		rates = h.rates
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": models.Compare(vehicles, currency, rates),
+	})
+}
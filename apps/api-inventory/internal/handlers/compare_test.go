@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestCompareVehicles(t *testing.T) {
+	r := newTestVehicleRouter(t)
+	buyer := asUser(r, "user-001")
+
+	rec := doRequest(buyer, "GET", "/vehicles/compare?ids=veh-001,veh-004", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Data struct {
+			VehicleIDs []string `json:"vehicleIds"`
+			Currency   string   `json:"currency"`
+			Attributes []struct {
+				Name    string        `json:"name"`
+				Values  []interface{} `json:"values"`
+				Winners []string      `json:"winners"`
+			} `json:"attributes"`
+			Features struct {
+				Shared []string            `json:"shared"`
+				Unique map[string][]string `json:"unique"`
+			} `json:"features"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Data.Currency != "USD" || len(body.Data.VehicleIDs) != 2 || body.Data.VehicleIDs[0] != "veh-001" {
+		t.Fatalf("Unexpected comparison %+v", body.Data)
+	}
+
+	winners := map[string][]string{}
+	for _, row := range body.Data.Attributes {
+		winners[row.Name] = row.Winners
+		if row.Name == "price" {
+			if amount, ok := row.Values[1].(float64); !ok || amount == 76500 {
+				t.Errorf("Expected the CAD price converted to USD, got %v", row.Values[1])
+			}
+		}
+	}
+	if w := winners["price"]; len(w) != 1 || w[0] != "veh-001" {
+		t.Errorf("Expected veh-001 to win on price, got %v", w)
+	}
+	if w := winners["mileage"]; len(w) != 1 || w[0] != "veh-004" {
+		t.Errorf("Expected veh-004 to win on mileage, got %v", w)
+	}
+	if w := winners["year"]; len(w) != 2 {
+		t.Errorf("Expected a tie on year, got %v", w)
+	}
+	if len(body.Data.Features.Shared) != 0 || len(body.Data.Features.Unique["veh-004"]) != 5 {
+		t.Errorf("Unexpected features %+v", body.Data.Features)
+	}
+
+	rec = doRequest(buyer, "GET", "/vehicles/compare?ids=veh-001,veh-004&priceCurrency=CAD", nil)
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Data.Currency != "CAD" {
+		t.Errorf("Expected prices in CAD, got %s", body.Data.Currency)
+	}
+
+	for _, tt := range []struct {
+		query  string
+		status int
+	}{
+		{"ids=veh-001", http.StatusBadRequest},
+		{"ids=veh-001,veh-002,veh-003,veh-004,veh-005,veh-006", http.StatusBadRequest},
+		{"ids=veh-001,veh-001", http.StatusBadRequest},
+		{"ids=veh-001,veh-002&priceCurrency=XYZ", http.StatusBadRequest},
+		{"ids=veh-001,veh-999", http.StatusNotFound},
+	} {
+		if rec := doRequest(buyer, "GET", "/vehicles/compare?"+tt.query, nil); rec.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.query, tt.status, rec.Code)
+		}
+	}
+}
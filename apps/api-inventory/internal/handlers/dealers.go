@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// DealerHandler serves dealer profiles and reviews
+type DealerHandler struct {
+	repo   repository.Store
+	logger *logrus.Logger
+}
+
+// NewDealerHandler creates a new dealer handler
+func NewDealerHandler(repo repository.Store, logger *logrus.Logger) *DealerHandler {
+	return &DealerHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// dealerProfile is a dealer with the size of its public inventory
+type dealerProfile struct {
+	*models.Dealer
+	VehicleCount int `json:"vehicleCount"`
+}
+
+// HandleListDealers returns every dealer with its rating
+func (h *DealerHandler) HandleListDealers(w http.ResponseWriter, r *http.Request) {
+	dealers := h.repo.GetDealers()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  dealers,
+		"count": len(dealers),
+	})
+}
+
+// HandleGetDealer returns a dealer's profile: contact details, locations,
+// rating and the number of vehicles it lists
+func (h *DealerHandler) HandleGetDealer(w http.ResponseWriter, r *http.Request) {
+	dealerID := mux.Vars(r)["id"]
+
+	dealer, err := h.repo.GetDealerByID(dealerID)
+	if err != nil {
+		h.writeDealerError(w, err, dealerID)
+		return
+	}
+	inventory := h.repo.SearchVehicles(&models.VehicleFilter{
+		DealerID: dealerID,
+		Statuses: models.PublicStatuses,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": &dealerProfile{Dealer: dealer, VehicleCount: len(inventory)},
+	})
+}
+
+// HandleListReviews returns a dealer's reviews, newest first, without the
+// reviewers' user IDs
+func (h *DealerHandler) HandleListReviews(w http.ResponseWriter, r *http.Request) {
+	dealerID := mux.Vars(r)["id"]
+
+	dealer, err := h.repo.GetDealerByID(dealerID)
+	if err != nil {
+		h.writeDealerError(w, err, dealerID)
+		return
+	}
+
+	reviews := h.repo.GetDealerReviews(dealerID)
+	public := make([]*models.DealerReview, len(reviews))
+	for i, review := range reviews {
+		public[i] = review.Public()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":   public,
+		"count":  len(public),
+		"rating": dealer.Rating,
+	})
+}
+
+// HandlePostReview adds the caller's review of a dealer, or replaces the
+// one they already wrote. Only callers who bought one of the dealer's
+// vehicles may review it.
+func (h *DealerHandler) HandlePostReview(w http.ResponseWriter, r *http.Request) {
+	dealerID := mux.Vars(r)["id"]
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req struct {
+		Rating  int    `json:"rating"`
+		Title   string `json:"title"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.repo.GetDealerByID(dealerID); err != nil {
+		h.writeDealerError(w, err, dealerID)
+		return
+	}
+	user, err := h.repo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vehicleID := h.purchasedVehicle(dealerID, userID)
+	if vehicleID == "" {
+		http.Error(w, "Only buyers who bought a vehicle from this dealer can review it", http.StatusForbidden)
+		return
+	}
+
+	review := &models.DealerReview{
+		DealerID:  dealerID,
+		UserID:    userID,
+		Author:    user.Name,
+		VehicleID: vehicleID,
+		Rating:    req.Rating,
+		Title:     strings.TrimSpace(req.Title),
+		Comment:   strings.TrimSpace(req.Comment),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := review.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	for _, existing := range h.repo.GetDealerReviews(dealerID) {
+		if existing.UserID == userID {
+			status = http.StatusOK
+			break
+		}
+	}
+	if err := h.repo.PutDealerReview(review); err != nil {
+		h.writeDealerError(w, err, dealerID)
+		return
+	}
+	dealer, err := h.repo.GetDealerByID(dealerID)
+	if err != nil {
+		h.writeDealerError(w, err, dealerID)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"dealer_id": dealerID,
+		"user_id":   userID,
+		"rating":    review.Rating,
+	}).Info("Dealer review saved")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":   review,
+		"rating": dealer.Rating,
+	})
+}
+
+// purchasedVehicle returns the ID of a vehicle of the dealer the user
+// bought, or "" if there is none
+func (h *DealerHandler) purchasedVehicle(dealerID, userID string) string {
+	vehicles := h.repo.SearchVehicles(&models.VehicleFilter{DealerID: dealerID, Statuses: []string{models.StatusSold}})
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].ID < vehicles[j].ID })
+	for _, vehicle := range vehicles {
+		if vehicle.PurchasedBy(userID) {
+			return vehicle.ID
+		}
+	}
+	return ""
+}
+
+// writeDealerError maps dealer errors to HTTP responses
+func (h *DealerHandler) writeDealerError(w http.ResponseWriter, err error, dealerID string) {
+	switch {
+	case errors.Is(err, repository.ErrDealerNotFound):
+		http.Error(w, "Dealer not found", http.StatusNotFound)
+	default:
+		h.logger.WithError(err).WithField("dealer_id", dealerID).Error("Failed to handle dealer request")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
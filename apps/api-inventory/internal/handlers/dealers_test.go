@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+type dealerRatingBody struct {
+	Average float64 `json:"average"`
+	Score   float64 `json:"score"`
+	Count   int     `json:"count"`
+}
+
+func TestDealerProfileAndInventory(t *testing.T) {
+	r := asUser(newTestVehicleRouter(t), "user-001")
+
+	rec := doRequest(r, "GET", "/dealers", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var list struct {
+		Data []struct {
+			ID     string            `json:"id"`
+			Rating *dealerRatingBody `json:"rating"`
+		} `json:"data"`
+		Count int `json:"count"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if list.Count != 10 || list.Data[0].ID != "dlr-001" {
+		t.Fatalf("Expected 10 dealers in ID order, got %+v", list)
+	}
+	if rating := list.Data[4].Rating; rating == nil || rating.Count != 5 || rating.Average != 4.8 || rating.Score >= rating.Average {
+		t.Errorf("Expected dlr-005 to be rated from 5 reviews and smoothed down, got %+v", rating)
+	}
+
+	rec = doRequest(r, "GET", "/dealers/dlr-003", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var profile struct {
+		Data struct {
+			Name         string              `json:"name"`
+			Locations    []map[string]string `json:"locations"`
+			VehicleCount int                 `json:"vehicleCount"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&profile)
+	if profile.Data.Name != "Thames Valley Cars" || len(profile.Data.Locations) != 2 || profile.Data.VehicleCount != 5 {
+		t.Errorf("Unexpected profile %+v", profile.Data)
+	}
+	if rec := doRequest(r, "GET", "/dealers/dlr-999", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "GET", "/dealers/dlr-003/vehicles?make=McLaren", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var inventory struct {
+		Data []struct {
+			ID           string  `json:"id"`
+			DealerID     string  `json:"dealerId"`
+			DealerRating float64 `json:"dealerRating"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&inventory)
+	if len(inventory.Data) != 1 || inventory.Data[0].ID != "veh-032" || inventory.Data[0].DealerID != "dlr-003" {
+		t.Errorf("Expected the dealer's McLaren, got %+v", inventory.Data)
+	}
+	if inventory.Data[0].DealerRating == 0 {
+		t.Errorf("Expected the vehicle to carry the dealer's rating")
+	}
+	if rec := doRequest(r, "GET", "/dealers/dlr-999/vehicles", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "GET", "/vehicles?dealerId=dlr-010", nil)
+	json.NewDecoder(rec.Body).Decode(&inventory)
+	if len(inventory.Data) != 4 {
+		t.Errorf("Expected 4 vehicles from dlr-010, got %+v", inventory.Data)
+	}
+}
+
+func TestDealerReviews(t *testing.T) {
+	router := newTestVehicleRouter(t)
+	buyer := asUser(router, "user-001")
+	admin := asUser(router, "user-002")
+
+	rec := doRequest(buyer, "GET", "/dealers/dlr-003/reviews", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var reviews struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&reviews)
+	if len(reviews.Data) != 4 || reviews.Data[0]["author"] != "Sarah Jones" {
+		t.Fatalf("Expected 4 reviews, newest first, got %+v", reviews.Data)
+	}
+	for _, review := range reviews.Data {
+		if _, ok := review["userId"]; ok {
+			t.Errorf("Expected reviewer IDs to be hidden, got %+v", review)
+		}
+	}
+
+	// Only buyers who bought one of the dealer's vehicles may review it
+	rec = doRequest(buyer, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{"rating": 5})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", rec.Code)
+	}
+	if rec := doRequest(buyer, "POST", "/dealers/dlr-999/reviews", map[string]interface{}{"rating": 5}); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+
+	// Reserving is not buying, and neither is a cancelled reservation
+	rec = doRequest(buyer, "POST", "/vehicles/veh-048/reservation", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(buyer, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{"rating": 1}); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 while reserved, got %d", rec.Code)
+	}
+	if rec := doRequest(buyer, "DELETE", "/vehicles/veh-048/reservation", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(buyer, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{"rating": 1}); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 after cancelling, got %d", rec.Code)
+	}
+
+	// The vehicle is sold to the buyer holding it
+	rec = doRequest(buyer, "POST", "/vehicles/veh-048/reservation", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(admin, "POST", "/vehicles/veh-048/transitions", map[string]string{"status": "sold"}); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := doRequest(admin, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{"rating": 1}); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for the admin who sold it, got %d", rec.Code)
+	}
+	if rec := doRequest(buyer, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{"rating": 6}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a rating of 6, got %d", rec.Code)
+	}
+
+	before := vehicleDealerRating(t, buyer, "veh-001")
+	rec = doRequest(buyer, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{
+		"rating":  5,
+		"title":   "Smooth purchase",
+		"comment": "Held the car for me while I arranged finance.",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data struct {
+			VehicleID string `json:"vehicleId"`
+			Author    string `json:"author"`
+		} `json:"data"`
+		Rating dealerRatingBody `json:"rating"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Data.VehicleID != "veh-048" || created.Data.Author != "Demo User" {
+		t.Errorf("Expected a review verified by veh-048, got %+v", created.Data)
+	}
+	if created.Rating.Count != 2 || created.Rating.Average != 4 {
+		t.Errorf("Expected the rating to include the review, got %+v", created.Rating)
+	}
+
+	// Every vehicle of the dealer shows the new score, and other dealers
+	// move with the mean they are smoothed towards
+	if got := vehicleDealerRating(t, buyer, "veh-019"); got != created.Rating.Score {
+		t.Errorf("Expected veh-019 to show the score %v, got %v", created.Rating.Score, got)
+	}
+	if after := vehicleDealerRating(t, buyer, "veh-001"); after == before {
+		t.Errorf("Expected veh-001's dealer score to follow the new mean, still %v", after)
+	}
+
+	// A second review replaces the first
+	rec = doRequest(buyer, "POST", "/dealers/dlr-010/reviews", map[string]interface{}{"rating": 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Rating.Count != 2 || created.Rating.Average != 2 {
+		t.Errorf("Expected the review to be replaced, got %+v", created.Rating)
+	}
+}
+
+func TestVehicleDealerMustExist(t *testing.T) {
+	r := asUser(newTestVehicleRouter(t), "user-002")
+
+	rec := doRequest(r, "PATCH", "/vehicles/veh-014", map[string]interface{}{"dealerId": "dlr-999"})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+	rec = doRequest(r, "PATCH", "/vehicles/veh-014", map[string]interface{}{"dealerId": "dlr-001"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got, want := vehicleDealerRating(t, r, "veh-014"), dealerScore(t, r, "dlr-001"); got != want {
+		t.Errorf("Expected the new dealer's score %v, got %v", want, got)
+	}
+}
+
+func vehicleDealerRating(t *testing.T, h http.Handler, vehicleID string) float64 {
+	t.Helper()
+	rec := doRequest(h, "GET", "/vehicles/"+vehicleID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body struct {
+		Data struct {
+			DealerRating float64 `json:"dealerRating"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	return body.Data.DealerRating
+}
+
+func dealerScore(t *testing.T, h http.Handler, dealerID string) float64 {
+	t.Helper()
+	rec := doRequest(h, "GET", "/dealers/"+dealerID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body struct {
+		Data struct {
+			Rating dealerRatingBody `json:"rating"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	return body.Data.Rating.Score
+}
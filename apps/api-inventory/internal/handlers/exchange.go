@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
+	"github.com/sirupsen/logrus"
+)
+
+// ExchangeRateHandler lets administrators view and update exchange rates
+type ExchangeRateHandler struct {
+	rates  *exchange.Table
+	logger *logrus.Logger
+}
+
+// NewExchangeRateHandler creates a new exchange rate handler
+func NewExchangeRateHandler(rates *exchange.Table, logger *logrus.Logger) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		rates:  rates,
+		logger: logger,
+	}
+}
+
+// HandleListRates returns every dated rate
+func (h *ExchangeRateHandler) HandleListRates(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"baseCurrency": exchange.BaseCurrency,
+		"data":         h.rates.Rates(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleUpdateRates adds or replaces dated rates and returns the full table
+func (h *ExchangeRateHandler) HandleUpdateRates(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rates []exchange.Rate `json:"rates"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Rates) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.rates.Update(req.Rates); err != nil {
+		if errors.Is(err, exchange.ErrInvalidRate) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.WithError(err).Error("Failed to update exchange rates")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.WithField("rates", len(req.Rates)).Info("Exchange rates updated")
+
+	h.HandleListRates(w, r)
+}
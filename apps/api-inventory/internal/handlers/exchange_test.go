@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUpdateExchangeRates(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+
+	path := filepath.Join(t.TempDir(), "exchange_rates.json")
+	rates, err := exchange.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open exchange rates: %v", err)
+	}
+	handler := NewExchangeRateHandler(rates, logger)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/admin/exchange-rates", handler.HandleListRates).Methods("GET")
+	r.HandleFunc("/admin/exchange-rates", handler.HandleUpdateRates).Methods("PUT")
+
+	rec := doRequest(r, "PUT", "/admin/exchange-rates", map[string]interface{}{
+		"rates": []exchange.Rate{{Currency: "GBP", Date: "2024-13-01", Rate: 0.8}},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid date, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "PUT", "/admin/exchange-rates", map[string]interface{}{
+		"rates": []exchange.Rate{{Currency: "GBP", Date: "2024-01-01", Rate: 0.8}},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(r, "GET", "/admin/exchange-rates", nil)
+	var response struct {
+		BaseCurrency string          `json:"baseCurrency"`
+		Data         []exchange.Rate `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if response.BaseCurrency != "USD" || len(response.Data) != 1 || response.Data[0].Rate != 0.8 {
+		t.Errorf("Unexpected rates %+v", response)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected the rates to be saved: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/importer"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Export formats
+const (
+	exportNDJSON = "ndjson"
+	exportCSV    = "csv"
+	exportJSON   = "json"
+)
+
+// exportFlushEvery is how many records are written between flushes
+const exportFlushEvery = 100
+
+var errExportFormat = errors.New("format must be ndjson, csv or json")
+
+// exportContentTypes maps each export format to its media type
+var exportContentTypes = map[string]string{
+	exportNDJSON: "application/x-ndjson",
+	exportCSV:    "text/csv; charset=utf-8",
+	exportJSON:   "application/json",
+}
+
+// exportFormat picks the export format from the format parameter, else the
+// Accept header, defaulting to NDJSON
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		format = strings.ToLower(format)
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", errExportFormat
+		}
+		return format, nil
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		switch mediaType {
+		case "application/x-ndjson":
+			return exportNDJSON, nil
+		case "text/csv":
+			return exportCSV, nil
+		case "application/json":
+			return exportJSON, nil
+		}
+	}
+	return exportNDJSON, nil
+}
+
+// exportWriter streams records as a file download. NDJSON writes one JSON
+// object per line, JSON a single array and CSV a header row followed by the
+// cells row returns for each record.
+type exportWriter[T any] struct {
+	format     string
+	w          io.Writer
+	controller *http.ResponseController
+	csv        *csv.Writer
+	row        func(T) []string
+	count      int
+}
+
+// newExportWriter sets the download headers and starts the response. name
+// is the file name without its extension.
+func newExportWriter[T any](w http.ResponseWriter, format, name string, header []string, row func(T) []string) (*exportWriter[T], error) {
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	w.WriteHeader(http.StatusOK)
+
+	ew := &exportWriter[T]{
+		format:     format,
+		w:          w,
+		controller: http.NewResponseController(w),
+		row:        row,
+	}
+	switch format {
+	case exportCSV:
+		ew.csv = csv.NewWriter(w)
+		return ew, ew.csv.Write(header)
+	case exportJSON:
+		_, err := io.WriteString(w, "[")
+		return ew, err
+	}
+	return ew, nil
+}
+
+// Write writes one record, flushing the response every exportFlushEvery
+// records
+func (ew *exportWriter[T]) Write(record T) error {
+	var err error
+	if ew.csv != nil {
+		err = ew.csv.Write(ew.row(record))
+	} else {
+		var data []byte
+		if data, err = json.Marshal(record); err != nil {
+			return err
+		}
+		switch {
+		case ew.format == exportNDJSON:
+			data = append(data, '\n')
+		case ew.count > 0:
+			data = append([]byte(",\n"), data...)
+		default:
+			data = append([]byte("\n"), data...)
+		}
+		_, err = ew.w.Write(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	ew.count++
+	if ew.count%exportFlushEvery == 0 {
+		return ew.flush()
+	}
+	return nil
+}
+
+// Close ends the file and flushes the rest of it
+func (ew *exportWriter[T]) Close() error {
+	if ew.format == exportJSON {
+		end := "]\n"
+		if ew.count > 0 {
+			end = "\n]\n"
+		}
+		if _, err := io.WriteString(ew.w, end); err != nil {
+			return err
+		}
+	}
+	return ew.flush()
+}
+
+// flush sends what has been written so far to the client
+func (ew *exportWriter[T]) flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := ew.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// HandleExportVehicles streams every vehicle matching the query parameters
+// of GET /vehicles (none for every public vehicle) as a download in NDJSON,
+// CSV or JSON. Vehicles are written as they are read from the store, in ID
+// order, so sort and q are rejected rather than collecting every match
+// first. The export stops when the client goes away.
+func (h *VehicleHandler) HandleExportVehicles(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	switch {
+	case query.Get("sort") != "":
+		writeFieldError(w, "sort", "exports are in ID order and cannot be sorted")
+		return
+	case query.Get("q") != "":
+		writeFieldError(w, "q", "exports cannot be ranked by a text query")
+		return
+	}
+	filter, err := h.queryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	columns := append([]string{"id"}, importer.Fields...)
+	if filter.ConvertsPrices() {
+		columns = append(columns, "convertedPrice", "convertedCurrency")
+	}
+	if filter.Near != nil {
+		columns = append(columns, "distanceKm")
+	}
+	name := "vehicles-" + time.Now().UTC().Format("20060102")
+	out, err := newExportWriter(w, format, name, columns, func(result *vehicleResult) []string {
+		return vehicleCells(result, columns)
+	})
+
+	if err == nil {
+		err = h.repo.EachVehicle(r.Context(), filter, func(vehicle *models.Vehicle) error {
+			results := vehicleResults([]*models.Vehicle{vehicle})
+			measureDistances(results, filter.Near)
+			convertPrices(results, filter)
+			return out.Write(results[0])
+		})
+	}
+	if err == nil {
+		err = out.Close()
+	}
+
+	fields := logrus.Fields{"format": format, "count": out.count}
+	switch {
+	case r.Context().Err() != nil:
+		h.logger.WithFields(fields).Info("Vehicle export cancelled by the client")
+	case err != nil:
+		// The status has been sent, so the client only sees a truncated file
+		h.logger.WithError(err).WithFields(fields).Error("Vehicle export failed")
+	default:
+		h.logger.WithFields(fields).Info("Vehicles exported")
+	}
+}
+
+// vehicleCells formats a vehicle as CSV cells. The columns are named like
+// the import fields, so an export can be imported again.
+func vehicleCells(result *vehicleResult, columns []string) []string {
+	v := result.Vehicle
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "id":
+			cells[i] = v.ID
+		case "vin":
+			cells[i] = v.VIN
+		case "year":
+			cells[i] = strconv.Itoa(v.Year)
+		case "make":
+			cells[i] = v.Make
+		case "model":
+			cells[i] = v.Model
+		case "trim":
+			cells[i] = v.Trim
+		case "type":
+			cells[i] = v.Type
+		case "condition":
+			cells[i] = v.Condition
+		case "mileage":
+			cells[i] = strconv.Itoa(v.Mileage)
+		case "price":
+			cells[i] = formatNumber(v.Price)
+		case "currency":
+			cells[i] = v.Currency
+		case "country":
+			cells[i] = v.Country
+		case "status":
+			cells[i] = v.Status
+		case "fuelType":
+			cells[i] = v.FuelType
+		case "transmission":
+			cells[i] = v.Transmission
+		case "drivetrain":
+			cells[i] = v.Drivetrain
+		case "exteriorColor":
+			cells[i] = v.ExteriorColor
+		case "interiorColor":
+			cells[i] = v.InteriorColor
+		case "features":
+			cells[i] = strings.Join(v.Features, "; ")
+		case "images":
+			cells[i] = strings.Join(v.Images, "; ")
+		case "dealerId":
+			cells[i] = v.DealerID
+		case "location":
+			cells[i] = v.Location
+		case "latitude":
+			if v.Coordinates != nil {
+				cells[i] = formatNumber(v.Coordinates.Lat)
+			}
+		case "longitude":
+			if v.Coordinates != nil {
+				cells[i] = formatNumber(v.Coordinates.Lng)
+			}
+		case "listingDate":
+			if !v.ListingDate.IsZero() {
+				cells[i] = v.ListingDate.Format(time.RFC3339)
+			}
+		case "convertedPrice":
+			if result.ConvertedPrice != nil {
+				cells[i] = formatNumber(result.ConvertedPrice.Amount)
+			}
+		case "convertedCurrency":
+			if result.ConvertedPrice != nil {
+				cells[i] = result.ConvertedPrice.Currency
+			}
+		case "distanceKm":
+			if result.DistanceKm != nil {
+				cells[i] = formatNumber(*result.DistanceKm)
+			}
+		}
+	}
+	return cells
+}
+
+// formatNumber formats a number without exponent or trailing zeros
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExportVehiclesNDJSON(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	rec := doRequest(r, "GET", "/vehicles?limit=500", nil)
+	var list struct {
+		Count int `json:"count"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+
+	req := httptest.NewRequest("GET", "/vehicles/export", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON by default, got %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="vehicles-`) || !strings.HasSuffix(cd, `.ndjson"`) {
+		t.Errorf("Expected a download file name, got %q", cd)
+	}
+
+	var ids []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var vehicle struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &vehicle); err != nil {
+			t.Fatalf("Expected one JSON object per line, got %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, vehicle.ID)
+	}
+	if len(ids) != list.Count || !sort.StringsAreSorted(ids) {
+		t.Errorf("Expected the %d listed vehicles in ID order, got %d: %v", list.Count, len(ids), ids)
+	}
+}
+
+func TestExportVehiclesCSV(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	rec := doRequest(r, "GET", "/vehicles/export?format=csv&country=US&priceCurrency=EUR", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, `.csv"`) {
+		t.Errorf("Expected a .csv file name, got %q", cd)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV: %v", err)
+	}
+	header := records[0]
+	column := func(name string) int {
+		for i, h := range header {
+			if h == name {
+				return i
+			}
+		}
+		t.Fatalf("Expected a %s column in %v", name, header)
+		return -1
+	}
+	country, converted, currency := column("country"), column("convertedPrice"), column("convertedCurrency")
+	if header[0] != "id" || len(records) < 3 {
+		t.Fatalf("Expected an id column and several rows, got %v", records)
+	}
+
+	for _, row := range records[1:] {
+		if row[country] != "US" || row[currency] != "EUR" {
+			t.Errorf("Expected US vehicles priced in EUR, got %v", row)
+		}
+		if _, err := strconv.ParseFloat(row[converted], 64); err != nil {
+			t.Fatalf("Expected a converted price, got %q", row[converted])
+		}
+	}
+}
+
+func TestExportVehiclesNear(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	// A radius search filters and adds distances, but keeps the ID order
+	rec := doRequest(r, "GET", "/vehicles/export?format=csv&near=51.5074,-0.1278&radiusKm=200", nil)
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) < 2 {
+		t.Fatalf("Expected vehicles near London: %v %v", records, err)
+	}
+	header := records[0]
+	if header[len(header)-1] != "distanceKm" {
+		t.Fatalf("Expected a distanceKm column, got %v", header)
+	}
+	for i, row := range records[1:] {
+		distance, err := strconv.ParseFloat(row[len(row)-1], 64)
+		if err != nil || distance > 200 {
+			t.Errorf("Expected a distance within 200 km, got %q", row[len(row)-1])
+		}
+		if i > 0 && row[0] < records[i][0] {
+			t.Errorf("Expected ID order, got %s after %s", row[0], records[i][0])
+		}
+	}
+}
+
+func TestExportVehiclesJSON(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	req := httptest.NewRequest("GET", "/vehicles/export?make=BMW", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	var vehicles []struct {
+		Make string `json:"make"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&vehicles); err != nil {
+		t.Fatalf("Expected a JSON array: %v", err)
+	}
+	if len(vehicles) == 0 {
+		t.Fatal("Expected BMWs to be exported")
+	}
+	for _, vehicle := range vehicles {
+		if vehicle.Make != "BMW" {
+			t.Errorf("Expected only BMWs, got %s", vehicle.Make)
+		}
+	}
+
+	rec = doRequest(r, "GET", "/vehicles/export?format=json&make=Nobody", nil)
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", body)
+	}
+}
+
+func TestExportVehiclesRejectsBadRequests(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"unknown format", "/vehicles/export?format=xlsx"},
+		{"sort", "/vehicles/export?sort=-price"},
+		{"text query", "/vehicles/export?q=bmw"},
+		{"unknown currency", "/vehicles/export?priceCurrency=XYZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := doRequest(r, "GET", tt.path, nil); rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestExportVehiclesStopsWhenCancelled(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("GET", "/vehicles/export", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("Expected nothing to be written once the client is gone, got %d bytes", rec.Body.Len())
+	}
+}
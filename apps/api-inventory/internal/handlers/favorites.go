@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// FavoriteHandler serves the caller's favorite vehicles
+type FavoriteHandler struct {
+	repo   repository.Store
+	logger *logrus.Logger
+}
+
+// NewFavoriteHandler creates a new favorite handler
+func NewFavoriteHandler(repo repository.Store, logger *logrus.Logger) *FavoriteHandler {
+	return &FavoriteHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// favoriteResult is a favorite in a list response. Vehicle is nil once the
+// listing has been deleted; PriceChange is set while the vehicle is still
+// listed in the currency it was favorited in.
+type favoriteResult struct {
+	*models.Favorite
+	Vehicle     *vehicleResult `json:"vehicle"`
+	Sold        bool           `json:"sold"`
+	Removed     bool           `json:"removed"`
+	PriceChange *priceChange   `json:"priceChange,omitempty"`
+}
+
+// priceChange is the difference between the current price and the price
+// when the vehicle was favorited; negative when the price has fallen
+type priceChange struct {
+	Amount  float64 `json:"amount"`
+	Percent float64 `json:"percent"`
+}
+
+// HandleListFavorites returns the caller's favorites, newest first, with
+// the current state and price of each vehicle
+func (h *FavoriteHandler) HandleListFavorites(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	favorites := h.repo.GetFavorites(userID)
+	results := make([]*favoriteResult, 0, len(favorites))
+	for _, favorite := range favorites {
+		result := &favoriteResult{Favorite: favorite}
+		vehicle, err := h.repo.GetVehicleByID(favorite.VehicleID)
+		if err != nil {
+			result.Removed = true
+			results = append(results, result)
+			continue
+		}
+		result.Vehicle = vehicleResults([]*models.Vehicle{vehicle})[0]
+		result.Sold = vehicle.Status == models.StatusSold
+		result.Removed = vehicle.Status == models.StatusWithdrawn
+		result.PriceChange = priceChangeSince(favorite.Price, vehicle)
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":  results,
+		"count": len(results),
+	})
+}
+
+// HandleAddFavorite adds a vehicle to the caller's favorites. Adding a
+// vehicle that is already a favorite keeps the original entry.
+func (h *FavoriteHandler) HandleAddFavorite(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	vehicleID := mux.Vars(r)["vehicleId"]
+
+	vehicle, err := h.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		http.Error(w, "Vehicle not found", http.StatusNotFound)
+		return
+	}
+
+	for _, favorite := range h.repo.GetFavorites(userID) {
+		if favorite.VehicleID == vehicleID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": favorite,
+			})
+			return
+		}
+	}
+
+	favorite := &models.Favorite{
+		UserID:    userID,
+		VehicleID: vehicleID,
+		AddedAt:   time.Now().UTC(),
+		Price:     models.Money{Amount: vehicle.Price, Currency: vehicle.Currency},
+	}
+	if err := h.repo.PutFavorite(favorite); err != nil {
+		h.logger.WithError(err).WithField("vehicle_id", vehicleID).Error("Failed to add favorite")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": favorite,
+	})
+}
+
+// HandleRemoveFavorite removes a vehicle from the caller's favorites
+func (h *FavoriteHandler) HandleRemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	vehicleID := mux.Vars(r)["vehicleId"]
+
+	if err := h.repo.DeleteFavorite(userID, vehicleID); err != nil {
+		if errors.Is(err, repository.ErrFavoriteNotFound) {
+			http.Error(w, "Favorite not found", http.StatusNotFound)
+			return
+		}
+		h.logger.WithError(err).WithField("vehicle_id", vehicleID).Error("Failed to remove favorite")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// priceChangeSince compares the vehicle's price with the favorited price.
+// It returns nil when the listing has changed currency.
+func priceChangeSince(favorited models.Money, vehicle *models.Vehicle) *priceChange {
+	if vehicle.Currency != favorited.Currency {
+		return nil
+	}
+	change := &priceChange{Amount: vehicle.Price - favorited.Amount}
+	if favorited.Amount > 0 {
+		change.Percent = math.Round(change.Amount/favorited.Amount*10000) / 100
+	}
+	return change
+}
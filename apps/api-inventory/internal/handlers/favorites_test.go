@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestFavorites(t *testing.T) {
+	r := newTestVehicleRouter(t)
+	buyer := asUser(r, "user-003")
+	admin := asUser(r, "user-002")
+
+	for _, id := range []string{"veh-014", "veh-002", "veh-003"} {
+		rec := doRequest(buyer, "PUT", "/me/favorites/"+id, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201 for %s, got %d: %s", id, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Favoriting again keeps the original entry
+	rec := doRequest(buyer, "PUT", "/me/favorites/veh-014", nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	rec = doRequest(buyer, "PUT", "/me/favorites/veh-999", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown vehicle, got %d", rec.Code)
+	}
+
+	// veh-014 drops in price, veh-002 sells and veh-003 is deleted
+	rec = doRequest(admin, "PATCH", "/vehicles/veh-014", map[string]interface{}{"price": 55791})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(admin, "POST", "/vehicles/veh-002/transitions", map[string]string{"status": "sold"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(admin, "DELETE", "/vehicles/veh-003", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+
+	type favoriteEntry struct {
+		VehicleID string `json:"vehicleId"`
+		Vehicle   *struct {
+			ID    string  `json:"id"`
+			Price float64 `json:"price"`
+		} `json:"vehicle"`
+		Sold        bool `json:"sold"`
+		Removed     bool `json:"removed"`
+		PriceChange *struct {
+			Amount  float64 `json:"amount"`
+			Percent float64 `json:"percent"`
+		} `json:"priceChange"`
+	}
+	listFavorites := func(h http.Handler) []favoriteEntry {
+		rec := doRequest(h, "GET", "/me/favorites", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+		var body struct {
+			Data []favoriteEntry `json:"data"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		return body.Data
+	}
+
+	favorites := listFavorites(buyer)
+	if len(favorites) != 3 {
+		t.Fatalf("Expected 3 favorites, got %+v", favorites)
+	}
+	byID := map[string]favoriteEntry{}
+	for _, favorite := range favorites {
+		byID[favorite.VehicleID] = favorite
+	}
+	if f := byID["veh-014"]; f.Sold || f.Removed || f.PriceChange == nil || f.PriceChange.Amount != -6199 || f.PriceChange.Percent != -10 {
+		t.Errorf("Expected a 10%% price drop on veh-014, got %+v", f)
+	}
+	if f := byID["veh-002"]; !f.Sold || f.Removed || f.Vehicle == nil {
+		t.Errorf("Expected veh-002 to be flagged sold, got %+v", f)
+	}
+	if f := byID["veh-003"]; !f.Removed || f.Vehicle != nil || f.PriceChange != nil {
+		t.Errorf("Expected veh-003 to be flagged removed, got %+v", f)
+	}
+	if others := listFavorites(admin); len(others) != 0 {
+		t.Errorf("Expected no favorites for another user, got %+v", others)
+	}
+
+	rec = doRequest(buyer, "DELETE", "/me/favorites/veh-003", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+	rec = doRequest(buyer, "DELETE", "/me/favorites/veh-003", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+	if favorites := listFavorites(buyer); len(favorites) != 2 {
+		t.Errorf("Expected 2 favorites after removal, got %+v", favorites)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/images"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// imageFormField is the multipart field an upload carries the image in
+const imageFormField = "image"
+
+// imageCacheControl lets clients and proxies keep served images for a
+// year. Image files are never rewritten: an upload always gets a new name.
+const imageCacheControl = "public, max-age=31536000, immutable"
+
+// multipartOverhead allows for the multipart headers and boundaries
+// around an uploaded image
+const multipartOverhead = 64 << 10
+
+// ImageHandler uploads, orders, deletes and serves vehicle images
+type ImageHandler struct {
+	manager *images.Manager
+	logger  *logrus.Logger
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(manager *images.Manager, logger *logrus.Logger) *ImageHandler {
+	return &ImageHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// HandleListImages returns the images of a vehicle in display order
+func (h *ImageHandler) HandleListImages(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	list, err := h.manager.List(vehicleID)
+	if err != nil {
+		h.writeImageError(w, err, vehicleID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": list,
+	})
+}
+
+// HandleUploadImage adds an image to a vehicle from a multipart/form-data
+// body carrying the file in the "image" field. The image is appended to
+// the vehicle's images.
+func (h *ImageHandler) HandleUploadImage(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.manager.MaxSize()+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Request body must be multipart/form-data", http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeFieldError(w, imageFormField, "is required")
+			return
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeImageError(w, err, vehicleID)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Invalid multipart body", http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != imageFormField {
+			continue
+		}
+
+		img, err := h.manager.Upload(r.Context(), vehicleID, part)
+		if err != nil {
+			h.writeImageError(w, err, vehicleID)
+			return
+		}
+
+		h.logger.WithFields(logrus.Fields{
+			"vehicle_id": vehicleID,
+			"image_id":   img.ID,
+			"size":       img.Size,
+		}).Info("Vehicle image uploaded")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": img,
+		})
+		return
+	}
+}
+
+// HandleReorderImages sets the display order of a vehicle's images from a
+// body listing every image ID, e.g. {"order": ["img-...", "/assets/..."]}
+func (h *ImageHandler) HandleReorderImages(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	var req struct {
+		Order []string `json:"order"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.manager.Reorder(vehicleID, req.Order)
+	if err != nil {
+		h.writeImageError(w, err, vehicleID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": list,
+	})
+}
+
+// HandleDeleteImage removes an uploaded image and its thumbnails
+func (h *ImageHandler) HandleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	vehicleID := vars["id"]
+
+	if err := h.manager.Delete(r.Context(), vehicleID, vars["imageId"]); err != nil {
+		h.writeImageError(w, err, vehicleID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleServeImage serves an uploaded image or thumbnail. It needs no
+// token, so image URLs work in <img> tags, and answers conditional and
+// range requests.
+func (h *ImageHandler) HandleServeImage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	vehicleID, file := vars["id"], vars["file"]
+
+	content, info, contentType, err := h.manager.Open(r.Context(), vehicleID, file)
+	if err != nil {
+		h.writeImageError(w, err, vehicleID)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", imageCacheControl)
+	w.Header().Set("ETag", fmt.Sprintf(`"%s"`, file))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, file, info.ModTime, content)
+}
+
+// writeImageError maps image errors to HTTP responses
+func (h *ImageHandler) writeImageError(w http.ResponseWriter, err error, vehicleID string) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		http.Error(w, "Vehicle not found", http.StatusNotFound)
+	case errors.Is(err, images.ErrImageNotFound):
+		http.Error(w, "Image not found", http.StatusNotFound)
+	case errors.Is(err, images.ErrUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, images.ErrTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.As(err, &maxBytesErr):
+		http.Error(w, fmt.Sprintf("%s: the limit is %d bytes", images.ErrTooLarge, h.manager.MaxSize()), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, images.ErrInvalidImage):
+		writeFieldError(w, imageFormField, err.Error())
+	case errors.Is(err, images.ErrInvalidOrder):
+		writeFieldError(w, "order", err.Error())
+	case errors.Is(err, images.ErrTooMany):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.WithError(err).WithField("vehicle_id", vehicleID).Error("Failed to handle vehicle image")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type uploadedImage struct {
+	ID         string            `json:"id"`
+	URL        string            `json:"url"`
+	Managed    bool              `json:"managed"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// uploadImage posts content as the named multipart field
+func uploadImage(r *mux.Router, vehicleID, field string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile(field, "photo.png")
+	part.Write(content)
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/vehicles/"+vehicleID+"/images", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// servedPath maps an image URL onto the test router, which has no /api/v1
+func servedPath(url string) string {
+	return strings.TrimPrefix(url, "/api/v1")
+}
+
+func TestUploadAndServeImage(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	rec := uploadImage(r, "veh-001", "image", testPNG(t, 1200, 600))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data uploadedImage `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	img := created.Data
+	if !img.Managed || len(img.Thumbnails) != 2 {
+		t.Fatalf("Expected a managed image with thumbnails, got %+v", img)
+	}
+
+	rec = doRequest(r, "GET", "/vehicles/veh-001", nil)
+	if !strings.Contains(rec.Body.String(), img.URL) {
+		t.Errorf("Expected the vehicle's images to include %s", img.URL)
+	}
+
+	rec = doRequest(r, "GET", servedPath(img.Thumbnails["320"]), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected image/png, got %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age=31536000") {
+		t.Errorf("Expected a long cache lifetime, got %q", cc)
+	}
+	thumb, err := png.DecodeConfig(rec.Body)
+	if err != nil || thumb.Width != 320 || thumb.Height != 160 {
+		t.Errorf("Expected a 320x160 thumbnail, got %+v %v", thumb, err)
+	}
+
+	req := httptest.NewRequest("GET", servedPath(img.URL), nil)
+	req.Header.Set("If-None-Match", `"`+img.URL[strings.LastIndex(img.URL, "/")+1:]+`"`)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 for a cached image, got %d", rec.Code)
+	}
+
+	if rec := doRequest(r, "GET", "/vehicles/veh-001/images/img-0000000000000000.png", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown image, got %d", rec.Code)
+	}
+}
+
+func TestUploadImageRejectsBadRequests(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	tests := []struct {
+		name    string
+		vehicle string
+		field   string
+		content []byte
+		want    int
+	}{
+		{"not an image", "veh-001", "image", []byte("%PDF-1.4 not a photo"), http.StatusUnsupportedMediaType},
+		{"corrupt image", "veh-001", "image", []byte("\x89PNG\r\n\x1a\nbroken"), http.StatusBadRequest},
+		{"missing field", "veh-001", "file", testPNG(t, 10, 10), http.StatusBadRequest},
+		{"unknown vehicle", "veh-999", "image", testPNG(t, 10, 10), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := uploadImage(r, tt.vehicle, tt.field, tt.content); rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	big := make([]byte, 11<<20)
+	copy(big, "\x89PNG\r\n\x1a\n")
+	if rec := uploadImage(r, "veh-001", "image", big); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rec.Code)
+	}
+	if rec := doRequest(r, "POST", "/vehicles/veh-001/images", map[string]string{"image": "x"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a JSON body, got %d", rec.Code)
+	}
+}
+
+func TestReorderAndDeleteImages(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	uploadImage(r, "veh-002", "image", testPNG(t, 20, 20))
+	var listed struct {
+		Data []uploadedImage `json:"data"`
+	}
+	json.NewDecoder(doRequest(r, "GET", "/vehicles/veh-002/images", nil).Body).Decode(&listed)
+	if len(listed.Data) != 2 || listed.Data[0].Managed || !listed.Data[1].Managed {
+		t.Fatalf("Expected the seed image then the upload, got %+v", listed.Data)
+	}
+	static, uploaded := listed.Data[0], listed.Data[1]
+
+	rec := doRequest(r, "PUT", "/vehicles/veh-002/images", map[string]interface{}{"order": []string{uploaded.ID}})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an incomplete order, got %d", rec.Code)
+	}
+	rec = doRequest(r, "PUT", "/vehicles/veh-002/images", map[string]interface{}{"order": []string{uploaded.ID, static.ID}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var vehicle struct {
+		Data struct {
+			Images []string `json:"images"`
+		} `json:"data"`
+	}
+	json.NewDecoder(doRequest(r, "GET", "/vehicles/veh-002", nil).Body).Decode(&vehicle)
+	if imgs := vehicle.Data.Images; len(imgs) != 2 || imgs[0] != uploaded.URL {
+		t.Errorf("Expected the upload first, got %v", imgs)
+	}
+
+	if rec := doRequest(r, "DELETE", "/vehicles/veh-002/images/"+uploaded.ID, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+	if rec := doRequest(r, "GET", servedPath(uploaded.URL), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted image to be gone, got %d", rec.Code)
+	}
+	if rec := doRequest(r, "DELETE", "/vehicles/veh-002/images/"+uploaded.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 deleting twice, got %d", rec.Code)
+	}
+}
+
+func TestDeletedVehicleImagesAreNotServed(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	var created struct {
+		Data uploadedImage `json:"data"`
+	}
+	json.NewDecoder(uploadImage(r, "veh-005", "image", testPNG(t, 20, 20)).Body).Decode(&created)
+	if rec := doRequest(r, "GET", servedPath(created.Data.Thumbnails["320"]), nil); rec.Code != http.StatusOK {
+		t.Fatalf("Expected the thumbnail to be served, got %d", rec.Code)
+	}
+
+	if rec := doRequest(r, "DELETE", "/vehicles/veh-005", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+	for _, url := range []string{created.Data.URL, created.Data.Thumbnails["320"], created.Data.Thumbnails["800"]} {
+		if rec := doRequest(r, "GET", servedPath(url), nil); rec.Code != http.StatusNotFound {
+			t.Errorf("Expected %s of a deleted vehicle to be gone, got %d", url, rec.Code)
+		}
+	}
+}
+
+func TestUpdatesKeepUploadedImages(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	var created struct {
+		Data uploadedImage `json:"data"`
+	}
+	json.NewDecoder(uploadImage(r, "veh-003", "image", testPNG(t, 20, 20)).Body).Decode(&created)
+	uploaded := created.Data.URL
+
+	var got struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	json.NewDecoder(doRequest(r, "GET", "/vehicles/veh-003", nil).Body).Decode(&got)
+
+	// A replacement without images cannot remove the upload
+	body := got.Data
+	body["images"] = []string{}
+	if rec := doRequest(r, "PUT", "/vehicles/veh-003", body); rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var vehicle struct {
+		Data struct {
+			Images []string `json:"images"`
+		} `json:"data"`
+	}
+	json.NewDecoder(doRequest(r, "GET", "/vehicles/veh-003", nil).Body).Decode(&vehicle)
+	if imgs := vehicle.Data.Images; len(imgs) != 1 || imgs[0] != uploaded {
+		t.Fatalf("Expected the upload to be kept, got %v", imgs)
+	}
+
+	// Nor can a patch add an upload that does not exist; other images and
+	// the order are the patch's
+	images := []string{uploaded, "/images/vehicles/extra.jpg", "/api/v1/vehicles/veh-003/images/img-0123456789abcdef.png"}
+	rec := doRequest(r, "PATCH", "/vehicles/veh-003", map[string]interface{}{"images": images})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	json.NewDecoder(rec.Body).Decode(&vehicle)
+	if imgs := vehicle.Data.Images; len(imgs) != 2 || imgs[0] != uploaded || imgs[1] != images[1] {
+		t.Errorf("Expected the upload then the static image, got %v", imgs)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/importer"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// maxImportSize is the largest import file accepted
+const maxImportSize = 10 << 20
+
+// ImportHandler handles bulk vehicle imports
+type ImportHandler struct {
+	importer *importer.Importer
+	logger   *logrus.Logger
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(imp *importer.Importer, logger *logrus.Logger) *ImportHandler {
+	return &ImportHandler{
+		importer: imp,
+		logger:   logger,
+	}
+}
+
+// HandleImportVehicles imports a CSV file or JSON array of vehicles from
+// the request body, upserting by VIN. The format comes from the format
+// parameter or the Content-Type; mapping is a JSON object naming the CSV
+// column of each field, and dryRun=true only validates. Nothing is stored
+// unless every record is valid.
+func (h *ImportHandler) HandleImportVehicles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = importer.FormatCSV
+		case "application/json":
+			format = importer.FormatJSON
+		}
+	}
+
+	opts := importer.Options{
+		Format: format,
+		By:     middleware.UserIDFromContext(r.Context()),
+	}
+	if value := query.Get("dryRun"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "dryRun must be true or false", http.StatusBadRequest)
+			return
+		}
+		opts.DryRun = dryRun
+	}
+	if value := query.Get("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &opts.Mapping); err != nil {
+			http.Error(w, "mapping must be a JSON object of field names to column names", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.importer.Import(http.MaxBytesReader(w, r.Body, maxImportSize), opts)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, "Import file too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, importer.ErrUnknownFormat), errors.Is(err, importer.ErrMalformed), errors.Is(err, importer.ErrInvalidMapping):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, repository.ErrDuplicateVIN), errors.Is(err, repository.ErrVehicleNotFound):
+			// Another write changed the inventory between planning and storing
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			h.logger.WithError(err).Error("Failed to import vehicles")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if len(report.Errors) > 0 {
+		status = http.StatusBadRequest
+	}
+	if report.Committed {
+		h.logger.WithFields(logrus.Fields{
+			"created": report.Created,
+			"updated": report.Updated,
+		}).Info("Vehicles imported")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": report,
+	})
+}
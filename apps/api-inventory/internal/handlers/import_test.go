@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func postImport(r http.Handler, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+type importBody struct {
+	Data struct {
+		DryRun    bool `json:"dryRun"`
+		Committed bool `json:"committed"`
+		Created   int  `json:"created"`
+		Updated   int  `json:"updated"`
+		Rows      []struct {
+			Line      int    `json:"line"`
+			Action    string `json:"action"`
+			VehicleID string `json:"vehicleId"`
+		} `json:"rows"`
+		Errors []struct {
+			Line  int    `json:"line"`
+			Field string `json:"field"`
+		} `json:"errors"`
+	} `json:"data"`
+}
+
+func TestImportVehicles(t *testing.T) {
+	r := newTestVehicleRouter(t)
+	admin := asUser(r, "user-002")
+
+	csv := "VIN,Year,Make,Model,Price,Currency,Miles\n" +
+		"WAUZZZ8V8NA123456,2023,Audi,Q7,58990,USD,15000\n" +
+		"1HGCV1F33NA000101,2022,Honda,Accord,27500,USD,12000\n"
+	mapping := url.QueryEscape(`{"mileage":"Miles"}`)
+
+	rec := postImport(admin, "/vehicles/import?dryRun=true&mapping="+mapping, "text/csv", csv)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body importBody
+	json.NewDecoder(rec.Body).Decode(&body)
+	if !body.Data.DryRun || body.Data.Committed || body.Data.Created != 1 || body.Data.Updated != 1 {
+		t.Fatalf("Unexpected dry run report %+v", body.Data)
+	}
+	if rec := doRequest(admin, "GET", "/vehicles/veh-014", nil); strings.Contains(rec.Body.String(), "58990") {
+		t.Fatal("Expected a dry run to leave veh-014 unchanged")
+	}
+
+	rec = postImport(admin, "/vehicles/import?mapping="+mapping, "text/csv; charset=utf-8", csv)
+	body = importBody{}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusOK || !body.Data.Committed {
+		t.Fatalf("Expected the import to be committed, got %d: %+v", rec.Code, body.Data)
+	}
+	created := body.Data.Rows[1].VehicleID
+	if rec := doRequest(admin, "GET", "/vehicles/"+created, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the imported vehicle %q to exist, got %d", created, rec.Code)
+	}
+
+	rec = doRequest(admin, "GET", "/vehicles/veh-014/price-history", nil)
+	if !strings.Contains(rec.Body.String(), `"by":"user-002"`) {
+		t.Errorf("Expected the import to record a price change by user-002, got %s", rec.Body.String())
+	}
+}
+
+func TestImportVehiclesRejectsInvalidRecords(t *testing.T) {
+	r := newTestVehicleRouter(t)
+	admin := asUser(r, "user-002")
+
+	input := `[
+  {"vin": "1HGCV1F33NA000101", "year": 2022, "make": "Honda", "model": "Accord", "price": 27500, "currency": "USD"},
+  {"vin": "1HGCV1F35NA000102", "year": 2022, "make": "Honda", "model": "Civic", "price": -1, "currency": "USD"}
+]`
+	rec := postImport(admin, "/vehicles/import", "application/json", input)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body importBody
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Data.Committed || len(body.Data.Errors) != 1 || body.Data.Errors[0].Line != 3 || body.Data.Errors[0].Field != "price" {
+		t.Fatalf("Expected one price error on line 3, got %+v", body.Data)
+	}
+
+	rec = doRequest(admin, "GET", "/vehicles?make=Honda&model=Accord", nil)
+	if strings.Contains(rec.Body.String(), "1HGCV1F33NA000101") {
+		t.Error("Expected nothing to be stored when a record is invalid")
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"no format", "/vehicles/import", "text/plain", "vin\n", http.StatusBadRequest},
+		{"malformed JSON", "/vehicles/import", "application/json", "[{", http.StatusBadRequest},
+		{"bad mapping", "/vehicles/import?format=csv&mapping=%7B", "text/plain", "vin\n", http.StatusBadRequest},
+		{"unknown mapped field", "/vehicles/import?mapping=" + url.QueryEscape(`{"colour":"Color"}`), "text/csv", "vin\n", http.StatusBadRequest},
+		{"bad dryRun", "/vehicles/import?dryRun=maybe", "text/csv", "vin\n", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postImport(admin, tt.path, tt.contentType, tt.body); rec.Code != tt.code {
+				t.Errorf("Expected status %d, got %d: %s", tt.code, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
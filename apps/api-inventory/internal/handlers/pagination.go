@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Page sizes for list endpoints
+const (
+	defaultPageSize = 100
+	maxPageSize     = 500
+)
+
+var (
+	errInvalidLimit  = errors.New("limit must be a positive integer")
+	errInvalidCursor = errors.New("invalid cursor")
+)
+
+// pageRequest holds the pagination parameters of a list request
+type pageRequest struct {
+	limit  int
+	cursor pageCursor
+}
+
+// pageCursor is the position after which the next page starts. Pages are
+// keyed on the last item returned rather than an offset, so a cursor stays
+// valid when items are added or removed before it.
+type pageCursor struct {
+	// After is the ID of the last item returned
+	After string `json:"after"`
+	// Sort is the sort specification the cursor was issued for
+	Sort string `json:"sort,omitempty"`
+	// Key holds the sort field values of the last item returned
+	Key json.RawMessage `json:"key,omitempty"`
+}
+
+// parsePageRequest reads the limit and cursor query parameters
+func parsePageRequest(query url.Values) (pageRequest, error) {
+	page := pageRequest{limit: defaultPageSize}
+
+	if limit := query.Get("limit"); limit != "" {
+		val, err := strconv.Atoi(limit)
+		if err != nil || val < 1 {
+			return page, errInvalidLimit
+		}
+		if val > maxPageSize {
+			val = maxPageSize
+		}
+		page.limit = val
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.cursor = decoded
+	}
+
+	return page, nil
+}
+
+// encodeCursor returns the opaque form of a cursor
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor produced by encodeCursor
+func decodeCursor(s string) (pageCursor, error) {
+	var cursor pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.After == "" {
+		return cursor, errInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// paginate returns the page of sorted items selected by the request, along
+// with the cursor of the following page if there is one. after reports
+// whether an item sorts after the request cursor, and cursorFor builds the
+// cursor positioned at an item.
+func paginate[T any](items []T, page pageRequest, after func(T) bool, cursorFor func(T) pageCursor) ([]T, string, bool) {
+	start := 0
+	if page.cursor.After != "" {
+		start = sort.Search(len(items), func(i int) bool { return after(items[i]) })
+	}
+
+	end := start + page.limit
+	if end >= len(items) {
+		return items[start:], "", false
+	}
+
+	return items[start:end], encodeCursor(cursorFor(items[end-1])), true
+}
+
+// pageResponse builds the JSON body of a paginated list
+func pageResponse(data interface{}, count int, nextCursor string, hasMore bool) map[string]interface{} {
+	response := map[string]interface{}{
+		"data":       data,
+		"count":      count,
+		"hasMore":    hasMore,
+		"nextCursor": nil,
+	}
+	if hasMore {
+		response["nextCursor"] = nextCursor
+	}
+	return response
+}
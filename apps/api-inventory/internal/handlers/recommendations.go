@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/recommend"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// Number of recommendations returned
+const (
+	defaultRecommendations = 10
+	maxRecommendations     = 50
+)
+
+// RecommendationHandler recommends vehicles to the caller
+type RecommendationHandler struct {
+	repo   repository.Store
+	views  *recommend.Views
+	rates  *exchange.Table
+	logger *logrus.Logger
+}
+
+// NewRecommendationHandler creates a new recommendation handler. rates may
+// be nil, in which case only prices in the caller's currency are compared
+// with their budget.
+func NewRecommendationHandler(repo repository.Store, views *recommend.Views, rates *exchange.Table, logger *logrus.Logger) *RecommendationHandler {
+	return &RecommendationHandler{
+		repo:   repo,
+		views:  views,
+		rates:  rates,
+		logger: logger,
+	}
+}
+
+// recommendationResult is a recommended vehicle in a list response
+type recommendationResult struct {
+	Vehicle     *vehicleResult `json:"vehicle"`
+	Score       float64        `json:"score"`
+	Reasons     []string       `json:"reasons"`
+	Explanation string         `json:"explanation"`
+}
+
+// HandleRecommendations ranks the available vehicles for the caller, best
+// first. deterministic=true gives the same ranking for the same inventory
+// and history on every request.
+func (h *RecommendationHandler) HandleRecommendations(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+	user, err := h.repo.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Unauthorized: unknown user", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := defaultRecommendations
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			http.Error(w, errInvalidLimit.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit > maxRecommendations {
+			limit = maxRecommendations
+		}
+	}
+	deterministic := false
+	if value := query.Get("deterministic"); value != "" {
+		if deterministic, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "deterministic must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	profile := recommend.Profile{User: user}
+	for _, favorite := range h.repo.GetFavorites(userID) {
+		if vehicle, err := h.repo.GetVehicleByID(favorite.VehicleID); err == nil {
+			profile.Favorites = append(profile.Favorites, vehicle)
+		}
+	}
+	for _, vehicleID := range h.views.Recent(userID) {
+		if vehicle, err := h.repo.GetVehicleByID(vehicleID); err == nil {
+			profile.Viewed = append(profile.Viewed, vehicle)
+		}
+	}
+
+	opts := recommend.Options{
+		Now:           time.Now().UTC(),
+		Deterministic: deterministic,
+	}
+	var filter models.VehicleFilter
+	if h.rates != nil {
+		opts.Rates = h.rates
+		if models.IsSupportedCurrency(user.PreferredCurrency) {
+			filter.PriceCurrency = strings.ToUpper(user.PreferredCurrency)
+			filter.Rates = h.rates
+		}
+	}
+
+	recommendations := recommend.Recommend(profile, h.repo.GetAllVehicles(), limit, opts)
+	vehicles := make([]*models.Vehicle, len(recommendations))
+	for i, rec := range recommendations {
+		vehicles[i] = rec.Vehicle
+	}
+	listed := vehicleResults(vehicles)
+	convertPrices(listed, &filter)
+
+	results := make([]*recommendationResult, len(recommendations))
+	for i, rec := range recommendations {
+		results[i] = &recommendationResult{
+			Vehicle:     listed[i],
+			Score:       rec.Score,
+			Reasons:     rec.Reasons,
+			Explanation: rec.Explanation(),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":          results,
+		"count":         len(results),
+		"deterministic": deterministic,
+	})
+}
+
+// TrackViews wraps the vehicle detail handler to add each vehicle the
+// caller successfully fetches to their view history
+func (h *RecommendationHandler) TrackViews(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		if rec.status == http.StatusOK {
+			h.views.Record(middleware.UserIDFromContext(r.Context()), mux.Vars(r)["id"])
+		}
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
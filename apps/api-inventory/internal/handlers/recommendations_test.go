@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecommendations(t *testing.T) {
+	r := newTestVehicleRouter(t)
+	buyer := asUser(r, "user-001")
+
+	type recommendation struct {
+		Vehicle struct {
+			ID             string `json:"id"`
+			Type           string `json:"type"`
+			ConvertedPrice *struct {
+				Currency string `json:"currency"`
+			} `json:"convertedPrice"`
+		} `json:"vehicle"`
+		Score       float64  `json:"score"`
+		Reasons     []string `json:"reasons"`
+		Explanation string   `json:"explanation"`
+	}
+	recommend := func(query string) []recommendation {
+		t.Helper()
+		rec := doRequest(buyer, "GET", "/me/recommendations?deterministic=true"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var body struct {
+			Data  []recommendation `json:"data"`
+			Count int              `json:"count"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		if body.Count != len(body.Data) {
+			t.Errorf("Expected count %d, got %d", len(body.Data), body.Count)
+		}
+		return body.Data
+	}
+	order := func(recs []recommendation) []string {
+		ids := make([]string, len(recs))
+		for i, rec := range recs {
+			ids[i] = rec.Vehicle.ID
+		}
+		return ids
+	}
+
+	first := recommend("")
+	if len(first) != defaultRecommendations {
+		t.Fatalf("Expected %d recommendations, got %d", defaultRecommendations, len(first))
+	}
+	if !reflect.DeepEqual(order(first), order(recommend(""))) {
+		t.Error("Expected the deterministic ranking to repeat")
+	}
+	top := first[0]
+	if top.Vehicle.Type != "sedan" && top.Vehicle.Type != "suv" {
+		t.Errorf("Expected a preferred type first, got %+v", top)
+	}
+	if !strings.Contains(top.Explanation, "preference") || !strings.Contains(top.Explanation, "within budget") {
+		t.Errorf("Expected the explanation to cite the preferences, got %q", top.Explanation)
+	}
+	if top.Vehicle.ConvertedPrice == nil || top.Vehicle.ConvertedPrice.Currency != "USD" {
+		t.Errorf("Expected prices converted to USD, got %+v", top.Vehicle)
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i].Score > first[i-1].Score {
+			t.Fatalf("Expected scores in descending order, got %v after %v", first[i].Score, first[i-1].Score)
+		}
+	}
+
+	// Favorites are not recommended again
+	if rec := doRequest(buyer, "PUT", "/me/favorites/"+top.Vehicle.ID, nil); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", rec.Code)
+	}
+	for _, rec := range recommend("&limit=50") {
+		if rec.Vehicle.ID == top.Vehicle.ID {
+			t.Errorf("Expected favorite %s to be left out", top.Vehicle.ID)
+		}
+	}
+
+	// Viewed vehicles shape the ranking
+	for _, id := range []string{"veh-010", "veh-020"} {
+		if rec := doRequest(buyer, "GET", "/vehicles/"+id, nil); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", rec.Code)
+		}
+	}
+	viewed := false
+	for _, rec := range recommend("&limit=50") {
+		for _, reason := range rec.Reasons {
+			viewed = viewed || reason == "similar to vehicles you viewed"
+		}
+	}
+	if !viewed {
+		t.Error("Expected recommendations similar to viewed vehicles")
+	}
+
+	if got := recommend("&limit=3"); len(got) != 3 {
+		t.Errorf("Expected 3 recommendations, got %d", len(got))
+	}
+	for _, query := range []string{"?limit=0", "?deterministic=maybe"} {
+		if rec := doRequest(buyer, "GET", "/me/recommendations"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
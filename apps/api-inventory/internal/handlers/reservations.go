@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/reservations"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// ReservationHandler lets buyers hold vehicles and manage their holds
+type ReservationHandler struct {
+	manager *reservations.Manager
+	logger  *logrus.Logger
+}
+
+// NewReservationHandler creates a new reservation handler
+func NewReservationHandler(manager *reservations.Manager, logger *logrus.Logger) *ReservationHandler {
+	return &ReservationHandler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// HandleReserveVehicle reserves a vehicle for the caller. The body may set
+// a shorter hold than the maximum, e.g. {"duration": "24h"}.
+func (h *ReservationHandler) HandleReserveVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+	userID := middleware.UserIDFromContext(r.Context())
+
+	var req struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var hold time.Duration
+	if req.Duration != "" {
+		var err error
+		if hold, err = time.ParseDuration(req.Duration); err != nil || hold <= 0 {
+			writeFieldError(w, "duration", "must be a positive duration such as 24h")
+			return
+		}
+	}
+
+	reservation, err := h.manager.Reserve(vehicleID, userID, hold, time.Now().UTC())
+	if err != nil {
+		h.writeReservationError(w, err, vehicleID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": reservation,
+	})
+}
+
+// HandleCancelReservation releases the caller's reservation of a vehicle
+func (h *ReservationHandler) HandleCancelReservation(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+	userID := middleware.UserIDFromContext(r.Context())
+
+	if err := h.manager.Cancel(vehicleID, userID, time.Now().UTC()); err != nil {
+		h.writeReservationError(w, err, vehicleID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListReservations returns the caller's reservations
+func (h *ReservationHandler) HandleListReservations(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.UserIDFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data":    h.manager.ForUser(userID),
+		"maxHold": h.manager.MaxHold().String(),
+	})
+}
+
+// writeReservationError maps reservation errors to HTTP responses
+func (h *ReservationHandler) writeReservationError(w http.ResponseWriter, err error, vehicleID string) {
+	switch {
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		http.Error(w, "Vehicle not found", http.StatusNotFound)
+	case errors.Is(err, reservations.ErrReservationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, reservations.ErrNotAvailable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, reservations.ErrInvalidHold):
+		writeFieldError(w, "duration", err.Error())
+	default:
+		h.logger.WithError(err).WithField("vehicle_id", vehicleID).Error("Failed to update reservation")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVehicleReservations(t *testing.T) {
+	r := newTestVehicleRouter(t)
+	buyer := asUser(r, "user-003")
+	other := asUser(r, "user-004")
+
+	rec := doRequest(buyer, "POST", "/vehicles/veh-001/reservation", map[string]string{"duration": "24h"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data struct {
+			VehicleID  string    `json:"vehicleId"`
+			UserID     string    `json:"userId"`
+			ReservedAt time.Time `json:"reservedAt"`
+			ExpiresAt  time.Time `json:"expiresAt"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Data.VehicleID != "veh-001" || created.Data.UserID != "user-003" {
+		t.Errorf("Unexpected reservation %+v", created.Data)
+	}
+	if hold := created.Data.ExpiresAt.Sub(created.Data.ReservedAt); hold != 24*time.Hour {
+		t.Errorf("Expected a 24h hold, got %s", hold)
+	}
+
+	// A held vehicle cannot be reserved again
+	rec = doRequest(other, "POST", "/vehicles/veh-001/reservation", nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", rec.Code)
+	}
+
+	// Other buyers see the hold but not who holds it
+	rec = doRequest(other, "GET", "/vehicles/veh-001", nil)
+	var vehicle struct {
+		Data struct {
+			Status        string                   `json:"status"`
+			Reservation   map[string]interface{}   `json:"reservation"`
+			StatusHistory []map[string]interface{} `json:"statusHistory"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&vehicle)
+	if vehicle.Data.Status != "reserved" || vehicle.Data.Reservation["expiresAt"] == nil {
+		t.Errorf("Expected a reserved vehicle with its expiry, got %+v", vehicle.Data)
+	}
+	if _, ok := vehicle.Data.Reservation["userId"]; ok {
+		t.Error("Expected the buyer to be left out of the vehicle")
+	}
+	if history := vehicle.Data.StatusHistory; len(history) != 1 || history[0]["to"] != "reserved" {
+		t.Errorf("Expected the reservation in the status history, got %+v", history)
+	}
+	for _, transition := range vehicle.Data.StatusHistory {
+		if _, ok := transition["by"]; ok {
+			t.Error("Expected the buyer to be left out of the status history")
+		}
+	}
+
+	listReservations := func(h http.Handler) []string {
+		rec := doRequest(h, "GET", "/reservations", nil)
+		var body struct {
+			Data []struct {
+				VehicleID string `json:"vehicleId"`
+			} `json:"data"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		ids := []string{}
+		for _, reservation := range body.Data {
+			ids = append(ids, reservation.VehicleID)
+		}
+		return ids
+	}
+	if ids := listReservations(buyer); len(ids) != 1 || ids[0] != "veh-001" {
+		t.Errorf("Expected the buyer's reservation, got %v", ids)
+	}
+	if ids := listReservations(other); len(ids) != 0 {
+		t.Errorf("Expected no reservations for another user, got %v", ids)
+	}
+
+	// Only the holder can cancel
+	rec = doRequest(other, "DELETE", "/vehicles/veh-001/reservation", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 cancelling another buyer's hold, got %d", rec.Code)
+	}
+	rec = doRequest(buyer, "DELETE", "/vehicles/veh-001/reservation", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(buyer, "GET", "/vehicles/veh-001/transitions", nil)
+	var transitions struct {
+		Data struct {
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&transitions)
+	if transitions.Data.Status != "available" {
+		t.Errorf("Expected the vehicle to be available again, got %s", transitions.Data.Status)
+	}
+
+	rec = doRequest(other, "POST", "/vehicles/veh-001/reservation", map[string]string{"duration": "720h"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a hold over the maximum, got %d", rec.Code)
+	}
+	rec = doRequest(other, "POST", "/vehicles/veh-999/reservation", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown vehicle, got %d", rec.Code)
+	}
+}
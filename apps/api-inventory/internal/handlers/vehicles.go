@@ -2,34 +2,126 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/geo"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/images"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/search"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
 // VehicleHandler handles vehicle-related requests
 type VehicleHandler struct {
-	repo   *repository.Repository
+	repo   repository.Store
+	rates  *exchange.Table
 	logger *logrus.Logger
+	// images removes the image files of deleted vehicles; nil when there
+	// are none to remove
+	images *images.Manager
+	// updateMu serialises read-modify-write updates so a status transition
+	// and an edit of the same vehicle cannot overwrite each other
+	updateMu sync.Mutex
 }
 
-// NewVehicleHandler creates a new vehicle handler
-func NewVehicleHandler(repo *repository.Repository, logger *logrus.Logger) *VehicleHandler {
+// NewVehicleHandler creates a new vehicle handler. rates may be nil, in
+// which case prices are never converted.
+func NewVehicleHandler(repo repository.Store, rates *exchange.Table, logger *logrus.Logger) *VehicleHandler {
 	return &VehicleHandler{
 		repo:   repo,
+		rates:  rates,
 		logger: logger,
 	}
 }
 
-// HandleListVehicles returns all vehicles or filtered results
+// UpdateLock returns the lock held while a vehicle is read, changed and
+// written back, for other writers such as reservations to share
+func (h *VehicleHandler) UpdateLock() sync.Locker {
+	return &h.updateMu
+}
+
+// SetImages makes the handler remove the uploaded images of the vehicles
+// it deletes. The manager shares UpdateLock, so it is set after creation.
+func (h *VehicleHandler) SetImages(manager *images.Manager) {
+	h.images = manager
+}
+
+// HandleListVehicles returns a page of vehicles, optionally filtered
 func (h *VehicleHandler) HandleListVehicles(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 
-	// Build filter from query parameters
+	page, err := parsePageRequest(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := h.queryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A text query ranks the matches; otherwise the filter alone applies
+	var results []*vehicleResult
+	q := query.Get("q")
+	if q != "" {
+		for _, match := range h.repo.SearchText(q, filter) {
+			result := vehicleResults([]*models.Vehicle{match.Vehicle})[0]
+			result.Score = match.Score
+			results = append(results, result)
+		}
+	} else {
+		// Every filter field applies, so the results agree with the facets
+		results = vehicleResults(h.repo.SearchVehicles(filter))
+	}
+	measureDistances(results, filter.Near)
+	convertPrices(results, filter)
+
+	// Without an explicit sort, text matches rank by relevance and radius
+	// searches by distance
+	defaultOrder := ""
+	switch {
+	case q != "":
+		defaultOrder = relevanceSort
+	case filter.Near != nil:
+		defaultOrder = distanceSort
+	}
+
+	results, nextCursor, hasMore, err := pageVehicles(results, filter, defaultOrder, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q != "" {
+		highlightResults(results, search.Terms(q))
+	}
+	response := pageResponse(results, len(results), nextCursor, hasMore)
+	if facets := h.facets(r, q, filter); facets != nil {
+		response["facets"] = facets
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// queryFilter builds a vehicle filter from the query parameters of
+// HandleListVehicles. Malformed numbers and dates are ignored.
+func (h *VehicleHandler) queryFilter(r *http.Request) (*models.VehicleFilter, error) {
+	query := r.URL.Query()
 	filter := &models.VehicleFilter{}
 
 	if make := query.Get("make"); make != "" {
@@ -59,6 +151,13 @@ func (h *VehicleHandler) HandleListVehicles(w http.ResponseWriter, r *http.Reque
 	if drivetrain := query.Get("drivetrain"); drivetrain != "" {
 		filter.Drivetrain = drivetrain
 	}
+	if dealerID := query.Get("dealerId"); dealerID != "" {
+		filter.DealerID = dealerID
+	}
+	filter.Sort = query.Get("sort")
+	if err := h.setPriceCurrency(r, filter, query.Get("priceCurrency")); err != nil {
+		return nil, err
+	}
 
 	// Parse numeric filters
 	if minPrice := query.Get("minPrice"); minPrice != "" {
@@ -81,23 +180,89 @@ func (h *VehicleHandler) HandleListVehicles(w http.ResponseWriter, r *http.Reque
 			filter.MaxYear = val
 		}
 	}
+	if minMileage := query.Get("minMileage"); minMileage != "" {
+		if val, err := strconv.Atoi(minMileage); err == nil {
+			filter.MinMileage = val
+		}
+	}
+	if maxMileage := query.Get("maxMileage"); maxMileage != "" {
+		if val, err := strconv.Atoi(maxMileage); err == nil {
+			filter.MaxMileage = val
+		}
+	}
+	if minRating := query.Get("minDealerRating"); minRating != "" {
+		if val, err := strconv.ParseFloat(minRating, 64); err == nil {
+			filter.MinDealerRating = val
+		}
+	}
+	if listedAfter := query.Get("listedAfter"); listedAfter != "" {
+		if val, err := parseDateParam(listedAfter); err == nil {
+			filter.ListedAfter = &val
+		}
+	}
+	if listedBefore := query.Get("listedBefore"); listedBefore != "" {
+		if val, err := parseDateParam(listedBefore); err == nil {
+			filter.ListedBefore = &val
+		}
+	}
 
-	// Get vehicles
-	var vehicles []*models.Vehicle
-	if filter.Make != "" || filter.Model != "" || filter.Type != "" || filter.MinPrice > 0 || filter.Currency != "" {
-		vehicles = h.repo.SearchVehicles(filter)
-	} else {
-		vehicles = h.repo.GetAllVehicles()
+	// List filters accept repeated parameters or comma-separated values
+	filter.VehicleTypes = listParam(query, "vehicleTypes")
+	filter.Features = listParam(query, "features")
+	filter.AnyFeatures = listParam(query, "anyFeatures")
+	filter.ExteriorColors = listParam(query, "exteriorColors")
+	filter.InteriorColors = listParam(query, "interiorColors")
+	filter.Statuses = listParam(query, "statuses")
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = models.PublicStatuses
 	}
 
-	response := map[string]interface{}{
-		"data":  vehicles,
-		"count": len(vehicles),
+	// Price drops, e.g. priceDropped=true&minPriceDrop=5&priceDropDays=14
+	if dropped := query.Get("priceDropped"); dropped != "" {
+		if val, err := strconv.ParseBool(dropped); err == nil {
+			filter.PriceDropped = val
+		}
+	}
+	if minDrop := query.Get("minPriceDrop"); minDrop != "" {
+		if val, err := strconv.ParseFloat(minDrop, 64); err == nil {
+			filter.MinPriceDrop = val
+		}
+	}
+	if days := query.Get("priceDropDays"); days != "" {
+		if val, err := strconv.Atoi(days); err == nil {
+			filter.PriceDropDays = val
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+	// Radius search around near=lat,lng
+	if near := query.Get("near"); near != "" {
+		if val, err := parseGeoPoint(near); err == nil {
+			filter.Near = &val
+		}
+	}
+	if radius := query.Get("radiusKm"); radius != "" {
+		if val, err := strconv.ParseFloat(radius, 64); err == nil {
+			filter.RadiusKm = val
+		}
+	}
+
+	return filter, nil
+}
+
+// HandleDealerVehicles returns a page of a dealer's inventory. It takes
+// the same query parameters as HandleListVehicles.
+func (h *VehicleHandler) HandleDealerVehicles(w http.ResponseWriter, r *http.Request) {
+	dealerID := mux.Vars(r)["id"]
+	if _, err := h.repo.GetDealerByID(dealerID); err != nil {
+		h.writeRepositoryError(w, err, "")
+		return
+	}
+
+	r = r.Clone(r.Context())
+	query := r.URL.Query()
+	query.Set("dealerId", dealerID)
+	r.URL.RawQuery = query.Encode()
+	h.HandleListVehicles(w, r)
 }
 
 // HandleGetVehicle returns a single vehicle by ID
@@ -105,6 +270,12 @@ func (h *VehicleHandler) HandleGetVehicle(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	vehicleID := vars["id"]
 
+	var filter models.VehicleFilter
+	if err := h.setPriceCurrency(r, &filter, r.URL.Query().Get("priceCurrency")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	vehicle, err := h.repo.GetVehicleByID(vehicleID)
 	if err != nil {
 		h.logger.WithField("vehicle_id", vehicleID).Warn("Vehicle not found")
@@ -112,8 +283,11 @@ func (h *VehicleHandler) HandleGetVehicle(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	results := vehicleResults([]*models.Vehicle{vehicle})
+	convertPrices(results, &filter)
+
 	response := map[string]interface{}{
-		"data": vehicle,
+		"data": results[0],
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -121,23 +295,726 @@ func (h *VehicleHandler) HandleGetVehicle(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(response)
 }
 
-// HandleSearchVehicles handles POST requests for vehicle search
+// HandleSearchVehicles handles POST requests for vehicle search. The page is
+// selected with the limit and cursor query parameters.
 func (h *VehicleHandler) HandleSearchVehicles(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var filter models.VehicleFilter
 	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
 		h.logger.WithError(err).Warn("Invalid search request")
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if err := h.setPriceCurrency(r, &filter, filter.PriceCurrency); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = models.PublicStatuses
+	}
+
+	results := vehicleResults(h.repo.SearchVehicles(&filter))
+	measureDistances(results, filter.Near)
+	convertPrices(results, &filter)
+
+	defaultOrder := ""
+	if filter.Near != nil {
+		defaultOrder = distanceSort
+	}
+
+	results, nextCursor, hasMore, err := pageVehicles(results, &filter, defaultOrder, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := pageResponse(results, len(results), nextCursor, hasMore)
+	if facets := h.facets(r, "", &filter); facets != nil {
+		response["facets"] = facets
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleCreateVehicle creates a new vehicle listing with a server-generated ID
+func (h *VehicleHandler) HandleCreateVehicle(w http.ResponseWriter, r *http.Request) {
+	var vehicle models.Vehicle
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		h.logger.WithError(err).Warn("Invalid create vehicle request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	vehicle.Normalize()
+	if vehicle.Status == "" {
+		vehicle.Status = models.StatusAvailable
+	}
+	vehicle.StatusHistory = nil
+	vehicle.Reservation = nil
+	vehicle.PriceHistory = nil
+	vehicle.PriceDrop = nil
+	if vehicle.ListingDate.IsZero() {
+		vehicle.ListingDate = time.Now().UTC()
+	}
+
+	if err := vehicle.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !models.IsInitialStatus(vehicle.Status) {
+		writeFieldError(w, "status", "new listings must be "+strings.Join(models.InitialStatuses, " or "))
+		return
+	}
+	if err := vehicle.CheckVIN(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.checkDealer(w, &vehicle) {
+		return
+	}
+
+	if err := h.repo.CreateVehicle(&vehicle); err != nil {
+		h.writeRepositoryError(w, err, "")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"vehicle_id": vehicle.ID,
+		"vin":        vehicle.VIN,
+	}).Info("Vehicle created")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": vehicle,
+	})
+}
+
+// HandleUpdateVehicle replaces a vehicle listing (PUT)
+func (h *VehicleHandler) HandleUpdateVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	h.updateMu.Lock()
+	defer h.updateMu.Unlock()
+
+	existing, err := h.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
+
+	var vehicle models.Vehicle
+	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
+		h.logger.WithError(err).Warn("Invalid update vehicle request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// The ID comes from the path and the listing date is kept unless replaced
+	vehicle.ID = vehicleID
+	if vehicle.ListingDate.IsZero() {
+		vehicle.ListingDate = existing.ListingDate
+	}
+
+	h.saveVehicle(w, r, &vehicle, existing)
+}
+
+// HandlePatchVehicle applies a partial update to a vehicle listing (PATCH).
+// Fields present in the body replace the stored values; others are kept.
+func (h *VehicleHandler) HandlePatchVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	h.updateMu.Lock()
+	defer h.updateMu.Unlock()
+
+	existing, err := h.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
 
-	vehicles := h.repo.SearchVehicles(&filter)
+	// Decode onto a copy so readers of the stored vehicle never see a
+	// half-applied patch
+	vehicle := existing.Clone()
+	if err := json.NewDecoder(r.Body).Decode(vehicle); err != nil {
+		h.logger.WithError(err).Warn("Invalid patch vehicle request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	vehicle.ID = vehicleID
+
+	h.saveVehicle(w, r, vehicle, existing)
+}
+
+// saveVehicle validates and stores an update of existing, then writes it
+// back. The status, its history and any reservation are only changed by
+// transitions and reservations, uploaded images only through the images
+// endpoints, and price changes are added to the price history.
+func (h *VehicleHandler) saveVehicle(w http.ResponseWriter, r *http.Request, vehicle, existing *models.Vehicle) {
+	vehicle.Normalize()
+
+	if vehicle.Status == "" {
+		vehicle.Status = existing.Status
+	}
+	if vehicle.Status != existing.Status {
+		writeFieldError(w, "status", "can only be changed through the transitions endpoint")
+		return
+	}
+	vehicle.StatusHistory = existing.StatusHistory
+	vehicle.Reservation = existing.Reservation
+	vehicle.PriceHistory = existing.PriceHistory
+	vehicle.PriceDrop = existing.PriceDrop
+	images.KeepUploaded(vehicle, existing)
+
+	if err := vehicle.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	warnings, err := vehicle.CheckVINUpdate(existing)
+	if err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if !h.checkDealer(w, vehicle) {
+		return
+	}
+	vehicle.RecordPriceChange(existing.Price, existing.Currency, middleware.UserIDFromContext(r.Context()), time.Now().UTC())
+
+	if err := h.repo.UpdateVehicle(vehicle); err != nil {
+		h.writeRepositoryError(w, err, vehicle.ID)
+		return
+	}
+
+	h.logger.WithField("vehicle_id", vehicle.ID).Info("Vehicle updated")
 
 	response := map[string]interface{}{
-		"data":  vehicles,
-		"count": len(vehicles),
+		"data": vehicle,
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// HandleTransitionVehicle moves a vehicle to another status. Moves the
+// lifecycle does not allow are rejected with 409 and the allowed statuses.
+// Vehicles are only reserved through the reservation endpoint, so every
+// hold has a buyer and an expiry.
+func (h *VehicleHandler) HandleTransitionVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	var req struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid transition request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	status := strings.ToLower(strings.TrimSpace(req.Status))
+	if !models.IsValidStatus(status) {
+		writeFieldError(w, "status", "must be one of "+strings.Join(models.Statuses, ", "))
+		return
+	}
+	if status == models.StatusReserved {
+		writeFieldError(w, "status", "vehicles are reserved through the reservation endpoint")
+		return
+	}
+
+	h.updateMu.Lock()
+	defer h.updateMu.Unlock()
+
+	existing, err := h.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
+
+	vehicle := existing.Clone()
+	userID := middleware.UserIDFromContext(r.Context())
+	if err := vehicle.Transition(status, userID, req.Note, time.Now().UTC()); err != nil {
+		var terr *models.TransitionError
+		if errors.As(err, &terr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   terr.Error(),
+				"from":    terr.From,
+				"to":      terr.To,
+				"allowed": terr.Allowed,
+			})
+			return
+		}
+		h.logger.WithError(err).Error("Failed to transition vehicle")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.repo.UpdateVehicle(vehicle); err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"vehicle_id": vehicleID,
+		"user_id":    userID,
+		"from":       existing.Status,
+		"to":         status,
+	}).Info("Vehicle status changed")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": vehicle,
+	})
+}
+
+// HandleListTransitions returns a vehicle's status, the statuses it can move
+// to and its transition history, with who made each change. It is for
+// administrators; other responses leave the users out of the history.
+func (h *VehicleHandler) HandleListTransitions(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	vehicle, err := h.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
+
+	history := vehicle.StatusHistory
+	if history == nil {
+		history = []models.StatusTransition{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"status":  vehicle.Status,
+			"allowed": models.AllowedTransitions(vehicle.Status),
+			"history": history,
+		},
+	})
+}
+
+// HandlePriceHistory returns a vehicle's price changes, oldest first, and
+// its latest price drop
+func (h *VehicleHandler) HandlePriceHistory(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	vehicle, err := h.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
+
+	history := vehicle.PriceHistory
+	if history == nil {
+		history = []models.PriceChange{}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{
+			"price":     vehicle.Price,
+			"currency":  vehicle.Currency,
+			"priceDrop": vehicle.PriceDrop,
+			"history":   history,
+		},
+	})
+}
+
+// HandleDeleteVehicle removes a vehicle listing
+func (h *VehicleHandler) HandleDeleteVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := mux.Vars(r)["id"]
+
+	// Hold the lock so an image cannot be added between the read and the
+	// delete; an upload that loses the race removes its own files
+	h.updateMu.Lock()
+	vehicle, err := h.repo.GetVehicleByID(vehicleID)
+	if err == nil {
+		err = h.repo.DeleteVehicle(vehicleID)
+	}
+	h.updateMu.Unlock()
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicleID)
+		return
+	}
+	if h.images != nil {
+		h.images.DeleteVehicle(vehicle)
+	}
+
+	h.logger.WithField("vehicle_id", vehicleID).Info("Vehicle deleted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkDealer checks that the vehicle's dealer exists, writing a 400
+// response and returning false if it does not
+func (h *VehicleHandler) checkDealer(w http.ResponseWriter, vehicle *models.Vehicle) bool {
+	if vehicle.DealerID == "" {
+		return true
+	}
+	_, err := h.repo.GetDealerByID(vehicle.DealerID)
+	if errors.Is(err, repository.ErrDealerNotFound) {
+		writeFieldError(w, "dealerId", "does not match a dealer")
+		return false
+	}
+	if err != nil {
+		h.writeRepositoryError(w, err, vehicle.ID)
+		return false
+	}
+	return true
+}
+
+// writeRepositoryError maps repository errors to HTTP responses
+func (h *VehicleHandler) writeRepositoryError(w http.ResponseWriter, err error, vehicleID string) {
+	switch {
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		h.logger.WithField("vehicle_id", vehicleID).Warn("Vehicle not found")
+		http.Error(w, "Vehicle not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDealerNotFound):
+		http.Error(w, "Dealer not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDuplicateVIN):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.WithError(err).Error("Failed to store vehicle")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeValidationError writes a 400 response listing the invalid fields
+func writeValidationError(w http.ResponseWriter, err error) {
+	response := map[string]interface{}{
+		"error": err.Error(),
+	}
+
+	var verr *models.ValidationError
+	if errors.As(err, &verr) {
+		response["error"] = "validation failed"
+		response["fields"] = verr.Errors
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
 	json.NewEncoder(w).Encode(response)
 }
+
+// writeFieldError writes a 400 response for a single invalid field
+func writeFieldError(w http.ResponseWriter, field, message string) {
+	writeValidationError(w, &models.ValidationError{
+		Errors: []models.FieldError{{Field: field, Message: message}},
+	})
+}
+
+// listParam returns the values of a list query parameter, given either as
+// repeated parameters or as a comma-separated list
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, param := range query[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// parseDateParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC)
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseGeoPoint parses a "lat,lng" pair in decimal degrees
+func parseGeoPoint(value string) (models.GeoPoint, error) {
+	latText, lngText, ok := strings.Cut(value, ",")
+	if !ok {
+		return models.GeoPoint{}, errors.New("expected lat,lng")
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return models.GeoPoint{}, errors.New("invalid latitude")
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(lngText), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return models.GeoPoint{}, errors.New("invalid longitude")
+	}
+	return models.GeoPoint{Lat: lat, Lng: lng}, nil
+}
+
+// errPriceCurrency is returned for an unsupported price currency
+var errPriceCurrency = errors.New("unsupported price currency")
+
+// setPriceCurrency sets the currency the filter's prices are expressed in:
+// the requested currency, or else the caller's preferred currency. Without
+// exchange rates prices are left unconverted.
+func (h *VehicleHandler) setPriceCurrency(r *http.Request, filter *models.VehicleFilter, requested string) error {
+	currency := strings.ToUpper(strings.TrimSpace(requested))
+	if currency != "" && !models.IsSupportedCurrency(currency) {
+		return errPriceCurrency
+	}
+	if currency == "" {
+		if user, err := h.repo.GetUserByID(middleware.UserIDFromContext(r.Context())); err == nil && models.IsSupportedCurrency(user.PreferredCurrency) {
+			currency = strings.ToUpper(user.PreferredCurrency)
+		}
+	}
+
+	filter.PriceCurrency = currency
+	if currency != "" && h.rates != nil {
+		filter.Rates = h.rates
+	}
+	return nil
+}
+
+// facets counts the facets of the filtered result set, over the matches of
+// the text query q if one is given. Callers that do not need them can skip
+// the cost with facets=false.
+func (h *VehicleHandler) facets(r *http.Request, q string, filter *models.VehicleFilter) *models.VehicleFacets {
+	if r.URL.Query().Get("facets") == "false" {
+		return nil
+	}
+
+	// Only the vehicles passing the predicates no facet ignores can be
+	// counted, so let the store narrow them down through its indexes
+	base := repository.FacetBase(filter)
+	var vehicles []*models.Vehicle
+	if q != "" {
+		for _, match := range h.repo.SearchText(q, base) {
+			vehicles = append(vehicles, match.Vehicle)
+		}
+	} else {
+		vehicles = h.repo.SearchVehicles(base)
+	}
+
+	return repository.ComputeFacets(vehicles, filter)
+}
+
+// errCursorSort is returned when a cursor is reused with a different sort
+var errCursorSort = errors.New("cursor was issued for a different sort")
+
+// relevanceSort identifies the default order of text query results in
+// cursors: best match first, then by ID
+const relevanceSort = "relevance"
+
+// distanceSort identifies the default order of radius search results in
+// cursors: nearest first, then by ID
+const distanceSort = "distance"
+
+// vehicleResult is a vehicle in a list response. Results of a text query
+// also carry their relevance score and the matched words of each field,
+// results of a radius search their distance from the search point, and
+// results in a price currency their converted price.
+type vehicleResult struct {
+	*models.Vehicle
+	Score          float64             `json:"score,omitempty"`
+	DistanceKm     *float64            `json:"distanceKm,omitempty"`
+	ConvertedPrice *models.Money       `json:"convertedPrice,omitempty"`
+	Highlights     map[string][]string `json:"highlights,omitempty"`
+	// sortView is the vehicle as sorts see it, with the converted price
+	sortView *models.Vehicle
+}
+
+// sortVehicle returns the vehicle the sort keys are read from
+func (r *vehicleResult) sortVehicle() *models.Vehicle {
+	if r.sortView != nil {
+		return r.sortView
+	}
+	return r.Vehicle
+}
+
+// vehicleResults wraps vehicles for a list response
+func vehicleResults(vehicles []*models.Vehicle) []*vehicleResult {
+	results := make([]*vehicleResult, len(vehicles))
+	for i, vehicle := range vehicles {
+		if vehicle.Reservation != nil || len(vehicle.StatusHistory) > 0 {
+			// Buyers only see that a vehicle is held and until when, not
+			// who holds or bought it
+			public := *vehicle
+			if vehicle.Reservation != nil {
+				public.Reservation = vehicle.Reservation.Public()
+			}
+			public.StatusHistory = models.PublicHistory(vehicle.StatusHistory)
+			vehicle = &public
+		}
+		results[i] = &vehicleResult{Vehicle: vehicle}
+	}
+	return results
+}
+
+// measureDistances sets the distance of each result from near. Results
+// without coordinates are left without a distance.
+func measureDistances(results []*vehicleResult, near *models.GeoPoint) {
+	if near == nil {
+		return
+	}
+	for _, result := range results {
+		if result.Coordinates != nil {
+			distance := geo.DistanceKm(*near, *result.Coordinates)
+			result.DistanceKm = &distance
+		}
+	}
+}
+
+// convertPrices sets the price of each result in the filter's price
+// currency, rounded to cents. Price sorts use the converted price; results
+// whose price cannot be converted keep their listed price.
+func convertPrices(results []*vehicleResult, filter *models.VehicleFilter) {
+	if !filter.ConvertsPrices() {
+		return
+	}
+	for _, result := range results {
+		price, ok := filter.PriceOf(result.Vehicle)
+		if !ok {
+			continue
+		}
+		price = math.Round(price*100) / 100
+		result.ConvertedPrice = &models.Money{Amount: price, Currency: filter.PriceCurrency}
+
+		view := *result.Vehicle
+		view.Price = price
+		result.sortView = &view
+	}
+}
+
+// pageVehicles sorts the results by the filter's sort specification, or in
+// the default order (relevanceSort, distanceSort or by ID) when no sort is
+// given, and selects the requested page
+func pageVehicles(results []*vehicleResult, filter *models.VehicleFilter, defaultOrder string, page pageRequest) ([]*vehicleResult, string, bool, error) {
+	keys, err := models.ParseVehicleSort(filter.Sort)
+	if err != nil {
+		return nil, "", false, err
+	}
+	sortSpec := models.FormatVehicleSort(keys)
+	// Price keys in a cursor are in the price currency, so the cursor is
+	// only valid for that currency
+	if filter.ConvertsPrices() {
+		for _, key := range keys {
+			if key.Field == "price" {
+				sortSpec += "@" + filter.PriceCurrency
+				break
+			}
+		}
+	}
+
+	compare := func(a, b *vehicleResult) int {
+		return models.CompareVehicles(a.sortVehicle(), b.sortVehicle(), keys)
+	}
+	keyOf := func(r *vehicleResult) interface{} {
+		return models.VehicleSortValues(r.sortVehicle(), keys)
+	}
+	switch {
+	case len(keys) > 0:
+		// An explicit sort overrides the default order
+	case defaultOrder == relevanceSort:
+		sortSpec = relevanceSort
+		compare = func(a, b *vehicleResult) int {
+			switch {
+			case a.Score > b.Score:
+				return -1
+			case a.Score < b.Score:
+				return 1
+			}
+			return strings.Compare(a.ID, b.ID)
+		}
+		keyOf = func(r *vehicleResult) interface{} {
+			return map[string]float64{"score": r.Score}
+		}
+	case defaultOrder == distanceSort:
+		sortSpec = distanceSort
+		compare = func(a, b *vehicleResult) int {
+			if c := compareDistance(a.DistanceKm, b.DistanceKm); c != 0 {
+				return c
+			}
+			return strings.Compare(a.ID, b.ID)
+		}
+		keyOf = func(r *vehicleResult) interface{} {
+			return map[string]*float64{"distanceKm": r.DistanceKm}
+		}
+	}
+
+	// pivot is the last result of the previous page, rebuilt from the cursor
+	pivot := &vehicleResult{Vehicle: &models.Vehicle{}}
+	if page.cursor.After != "" {
+		if page.cursor.Sort != sortSpec {
+			return nil, "", false, errCursorSort
+		}
+		if len(page.cursor.Key) > 0 {
+			if err := json.Unmarshal(page.cursor.Key, pivot); err != nil {
+				return nil, "", false, errInvalidCursor
+			}
+		}
+		pivot.ID = page.cursor.After
+	}
+
+	sort.Slice(results, func(i, k int) bool { return compare(results[i], results[k]) < 0 })
+	results, nextCursor, hasMore := paginate(results, page,
+		func(r *vehicleResult) bool { return compare(r, pivot) > 0 },
+		func(r *vehicleResult) pageCursor {
+			cursor := pageCursor{After: r.ID, Sort: sortSpec}
+			if sortSpec != "" {
+				cursor.Key, _ = json.Marshal(keyOf(r))
+			}
+			return cursor
+		})
+
+	return results, nextCursor, hasMore, nil
+}
+
+// compareDistance orders distances nearest first, with unknown distances last
+func compareDistance(a, b *float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	case *a < *b:
+		return -1
+	case *a > *b:
+		return 1
+	}
+	return 0
+}
+
+// highlightResults records the words of each text field that match the
+// query terms
+func highlightResults(results []*vehicleResult, terms []string) {
+	for _, result := range results {
+		highlights := make(map[string][]string)
+		add := func(field, text string) {
+			if marked, ok := search.Highlight(text, terms); ok {
+				highlights[field] = append(highlights[field], marked)
+			}
+		}
+
+		add("make", result.Make)
+		add("model", result.Model)
+		add("trim", result.Trim)
+		add("exteriorColor", result.ExteriorColor)
+		add("interiorColor", result.InteriorColor)
+		add("location", result.Location)
+		for _, feature := range result.Features {
+			add("features", feature)
+		}
+
+		if len(highlights) > 0 {
+			result.Highlights = highlights
+		}
+	}
+}
@@ -0,0 +1,1024 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/blob"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/exchange"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/images"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/importer"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/middleware"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/recommend"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/reservations"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestVehicleRouter(t *testing.T) *mux.Router {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := repository.NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	rates, err := exchange.OpenWithSeed(filepath.Join(dataPath, "exchange_rates.json"), "")
+	if err != nil {
+		t.Fatalf("Failed to load exchange rates: %v", err)
+	}
+
+	handler := NewVehicleHandler(repo, rates, logger)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/vehicles", handler.HandleListVehicles).Methods("GET")
+	r.HandleFunc("/vehicles", handler.HandleCreateVehicle).Methods("POST")
+	r.HandleFunc("/vehicles/search", handler.HandleSearchVehicles).Methods("POST")
+	r.HandleFunc("/vehicles/export", handler.HandleExportVehicles).Methods("GET")
+	r.HandleFunc("/vehicles/compare", handler.HandleCompareVehicles).Methods("GET")
+	importHandler := NewImportHandler(importer.New(repo, handler.UpdateLock()), logger)
+	r.HandleFunc("/vehicles/import", importHandler.HandleImportVehicles).Methods("POST")
+	recommendationHandler := NewRecommendationHandler(repo, recommend.NewViews(), rates, logger)
+	r.HandleFunc("/vehicles/{id}", recommendationHandler.TrackViews(handler.HandleGetVehicle)).Methods("GET")
+	r.HandleFunc("/vehicles/{id}", handler.HandleUpdateVehicle).Methods("PUT")
+	r.HandleFunc("/vehicles/{id}", handler.HandlePatchVehicle).Methods("PATCH")
+	r.HandleFunc("/vehicles/{id}", handler.HandleDeleteVehicle).Methods("DELETE")
+	r.HandleFunc("/vehicles/{id}/transitions", handler.HandleTransitionVehicle).Methods("POST")
+	r.HandleFunc("/vehicles/{id}/transitions", handler.HandleListTransitions).Methods("GET")
+	r.HandleFunc("/vehicles/{id}/price-history", handler.HandlePriceHistory).Methods("GET")
+
+	reservationHandler := NewReservationHandler(reservations.NewManager(repo, handler.UpdateLock(), 48*time.Hour, logger), logger)
+	r.HandleFunc("/vehicles/{id}/reservation", reservationHandler.HandleReserveVehicle).Methods("POST")
+	r.HandleFunc("/vehicles/{id}/reservation", reservationHandler.HandleCancelReservation).Methods("DELETE")
+	r.HandleFunc("/reservations", reservationHandler.HandleListReservations).Methods("GET")
+
+	favoriteHandler := NewFavoriteHandler(repo, logger)
+	r.HandleFunc("/me/favorites", favoriteHandler.HandleListFavorites).Methods("GET")
+	r.HandleFunc("/me/favorites/{vehicleId}", favoriteHandler.HandleAddFavorite).Methods("PUT")
+	r.HandleFunc("/me/favorites/{vehicleId}", favoriteHandler.HandleRemoveFavorite).Methods("DELETE")
+	r.HandleFunc("/me/recommendations", recommendationHandler.HandleRecommendations).Methods("GET")
+
+	dealerHandler := NewDealerHandler(repo, logger)
+	r.HandleFunc("/dealers", dealerHandler.HandleListDealers).Methods("GET")
+	r.HandleFunc("/dealers/{id}", dealerHandler.HandleGetDealer).Methods("GET")
+	r.HandleFunc("/dealers/{id}/vehicles", handler.HandleDealerVehicles).Methods("GET")
+	r.HandleFunc("/dealers/{id}/reviews", dealerHandler.HandleListReviews).Methods("GET")
+	r.HandleFunc("/dealers/{id}/reviews", dealerHandler.HandlePostReview).Methods("POST")
+
+	blobs, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create blob store: %v", err)
+	}
+	imageManager := images.NewManager(repo, blobs, handler.UpdateLock(), 0, logger)
+	handler.SetImages(imageManager)
+	imageHandler := NewImageHandler(imageManager, logger)
+	r.HandleFunc("/vehicles/{id}/images", imageHandler.HandleListImages).Methods("GET")
+	r.HandleFunc("/vehicles/{id}/images", imageHandler.HandleUploadImage).Methods("POST")
+	r.HandleFunc("/vehicles/{id}/images", imageHandler.HandleReorderImages).Methods("PUT")
+	r.HandleFunc("/vehicles/{id}/images/{file}", imageHandler.HandleServeImage).Methods("GET")
+	r.HandleFunc("/vehicles/{id}/images/{imageId}", imageHandler.HandleDeleteImage).Methods("DELETE")
+
+	return r
+}
+
+// asUser serves requests as the given user, as AuthMiddleware would
+func asUser(next http.Handler, userID string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, userID)))
+	})
+}
+
+func doRequest(r http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestVehicleCRUD(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	newVehicle := map[string]interface{}{
+		"vin":      "1hgcm82633a004352",
+		"year":     2003,
+		"make":     "Honda",
+		"model":    "Accord",
+		"type":     "sedan",
+		"price":    24000,
+		"currency": "usd",
+	}
+
+	rec := doRequest(r, "POST", "/vehicles", newVehicle)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var created struct {
+		Data struct {
+			ID       string `json:"id"`
+			VIN      string `json:"vin"`
+			Currency string `json:"currency"`
+			Status   string `json:"status"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Data.ID == "" {
+		t.Fatal("Expected a generated ID")
+	}
+	if created.Data.VIN != "1HGCM82633A004352" || created.Data.Currency != "USD" {
+		t.Errorf("Expected normalised VIN and currency, got %s %s", created.Data.VIN, created.Data.Currency)
+	}
+	if created.Data.Status != "available" {
+		t.Errorf("Expected default status available, got %s", created.Data.Status)
+	}
+
+	// VINs that disagree with the listing are rejected
+	mismatched := map[string]interface{}{}
+	for k, v := range newVehicle {
+		mismatched[k] = v
+	}
+	for field, value := range map[string]interface{}{"make": "Toyota", "year": 2021, "vin": "1HGCM82643A004352"} {
+		original := mismatched[field]
+		mismatched[field] = value
+		rec = doRequest(r, "POST", "/vehicles", mismatched)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a VIN disagreeing on %s, got %d", field, rec.Code)
+		}
+		mismatched[field] = original
+	}
+
+	// Duplicate VIN is rejected
+	rec = doRequest(r, "POST", "/vehicles", newVehicle)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for duplicate VIN, got %d", rec.Code)
+	}
+
+	// Partial update keeps the other fields
+	rec = doRequest(r, "PATCH", "/vehicles/"+created.Data.ID, map[string]interface{}{"price": 21000})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var patched struct {
+		Data struct {
+			Make  string  `json:"make"`
+			Price float64 `json:"price"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&patched)
+	if patched.Data.Price != 21000 || patched.Data.Make != "Honda" {
+		t.Errorf("Unexpected patched vehicle: %+v", patched.Data)
+	}
+
+	// Invalid updates are rejected with field errors
+	rec = doRequest(r, "PATCH", "/vehicles/"+created.Data.ID, map[string]interface{}{"mileage": -5})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for negative mileage, got %d", rec.Code)
+	}
+
+	newVehicle["price"] = 19500
+	rec = doRequest(r, "PUT", "/vehicles/"+created.Data.ID, newVehicle)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for PUT, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(r, "PUT", "/vehicles/veh-999", newVehicle)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown vehicle, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "DELETE", "/vehicles/"+created.Data.ID, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "GET", "/vehicles/"+created.Data.ID, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", rec.Code)
+	}
+}
+
+func TestUpdateVehicleWithInvalidSeedVIN(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	// veh-004 was seeded with a VIN whose check digit and model year are
+	// wrong; other fields can still be edited, with the findings as warnings
+	rec := doRequest(r, "PATCH", "/vehicles/veh-004", map[string]interface{}{"price": 74990})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated struct {
+		Data struct {
+			Price float64 `json:"price"`
+		} `json:"data"`
+		Warnings []struct {
+			Field string `json:"field"`
+		} `json:"warnings"`
+	}
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if updated.Data.Price != 74990 {
+		t.Errorf("Expected the new price, got %v", updated.Data.Price)
+	}
+	fields := make(map[string]bool)
+	for _, warning := range updated.Warnings {
+		fields[warning.Field] = true
+	}
+	if !fields["vin"] || !fields["year"] {
+		t.Errorf("Expected VIN and year warnings, got %+v", updated.Warnings)
+	}
+
+	// Changing the year is checked against the VIN as for a new listing
+	rec = doRequest(r, "PATCH", "/vehicles/veh-004", map[string]interface{}{"year": 2021})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a year change, got %d", rec.Code)
+	}
+
+	// So is changing the make, including on a listing whose VIN is valid
+	for _, id := range []string{"veh-004", "veh-001"} {
+		rec = doRequest(r, "PATCH", "/vehicles/"+id, map[string]interface{}{"make": "Honda"})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a make %s's VIN does not build, got %d", id, rec.Code)
+		}
+	}
+}
+
+type vehiclePage struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	Count      int     `json:"count"`
+	HasMore    bool    `json:"hasMore"`
+	NextCursor *string `json:"nextCursor"`
+}
+
+func TestListVehiclesPagination(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	var ids []string
+	path := "/vehicles?limit=10"
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("Pagination did not terminate")
+		}
+
+		rec := doRequest(r, "GET", path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var page vehiclePage
+		json.NewDecoder(rec.Body).Decode(&page)
+		if page.Count != len(page.Data) || page.Count > 10 {
+			t.Fatalf("Unexpected page size %d (count %d)", len(page.Data), page.Count)
+		}
+		for _, v := range page.Data {
+			ids = append(ids, v.ID)
+		}
+
+		if !page.HasMore {
+			if page.NextCursor != nil {
+				t.Error("Expected no cursor on the last page")
+			}
+			break
+		}
+
+		// Deleting an item already returned must not disturb the next page
+		if pages == 1 {
+			doRequest(r, "DELETE", "/vehicles/"+page.Data[0].ID, nil)
+		}
+		path = "/vehicles?limit=10&cursor=" + url.QueryEscape(*page.NextCursor)
+	}
+
+	if len(ids) != 51 {
+		t.Errorf("Expected 51 vehicles across pages, got %d", len(ids))
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Error("Expected vehicles in stable ID order")
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] == ids[i-1] {
+			t.Errorf("Vehicle %s returned twice", ids[i])
+		}
+	}
+}
+
+func TestSearchVehiclesPagination(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	rec := doRequest(r, "POST", "/vehicles/search?limit=2", map[string]interface{}{"country": "US"})
+	var first vehiclePage
+	json.NewDecoder(rec.Body).Decode(&first)
+	if len(first.Data) != 2 || !first.HasMore || first.NextCursor == nil {
+		t.Fatalf("Unexpected first page: %+v", first)
+	}
+
+	rec = doRequest(r, "POST", "/vehicles/search?limit=2&cursor="+*first.NextCursor, map[string]interface{}{"country": "US"})
+	var second vehiclePage
+	json.NewDecoder(rec.Body).Decode(&second)
+	if len(second.Data) == 0 || second.Data[0].ID <= first.Data[1].ID {
+		t.Errorf("Second page does not follow the first: %+v", second)
+	}
+
+	for _, path := range []string{"/vehicles?limit=0", "/vehicles?limit=abc", "/vehicles?cursor=not-a-cursor"} {
+		if rec := doRequest(r, "GET", path, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestListVehiclesSorted(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	type sortedPage struct {
+		Data []struct {
+			ID    string  `json:"id"`
+			Year  int     `json:"year"`
+			Price float64 `json:"price"`
+		} `json:"data"`
+		HasMore    bool    `json:"hasMore"`
+		NextCursor *string `json:"nextCursor"`
+	}
+
+	// Walk every page of -year,price and check the order holds across pages
+	var all []sortedPage
+	path := "/vehicles?sort=-year,price&limit=7"
+	for len(all) < 20 {
+		rec := doRequest(r, "GET", path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var page sortedPage
+		json.NewDecoder(rec.Body).Decode(&page)
+		all = append(all, page)
+		if !page.HasMore {
+			break
+		}
+		path = "/vehicles?sort=-year,price&limit=7&cursor=" + *page.NextCursor
+	}
+
+	seen := make(map[string]bool)
+	var prevYear int
+	var prevPrice float64
+	for _, page := range all {
+		for _, v := range page.Data {
+			if seen[v.ID] {
+				t.Errorf("Vehicle %s returned twice", v.ID)
+			}
+			seen[v.ID] = true
+			if len(seen) > 1 && (v.Year > prevYear || (v.Year == prevYear && v.Price < prevPrice)) {
+				t.Errorf("Vehicle %s (%d, %.0f) out of order after (%d, %.0f)", v.ID, v.Year, v.Price, prevYear, prevPrice)
+			}
+			prevYear, prevPrice = v.Year, v.Price
+		}
+	}
+	if len(seen) != 51 {
+		t.Errorf("Expected 51 vehicles across pages, got %d", len(seen))
+	}
+
+	// The search body carries the sort
+	rec := doRequest(r, "POST", "/vehicles/search?limit=1", map[string]interface{}{"sort": "-price"})
+	var priciest sortedPage
+	json.NewDecoder(rec.Body).Decode(&priciest)
+	if len(priciest.Data) != 1 {
+		t.Fatalf("Expected one vehicle, got %d", len(priciest.Data))
+	}
+	for _, page := range all {
+		for _, other := range page.Data {
+			if other.Price > priciest.Data[0].Price {
+				t.Errorf("Vehicle %s costs more than the first result of -price", other.ID)
+			}
+		}
+	}
+
+	if rec := doRequest(r, "GET", "/vehicles?sort=features", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unsortable field, got %d", rec.Code)
+	}
+
+	// A cursor is bound to the sort it was issued for
+	path = "/vehicles?sort=price&cursor=" + *all[0].NextCursor
+	if rec := doRequest(r, "GET", path, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a cursor from another sort, got %d", rec.Code)
+	}
+}
+
+func TestListVehiclesTextQuery(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	type textPage struct {
+		Data []struct {
+			ID         string              `json:"id"`
+			Score      float64             `json:"score"`
+			Highlights map[string][]string `json:"highlights"`
+		} `json:"data"`
+		HasMore    bool    `json:"hasMore"`
+		NextCursor *string `json:"nextCursor"`
+	}
+
+	rec := doRequest(r, "GET", "/vehicles?q="+url.QueryEscape("tesla awd glass roof")+"&limit=3", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var first textPage
+	json.NewDecoder(rec.Body).Decode(&first)
+	if len(first.Data) != 3 || !first.HasMore {
+		t.Fatalf("Expected a full first page, got %+v", first)
+	}
+
+	top := first.Data[0]
+	if top.ID != "veh-001" || top.Score <= 0 {
+		t.Errorf("Expected veh-001 first with a positive score, got %s (%v)", top.ID, top.Score)
+	}
+	if got := top.Highlights["make"]; len(got) != 1 || got[0] != "<em>Tesla</em>" {
+		t.Errorf("Unexpected make highlight %v", got)
+	}
+	if got := top.Highlights["features"]; len(got) != 1 || got[0] != "<em>Glass</em> <em>Roof</em>" {
+		t.Errorf("Unexpected feature highlights %v", got)
+	}
+
+	// Relevance order continues on the next page
+	rec = doRequest(r, "GET", "/vehicles?q=tesla+awd+glass+roof&limit=3&cursor="+*first.NextCursor, nil)
+	var second textPage
+	json.NewDecoder(rec.Body).Decode(&second)
+	if len(second.Data) == 0 || second.Data[0].Score > first.Data[2].Score {
+		t.Errorf("Second page does not follow the first in relevance order: %+v", second.Data)
+	}
+	for _, v := range second.Data {
+		for _, prev := range first.Data {
+			if v.ID == prev.ID {
+				t.Errorf("Vehicle %s returned on both pages", v.ID)
+			}
+		}
+	}
+
+	// Without q there are no scores
+	rec = doRequest(r, "GET", "/vehicles?limit=1", nil)
+	if body := rec.Body.String(); strings.Contains(body, `"score"`) || strings.Contains(body, `"highlights"`) {
+		t.Errorf("Unexpected relevance fields in a plain list: %s", body)
+	}
+}
+
+func TestListVehiclesFacets(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	var response struct {
+		Data   []json.RawMessage `json:"data"`
+		Facets struct {
+			Terms map[string][]struct {
+				Value string
+				Count int
+			} `json:"terms"`
+			Histograms map[string][]struct {
+				Min, Max float64
+				Count    int
+			} `json:"histograms"`
+		} `json:"facets"`
+	}
+	rec := doRequest(r, "GET", "/vehicles?make=BMW", nil)
+	json.NewDecoder(rec.Body).Decode(&response)
+
+	makes := response.Facets.Terms["make"]
+	if len(makes) < 2 {
+		t.Errorf("Expected counts for other makes while BMW is selected, got %+v", makes)
+	}
+	for _, value := range makes {
+		if value.Value == "BMW" && value.Count != len(response.Data) {
+			t.Errorf("Expected BMW count %d, got %d", len(response.Data), value.Count)
+		}
+	}
+	for _, name := range []string{"type", "fuelType", "transmission", "drivetrain", "condition", "country"} {
+		if _, ok := response.Facets.Terms[name]; !ok {
+			t.Errorf("Missing %s facet", name)
+		}
+	}
+	for _, name := range []string{"price", "year", "mileage"} {
+		if len(response.Facets.Histograms[name]) == 0 {
+			t.Errorf("Missing %s histogram", name)
+		}
+	}
+
+	rec = doRequest(r, "POST", "/vehicles/search", map[string]interface{}{"make": "BMW"})
+	if !strings.Contains(rec.Body.String(), `"facets"`) {
+		t.Error("Expected search results to have facets")
+	}
+	rec = doRequest(r, "POST", "/vehicles/search?facets=false", map[string]interface{}{"make": "BMW"})
+	if strings.Contains(rec.Body.String(), `"facets"`) {
+		t.Error("Expected no facets with facets=false")
+	}
+}
+
+func TestListVehiclesExtendedFilters(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	type filteredPage struct {
+		Data []struct {
+			ID           string    `json:"id"`
+			Mileage      int       `json:"mileage"`
+			Features     []string  `json:"features"`
+			DealerRating float64   `json:"dealerRating"`
+			ListingDate  time.Time `json:"listingDate"`
+		} `json:"data"`
+	}
+
+	rec := doRequest(r, "GET", "/vehicles?maxMileage=20000&minDealerRating=4.5&anyFeatures=AWD,4WD&listedAfter=2024-01-01&statuses=available", nil)
+	var page filteredPage
+	json.NewDecoder(rec.Body).Decode(&page)
+	if len(page.Data) == 0 {
+		t.Fatal("Expected matching vehicles")
+	}
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, v := range page.Data {
+		hasDrive := false
+		for _, feature := range v.Features {
+			if feature == "AWD" || feature == "4WD" {
+				hasDrive = true
+			}
+		}
+		if v.Mileage > 20000 || v.DealerRating < 4.5 || !hasDrive || v.ListingDate.Before(after) {
+			t.Errorf("Vehicle %s does not match the filter: %+v", v.ID, v)
+		}
+	}
+
+	// The same filter in the search body
+	rec = doRequest(r, "POST", "/vehicles/search", map[string]interface{}{
+		"maxMileage":      20000,
+		"minDealerRating": 4.5,
+		"anyFeatures":     []string{"AWD", "4WD"},
+		"listedAfter":     "2024-01-01T00:00:00Z",
+		"statuses":        []string{"available"},
+	})
+	var body filteredPage
+	json.NewDecoder(rec.Body).Decode(&body)
+	if len(body.Data) != len(page.Data) {
+		t.Errorf("Expected %d vehicles from the search body, got %d", len(page.Data), len(body.Data))
+	}
+
+	rec = doRequest(r, "GET", "/vehicles?statuses=sold", nil)
+	var sold filteredPage
+	json.NewDecoder(rec.Body).Decode(&sold)
+	if len(sold.Data) != 0 {
+		t.Errorf("Expected no sold vehicles, got %d", len(sold.Data))
+	}
+}
+
+func TestMovedVehicleLeavesRadius(t *testing.T) {
+	r := asUser(newTestVehicleRouter(t), "user-002")
+	near := map[string]interface{}{
+		"near":     map[string]float64{"lat": 51.5074, "lng": -0.1278},
+		"radiusKm": 100,
+	}
+	countNear := func() int {
+		rec := doRequest(r, "POST", "/vehicles/search", near)
+		var body struct {
+			Data []json.RawMessage `json:"data"`
+		}
+		json.NewDecoder(rec.Body).Decode(&body)
+		return len(body.Data)
+	}
+	before := countNear()
+
+	// A move to a place the gazetteer does not know drops the old position
+	rec := doRequest(r, "PATCH", "/vehicles/veh-002", map[string]interface{}{"location": "Little Snoring, England"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), `"coordinates"`) {
+		t.Errorf("Expected the old coordinates to be cleared, got %s", rec.Body.String())
+	}
+	if got := countNear(); got != before-1 {
+		t.Errorf("Expected %d vehicles near London after the move, got %d", before-1, got)
+	}
+
+	// Coordinates given with the move are kept
+	rec = doRequest(r, "PATCH", "/vehicles/veh-002", map[string]interface{}{
+		"location":    "Great Snoring, England",
+		"coordinates": map[string]float64{"lat": 52.87, "lng": 0.9},
+	})
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"lat":52.87`) {
+		t.Errorf("Expected the given coordinates to be kept, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListVehiclesNear(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	type nearPage struct {
+		Data []struct {
+			ID         string   `json:"id"`
+			Location   string   `json:"location"`
+			DistanceKm *float64 `json:"distanceKm"`
+		} `json:"data"`
+		HasMore    bool    `json:"hasMore"`
+		NextCursor *string `json:"nextCursor"`
+	}
+
+	// Central London: the London, Oxford and Birmingham listings are within
+	// 200 km but Manchester is not
+	var locations []string
+	var distances []float64
+	path := "/vehicles?near=51.5074,-0.1278&radiusKm=200&limit=3"
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination did not terminate")
+		}
+
+		rec := doRequest(r, "GET", path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var page nearPage
+		json.NewDecoder(rec.Body).Decode(&page)
+		for _, v := range page.Data {
+			if v.DistanceKm == nil {
+				t.Fatalf("Expected a distance for %s", v.ID)
+			}
+			locations = append(locations, v.Location)
+			distances = append(distances, *v.DistanceKm)
+		}
+
+		if !page.HasMore {
+			break
+		}
+		path = "/vehicles?near=51.5074,-0.1278&radiusKm=200&limit=3&cursor=" + url.QueryEscape(*page.NextCursor)
+	}
+
+	if len(locations) != 7 {
+		t.Fatalf("Expected 7 vehicles within 200 km, got %v", locations)
+	}
+	if !sort.Float64sAreSorted(distances) {
+		t.Errorf("Expected vehicles nearest first, got %v", distances)
+	}
+	if locations[0] != "London, England" || locations[6] != "Birmingham, England" {
+		t.Errorf("Unexpected order %v", locations)
+	}
+	if distances[6] > 200 {
+		t.Errorf("Expected no vehicle beyond 200 km, got %.1f", distances[6])
+	}
+
+	// The search body takes the same point and radius
+	rec := doRequest(r, "POST", "/vehicles/search", map[string]interface{}{
+		"near":     map[string]float64{"lat": 51.5074, "lng": -0.1278},
+		"radiusKm": 100,
+	})
+	var body nearPage
+	json.NewDecoder(rec.Body).Decode(&body)
+	if len(body.Data) != 5 {
+		t.Errorf("Expected the London and Oxford vehicles within 100 km, got %d", len(body.Data))
+	}
+
+	// Without near there is no distance
+	rec = doRequest(r, "GET", "/vehicles?limit=1", nil)
+	var plain nearPage
+	json.NewDecoder(rec.Body).Decode(&plain)
+	if len(plain.Data) != 1 || plain.Data[0].DistanceKm != nil {
+		t.Errorf("Expected no distance without near, got %+v", plain.Data)
+	}
+}
+
+func TestListVehiclesPriceCurrency(t *testing.T) {
+	r := newTestVehicleRouter(t)
+
+	type pricedPage struct {
+		Data []struct {
+			ID             string  `json:"id"`
+			Price          float64 `json:"price"`
+			Currency       string  `json:"currency"`
+			ConvertedPrice *struct {
+				Amount   float64 `json:"amount"`
+				Currency string  `json:"currency"`
+			} `json:"convertedPrice"`
+		} `json:"data"`
+		HasMore    bool    `json:"hasMore"`
+		NextCursor *string `json:"nextCursor"`
+	}
+
+	rec := doRequest(r, "GET", "/vehicles?priceCurrency=usd&maxPrice=40000&sort=-price", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var page pricedPage
+	json.NewDecoder(rec.Body).Decode(&page)
+	if len(page.Data) == 0 {
+		t.Fatal("Expected vehicles under 40,000 USD")
+	}
+	currencies := make(map[string]bool)
+	for i, v := range page.Data {
+		if v.ConvertedPrice == nil || v.ConvertedPrice.Currency != "USD" {
+			t.Fatalf("Expected a USD price for %s, got %+v", v.ID, v.ConvertedPrice)
+		}
+		if v.ConvertedPrice.Amount > 40000 {
+			t.Errorf("Vehicle %s costs %.2f USD", v.ID, v.ConvertedPrice.Amount)
+		}
+		if i > 0 && v.ConvertedPrice.Amount > page.Data[i-1].ConvertedPrice.Amount {
+			t.Errorf("Expected descending USD prices, got %s after %s", v.ID, page.Data[i-1].ID)
+		}
+		// veh-002 is listed at 32,500 GBP, over 40,000 USD
+		if v.ID == "veh-002" {
+			t.Error("Expected the GBP price of veh-002 to be converted before filtering")
+		}
+		currencies[v.Currency] = true
+	}
+	if len(currencies) < 2 {
+		t.Errorf("Expected listings in several currencies, got %v", currencies)
+	}
+
+	// A cursor holds prices in the currency it was issued for
+	rec = doRequest(r, "GET", "/vehicles?priceCurrency=USD&sort=price&limit=5", nil)
+	var first pricedPage
+	json.NewDecoder(rec.Body).Decode(&first)
+	if !first.HasMore {
+		t.Fatal("Expected more pages")
+	}
+	rec = doRequest(r, "GET", "/vehicles?priceCurrency=EUR&sort=price&limit=5&cursor="+url.QueryEscape(*first.NextCursor), nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a cursor in another currency, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "GET", "/vehicles?priceCurrency=XYZ", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unsupported currency, got %d", rec.Code)
+	}
+
+	// Without a currency parameter the caller's preferred currency applies;
+	// user-003 prefers GBP
+	req := httptest.NewRequest("GET", "/vehicles/veh-001", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "user-003"))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var single struct {
+		Data struct {
+			Price          float64 `json:"price"`
+			ConvertedPrice *struct {
+				Amount   float64 `json:"amount"`
+				Currency string  `json:"currency"`
+			} `json:"convertedPrice"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&single)
+	if single.Data.ConvertedPrice == nil || single.Data.ConvertedPrice.Currency != "GBP" || single.Data.ConvertedPrice.Amount >= single.Data.Price {
+		t.Errorf("Expected the USD price converted to GBP, got %+v", single.Data)
+	}
+
+	// Anonymous requests without a currency are not converted
+	rec = doRequest(r, "GET", "/vehicles?limit=1", nil)
+	var plain pricedPage
+	json.NewDecoder(rec.Body).Decode(&plain)
+	if len(plain.Data) != 1 || plain.Data[0].ConvertedPrice != nil {
+		t.Errorf("Expected no converted price, got %+v", plain.Data)
+	}
+}
+
+func TestVehicleTransitions(t *testing.T) {
+	r := asUser(newTestVehicleRouter(t), "user-002")
+
+	rec := doRequest(r, "POST", "/vehicles", map[string]interface{}{
+		"vin":      "1HGCM82633A004352",
+		"year":     2003,
+		"make":     "Honda",
+		"model":    "Accord",
+		"price":    9000,
+		"currency": "USD",
+		"status":   "draft",
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	id := created.Data.ID
+
+	listed := func(query string) bool {
+		rec := doRequest(r, "GET", "/vehicles?limit=500&facets=false"+query, nil)
+		var page vehiclePage
+		json.NewDecoder(rec.Body).Decode(&page)
+		for _, v := range page.Data {
+			if v.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Drafts are hidden unless asked for
+	if listed("") {
+		t.Error("Expected the draft to be hidden from search")
+	}
+	if !listed("&statuses=draft") {
+		t.Error("Expected the draft with statuses=draft")
+	}
+
+	// The status cannot be edited directly
+	rec = doRequest(r, "PATCH", "/vehicles/"+id, map[string]interface{}{"status": "available"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a status edit, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "POST", "/vehicles/"+id+"/transitions", map[string]string{"status": "available", "note": "to available"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 moving to available, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Holds are only placed through the reservation endpoint, which the
+	// sweeper releases
+	rec = doRequest(r, "POST", "/vehicles/"+id+"/transitions", map[string]string{"status": "reserved"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 moving to reserved, got %d", rec.Code)
+	}
+	if rec := doRequest(r, "POST", "/vehicles/"+id+"/reservation", nil); rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !listed("") {
+		t.Error("Expected a reserved vehicle to be listed")
+	}
+
+	rec = doRequest(r, "POST", "/vehicles/"+id+"/transitions", map[string]string{"status": "sold", "note": "to sold"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 moving to sold, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if listed("") {
+		t.Error("Expected a sold vehicle to be hidden from search")
+	}
+
+	// Sold is final
+	rec = doRequest(r, "POST", "/vehicles/"+id+"/transitions", map[string]string{"status": "available"})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 leaving sold, got %d", rec.Code)
+	}
+	var conflict struct {
+		Error   string   `json:"error"`
+		From    string   `json:"from"`
+		Allowed []string `json:"allowed"`
+	}
+	json.NewDecoder(rec.Body).Decode(&conflict)
+	if conflict.From != "sold" || len(conflict.Allowed) != 0 || conflict.Error == "" {
+		t.Errorf("Unexpected conflict response %+v", conflict)
+	}
+
+	rec = doRequest(r, "POST", "/vehicles/"+id+"/transitions", map[string]string{"status": "parked"})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown status, got %d", rec.Code)
+	}
+
+	rec = doRequest(r, "GET", "/vehicles/"+id+"/transitions", nil)
+	var history struct {
+		Data struct {
+			Status  string `json:"status"`
+			History []struct {
+				From string    `json:"from"`
+				To   string    `json:"to"`
+				By   string    `json:"by"`
+				At   time.Time `json:"at"`
+				Note string    `json:"note"`
+			} `json:"history"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&history)
+	if history.Data.Status != "sold" || len(history.Data.History) != 3 {
+		t.Fatalf("Unexpected history %+v", history.Data)
+	}
+	first := history.Data.History[0]
+	if first.From != "draft" || first.To != "available" || first.By != "user-002" || first.At.IsZero() || first.Note != "to available" {
+		t.Errorf("Unexpected first transition %+v", first)
+	}
+
+	// Edits keep the status and its history
+	rec = doRequest(r, "PATCH", "/vehicles/"+id, map[string]interface{}{"price": 8500})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = doRequest(r, "GET", "/vehicles/"+id+"/transitions", nil)
+	json.NewDecoder(rec.Body).Decode(&history)
+	if history.Data.Status != "sold" || len(history.Data.History) != 3 {
+		t.Errorf("Expected the history to survive an edit, got %+v", history.Data)
+	}
+
+	// New listings start as draft or available
+	rec = doRequest(r, "POST", "/vehicles", map[string]interface{}{
+		"vin":      "5YJ3E1EB5KF123456",
+		"year":     2019,
+		"make":     "Tesla",
+		"model":    "Model 3",
+		"currency": "USD",
+		"status":   "sold",
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a new sold listing, got %d", rec.Code)
+	}
+}
+
+func TestVehiclePriceHistory(t *testing.T) {
+	r := asUser(newTestVehicleRouter(t), "user-002")
+
+	// veh-014 drops 10%, veh-002 4% and veh-003 rises
+	for id, price := range map[string]float64{"veh-014": 55791, "veh-002": 31200, "veh-003": 49900} {
+		rec := doRequest(r, "PATCH", "/vehicles/"+id, map[string]interface{}{"price": price})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	// Edits that keep the price add nothing
+	doRequest(r, "PATCH", "/vehicles/veh-014", map[string]interface{}{"mileage": 1000})
+
+	rec := doRequest(r, "GET", "/vehicles/veh-014/price-history", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var history struct {
+		Data struct {
+			Price     float64 `json:"price"`
+			PriceDrop *struct {
+				Amount  float64 `json:"amount"`
+				Percent float64 `json:"percent"`
+			} `json:"priceDrop"`
+			History []struct {
+				By       string  `json:"by"`
+				OldPrice float64 `json:"oldPrice"`
+				NewPrice float64 `json:"newPrice"`
+				Currency string  `json:"currency"`
+			} `json:"history"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&history)
+	if len(history.Data.History) != 1 {
+		t.Fatalf("Expected one price change, got %+v", history.Data.History)
+	}
+	change := history.Data.History[0]
+	if change.By != "user-002" || change.OldPrice != 61990 || change.NewPrice != 55791 || change.Currency != "USD" {
+		t.Errorf("Unexpected price change %+v", change)
+	}
+	if drop := history.Data.PriceDrop; drop == nil || drop.Amount != 6199 || drop.Percent != 10 {
+		t.Errorf("Unexpected price drop %+v", drop)
+	}
+
+	ids := func(query string) []string {
+		rec := doRequest(r, "GET", "/vehicles?facets=false&"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", query, rec.Code)
+		}
+		var page vehiclePage
+		json.NewDecoder(rec.Body).Decode(&page)
+		var ids []string
+		for _, v := range page.Data {
+			ids = append(ids, v.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"priceDropped=true", []string{"veh-002", "veh-014"}},
+		{"minPriceDrop=5&priceDropDays=14", []string{"veh-014"}},
+		{"minPriceDrop=20", nil},
+		{"priceDropped=true&sort=-priceDrop", []string{"veh-014", "veh-002"}},
+	}
+	for _, tt := range tests {
+		if got := ids(tt.query); strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.query, tt.expected, got)
+		}
+	}
+
+	// The latest drop is carried on list results and sorts vehicles
+	rec = doRequest(r, "GET", "/vehicles?sort=-priceDrop&limit=1&facets=false", nil)
+	var page struct {
+		Data []struct {
+			ID        string `json:"id"`
+			PriceDrop *struct {
+				Amount float64 `json:"amount"`
+			} `json:"priceDrop"`
+		} `json:"data"`
+		NextCursor string `json:"nextCursor"`
+	}
+	json.NewDecoder(rec.Body).Decode(&page)
+	if len(page.Data) != 1 || page.Data[0].ID != "veh-014" || page.Data[0].PriceDrop == nil || page.Data[0].PriceDrop.Amount != 6199 {
+		t.Fatalf("Expected veh-014 with its drop first, got %+v", page.Data)
+	}
+	rec = doRequest(r, "GET", "/vehicles?sort=-priceDrop&limit=1&facets=false&cursor="+page.NextCursor, nil)
+	json.NewDecoder(rec.Body).Decode(&page)
+	if len(page.Data) != 1 || page.Data[0].ID != "veh-002" {
+		t.Errorf("Expected veh-002 on the second page, got %+v", page.Data)
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/vin"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// VINHandler decodes vehicle identification numbers
+type VINHandler struct {
+	logger *logrus.Logger
+}
+
+// NewVINHandler creates a new VIN handler
+func NewVINHandler(logger *logrus.Logger) *VINHandler {
+	return &VINHandler{
+		logger: logger,
+	}
+}
+
+// HandleDecodeVIN validates a VIN and returns its decoded sections,
+// manufacturer, country and model year. A VIN with a wrong check digit is
+// still decoded, with valid set to false.
+func (h *VINHandler) HandleDecodeVIN(w http.ResponseWriter, r *http.Request) {
+	info, err := vin.Decode(mux.Vars(r)["vin"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data": info,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
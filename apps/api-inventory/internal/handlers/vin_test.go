@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDecodeVIN(t *testing.T) {
+	handler := NewVINHandler(logrus.New())
+	r := mux.NewRouter()
+	r.HandleFunc("/vin/{vin}", handler.HandleDecodeVIN).Methods("GET")
+
+	rec := doRequest(r, "GET", "/vin/1hgcm82633a004352", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response struct {
+		Data struct {
+			VIN          string `json:"vin"`
+			Valid        bool   `json:"valid"`
+			Manufacturer string `json:"manufacturer"`
+			Country      string `json:"country"`
+			ModelYear    int    `json:"modelYear"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&response)
+	if !response.Data.Valid || response.Data.VIN != "1HGCM82633A004352" || response.Data.Country != "US" || response.Data.ModelYear != 2003 {
+		t.Errorf("Unexpected decoded VIN %+v", response.Data)
+	}
+
+	// A wrong check digit still decodes
+	rec = doRequest(r, "GET", "/vin/1HGCM82643A004352", nil)
+	json.NewDecoder(rec.Body).Decode(&response)
+	if rec.Code != http.StatusOK || response.Data.Valid {
+		t.Errorf("Expected an invalid VIN to decode, got %d %+v", rec.Code, response.Data)
+	}
+
+	rec = doRequest(r, "GET", "/vin/TOOSHORT", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a malformed VIN, got %d", rec.Code)
+	}
+}
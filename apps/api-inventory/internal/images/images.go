@@ -0,0 +1,475 @@
+// Package images stores the photos of vehicle listings in a blob store,
+// with resized thumbnails, and keeps each vehicle's Images in step with
+// them. Uploaded images are served from
+// /api/v1/vehicles/{id}/images/{file}; vehicles may also list other
+// images, such as the static assets of the seed data, which are left alone.
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	// Register the decoders of the accepted formats
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/blob"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxSize is the default limit on the size of an uploaded image
+	DefaultMaxSize = 10 << 20
+	// MaxPixels limits the dimensions of an uploaded image, so decoding it
+	// cannot take an unbounded amount of memory
+	MaxPixels = 25_000_000
+	// MaxPerVehicle is the most images a vehicle can have uploaded
+	MaxPerVehicle = 30
+)
+
+// ThumbnailWidths are the widths thumbnails are generated at. Images
+// narrower than a width keep their own size.
+var ThumbnailWidths = []int{320, 800}
+
+var (
+	// ErrUnsupportedType is returned for a file that is not a JPEG, PNG or
+	// GIF image
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	// ErrTooLarge is returned for an image over the size or pixel limit
+	ErrTooLarge = errors.New("image is too large")
+	// ErrInvalidImage is returned for a file that claims to be an image but
+	// does not decode
+	ErrInvalidImage = errors.New("image cannot be decoded")
+	// ErrTooMany is returned when a vehicle already has MaxPerVehicle
+	// uploaded images
+	ErrTooMany = errors.New("vehicle has too many images")
+	// ErrImageNotFound is returned when the vehicle has no such image
+	ErrImageNotFound = errors.New("image not found")
+	// ErrInvalidOrder is returned when a new order does not list every
+	// image of the vehicle exactly once
+	ErrInvalidOrder = errors.New("order must list every image of the vehicle exactly once")
+)
+
+// formats maps the sniffed content type of an accepted image to the
+// extension of the original and of its thumbnails. GIF thumbnails are
+// PNG, as only the first frame is kept.
+var formats = map[string]struct{ ext, thumbExt string }{
+	"image/jpeg": {"jpg", "jpg"},
+	"image/png":  {"png", "png"},
+	"image/gif":  {"gif", "png"},
+}
+
+// contentTypes maps file extensions to the content type they are served as
+var contentTypes = map[string]string{
+	"jpg": "image/jpeg",
+	"png": "image/png",
+	"gif": "image/gif",
+}
+
+// fileName matches the file of an uploaded image or one of its thumbnails
+var fileName = regexp.MustCompile(`^(img-[0-9a-f]{16})(?:_w([0-9]+))?\.(jpg|png|gif)$`)
+
+// Image is an image of a vehicle. Uploaded images are managed: they have
+// thumbnails and can be deleted. Other images are listed by URL, which is
+// also their ID.
+type Image struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Managed bool   `json:"managed"`
+	// Thumbnails maps each of ThumbnailWidths to the URL of the thumbnail
+	Thumbnails map[string]string `json:"thumbnails,omitempty"`
+	// The content type, dimensions and size are only known on upload
+	ContentType string `json:"contentType,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+// Manager uploads, orders and deletes vehicle images
+type Manager struct {
+	repo  repository.Store
+	blobs blob.Store
+	// lock is held while a vehicle is read, changed and written back; it
+	// is shared with the other vehicle writers
+	lock    sync.Locker
+	maxSize int64
+	logger  *logrus.Logger
+}
+
+// NewManager creates a manager storing images in blobs. Uploads over
+// maxSize bytes are rejected; 0 means DefaultMaxSize.
+func NewManager(repo repository.Store, blobs blob.Store, lock sync.Locker, maxSize int64, logger *logrus.Logger) *Manager {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &Manager{
+		repo:    repo,
+		blobs:   blobs,
+		lock:    lock,
+		maxSize: maxSize,
+		logger:  logger,
+	}
+}
+
+// MaxSize returns the largest image accepted, in bytes
+func (m *Manager) MaxSize() int64 {
+	return m.maxSize
+}
+
+// List returns the images of a vehicle in display order
+func (m *Manager) List(vehicleID string) ([]Image, error) {
+	vehicle, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	return describe(vehicle), nil
+}
+
+// Upload stores an image and its thumbnails and appends it to the
+// vehicle's images. The format is sniffed from the content; the name and
+// type the client gave are not trusted.
+func (m *Manager) Upload(ctx context.Context, vehicleID string, r io.Reader) (*Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, m.maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > m.maxSize {
+		return nil, fmt.Errorf("%w: the limit is %d bytes", ErrTooLarge, m.maxSize)
+	}
+
+	contentType := http.DetectContentType(data)
+	format, ok := formats[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("%w: the limit is %d pixels", ErrTooLarge, MaxPixels)
+	}
+
+	// Fail early, before the thumbnails are made; checked again on write
+	vehicle, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if managedCount(vehicle) >= MaxPerVehicle {
+		return nil, ErrTooMany
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	id, err := newImageID()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{id + "." + format.ext: data}
+	for _, width := range ThumbnailWidths {
+		thumb, err := encode(thumbnail(src, width), format.thumbExt)
+		if err != nil {
+			return nil, err
+		}
+		files[thumbnailFile(id, width, format.thumbExt)] = thumb
+	}
+
+	var stored []string
+	for file, content := range files {
+		if err := m.blobs.Put(ctx, blobKey(vehicleID, file), bytes.NewReader(content)); err != nil {
+			m.deleteBlobs(vehicleID, stored)
+			return nil, err
+		}
+		stored = append(stored, file)
+	}
+
+	url := imageURL(vehicleID, id+"."+format.ext)
+	if err := m.update(vehicleID, func(vehicle *models.Vehicle) error {
+		if managedCount(vehicle) >= MaxPerVehicle {
+			return ErrTooMany
+		}
+		vehicle.Images = append(vehicle.Images, url)
+		return nil
+	}); err != nil {
+		m.deleteBlobs(vehicleID, stored)
+		return nil, err
+	}
+
+	img := managedImage(vehicleID, url)
+	img.ContentType = contentType
+	img.Width = config.Width
+	img.Height = config.Height
+	img.Size = int64(len(data))
+	return &img, nil
+}
+
+// Reorder puts the images of a vehicle in the order of the IDs given,
+// which must list every image exactly once
+func (m *Manager) Reorder(vehicleID string, order []string) ([]Image, error) {
+	var images []Image
+	err := m.update(vehicleID, func(vehicle *models.Vehicle) error {
+		byID := make(map[string]string, len(vehicle.Images))
+		for _, img := range describe(vehicle) {
+			byID[img.ID] = img.URL
+		}
+		if len(order) != len(byID) || len(order) != len(vehicle.Images) {
+			return ErrInvalidOrder
+		}
+
+		urls := make([]string, 0, len(order))
+		for _, id := range order {
+			url, ok := byID[id]
+			if !ok {
+				return ErrInvalidOrder
+			}
+			delete(byID, id)
+			urls = append(urls, url)
+		}
+		vehicle.Images = urls
+		images = describe(vehicle)
+		return nil
+	})
+	return images, err
+}
+
+// Delete removes an uploaded image from the vehicle, then its files
+func (m *Manager) Delete(ctx context.Context, vehicleID, imageID string) error {
+	var url string
+	err := m.update(vehicleID, func(vehicle *models.Vehicle) error {
+		for i, img := range describe(vehicle) {
+			if img.Managed && img.ID == imageID {
+				url = img.URL
+				vehicle.Images = append(vehicle.Images[:i:i], vehicle.Images[i+1:]...)
+				return nil
+			}
+		}
+		return ErrImageNotFound
+	})
+	if err != nil {
+		return err
+	}
+
+	// The vehicle no longer refers to the files, so failing to remove them
+	// only leaves them orphaned
+	m.deleteBlobs(vehicleID, files(managedImage(vehicleID, url)))
+	return nil
+}
+
+// DeleteVehicle removes the files of every uploaded image of a vehicle
+// that has just been deleted from the store
+func (m *Manager) DeleteVehicle(vehicle *models.Vehicle) {
+	for _, img := range describe(vehicle) {
+		if img.Managed {
+			m.deleteBlobs(vehicle.ID, files(img))
+		}
+	}
+}
+
+// Open returns an uploaded image or thumbnail of a vehicle by file name,
+// with its content type. Only the files of the vehicle's current images
+// are served, so those of deleted images or vehicles that could not be
+// removed stay unreachable.
+func (m *Manager) Open(ctx context.Context, vehicleID, file string) (io.ReadSeekCloser, blob.Info, string, error) {
+	match := fileName.FindStringSubmatch(file)
+	if match == nil || strings.Contains(vehicleID, "/") {
+		return nil, blob.Info{}, "", ErrImageNotFound
+	}
+	vehicle, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		return nil, blob.Info{}, "", err
+	}
+	if !hasFile(vehicle, file) {
+		return nil, blob.Info{}, "", ErrImageNotFound
+	}
+
+	r, info, err := m.blobs.Open(ctx, blobKey(vehicleID, file))
+	if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+		return nil, blob.Info{}, "", ErrImageNotFound
+	}
+	if err != nil {
+		return nil, blob.Info{}, "", err
+	}
+	return r, info, contentTypes[match[3]], nil
+}
+
+// update applies change to a copy of the vehicle under the lock and
+// writes it back
+func (m *Manager) update(vehicleID string, change func(*models.Vehicle) error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		return err
+	}
+	vehicle := existing.Clone()
+	if err := change(vehicle); err != nil {
+		return err
+	}
+	return m.repo.UpdateVehicle(vehicle)
+}
+
+// deleteBlobs removes files of a vehicle, logging those that cannot be
+// removed
+func (m *Manager) deleteBlobs(vehicleID string, files []string) {
+	for _, file := range files {
+		err := m.blobs.Delete(context.Background(), blobKey(vehicleID, file))
+		if err != nil && !errors.Is(err, blob.ErrNotFound) {
+			m.logger.WithError(err).WithFields(logrus.Fields{
+				"vehicle_id": vehicleID,
+				"file":       file,
+			}).Warn("Failed to delete image file")
+		}
+	}
+}
+
+// KeepUploaded makes an update of existing keep its uploaded images, which
+// are only added and removed through the Manager. Uploaded images stay in
+// the order the update lists them; those it leaves out are appended, and
+// uploaded image URLs existing does not have are dropped.
+func KeepUploaded(vehicle, existing *models.Vehicle) {
+	uploaded := make(map[string]bool)
+	for _, url := range existing.Images {
+		if isManaged(existing.ID, url) {
+			uploaded[url] = true
+		}
+	}
+
+	urls := make([]string, 0, len(vehicle.Images)+len(uploaded))
+	for _, url := range vehicle.Images {
+		if !isManaged(existing.ID, url) {
+			urls = append(urls, url)
+		} else if uploaded[url] {
+			urls = append(urls, url)
+			delete(uploaded, url)
+		}
+	}
+	for _, url := range existing.Images {
+		if uploaded[url] {
+			urls = append(urls, url)
+		}
+	}
+	vehicle.Images = urls
+}
+
+// describe lists the images of a vehicle
+func describe(vehicle *models.Vehicle) []Image {
+	images := make([]Image, 0, len(vehicle.Images))
+	for _, url := range vehicle.Images {
+		if isManaged(vehicle.ID, url) {
+			images = append(images, managedImage(vehicle.ID, url))
+		} else {
+			images = append(images, Image{ID: url, URL: url})
+		}
+	}
+	return images
+}
+
+// managedImage describes an uploaded image from its URL
+func managedImage(vehicleID, url string) Image {
+	match := fileName.FindStringSubmatch(url[strings.LastIndex(url, "/")+1:])
+	id, ext := match[1], match[3]
+	thumbExt := ext
+	if ext == "gif" {
+		thumbExt = "png"
+	}
+
+	img := Image{
+		ID:         id,
+		URL:        url,
+		Managed:    true,
+		Thumbnails: make(map[string]string, len(ThumbnailWidths)),
+	}
+	for _, width := range ThumbnailWidths {
+		img.Thumbnails[strconv.Itoa(width)] = imageURL(vehicleID, thumbnailFile(id, width, thumbExt))
+	}
+	return img
+}
+
+// files names the files of an uploaded image: the original and its
+// thumbnails
+func files(img Image) []string {
+	names := []string{img.URL[strings.LastIndex(img.URL, "/")+1:]}
+	for _, thumb := range img.Thumbnails {
+		names = append(names, thumb[strings.LastIndex(thumb, "/")+1:])
+	}
+	return names
+}
+
+// hasFile reports whether file belongs to one of the uploaded images of
+// the vehicle
+func hasFile(vehicle *models.Vehicle, file string) bool {
+	for _, img := range describe(vehicle) {
+		if !img.Managed {
+			continue
+		}
+		for _, name := range files(img) {
+			if name == file {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isManaged reports whether url is an image uploaded for the vehicle
+func isManaged(vehicleID, url string) bool {
+	file, ok := strings.CutPrefix(url, imageURL(vehicleID, ""))
+	if !ok {
+		return false
+	}
+	match := fileName.FindStringSubmatch(file)
+	return match != nil && match[2] == ""
+}
+
+// managedCount counts the uploaded images of a vehicle
+func managedCount(vehicle *models.Vehicle) int {
+	n := 0
+	for _, url := range vehicle.Images {
+		if isManaged(vehicle.ID, url) {
+			n++
+		}
+	}
+	return n
+}
+
+// imageURL returns the path an image file of a vehicle is served at
+func imageURL(vehicleID, file string) string {
+	return "/api/v1/vehicles/" + vehicleID + "/images/" + file
+}
+
+// blobKey returns the key an image file of a vehicle is stored under
+func blobKey(vehicleID, file string) string {
+	return "vehicles/" + vehicleID + "/" + file
+}
+
+// thumbnailFile names the thumbnail of an image at the given width
+func thumbnailFile(id string, width int, ext string) string {
+	return fmt.Sprintf("%s_w%d.%s", id, width, ext)
+}
+
+// newImageID generates a random image ID, e.g. img-3f9a0c1b2d4e5f60
+func newImageID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "img-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,312 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/blob"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestManager(t *testing.T, maxSize int64) (*Manager, repository.Store) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	repo, err := repository.NewRepository(filepath.Join("..", "..", "..", "..", "data", "seed"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	blobs, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create blob store: %v", err)
+	}
+	return NewManager(repo, blobs, &sync.Mutex{}, maxSize, logger), repo
+}
+
+// testImage draws a width by height image with a gradient
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decodeBlob opens a served image file and decodes it
+func decodeBlob(t *testing.T, m *Manager, vehicleID, url string) (image.Image, string) {
+	t.Helper()
+	r, _, contentType, err := m.Open(context.Background(), vehicleID, url[strings.LastIndex(url, "/")+1:])
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", url, err)
+	}
+	defer r.Close()
+	img, _, err := image.Decode(r)
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %v", url, err)
+	}
+	return img, contentType
+}
+
+func TestUploadStoresImageAndThumbnails(t *testing.T) {
+	m, repo := newTestManager(t, 0)
+	ctx := context.Background()
+
+	img, err := m.Upload(ctx, "veh-001", bytes.NewReader(encodeJPEG(t, testImage(1000, 500))))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if !img.Managed || img.ContentType != "image/jpeg" || img.Width != 1000 || img.Height != 500 {
+		t.Errorf("Unexpected image %+v", img)
+	}
+	if !strings.HasPrefix(img.URL, "/api/v1/vehicles/veh-001/images/img-") || !strings.HasSuffix(img.URL, ".jpg") {
+		t.Errorf("Expected a managed URL, got %q", img.URL)
+	}
+
+	vehicle, _ := repo.GetVehicleByID("veh-001")
+	if n := len(vehicle.Images); n != 2 || vehicle.Images[1] != img.URL {
+		t.Errorf("Expected the image to be appended, got %v", vehicle.Images)
+	}
+
+	for width, want := range map[string]image.Point{"320": {320, 160}, "800": {800, 400}} {
+		thumb, contentType := decodeBlob(t, m, "veh-001", img.Thumbnails[width])
+		if thumb.Bounds().Size() != want || contentType != "image/jpeg" {
+			t.Errorf("Expected a %v JPEG thumbnail, got %v %s", want, thumb.Bounds().Size(), contentType)
+		}
+	}
+}
+
+func TestUploadKeepsTransparencyAndSmallSizes(t *testing.T) {
+	m, _ := newTestManager(t, 0)
+	ctx := context.Background()
+
+	src := image.NewNRGBA(image.Rect(0, 0, 640, 200))
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+	img, err := m.Upload(ctx, "veh-002", &buf)
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	thumb, contentType := decodeBlob(t, m, "veh-002", img.Thumbnails["320"])
+	if _, _, _, a := thumb.At(10, 10).RGBA(); a != 0 || contentType != "image/png" {
+		t.Errorf("Expected a transparent PNG thumbnail, got alpha %d %s", a, contentType)
+	}
+	if thumb, _ := decodeBlob(t, m, "veh-002", img.Thumbnails["800"]); thumb.Bounds().Dx() != 640 {
+		t.Errorf("Expected a narrow image not to be enlarged, got %v", thumb.Bounds())
+	}
+
+	buf.Reset()
+	gif.Encode(&buf, testImage(400, 300), nil)
+	img, err = m.Upload(ctx, "veh-002", &buf)
+	if err != nil {
+		t.Fatalf("GIF upload failed: %v", err)
+	}
+	if !strings.HasSuffix(img.URL, ".gif") || !strings.HasSuffix(img.Thumbnails["320"], ".png") {
+		t.Errorf("Expected a GIF with PNG thumbnails, got %+v", img)
+	}
+	if _, contentType := decodeBlob(t, m, "veh-002", img.URL); contentType != "image/gif" {
+		t.Errorf("Expected the original to be served as a GIF, got %s", contentType)
+	}
+}
+
+func TestUploadRejectsBadFiles(t *testing.T) {
+	m, repo := newTestManager(t, 4096)
+	ctx := context.Background()
+
+	pngHeader := []byte("\x89PNG\r\n\x1a\n")
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("not an image at all"), ErrUnsupportedType},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), ErrUnsupportedType},
+		{"truncated", append(pngHeader, "garbage"...), ErrInvalidImage},
+		{"too large", append(pngHeader, make([]byte, 5000)...), ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Upload(ctx, "veh-001", bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := m.Upload(ctx, "veh-999", bytes.NewReader(encodeJPEG(t, testImage(10, 10)))); !errors.Is(err, repository.ErrVehicleNotFound) {
+		t.Errorf("Expected ErrVehicleNotFound, got %v", err)
+	}
+	if vehicle, _ := repo.GetVehicleByID("veh-001"); len(vehicle.Images) != 1 {
+		t.Errorf("Expected no image to be added, got %v", vehicle.Images)
+	}
+}
+
+func TestReorderAndDelete(t *testing.T) {
+	m, repo := newTestManager(t, 0)
+	ctx := context.Background()
+
+	first, _ := m.Upload(ctx, "veh-003", bytes.NewReader(encodeJPEG(t, testImage(50, 50))))
+	second, _ := m.Upload(ctx, "veh-003", bytes.NewReader(encodeJPEG(t, testImage(60, 60))))
+	listed, err := m.List("veh-003")
+	if err != nil || len(listed) != 3 {
+		t.Fatalf("Expected the seed image and two uploads, got %v %v", listed, err)
+	}
+	static := listed[0]
+	if static.Managed || static.ID != static.URL {
+		t.Errorf("Expected the seed image to be unmanaged, got %+v", static)
+	}
+
+	for _, order := range [][]string{
+		{second.ID, first.ID},
+		{second.ID, first.ID, first.ID},
+		{second.ID, first.ID, "img-0000000000000000"},
+	} {
+		if _, err := m.Reorder("veh-003", order); !errors.Is(err, ErrInvalidOrder) {
+			t.Errorf("Expected %v to be rejected, got %v", order, err)
+		}
+	}
+	reordered, err := m.Reorder("veh-003", []string{second.ID, static.ID, first.ID})
+	if err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	if reordered[0].ID != second.ID || reordered[2].ID != first.ID {
+		t.Errorf("Expected the new order, got %+v", reordered)
+	}
+
+	if err := m.Delete(ctx, "veh-003", static.ID); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("Expected static images not to be deletable, got %v", err)
+	}
+	if err := m.Delete(ctx, "veh-003", second.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	vehicle, _ := repo.GetVehicleByID("veh-003")
+	if len(vehicle.Images) != 2 || vehicle.Images[0] != static.URL || vehicle.Images[1] != first.URL {
+		t.Errorf("Expected the image to be removed, got %v", vehicle.Images)
+	}
+	for _, url := range []string{second.URL, second.Thumbnails["320"], second.Thumbnails["800"]} {
+		file := url[strings.LastIndex(url, "/")+1:]
+		if _, _, _, err := m.Open(ctx, "veh-003", file); !errors.Is(err, ErrImageNotFound) {
+			t.Errorf("Expected %s to be deleted, got %v", file, err)
+		}
+	}
+	if err := m.Delete(ctx, "veh-003", second.ID); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("Expected a second delete to fail, got %v", err)
+	}
+}
+
+func TestOpenOnlyServesImageFiles(t *testing.T) {
+	m, _ := newTestManager(t, 0)
+
+	for _, file := range []string{"../../etc/passwd", "img-1.jpg", "notes.txt", "img-0123456789abcdef.exe"} {
+		if _, _, _, err := m.Open(context.Background(), "veh-001", file); !errors.Is(err, ErrImageNotFound) {
+			t.Errorf("Expected %q not to be served, got %v", file, err)
+		}
+	}
+	if _, _, _, err := m.Open(context.Background(), "../x", "img-0123456789abcdef.jpg"); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("Expected a bad vehicle ID not to be served, got %v", err)
+	}
+}
+
+func TestOpenOnlyServesCurrentImages(t *testing.T) {
+	m, repo := newTestManager(t, 0)
+	ctx := context.Background()
+
+	// A file left in the store that no image refers to
+	stray := "img-0123456789abcdef.jpg"
+	if err := m.blobs.Put(ctx, blobKey("veh-006", stray), bytes.NewReader(encodeJPEG(t, testImage(10, 10)))); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, _, _, err := m.Open(ctx, "veh-006", stray); !errors.Is(err, ErrImageNotFound) {
+		t.Errorf("Expected an unreferenced file not to be served, got %v", err)
+	}
+
+	img, err := m.Upload(ctx, "veh-006", bytes.NewReader(encodeJPEG(t, testImage(50, 50))))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	vehicle, _ := repo.GetVehicleByID("veh-006")
+	if err := repo.DeleteVehicle("veh-006"); err != nil {
+		t.Fatalf("DeleteVehicle failed: %v", err)
+	}
+	file := img.URL[strings.LastIndex(img.URL, "/")+1:]
+	if _, _, _, err := m.Open(ctx, "veh-006", file); !errors.Is(err, repository.ErrVehicleNotFound) {
+		t.Errorf("Expected the image of a deleted vehicle not to be served, got %v", err)
+	}
+
+	m.DeleteVehicle(vehicle)
+	for _, url := range []string{img.URL, img.Thumbnails["320"], img.Thumbnails["800"]} {
+		key := blobKey("veh-006", url[strings.LastIndex(url, "/")+1:])
+		if _, _, err := m.blobs.Open(ctx, key); !errors.Is(err, blob.ErrNotFound) {
+			t.Errorf("Expected %s to be removed, got %v", key, err)
+		}
+	}
+}
+
+func TestBoxResizeAverages(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		v := uint8(0)
+		if x%2 == 1 {
+			v = 200
+		}
+		for y := 0; y < 2; y++ {
+			src.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	dst := boxResize(src, 2, 1)
+	for x := 0; x < 2; x++ {
+		if c := dst.RGBAAt(x, 0); c.R != 100 || c.A != 255 {
+			t.Errorf("Expected the average of each pair, got %v", c)
+		}
+	}
+}
+
+func TestKeepUploaded(t *testing.T) {
+	first := imageURL("veh-001", "img-0000000000000001.jpg")
+	second := imageURL("veh-001", "img-0000000000000002.png")
+	existing := &models.Vehicle{ID: "veh-001", Images: []string{"/static/a.jpg", first, second}}
+
+	tests := []struct {
+		name   string
+		images []string
+		expect []string
+	}{
+		{"none listed", nil, []string{first, second}},
+		{"reordered", []string{second, "/static/b.jpg", first}, []string{second, "/static/b.jpg", first}},
+		{"one left out", []string{second}, []string{second, first}},
+		{"unknown upload", []string{imageURL("veh-001", "img-0000000000000003.jpg"), first, first}, []string{first, second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := &models.Vehicle{ID: "veh-001", Images: tt.images}
+			KeepUploaded(vehicle, existing)
+			if strings.Join(vehicle.Images, " ") != strings.Join(tt.expect, " ") {
+				t.Errorf("Expected %v, got %v", tt.expect, vehicle.Images)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+// Package importer loads batches of vehicle listings from CSV or JSON files
+// and upserts them by VIN in a single all-or-nothing write
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/images"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+)
+
+// Formats accepted by Import
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// What an import does with a record
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+)
+
+var (
+	// ErrUnknownFormat is returned for a format other than csv or json
+	ErrUnknownFormat = errors.New("format must be csv or json")
+	// ErrMalformed is returned when the file cannot be parsed at all
+	ErrMalformed = errors.New("malformed import file")
+	// ErrInvalidMapping is returned for a column mapping naming an unknown
+	// field or a column missing from the file
+	ErrInvalidMapping = errors.New("invalid column mapping")
+)
+
+// Options configures an import
+type Options struct {
+	// Format is FormatCSV or FormatJSON
+	Format string
+	// Mapping names the CSV column read for a field, for columns not named
+	// after the field itself
+	Mapping Mapping
+	// DryRun validates and plans the import without storing anything
+	DryRun bool
+	// By is the user recorded on price changes
+	By string
+	// Now is the listing date of new vehicles without one and the time of
+	// price changes
+	Now time.Time
+}
+
+// Row reports what the import does with one record
+type Row struct {
+	Line      int    `json:"line"`
+	VIN       string `json:"vin"`
+	Action    string `json:"action"`
+	VehicleID string `json:"vehicleId,omitempty"`
+}
+
+// RowError describes a problem with a record. Line is the line of the
+// file the record starts on.
+type RowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of an import. Rows lists every valid record;
+// nothing is stored unless Errors is empty. Warnings do not stop the import.
+type Report struct {
+	DryRun    bool       `json:"dryRun"`
+	Committed bool       `json:"committed"`
+	Total     int        `json:"total"`
+	Created   int        `json:"created"`
+	Updated   int        `json:"updated"`
+	Rows      []Row      `json:"rows"`
+	Errors    []RowError `json:"errors"`
+	Warnings  []RowError `json:"warnings"`
+}
+
+// record is a parsed record and the problems found with it
+type record struct {
+	line     int
+	vehicle  *models.Vehicle
+	errors   []RowError
+	warnings []RowError
+}
+
+// fail records a problem with the record
+func (r *record) fail(field, message string) {
+	r.errors = append(r.errors, RowError{Line: r.line, Field: field, Message: message})
+}
+
+// warn records a problem with the record that does not stop the import
+func (r *record) warn(field, message string) {
+	r.warnings = append(r.warnings, RowError{Line: r.line, Field: field, Message: message})
+}
+
+// Importer validates imports and writes them to a store
+type Importer struct {
+	store repository.Store
+	lock  sync.Locker
+}
+
+// New creates an importer. lock is held while an import is planned and
+// written, so other writers sharing it cannot change a vehicle in between.
+func New(store repository.Store, lock sync.Locker) *Importer {
+	return &Importer{
+		store: store,
+		lock:  lock,
+	}
+}
+
+// Import reads and validates every record, matches them to stored vehicles
+// by VIN and, unless it is a dry run or any record is invalid, stores them
+// all in one write. Invalid records are reported in the report; the error
+// is only set when the file cannot be read or the write fails.
+func (im *Importer) Import(r io.Reader, opts Options) (*Report, error) {
+	var records []*record
+	var err error
+	switch strings.ToLower(opts.Format) {
+	case FormatCSV:
+		records, err = readCSV(r, opts.Mapping)
+	case FormatJSON:
+		records, err = readJSON(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now().UTC()
+	}
+
+	im.lock.Lock()
+	defer im.lock.Unlock()
+
+	report := &Report{
+		DryRun:   opts.DryRun,
+		Total:    len(records),
+		Rows:     []Row{},
+		Errors:   []RowError{},
+		Warnings: []RowError{},
+	}
+	batch, rows := im.plan(records, opts)
+	for _, rec := range records {
+		report.Errors = append(report.Errors, rec.errors...)
+		report.Warnings = append(report.Warnings, rec.warnings...)
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Line < report.Errors[j].Line
+	})
+	sort.SliceStable(report.Warnings, func(i, j int) bool {
+		return report.Warnings[i].Line < report.Warnings[j].Line
+	})
+	for _, row := range rows {
+		if row.Action == ActionCreate {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+	report.Rows = rows
+
+	if opts.DryRun || len(report.Errors) > 0 || len(batch) == 0 {
+		return report, nil
+	}
+
+	if err := im.store.SaveVehicles(batch); err != nil {
+		return report, err
+	}
+	for i, vehicle := range batch {
+		report.Rows[i].VehicleID = vehicle.ID
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// plan validates the records against each other and the stored vehicles.
+// It returns the vehicles to write, in record order, and a row for each.
+func (im *Importer) plan(records []*record, opts Options) ([]*models.Vehicle, []Row) {
+	stored := make(map[string]*models.Vehicle)
+	for _, vehicle := range im.store.GetAllVehicles() {
+		stored[strings.ToUpper(vehicle.VIN)] = vehicle
+	}
+	dealers := make(map[string]bool)
+	for _, dealer := range im.store.GetDealers() {
+		dealers[dealer.ID] = true
+	}
+
+	seen := make(map[string]int)
+	var batch []*models.Vehicle
+	var rows []Row
+	for _, rec := range records {
+		if len(rec.errors) > 0 {
+			continue
+		}
+		vehicle := rec.vehicle
+		vehicle.Normalize()
+		vehicle.Status = strings.ToLower(strings.TrimSpace(vehicle.Status))
+		vehicle.ID = ""
+
+		if vehicle.VIN != "" {
+			if line, ok := seen[vehicle.VIN]; ok {
+				rec.fail("vin", fmt.Sprintf("duplicates the VIN on line %d", line))
+				continue
+			}
+			seen[vehicle.VIN] = rec.line
+		}
+
+		existing := stored[vehicle.VIN]
+		action := ActionCreate
+		if existing != nil {
+			action = ActionUpdate
+			prepareUpdate(rec, existing)
+		} else {
+			prepareCreate(rec, opts.Now)
+		}
+
+		validate(rec, existing)
+		if vehicle.DealerID != "" && !dealers[vehicle.DealerID] {
+			rec.fail("dealerId", "does not match a dealer")
+		}
+		if len(rec.errors) > 0 {
+			continue
+		}
+
+		if existing != nil {
+			vehicle.RecordPriceChange(existing.Price, existing.Currency, opts.By, opts.Now)
+		}
+		batch = append(batch, vehicle)
+		rows = append(rows, Row{
+			Line:      rec.line,
+			VIN:       vehicle.VIN,
+			Action:    action,
+			VehicleID: vehicle.ID,
+		})
+	}
+	return batch, rows
+}
+
+// validate checks the listing fields of the record, and its VIN against
+// the make and year when the VIN itself is well formed. For an update of
+// existing, VIN findings are only warnings unless the make or year changes
+// (the VIN is what matched the two).
+func validate(rec *record, existing *models.Vehicle) {
+	checkVIN := true
+	var verr *models.ValidationError
+	if err := rec.vehicle.Validate(); errors.As(err, &verr) {
+		for _, fe := range verr.Errors {
+			rec.fail(fe.Field, fe.Message)
+			if fe.Field == "vin" {
+				checkVIN = false
+			}
+		}
+	}
+	if !checkVIN {
+		return
+	}
+	var err error
+	if existing != nil {
+		var warnings []models.FieldError
+		warnings, err = rec.vehicle.CheckVINUpdate(existing)
+		for _, fe := range warnings {
+			rec.warn(fe.Field, fe.Message)
+		}
+	} else {
+		err = rec.vehicle.CheckVIN()
+	}
+	if errors.As(err, &verr) {
+		for _, fe := range verr.Errors {
+			rec.fail(fe.Field, fe.Message)
+		}
+	}
+}
+
+// prepareUpdate turns the record into a replacement of the stored vehicle.
+// As with PUT, the status, its history, any reservation, the price history
+// and the uploaded images are kept.
+func prepareUpdate(rec *record, existing *models.Vehicle) {
+	vehicle := rec.vehicle
+	vehicle.ID = existing.ID
+	if vehicle.Status == "" {
+		vehicle.Status = existing.Status
+	}
+	if vehicle.Status != existing.Status {
+		rec.fail("status", "can only be changed through the transitions endpoint")
+	}
+	if vehicle.ListingDate.IsZero() {
+		vehicle.ListingDate = existing.ListingDate
+	}
+	vehicle.StatusHistory = existing.StatusHistory
+	vehicle.Reservation = existing.Reservation
+	vehicle.PriceHistory = existing.PriceHistory
+	vehicle.PriceDrop = existing.PriceDrop
+	images.KeepUploaded(vehicle, existing)
+}
+
+// prepareCreate turns the record into a new listing
+func prepareCreate(rec *record, now time.Time) {
+	vehicle := rec.vehicle
+	if vehicle.Status == "" {
+		vehicle.Status = models.StatusAvailable
+	}
+	if models.IsValidStatus(vehicle.Status) && !models.IsInitialStatus(vehicle.Status) {
+		rec.fail("status", "new listings must be "+strings.Join(models.InitialStatuses, " or "))
+	}
+	if vehicle.ListingDate.IsZero() {
+		vehicle.ListingDate = now
+	}
+	vehicle.StatusHistory = nil
+	vehicle.Reservation = nil
+	vehicle.PriceHistory = nil
+	vehicle.PriceDrop = nil
+}
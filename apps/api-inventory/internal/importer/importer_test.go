@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestImporter(t *testing.T) (*Importer, repository.Store) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := repository.NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return New(repo, &sync.Mutex{}), repo
+}
+
+// dealerCSV uses the dealer's own column names. veh-014 is updated by VIN
+// and two new vehicles are listed.
+const dealerCSV = `Stock VIN,Model Year,Make,Model,Body,Miles,Asking Price,Currency,Country,Options
+WAUZZZ8V8NA123456,2023,Audi,Q7,suv,"15,200",59990,USD,US,Navigation;Sunroof
+1HGCV1F33NA000101,2022,Honda,Accord,sedan,12000,27500,usd,us,Heated Seats; Apple CarPlay
+1FTFW1E86PF000103,2023,Ford,F-150,truck,8000,52000,USD,US,
+`
+
+var dealerMapping = Mapping{
+	"vin":      "Stock VIN",
+	"year":     "Model Year",
+	"type":     "Body",
+	"mileage":  "Miles",
+	"price":    "Asking Price",
+	"features": "Options",
+}
+
+func TestImportCSV(t *testing.T) {
+	imp, repo := newTestImporter(t)
+	count := len(repo.GetAllVehicles())
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	opts := Options{Format: FormatCSV, Mapping: dealerMapping, DryRun: true, By: "user-002", Now: now}
+
+	report, err := imp.Import(strings.NewReader(dealerCSV), opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(report.Errors) != 0 {
+		t.Fatalf("Expected no errors, got %+v", report.Errors)
+	}
+	if report.Committed || report.Total != 3 || report.Created != 2 || report.Updated != 1 {
+		t.Fatalf("Unexpected dry run report %+v", report)
+	}
+	if row := report.Rows[0]; row.Line != 2 || row.Action != ActionUpdate || row.VehicleID != "veh-014" {
+		t.Errorf("Expected line 2 to update veh-014, got %+v", row)
+	}
+	if got := len(repo.GetAllVehicles()); got != count {
+		t.Fatalf("Expected a dry run to store nothing, got %d vehicles", got)
+	}
+
+	opts.DryRun = false
+	report, err = imp.Import(strings.NewReader(dealerCSV), opts)
+	if err != nil || !report.Committed {
+		t.Fatalf("Expected the import to be committed, got %+v, %v", report, err)
+	}
+	if got := len(repo.GetAllVehicles()); got != count+2 {
+		t.Errorf("Expected %d vehicles, got %d", count+2, got)
+	}
+
+	updated, _ := repo.GetVehicleByID("veh-014")
+	if updated.Mileage != 15200 || updated.Price != 59990 || len(updated.Features) != 2 {
+		t.Errorf("Expected veh-014 to be updated from the file, got %+v", updated)
+	}
+	if len(updated.PriceHistory) != 1 || updated.PriceHistory[0].By != "user-002" || updated.PriceHistory[0].OldPrice != 61990 {
+		t.Errorf("Expected the price change to be recorded, got %+v", updated.PriceHistory)
+	}
+	if updated.Status != "available" || updated.ListingDate.IsZero() || updated.ListingDate.Equal(now) {
+		t.Errorf("Expected the status and listing date to be kept, got %s %s", updated.Status, updated.ListingDate)
+	}
+
+	created, err := repo.GetVehicleByID(report.Rows[1].VehicleID)
+	if err != nil {
+		t.Fatalf("Expected the new vehicle to be stored: %v", err)
+	}
+	if created.Currency != "USD" || created.Status != "available" || !created.ListingDate.Equal(now) || created.Features[1] != "Apple CarPlay" {
+		t.Errorf("Unexpected created vehicle %+v", created)
+	}
+
+	// Importing the same file again only updates
+	report, _ = imp.Import(strings.NewReader(dealerCSV), opts)
+	if report.Created != 0 || report.Updated != 3 {
+		t.Errorf("Expected a re-import to update all 3 vehicles, got %+v", report)
+	}
+}
+
+func TestImportAllOrNothing(t *testing.T) {
+	imp, repo := newTestImporter(t)
+	count := len(repo.GetAllVehicles())
+
+	input := `vin,year,make,model,price,currency,mileage,status
+1HGCV1F33NA000101,2022,Honda,Accord,27500,USD,12000,
+1HGCV1F35NA000102,2022,Honda,Civic,-5,USD,ten,
+1HGCV1F33NA000101,2022,Honda,Accord,27500,USD,12000,
+1HGCV1F39NA000104,2022,Toyota,Camry,25000,XYZ,9000,sold
+WAUZZZ8V8NA123456,2023,Audi,Q7,61990,USD,14800,sold
+1HGCV1F35NA000102,2022,Honda
+`
+	report, err := imp.Import(strings.NewReader(input), Options{Format: FormatCSV})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.Committed {
+		t.Fatal("Expected nothing to be committed")
+	}
+	if got := len(repo.GetAllVehicles()); got != count {
+		t.Fatalf("Expected nothing to be stored, got %d vehicles", got)
+	}
+
+	want := map[int][]string{
+		3: {"mileage"},
+		4: {"vin"},
+		5: {"status", "make", "currency"},
+		6: {"status"},
+		7: {""},
+	}
+	got := map[int][]string{}
+	for _, e := range report.Errors {
+		got[e.Line] = append(got[e.Line], e.Field)
+	}
+	for line, fields := range want {
+		for _, field := range fields {
+			if !containsString(got[line], field) {
+				t.Errorf("Expected an error on line %d field %q, got %v", line, field, report.Errors)
+			}
+		}
+	}
+	if _, ok := got[2]; ok {
+		t.Errorf("Expected line 2 to be valid, got %v", got[2])
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Line != 2 {
+		t.Errorf("Expected only line 2 in the rows, got %+v", report.Rows)
+	}
+}
+
+func TestImportUpdatesInvalidSeedVIN(t *testing.T) {
+	imp, repo := newTestImporter(t)
+
+	// veh-004's seeded VIN fails its checks: updating it keeps them as
+	// warnings, but changing its year fails
+	input := `[{"vin": "1FTFW1E85NFA12345", "year": 2023, "make": "Ford", "model": "F-150", "price": 74990, "currency": "USD"}]`
+	report, err := imp.Import(strings.NewReader(input), Options{Format: FormatJSON})
+	if err != nil || !report.Committed || report.Updated != 1 {
+		t.Fatalf("Expected veh-004 to be updated, got %+v, %v", report, err)
+	}
+	if len(report.Warnings) != 2 || report.Warnings[0].Line != 1 {
+		t.Errorf("Expected VIN and year warnings on line 1, got %+v", report.Warnings)
+	}
+	if updated, _ := repo.GetVehicleByID("veh-004"); updated.Price != 74990 {
+		t.Errorf("Expected the new price, got %v", updated.Price)
+	}
+
+	input = strings.Replace(input, "2023", "2021", 1)
+	report, _ = imp.Import(strings.NewReader(input), Options{Format: FormatJSON})
+	if report.Committed || len(report.Errors) == 0 {
+		t.Errorf("Expected a year change to fail the VIN checks, got %+v", report)
+	}
+
+	input = strings.Replace(input, `"Ford"`, `"Honda"`, 1)
+	report, _ = imp.Import(strings.NewReader(strings.Replace(input, "2021", "2023", 1)), Options{Format: FormatJSON})
+	if report.Committed || len(report.Errors) == 0 {
+		t.Errorf("Expected a make change to fail the VIN checks, got %+v", report)
+	}
+}
+
+func TestImportJSON(t *testing.T) {
+	imp, repo := newTestImporter(t)
+
+	input := `[
+  {
+    "vin": "1hgcv1f33na000101",
+    "year": 2022,
+    "make": "Honda",
+    "model": "Accord",
+    "price": 27500,
+    "currency": "USD"
+  },
+  {"vin": "1HGCV1F35NA000102", "year": "2022", "make": "Honda", "model": "Civic", "price": 22000, "currency": "USD"}
+]`
+	report, err := imp.Import(strings.NewReader(input), Options{Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Line != 10 || report.Errors[0].Field != "year" {
+		t.Fatalf("Expected a year error on line 10, got %+v", report.Errors)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Line != 2 || report.Rows[0].VIN != "1HGCV1F33NA000101" {
+		t.Errorf("Expected line 2 to be valid, got %+v", report.Rows)
+	}
+
+	input = strings.Replace(input, `"2022"`, `2022`, 1)
+	report, err = imp.Import(strings.NewReader(input), Options{Format: FormatJSON})
+	if err != nil || !report.Committed || report.Created != 2 {
+		t.Fatalf("Expected 2 vehicles to be created, got %+v, %v", report, err)
+	}
+	if _, err := repo.GetVehicleByID(report.Rows[1].VehicleID); err != nil {
+		t.Errorf("Expected the vehicle to be stored: %v", err)
+	}
+}
+
+func TestImportRejectsUnreadableInput(t *testing.T) {
+	imp, _ := newTestImporter(t)
+
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		err   error
+	}{
+		{"unknown format", "", Options{Format: "xlsx"}, ErrUnknownFormat},
+		{"not an array", `{"vin": "x"}`, Options{Format: FormatJSON}, ErrMalformed},
+		{"truncated JSON", `[{"vin": "x"`, Options{Format: FormatJSON}, ErrMalformed},
+		{"bad CSV quoting", "vin,make\n\"abc,Honda\n", Options{Format: FormatCSV}, ErrMalformed},
+		{"no vin column", "make,model\nHonda,Accord\n", Options{Format: FormatCSV}, ErrInvalidMapping},
+		{"unknown field", "vin\nx\n", Options{Format: FormatCSV, Mapping: Mapping{"colour": "Color"}}, ErrInvalidMapping},
+		{"missing column", "vin\nx\n", Options{Format: FormatCSV, Mapping: Mapping{"price": "Asking"}}, ErrInvalidMapping},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := imp.Import(strings.NewReader(tt.input), tt.opts); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImportUpdateKeepsUploadedImages(t *testing.T) {
+	imp, repo := newTestImporter(t)
+
+	stored, _ := repo.GetVehicleByID("veh-004")
+	vehicle := stored.Clone()
+	uploaded := "/api/v1/vehicles/veh-004/images/img-0123456789abcdef.jpg"
+	vehicle.Images = append(vehicle.Images, uploaded)
+	if err := repo.UpdateVehicle(vehicle); err != nil {
+		t.Fatalf("UpdateVehicle failed: %v", err)
+	}
+
+	input := `[{"vin": "1FTFW1E85NFA12345", "year": 2023, "make": "Ford", "model": "F-150", "price": 74990, "currency": "USD", "images": ["/images/vehicles/f150.jpg"]}]`
+	report, err := imp.Import(strings.NewReader(input), Options{Format: FormatJSON})
+	if err != nil || !report.Committed || report.Updated != 1 {
+		t.Fatalf("Expected veh-004 to be updated, got %+v, %v", report, err)
+	}
+	updated, _ := repo.GetVehicleByID("veh-004")
+	if len(updated.Images) != 2 || updated.Images[0] != "/images/vehicles/f150.jpg" || updated.Images[1] != uploaded {
+		t.Errorf("Expected the imported image and the upload, got %v", updated.Images)
+	}
+}
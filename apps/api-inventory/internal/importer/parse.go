@@ -0,0 +1,320 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// Mapping maps vehicle fields to the CSV column holding them. Fields not in
+// the mapping are read from the column named after the field.
+type Mapping map[string]string
+
+// Fields lists the fields read from CSV columns. List fields (features,
+// images) are separated by semicolons; latitude and longitude set the
+// coordinates.
+var Fields = []string{
+	"vin", "year", "make", "model", "trim", "type", "condition", "mileage",
+	"price", "currency", "country", "status", "fuelType", "transmission",
+	"drivetrain", "exteriorColor", "interiorColor", "features", "images",
+	"dealerId", "location", "latitude", "longitude", "listingDate",
+}
+
+// listSeparator separates the values of list fields in a CSV cell
+const listSeparator = ";"
+
+// Validate checks that the mapping only names known fields
+func (m Mapping) Validate() error {
+	for field := range m {
+		if !isField(field) {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidMapping, field)
+		}
+	}
+	return nil
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// columnIndexes finds the column of each field in the header. Columns are
+// matched case-insensitively; a column named by the mapping must exist.
+func columnIndexes(header []string, mapping Mapping) (map[string]int, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, dup := positions[name]; !dup {
+			positions[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range Fields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(column))]; ok {
+			columns[field] = i
+		} else if mapped {
+			return nil, fmt.Errorf("%w: no column %q for %s", ErrInvalidMapping, column, field)
+		}
+	}
+	if _, ok := columns["vin"]; !ok {
+		return nil, fmt.Errorf("%w: no vin column", ErrInvalidMapping)
+	}
+	return columns, nil
+}
+
+// readCSV reads one record per row after the header line
+func readCSV(r io.Reader, mapping Mapping) ([]*record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns, err := columnIndexes(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set fields in a fixed order so errors are reported in column order
+	fields := make([]string, 0, len(columns))
+	for field := range columns {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return columns[fields[i]] < columns[fields[j]] })
+
+	var records []*record
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		rec := &record{line: line, vehicle: &models.Vehicle{}}
+		if len(row) != len(header) {
+			rec.fail("", fmt.Sprintf("has %d columns, expected %d", len(row), len(header)))
+			records = append(records, rec)
+			continue
+		}
+		var lat, lng string
+		for _, field := range fields {
+			value := strings.TrimSpace(row[columns[field]])
+			switch field {
+			case "latitude":
+				lat = value
+			case "longitude":
+				lng = value
+			default:
+				if err := setField(rec.vehicle, field, value); err != nil {
+					rec.fail(field, err.Error())
+				}
+			}
+		}
+		if err := setCoordinates(rec.vehicle, lat, lng); err != nil {
+			rec.fail("coordinates", err.Error())
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// csvError marks CSV syntax errors as ErrMalformed and passes read errors
+// through
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return err
+}
+
+// setField sets a vehicle field from a CSV cell. Empty cells leave the
+// field unset.
+func setField(v *models.Vehicle, field, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var err error
+	switch field {
+	case "vin":
+		v.VIN = value
+	case "year":
+		v.Year, err = parseInt(value)
+	case "make":
+		v.Make = value
+	case "model":
+		v.Model = value
+	case "trim":
+		v.Trim = value
+	case "type":
+		v.Type = strings.ToLower(value)
+	case "condition":
+		v.Condition = strings.ToLower(value)
+	case "mileage":
+		v.Mileage, err = parseInt(value)
+	case "price":
+		v.Price, err = parseFloat(value)
+	case "currency":
+		v.Currency = value
+	case "country":
+		v.Country = value
+	case "status":
+		v.Status = value
+	case "fuelType":
+		v.FuelType = strings.ToLower(value)
+	case "transmission":
+		v.Transmission = strings.ToLower(value)
+	case "drivetrain":
+		v.Drivetrain = strings.ToLower(value)
+	case "exteriorColor":
+		v.ExteriorColor = value
+	case "interiorColor":
+		v.InteriorColor = value
+	case "features":
+		v.Features = splitList(value)
+	case "images":
+		v.Images = splitList(value)
+	case "dealerId":
+		v.DealerID = value
+	case "location":
+		v.Location = value
+	case "listingDate":
+		v.ListingDate, err = parseDate(value)
+	}
+	return err
+}
+
+// setCoordinates sets the coordinates when both latitude and longitude are
+// given
+func setCoordinates(v *models.Vehicle, lat, lng string) error {
+	if lat == "" && lng == "" {
+		return nil
+	}
+	if lat == "" || lng == "" {
+		return errors.New("needs both latitude and longitude")
+	}
+	latitude, err := strconv.ParseFloat(lat, 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		return errors.New("latitude must be a number between -90 and 90")
+	}
+	longitude, err := strconv.ParseFloat(lng, 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		return errors.New("longitude must be a number between -180 and 180")
+	}
+	v.Coordinates = &models.GeoPoint{Lat: latitude, Lng: longitude}
+	return nil
+}
+
+// parseInt parses a whole number, allowing thousands separators
+func parseInt(value string) (int, error) {
+	n, err := strconv.Atoi(strings.ReplaceAll(value, ",", ""))
+	if err != nil {
+		return 0, fmt.Errorf("must be a whole number, got %q", value)
+	}
+	return n, nil
+}
+
+// parseFloat parses a number, allowing thousands separators
+func parseFloat(value string) (float64, error) {
+	n, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("must be a number, got %q", value)
+	}
+	return n, nil
+}
+
+// parseDate parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC)
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a date such as 2024-01-31, got %q", value)
+	}
+	return t, nil
+}
+
+// splitList splits a list cell into its trimmed, non-empty values
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, listSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// readJSON reads a JSON array of vehicles. Each record's line is the line
+// its object starts on.
+func readJSON(r io.Reader) ([]*record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("%w: expected a JSON array of vehicles", ErrMalformed)
+	}
+
+	var records []*record
+	for dec.More() {
+		start := int(dec.InputOffset())
+		for start < len(data) && strings.ContainsRune(" \t\r\n,", rune(data[start])) {
+			start++
+		}
+		rec := &record{
+			line:    bytes.Count(data[:start], []byte("\n")) + 1,
+			vehicle: &models.Vehicle{},
+		}
+
+		if err := dec.Decode(rec.vehicle); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrMalformed, rec.line, err)
+			}
+			rec.fail(typeErr.Field, fmt.Sprintf("must be a %s, got %s", typeErr.Type, typeErr.Value))
+		}
+		records = append(records, rec)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return records, nil
+}
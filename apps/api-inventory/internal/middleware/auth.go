@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/auth"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
@@ -54,3 +55,44 @@ func AuthMiddleware(jwtManager *auth.JWTManager, logger *logrus.Logger) func(htt
 		})
 	}
 }
+
+// UserIDFromContext returns the authenticated user ID set by AuthMiddleware
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(UserIDKey).(string)
+	return userID
+}
+
+// UserLookup finds users by ID
+type UserLookup interface {
+	GetUserByID(userID string) (*models.User, error)
+}
+
+// RequireRole creates middleware that only admits users holding the given
+// role. It must run after AuthMiddleware.
+func RequireRole(users UserLookup, role string, logger *logrus.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := UserIDFromContext(r.Context())
+
+			user, err := users.GetUserByID(userID)
+			if err != nil {
+				logger.WithField("user_id", userID).Warn("Unknown user")
+				http.Error(w, "Unauthorized: unknown user", http.StatusUnauthorized)
+				return
+			}
+
+			for _, userRole := range user.Roles {
+				if userRole == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			logger.WithFields(logrus.Fields{
+				"user_id": userID,
+				"role":    role,
+			}).Warn("Missing required role")
+			http.Error(w, "Forbidden: requires "+role+" role", http.StatusForbidden)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Notification events
+const (
+	// EventListed is a new listing matching a saved search
+	EventListed = "listed"
+	// EventUpdated is a changed listing that now matches a saved search
+	EventUpdated = "updated"
+)
+
+// SavedSearch is a named vehicle search a user is notified about
+type SavedSearch struct {
+	ID        string        `json:"id"`
+	UserID    string        `json:"userId"`
+	Name      string        `json:"name"`
+	Filter    VehicleFilter `json:"filter"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// Notification tells a user that a vehicle matches one of their saved
+// searches
+type Notification struct {
+	ID         string `json:"id"`
+	UserID     string `json:"userId"`
+	SearchID   string `json:"searchId"`
+	SearchName string `json:"searchName"`
+	Event      string `json:"event"`
+	VehicleID  string `json:"vehicleId"`
+	// Title describes the vehicle, e.g. "2021 Tesla Model 3"
+	Title     string    `json:"title"`
+	Price     Money     `json:"price"`
+	CreatedAt time.Time `json:"createdAt"`
+	Read      bool      `json:"read"`
+}
+
+// SearchFromPreferences returns a filter for the price range and vehicle
+// types in the user's preferences, with prices in their preferred currency
+func (u *User) SearchFromPreferences() VehicleFilter {
+	var filter VehicleFilter
+	if u.Preferences == nil {
+		return filter
+	}
+	if len(u.Preferences.PriceRange) == 2 {
+		filter.MinPrice = float64(u.Preferences.PriceRange[0])
+		filter.MaxPrice = float64(u.Preferences.PriceRange[1])
+		filter.PriceCurrency = u.PreferredCurrency
+	}
+	filter.VehicleTypes = append([]string(nil), u.Preferences.VehicleTypes...)
+	return filter
+}
@@ -0,0 +1,222 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Comparison limits
+const (
+	MinCompared = 2
+	MaxCompared = 5
+)
+
+// Which value of a numeric attribute wins a comparison
+const (
+	BetterLower  = "lower"
+	BetterHigher = "higher"
+)
+
+// Comparison lines up the attributes of several vehicles. Every attribute
+// has one value per vehicle, in the order of VehicleIDs.
+type Comparison struct {
+	VehicleIDs []string `json:"vehicleIds"`
+	// Currency is the currency all prices are normalized to
+	Currency   string              `json:"currency"`
+	Attributes []ComparedAttribute `json:"attributes"`
+	Features   FeatureComparison   `json:"features"`
+}
+
+// ComparedAttribute is one row of a comparison
+type ComparedAttribute struct {
+	Name   string        `json:"name"`
+	Values []interface{} `json:"values"`
+	// Same is true when every vehicle has the same value
+	Same bool `json:"same"`
+	// Better and Winners are set on numeric attributes: whether the lower
+	// or higher value wins, and the IDs of the vehicles that have it
+	Better  string   `json:"better,omitempty"`
+	Winners []string `json:"winners,omitempty"`
+}
+
+// FeatureComparison lists the features all compared vehicles share, and
+// for each vehicle the features none of the others have
+type FeatureComparison struct {
+	Shared []string            `json:"shared"`
+	Unique map[string][]string `json:"unique"`
+}
+
+// Compare builds the comparison of the vehicles with prices normalized to
+// currency. Prices in other currencies are converted with rates; a price
+// that cannot be converted is null and takes no part in picking a winner.
+func Compare(vehicles []*Vehicle, currency string, rates PriceConverter) *Comparison {
+	c := &Comparison{
+		VehicleIDs: make([]string, len(vehicles)),
+		Currency:   currency,
+	}
+	for i, v := range vehicles {
+		c.VehicleIDs[i] = v.ID
+	}
+
+	text := func(name string, value func(v *Vehicle) interface{}) {
+		c.add(vehicles, name, "", value)
+	}
+	numeric := func(name, better string, value func(v *Vehicle) interface{}) {
+		c.add(vehicles, name, better, value)
+	}
+
+	text("vin", func(v *Vehicle) interface{} { return v.VIN })
+	numeric("year", BetterHigher, func(v *Vehicle) interface{} { return float64(v.Year) })
+	text("make", func(v *Vehicle) interface{} { return v.Make })
+	text("model", func(v *Vehicle) interface{} { return v.Model })
+	text("trim", func(v *Vehicle) interface{} { return v.Trim })
+	text("type", func(v *Vehicle) interface{} { return v.Type })
+	text("condition", func(v *Vehicle) interface{} { return v.Condition })
+	numeric("mileage", BetterLower, func(v *Vehicle) interface{} { return float64(v.Mileage) })
+	numeric("price", BetterLower, func(v *Vehicle) interface{} {
+		if strings.EqualFold(v.Currency, currency) {
+			return v.Price
+		}
+		if rates == nil {
+			return nil
+		}
+		price, ok := rates.Convert(v.Price, v.Currency, currency)
+		if !ok {
+			return nil
+		}
+		return math.Round(price*100) / 100
+	})
+	text("listedPrice", func(v *Vehicle) interface{} { return Money{Amount: v.Price, Currency: v.Currency} })
+	text("country", func(v *Vehicle) interface{} { return v.Country })
+	text("status", func(v *Vehicle) interface{} { return v.Status })
+	text("fuelType", func(v *Vehicle) interface{} { return v.FuelType })
+	text("transmission", func(v *Vehicle) interface{} { return v.Transmission })
+	text("drivetrain", func(v *Vehicle) interface{} { return v.Drivetrain })
+	text("exteriorColor", func(v *Vehicle) interface{} { return v.ExteriorColor })
+	text("interiorColor", func(v *Vehicle) interface{} { return v.InteriorColor })
+	numeric("dealerRating", BetterHigher, func(v *Vehicle) interface{} { return v.DealerRating })
+	text("location", func(v *Vehicle) interface{} { return v.Location })
+	text("coordinates", func(v *Vehicle) interface{} {
+		if v.Coordinates == nil {
+			return nil
+		}
+		return *v.Coordinates
+	})
+	text("listingDate", func(v *Vehicle) interface{} { return v.ListingDate })
+	text("priceDrop", func(v *Vehicle) interface{} {
+		if v.PriceDrop == nil {
+			return nil
+		}
+		return *v.PriceDrop
+	})
+	text("features", func(v *Vehicle) interface{} { return append([]string{}, v.Features...) })
+	text("images", func(v *Vehicle) interface{} { return append([]string{}, v.Images...) })
+
+	c.Features = compareFeatures(vehicles)
+	return c
+}
+
+// add appends an attribute row, picking the winners of numeric rows
+func (c *Comparison) add(vehicles []*Vehicle, name, better string, value func(v *Vehicle) interface{}) {
+	row := ComparedAttribute{
+		Name:   name,
+		Values: make([]interface{}, len(vehicles)),
+		Same:   true,
+		Better: better,
+	}
+	for i, v := range vehicles {
+		row.Values[i] = value(v)
+		if i > 0 && !sameValue(row.Values[i], row.Values[0]) {
+			row.Same = false
+		}
+	}
+
+	if better != "" {
+		best, found := 0.0, false
+		for _, value := range row.Values {
+			n, ok := value.(float64)
+			if !ok {
+				continue
+			}
+			if !found || (better == BetterLower && n < best) || (better == BetterHigher && n > best) {
+				best, found = n, true
+			}
+		}
+		for i, value := range row.Values {
+			if n, ok := value.(float64); ok && found && n == best {
+				row.Winners = append(row.Winners, vehicles[i].ID)
+			}
+		}
+	}
+
+	c.Attributes = append(c.Attributes, row)
+}
+
+// sameValue compares two attribute values
+func sameValue(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []string:
+		b, ok := b.([]string)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case time.Time:
+		b, ok := b.(time.Time)
+		return ok && a.Equal(b)
+	}
+	return a == b
+}
+
+// compareFeatures finds the features all vehicles share and those only one
+// has. Features are matched case-insensitively and listed sorted.
+func compareFeatures(vehicles []*Vehicle) FeatureComparison {
+	counts := make(map[string]int)
+	names := make(map[string]string)
+	for _, v := range vehicles {
+		seen := make(map[string]bool)
+		for _, feature := range v.Features {
+			key := strings.ToLower(strings.TrimSpace(feature))
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			counts[key]++
+			if _, ok := names[key]; !ok {
+				names[key] = feature
+			}
+		}
+	}
+
+	result := FeatureComparison{
+		Shared: []string{},
+		Unique: make(map[string][]string, len(vehicles)),
+	}
+	for key, count := range counts {
+		if count == len(vehicles) {
+			result.Shared = append(result.Shared, names[key])
+		}
+	}
+	for _, v := range vehicles {
+		unique := []string{}
+		seen := make(map[string]bool)
+		for _, feature := range v.Features {
+			key := strings.ToLower(strings.TrimSpace(feature))
+			if key != "" && counts[key] == 1 && !seen[key] {
+				seen[key] = true
+				unique = append(unique, feature)
+			}
+		}
+		sort.Strings(unique)
+		result.Unique[v.ID] = unique
+	}
+	sort.Strings(result.Shared)
+	return result
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fixedRates converts GBP to USD at 1.25 and knows no other rates
+type fixedRates struct{}
+
+func (fixedRates) Convert(amount float64, from, to string) (float64, bool) {
+	if from == "GBP" && to == "USD" {
+		return amount * 1.25, true
+	}
+	return 0, false
+}
+
+func TestCompare(t *testing.T) {
+	vehicles := []*Vehicle{
+		{ID: "a", Year: 2022, Make: "Ford", Mileage: 9000, Price: 30000, Currency: "USD", DealerRating: 4.5, Features: []string{"Navigation", "Heated Seats", "Sunroof"}},
+		{ID: "b", Year: 2023, Make: "Ford", Mileage: 5000, Price: 20000, Currency: "GBP", DealerRating: 4.5, Features: []string{"heated seats", "Navigation", "Tow Hitch"}},
+		{ID: "c", Year: 2023, Make: "Ford", Mileage: 5000, Price: 10000, Currency: "EUR", DealerRating: 4.0, Features: []string{"Navigation", "Heated Seats", "Sunroof", "Heated Seats"}},
+	}
+
+	c := Compare(vehicles, "USD", fixedRates{})
+	if !reflect.DeepEqual(c.VehicleIDs, []string{"a", "b", "c"}) || c.Currency != "USD" {
+		t.Fatalf("Unexpected comparison header %v %s", c.VehicleIDs, c.Currency)
+	}
+
+	rows := map[string]ComparedAttribute{}
+	for _, row := range c.Attributes {
+		if len(row.Values) != len(vehicles) {
+			t.Errorf("Expected %d values for %s, got %d", len(vehicles), row.Name, len(row.Values))
+		}
+		rows[row.Name] = row
+	}
+
+	tests := []struct {
+		name    string
+		better  string
+		winners []string
+	}{
+		// c's EUR price cannot be converted, so it cannot win on price
+		{"price", BetterLower, []string{"b"}},
+		{"mileage", BetterLower, []string{"b", "c"}},
+		{"year", BetterHigher, []string{"b", "c"}},
+		{"dealerRating", BetterHigher, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		row := rows[tt.name]
+		if row.Better != tt.better || !reflect.DeepEqual(row.Winners, tt.winners) {
+			t.Errorf("%s: expected %s winners %v, got %s %v", tt.name, tt.better, tt.winners, row.Better, row.Winners)
+		}
+	}
+	if got := rows["price"].Values; got[1] != 25000.0 || got[2] != nil {
+		t.Errorf("Expected prices normalized to USD, got %v", got)
+	}
+	if !rows["make"].Same || rows["features"].Same {
+		t.Error("Expected make to be the same and features to differ")
+	}
+	if rows["vin"].Winners != nil || rows["vin"].Better != "" {
+		t.Error("Expected no winner on text attributes")
+	}
+
+	if want := []string{"Heated Seats", "Navigation"}; !reflect.DeepEqual(c.Features.Shared, want) {
+		t.Errorf("Expected shared features %v, got %v", want, c.Features.Shared)
+	}
+	wantUnique := map[string][]string{"a": {}, "b": {"Tow Hitch"}, "c": {}}
+	if !reflect.DeepEqual(c.Features.Unique, wantUnique) {
+		t.Errorf("Expected unique features %v, got %v", wantUnique, c.Features.Unique)
+	}
+}
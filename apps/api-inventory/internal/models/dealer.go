@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Review limits
+const (
+	MinReviewRating     = 1
+	MaxReviewRating     = 5
+	maxReviewTitleLen   = 120
+	maxReviewCommentLen = 2000
+)
+
+// RatingPriorWeight is how many reviews the prior counts for when a
+// dealer's score is smoothed (see RateDealers)
+const RatingPriorWeight = 5
+
+// Dealer is a business selling vehicles from one or more locations
+type Dealer struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Website     string           `json:"website,omitempty"`
+	Contact     DealerContact    `json:"contact"`
+	Locations   []DealerLocation `json:"locations"`
+	CreatedAt   time.Time        `json:"createdAt"`
+
+	// Rating is computed from the dealer's reviews; it is not stored
+	Rating *DealerRating `json:"rating,omitempty"`
+}
+
+// DealerContact holds the dealer's main contact details
+type DealerContact struct {
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+// DealerLocation is a showroom or lot of a dealer
+type DealerLocation struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone,omitempty"`
+}
+
+// DealerReview is a user's rating of a dealer. Only users who bought one of
+// the dealer's vehicles may review it, and each user has at most one
+// review per dealer.
+type DealerReview struct {
+	DealerID string `json:"dealerId"`
+	// UserID is the reviewer; it is left out of the review other users see
+	UserID string `json:"userId,omitempty"`
+	// Author is the reviewer's display name
+	Author string `json:"author"`
+	// VehicleID is the purchased vehicle that verifies the review
+	VehicleID string    `json:"vehicleId"`
+	Rating    int       `json:"rating"`
+	Title     string    `json:"title,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DealerRating summarises a dealer's reviews
+type DealerRating struct {
+	// Average is the plain mean of the ratings
+	Average float64 `json:"average"`
+	// Score is the average smoothed towards the mean of all reviews; it is
+	// the value vehicles show as their dealerRating
+	Score float64 `json:"score"`
+	Count int     `json:"count"`
+}
+
+// Public returns the review without the reviewer's user ID
+func (r *DealerReview) Public() *DealerReview {
+	public := *r
+	public.UserID = ""
+	return &public
+}
+
+// Validate checks the review fields and returns a *ValidationError or nil
+func (r *DealerReview) Validate() error {
+	verr := &ValidationError{}
+
+	if r.Rating < MinReviewRating || r.Rating > MaxReviewRating {
+		verr.add("rating", fmt.Sprintf("must be between %d and %d", MinReviewRating, MaxReviewRating))
+	}
+	if len(r.Title) > maxReviewTitleLen {
+		verr.add("title", fmt.Sprintf("must be at most %d characters", maxReviewTitleLen))
+	}
+	if len(r.Comment) > maxReviewCommentLen {
+		verr.add("comment", fmt.Sprintf("must be at most %d characters", maxReviewCommentLen))
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// RateDealers computes the rating of every reviewed dealer. The score is a
+// Bayesian average: RatingPriorWeight reviews at the mean of all ratings
+// are added to the dealer's own, so a dealer with two five-star reviews
+// does not outrank one with a hundred reviews averaging 4.8. Dealers
+// without reviews are left out.
+func RateDealers(reviews []*DealerReview) map[string]DealerRating {
+	if len(reviews) == 0 {
+		return map[string]DealerRating{}
+	}
+
+	type tally struct {
+		sum   int
+		count int
+	}
+	tallies := make(map[string]*tally)
+	total := 0
+	for _, review := range reviews {
+		t := tallies[review.DealerID]
+		if t == nil {
+			t = &tally{}
+			tallies[review.DealerID] = t
+		}
+		t.sum += review.Rating
+		t.count++
+		total += review.Rating
+	}
+
+	prior := float64(total) / float64(len(reviews))
+	ratings := make(map[string]DealerRating, len(tallies))
+	for dealerID, t := range tallies {
+		ratings[dealerID] = DealerRating{
+			Average: roundRating(float64(t.sum) / float64(t.count)),
+			Score:   roundRating((RatingPriorWeight*prior + float64(t.sum)) / float64(RatingPriorWeight+t.count)),
+			Count:   t.count,
+		}
+	}
+	return ratings
+}
+
+// roundRating rounds a rating to two decimal places
+func roundRating(rating float64) float64 {
+	return math.Round(rating*100) / 100
+}
+
+// PurchasedBy reports whether the user bought the vehicle: it was sold
+// while reserved or pending sale under the user's reservation. Holding a
+// reservation that ended any other way is not a purchase.
+func (v *Vehicle) PurchasedBy(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	holder := ""
+	for _, transition := range v.StatusHistory {
+		switch transition.To {
+		case StatusReserved:
+			holder = transition.By
+		case StatusPendingSale:
+			if transition.From != StatusReserved {
+				holder = ""
+			}
+		case StatusSold:
+			if holder == userID && (transition.From == StatusReserved || transition.From == StatusPendingSale) {
+				return true
+			}
+			holder = ""
+		default:
+			holder = ""
+		}
+	}
+	return false
+}
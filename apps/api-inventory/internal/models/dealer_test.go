@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateDealers(t *testing.T) {
+	var reviews []*DealerReview
+	add := func(dealerID string, ratings ...int) {
+		for _, rating := range ratings {
+			reviews = append(reviews, &DealerReview{DealerID: dealerID, Rating: rating})
+		}
+	}
+	// The mean of all ten ratings is 4
+	add("few", 5, 5)
+	add("many", 5, 5, 4, 4, 4, 4, 2, 2)
+
+	ratings := RateDealers(reviews)
+	few, many := ratings["few"], ratings["many"]
+	if few.Average != 5 || few.Count != 2 || few.Score != 4.29 {
+		t.Errorf("Expected 2 fives to score (5*4+10)/7, got %+v", few)
+	}
+	if many.Average != 3.75 || many.Count != 8 || many.Score != 3.85 {
+		t.Errorf("Expected 8 ratings to score (5*4+30)/13, got %+v", many)
+	}
+	if _, ok := ratings["none"]; ok {
+		t.Error("Expected dealers without reviews to be left out")
+	}
+	if len(RateDealers(nil)) != 0 {
+		t.Error("Expected no ratings without reviews")
+	}
+}
+
+func TestDealerReviewValidate(t *testing.T) {
+	for _, rating := range []int{0, 6} {
+		err := (&DealerReview{Rating: rating}).Validate()
+		var verr *ValidationError
+		if !errors.As(err, &verr) || verr.Errors[0].Field != "rating" {
+			t.Errorf("Expected rating %d to be rejected, got %v", rating, err)
+		}
+	}
+	if err := (&DealerReview{Rating: 3}).Validate(); err != nil {
+		t.Errorf("Expected a valid review, got %v", err)
+	}
+}
+
+func TestVehiclePurchasedBy(t *testing.T) {
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	transition := func(v *Vehicle, to, by string) {
+		t.Helper()
+		if err := v.Transition(to, by, "", at); err != nil {
+			t.Fatalf("Transition failed: %v", err)
+		}
+	}
+
+	// A reservation that was cancelled is not a purchase, even if someone
+	// else buys the vehicle later
+	v := &Vehicle{Status: StatusAvailable}
+	transition(v, StatusReserved, "user-001")
+	transition(v, StatusAvailable, "user-001")
+	if v.PurchasedBy("user-001") {
+		t.Error("Expected a cancelled reservation not to count")
+	}
+	transition(v, StatusSold, "user-002")
+	if v.PurchasedBy("user-001") || v.PurchasedBy("user-002") {
+		t.Error("Expected a sale without a reservation not to count")
+	}
+
+	// Sold through pending sale to the buyer holding it
+	v = &Vehicle{Status: StatusAvailable}
+	transition(v, StatusReserved, "user-001")
+	transition(v, StatusPendingSale, "user-002")
+	if v.PurchasedBy("user-001") {
+		t.Error("Expected a pending sale not to count")
+	}
+	transition(v, StatusSold, "user-002")
+	if !v.PurchasedBy("user-001") {
+		t.Error("Expected the buyer holding the vehicle to have bought it")
+	}
+	if v.PurchasedBy("user-002") || v.PurchasedBy("") {
+		t.Error("Expected only the buyer to count")
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+// FacetValue is the number of results that have a field value
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// HistogramBucket is the number of results with a value in [Min, Max)
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// VehicleFacets summarizes a vehicle result set so a search can be refined.
+// Each facet ignores the filter's own predicate on that field, so selecting
+// one make still reports counts for the other makes.
+type VehicleFacets struct {
+	Terms      map[string][]FacetValue      `json:"terms"`
+	Histograms map[string][]HistogramBucket `json:"histograms"`
+}
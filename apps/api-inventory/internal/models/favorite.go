@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Favorite is a vehicle a user has starred to come back to
+type Favorite struct {
+	UserID    string    `json:"userId"`
+	VehicleID string    `json:"vehicleId"`
+	AddedAt   time.Time `json:"addedAt"`
+	// Price is the listing price when the vehicle was favorited
+	Price Money `json:"price"`
+}
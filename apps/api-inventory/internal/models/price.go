@@ -0,0 +1,85 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// PriceChange records a change of a vehicle's listed price
+type PriceChange struct {
+	At       time.Time `json:"at"`
+	By       string    `json:"by"`
+	OldPrice float64   `json:"oldPrice"`
+	NewPrice float64   `json:"newPrice"`
+	Currency string    `json:"currency"`
+	// OldCurrency is set when the change also changed the currency
+	OldCurrency string `json:"oldCurrency,omitempty"`
+}
+
+// PriceDrop is a fall in a vehicle's price
+type PriceDrop struct {
+	Amount float64 `json:"amount"`
+	// Percent is the drop as a percentage of the previous price
+	Percent float64   `json:"percent"`
+	At      time.Time `json:"at"`
+}
+
+// RecordPriceChange adds an entry to the price history if the vehicle's
+// price or currency differs from the previous one, and updates PriceDrop
+// to describe the change when it lowered the price
+func (v *Vehicle) RecordPriceChange(oldPrice float64, oldCurrency, by string, at time.Time) {
+	if v.Price == oldPrice && v.Currency == oldCurrency {
+		return
+	}
+
+	change := PriceChange{
+		At:       at,
+		By:       by,
+		OldPrice: oldPrice,
+		NewPrice: v.Price,
+		Currency: v.Currency,
+	}
+	if oldCurrency != v.Currency {
+		change.OldCurrency = oldCurrency
+	}
+	v.PriceHistory = append(v.PriceHistory, change)
+
+	v.PriceDrop = nil
+	if change.OldCurrency == "" && v.Price < oldPrice {
+		v.PriceDrop = dropBetween(oldPrice, v.Price, at)
+	}
+}
+
+// PriceDropSince returns how far the price has fallen from the price in
+// effect at the given time, or nil if it has not fallen. A zero time
+// measures from the original listed price.
+func (v *Vehicle) PriceDropSince(since time.Time) *PriceDrop {
+	for i, change := range v.PriceHistory {
+		if change.At.Before(since) {
+			continue
+		}
+		// The price before the first change in the window was in effect
+		// at its start
+		for _, later := range v.PriceHistory[i:] {
+			if later.OldCurrency != "" {
+				// Prices in different currencies cannot be compared
+				return nil
+			}
+		}
+		if v.Price >= change.OldPrice {
+			return nil
+		}
+		last := v.PriceHistory[len(v.PriceHistory)-1]
+		return dropBetween(change.OldPrice, v.Price, last.At)
+	}
+	return nil
+}
+
+// dropBetween describes a fall from one price to a lower one
+func dropBetween(from, to float64, at time.Time) *PriceDrop {
+	drop := &PriceDrop{Amount: from - to, At: at}
+	if from > 0 {
+		drop.Percent = math.Round((from-to)/from*10000) / 100
+	}
+	return drop
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordPriceChange(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	vehicle := &Vehicle{Price: 20000, Currency: "USD"}
+
+	vehicle.RecordPriceChange(20000, "USD", "user-002", start)
+	if len(vehicle.PriceHistory) != 0 {
+		t.Fatalf("Expected no entry for an unchanged price, got %+v", vehicle.PriceHistory)
+	}
+
+	vehicle.Price = 18000
+	vehicle.RecordPriceChange(20000, "USD", "user-002", start)
+	if len(vehicle.PriceHistory) != 1 {
+		t.Fatalf("Expected one entry, got %+v", vehicle.PriceHistory)
+	}
+	if got := vehicle.PriceHistory[0]; got != (PriceChange{At: start, By: "user-002", OldPrice: 20000, NewPrice: 18000, Currency: "USD"}) {
+		t.Errorf("Unexpected entry %+v", got)
+	}
+	if vehicle.PriceDrop == nil || vehicle.PriceDrop.Amount != 2000 || vehicle.PriceDrop.Percent != 10 {
+		t.Errorf("Expected a 10%% drop, got %+v", vehicle.PriceDrop)
+	}
+
+	vehicle.Price = 19000
+	vehicle.RecordPriceChange(18000, "USD", "user-002", start.AddDate(0, 0, 1))
+	if vehicle.PriceDrop != nil {
+		t.Errorf("Expected a price rise to clear the drop, got %+v", vehicle.PriceDrop)
+	}
+
+	vehicle.Price, vehicle.Currency = 15000, "GBP"
+	vehicle.RecordPriceChange(19000, "USD", "user-002", start.AddDate(0, 0, 2))
+	if got := vehicle.PriceHistory[2]; got.OldCurrency != "USD" || got.Currency != "GBP" {
+		t.Errorf("Expected the currency change to be recorded, got %+v", got)
+	}
+	if vehicle.PriceDrop != nil {
+		t.Errorf("Expected no drop across currencies, got %+v", vehicle.PriceDrop)
+	}
+}
+
+func TestPriceDropSince(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	vehicle := &Vehicle{Price: 30000, Currency: "EUR"}
+
+	// 30000 -> 28000 on day 0, -> 27000 on day 20
+	vehicle.Price = 28000
+	vehicle.RecordPriceChange(30000, "EUR", "user-002", start)
+	vehicle.Price = 27000
+	vehicle.RecordPriceChange(28000, "EUR", "user-002", start.AddDate(0, 0, 20))
+
+	tests := []struct {
+		name    string
+		since   time.Time
+		percent float64
+	}{
+		{"since listing", time.Time{}, 10},
+		{"last two weeks", start.AddDate(0, 0, 10), 3.57},
+		{"nothing since", start.AddDate(0, 0, 30), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drop := vehicle.PriceDropSince(tt.since)
+			switch {
+			case tt.percent == 0 && drop != nil:
+				t.Errorf("Expected no drop, got %+v", drop)
+			case tt.percent != 0 && (drop == nil || drop.Percent != tt.percent):
+				t.Errorf("Expected a %.2f%% drop, got %+v", tt.percent, drop)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Reservation is a buyer's time-limited hold on a vehicle. It is kept on
+// the vehicle while the vehicle is reserved.
+type Reservation struct {
+	VehicleID string `json:"vehicleId"`
+	// UserID is the buyer holding the vehicle; it is left out of the
+	// reservation other users see
+	UserID     string    `json:"userId,omitempty"`
+	ReservedAt time.Time `json:"reservedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the hold has run out at the given time
+func (r *Reservation) Expired(at time.Time) bool {
+	return !at.Before(r.ExpiresAt)
+}
+
+// Public returns the reservation without the buyer
+func (r *Reservation) Public() *Reservation {
+	public := *r
+	public.UserID = ""
+	return &public
+}
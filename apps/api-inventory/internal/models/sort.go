@@ -0,0 +1,171 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VehicleSortKey is one field of a sort specification
+type VehicleSortKey struct {
+	Field string
+	Desc  bool
+}
+
+// vehicleSortFields maps each sortable field, by its JSON name, to its value
+var vehicleSortFields = map[string]func(v *Vehicle) interface{}{
+	"id":            func(v *Vehicle) interface{} { return v.ID },
+	"vin":           func(v *Vehicle) interface{} { return v.VIN },
+	"year":          func(v *Vehicle) interface{} { return v.Year },
+	"make":          func(v *Vehicle) interface{} { return v.Make },
+	"model":         func(v *Vehicle) interface{} { return v.Model },
+	"trim":          func(v *Vehicle) interface{} { return v.Trim },
+	"type":          func(v *Vehicle) interface{} { return v.Type },
+	"condition":     func(v *Vehicle) interface{} { return v.Condition },
+	"mileage":       func(v *Vehicle) interface{} { return v.Mileage },
+	"price":         func(v *Vehicle) interface{} { return v.Price },
+	"currency":      func(v *Vehicle) interface{} { return v.Currency },
+	"country":       func(v *Vehicle) interface{} { return v.Country },
+	"status":        func(v *Vehicle) interface{} { return v.Status },
+	"fuelType":      func(v *Vehicle) interface{} { return v.FuelType },
+	"transmission":  func(v *Vehicle) interface{} { return v.Transmission },
+	"drivetrain":    func(v *Vehicle) interface{} { return v.Drivetrain },
+	"exteriorColor": func(v *Vehicle) interface{} { return v.ExteriorColor },
+	"interiorColor": func(v *Vehicle) interface{} { return v.InteriorColor },
+	"dealerRating":  func(v *Vehicle) interface{} { return v.DealerRating },
+	"location":      func(v *Vehicle) interface{} { return v.Location },
+	"listingDate":   func(v *Vehicle) interface{} { return v.ListingDate },
+	// priceDrop orders by the percentage of the latest drop
+	"priceDrop": func(v *Vehicle) interface{} { return v.PriceDrop },
+}
+
+// SortableVehicleFields returns the field names accepted by ParseVehicleSort
+func SortableVehicleFields() []string {
+	fields := make([]string, 0, len(vehicleSortFields))
+	for field := range vehicleSortFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ParseVehicleSort parses a comma-separated sort specification such as
+// "-listingDate,price". A leading "-" sorts the field in descending order.
+func ParseVehicleSort(spec string) ([]VehicleSortKey, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	var keys []VehicleSortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		key := VehicleSortKey{Field: part}
+		switch {
+		case strings.HasPrefix(part, "-"):
+			key = VehicleSortKey{Field: part[1:], Desc: true}
+		case strings.HasPrefix(part, "+"):
+			key.Field = part[1:]
+		}
+
+		if _, ok := vehicleSortFields[key.Field]; !ok {
+			return nil, fmt.Errorf("cannot sort by %q", key.Field)
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("sort field %q given more than once", key.Field)
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// FormatVehicleSort returns the canonical specification of the keys
+func FormatVehicleSort(keys []VehicleSortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		} else {
+			parts[i] = key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// CompareVehicles orders two vehicles by the sort keys, breaking ties by
+// ascending ID so the order is total
+func CompareVehicles(a, b *Vehicle, keys []VehicleSortKey) int {
+	for _, key := range keys {
+		value := vehicleSortFields[key.Field]
+		c := compareValues(value(a), value(b))
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// SortVehicles sorts vehicles in place by the sort keys
+func SortVehicles(vehicles []*Vehicle, keys []VehicleSortKey) {
+	sort.Slice(vehicles, func(i, k int) bool {
+		return CompareVehicles(vehicles[i], vehicles[k], keys) < 0
+	})
+}
+
+// VehicleSortValues returns the values of the sort fields of a vehicle keyed
+// by JSON name, so they can be decoded back into a Vehicle
+func VehicleSortValues(v *Vehicle, keys []VehicleSortKey) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		values[key.Field] = vehicleSortFields[key.Field](v)
+	}
+	return values
+}
+
+// compareValues compares two values of the same sortable type. Strings are
+// compared case-insensitively first so "audi" sorts next to "Audi".
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		bv := b.(string)
+		if c := strings.Compare(strings.ToLower(av), strings.ToLower(bv)); c != 0 {
+			return c
+		}
+		return strings.Compare(av, bv)
+	case int:
+		bv := b.(int)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case float64:
+		bv := b.(float64)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	case *PriceDrop:
+		return compareValues(dropPercent(av), dropPercent(b.(*PriceDrop)))
+	}
+	return 0
+}
+
+// dropPercent returns the percentage of a drop, 0 when there is none
+func dropPercent(drop *PriceDrop) float64 {
+	if drop == nil {
+		return 0
+	}
+	return drop.Percent
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseVehicleSort(t *testing.T) {
+	keys, err := ParseVehicleSort("-listingDate, price,+make")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []VehicleSortKey{{Field: "listingDate", Desc: true}, {Field: "price"}, {Field: "make"}}
+	if len(keys) != len(expected) {
+		t.Fatalf("Expected %d keys, got %d", len(expected), len(keys))
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("Key %d: expected %+v, got %+v", i, expected[i], keys[i])
+		}
+	}
+	if spec := FormatVehicleSort(keys); spec != "-listingDate,price,make" {
+		t.Errorf("Unexpected canonical spec %q", spec)
+	}
+
+	for _, spec := range []string{"features", "price,-price", "-", "price,,year"} {
+		if _, err := ParseVehicleSort(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+
+	if keys, err := ParseVehicleSort(""); err != nil || keys != nil {
+		t.Errorf("Expected no keys for an empty spec, got %v, %v", keys, err)
+	}
+}
+
+func TestCompareVehicles(t *testing.T) {
+	now := time.Now()
+	a := &Vehicle{ID: "veh-001", Make: "audi", Price: 30000, ListingDate: now}
+	b := &Vehicle{ID: "veh-002", Make: "BMW", Price: 30000, ListingDate: now.Add(time.Hour)}
+
+	tests := []struct {
+		spec     string
+		expected int
+	}{
+		{spec: "price", expected: -1},               // tie broken by ID
+		{spec: "-price", expected: -1},              // ties stay ascending by ID
+		{spec: "make", expected: -1},                // case-insensitive
+		{spec: "-listingDate", expected: 1},         // newest first
+		{spec: "price,-listingDate", expected: 1},   // second key decides
+		{spec: "-dealerRating,price", expected: -1}, // all equal, ID decides
+	}
+	for _, tt := range tests {
+		keys, err := ParseVehicleSort(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseVehicleSort(%q): %v", tt.spec, err)
+		}
+		if got := CompareVehicles(a, b, keys); got != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.spec, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Vehicle listing statuses
+const (
+	StatusDraft       = "draft"
+	StatusAvailable   = "available"
+	StatusReserved    = "reserved"
+	StatusPendingSale = "pending-sale"
+	StatusSold        = "sold"
+	StatusWithdrawn   = "withdrawn"
+)
+
+// statusTransitions lists the statuses each status can move to
+var statusTransitions = map[string][]string{
+	StatusDraft:       {StatusAvailable, StatusWithdrawn},
+	StatusAvailable:   {StatusReserved, StatusPendingSale, StatusSold, StatusWithdrawn},
+	StatusReserved:    {StatusAvailable, StatusPendingSale, StatusSold, StatusWithdrawn},
+	StatusPendingSale: {StatusAvailable, StatusReserved, StatusSold},
+	StatusSold:        {},
+	StatusWithdrawn:   {StatusDraft, StatusAvailable},
+}
+
+// Statuses lists every vehicle status in lifecycle order
+var Statuses = []string{StatusDraft, StatusAvailable, StatusReserved, StatusPendingSale, StatusSold, StatusWithdrawn}
+
+// PublicStatuses are the statuses shown by searches that do not filter on
+// status
+var PublicStatuses = []string{StatusAvailable, StatusReserved, StatusPendingSale}
+
+// InitialStatuses are the statuses a new listing may be created with
+var InitialStatuses = []string{StatusDraft, StatusAvailable}
+
+// StatusTransition records a change of a vehicle's status. By is the user
+// who made it; it is left out of the history other users see.
+type StatusTransition struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	By   string    `json:"by,omitempty"`
+	At   time.Time `json:"at"`
+	Note string    `json:"note,omitempty"`
+}
+
+// PublicHistory returns a copy of a status history without who made each
+// change, as reservations record the buyer
+func PublicHistory(history []StatusTransition) []StatusTransition {
+	if history == nil {
+		return nil
+	}
+	public := make([]StatusTransition, len(history))
+	for i, transition := range history {
+		transition.By = ""
+		public[i] = transition
+	}
+	return public
+}
+
+// TransitionError is returned for a status change the lifecycle does not
+// allow
+type TransitionError struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Allowed []string `json:"allowed"`
+}
+
+// Error implements the error interface
+func (e *TransitionError) Error() string {
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("cannot move a vehicle from %s to %s: %s is final", e.From, e.To, e.From)
+	}
+	return fmt.Sprintf("cannot move a vehicle from %s to %s; allowed: %s", e.From, e.To, strings.Join(e.Allowed, ", "))
+}
+
+// IsValidStatus reports whether status is a known vehicle status
+func IsValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// IsInitialStatus reports whether a new listing may have status
+func IsInitialStatus(status string) bool {
+	for _, initial := range InitialStatuses {
+		if initial == status {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedTransitions returns the statuses a vehicle can move to from status
+func AllowedTransitions(status string) []string {
+	return append([]string{}, statusTransitions[status]...)
+}
+
+// CanTransition reports whether the lifecycle allows moving from one status
+// to another
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the vehicle to a new status and records who made the
+// change and when. Leaving reserved ends any reservation. It returns a
+// *TransitionError if the move is not allowed.
+func (v *Vehicle) Transition(to, by, note string, at time.Time) error {
+	if !CanTransition(v.Status, to) {
+		return &TransitionError{From: v.Status, To: to, Allowed: AllowedTransitions(v.Status)}
+	}
+
+	v.StatusHistory = append(v.StatusHistory, StatusTransition{
+		From: v.Status,
+		To:   to,
+		By:   by,
+		At:   at,
+		Note: note,
+	})
+	v.Status = to
+	if to != StatusReserved {
+		v.Reservation = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		expected bool
+	}{
+		{StatusDraft, StatusAvailable, true},
+		{StatusAvailable, StatusReserved, true},
+		{StatusReserved, StatusPendingSale, true},
+		{StatusPendingSale, StatusSold, true},
+		{StatusWithdrawn, StatusAvailable, true},
+		{StatusDraft, StatusSold, false},
+		{StatusSold, StatusAvailable, false},
+		{StatusAvailable, StatusAvailable, false},
+		{StatusAvailable, "parked", false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.expected {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.expected)
+		}
+	}
+
+	// Every target is a known status
+	for from, targets := range statusTransitions {
+		for _, to := range targets {
+			if !IsValidStatus(to) {
+				t.Errorf("Transition from %s to unknown status %s", from, to)
+			}
+		}
+	}
+}
+
+func TestVehicleTransition(t *testing.T) {
+	vehicle := &Vehicle{Status: StatusAvailable}
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := vehicle.Transition(StatusReserved, "user-002", "deposit paid", at); err != nil {
+		t.Fatalf("Transition failed: %v", err)
+	}
+	if vehicle.Status != StatusReserved || len(vehicle.StatusHistory) != 1 {
+		t.Fatalf("Unexpected vehicle after transition: %+v", vehicle)
+	}
+	if got := vehicle.StatusHistory[0]; got != (StatusTransition{From: StatusAvailable, To: StatusReserved, By: "user-002", At: at, Note: "deposit paid"}) {
+		t.Errorf("Unexpected transition record %+v", got)
+	}
+
+	err := vehicle.Transition(StatusDraft, "user-002", "", at)
+	var terr *TransitionError
+	if !errors.As(err, &terr) {
+		t.Fatalf("Expected TransitionError, got %v", err)
+	}
+	if terr.From != StatusReserved || terr.To != StatusDraft || len(terr.Allowed) == 0 {
+		t.Errorf("Unexpected error %+v", terr)
+	}
+	if vehicle.Status != StatusReserved || len(vehicle.StatusHistory) != 1 {
+		t.Error("Expected a rejected transition to leave the vehicle unchanged")
+	}
+}
@@ -1,32 +1,60 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/vin"
+)
 
 // Vehicle represents a vehicle listing in the inventory
 type Vehicle struct {
-	ID            string    `json:"id"`
-	VIN           string    `json:"vin"`
-	Year          int       `json:"year"`
-	Make          string    `json:"make"`
-	Model         string    `json:"model"`
-	Trim          string    `json:"trim"`
-	Type          string    `json:"type"`
-	Condition     string    `json:"condition"`
-	Mileage       int       `json:"mileage"`
-	Price         float64   `json:"price"`
-	Currency      string    `json:"currency"`
-	Country       string    `json:"country"`
-	Status        string    `json:"status"`
-	FuelType      string    `json:"fuelType"`
-	Transmission  string    `json:"transmission"`
-	Drivetrain    string    `json:"drivetrain"`
-	ExteriorColor string    `json:"exteriorColor"`
-	InteriorColor string    `json:"interiorColor"`
-	Features      []string  `json:"features"`
-	Images        []string  `json:"images"`
-	DealerRating  float64   `json:"dealerRating"`
-	Location      string    `json:"location"`
-	ListingDate   time.Time `json:"listingDate"`
+	ID            string   `json:"id"`
+	VIN           string   `json:"vin"`
+	Year          int      `json:"year"`
+	Make          string   `json:"make"`
+	Model         string   `json:"model"`
+	Trim          string   `json:"trim"`
+	Type          string   `json:"type"`
+	Condition     string   `json:"condition"`
+	Mileage       int      `json:"mileage"`
+	Price         float64  `json:"price"`
+	Currency      string   `json:"currency"`
+	Country       string   `json:"country"`
+	Status        string   `json:"status"`
+	FuelType      string   `json:"fuelType"`
+	Transmission  string   `json:"transmission"`
+	Drivetrain    string   `json:"drivetrain"`
+	ExteriorColor string   `json:"exteriorColor"`
+	InteriorColor string   `json:"interiorColor"`
+	Features      []string `json:"features"`
+	Images        []string `json:"images"`
+	DealerID      string   `json:"dealerId,omitempty"`
+	// DealerRating is the score of the dealer's reviews (see RateDealers).
+	// Stores compute it; any value written is replaced.
+	DealerRating float64   `json:"dealerRating"`
+	Location     string    `json:"location"`
+	Coordinates  *GeoPoint `json:"coordinates,omitempty"`
+	ListingDate  time.Time `json:"listingDate"`
+
+	// StatusHistory records every status change made through Transition
+	StatusHistory []StatusTransition `json:"statusHistory,omitempty"`
+	// Reservation is the buyer's hold while the vehicle is reserved through
+	// the reservations API
+	Reservation *Reservation `json:"reservation,omitempty"`
+	// PriceHistory records every change of the listed price, and PriceDrop
+	// the latest change when it lowered the price
+	PriceHistory []PriceChange `json:"priceHistory,omitempty"`
+	PriceDrop    *PriceDrop    `json:"priceDrop,omitempty"`
+}
+
+// GeoPoint is a position in decimal degrees
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
 }
 
 // VehicleFilter represents filter options for vehicle search
@@ -45,4 +73,241 @@ type VehicleFilter struct {
 	Transmission string   `json:"transmission,omitempty"`
 	Drivetrain   string   `json:"drivetrain,omitempty"`
 	VehicleTypes []string `json:"vehicleTypes,omitempty"`
+	MinMileage   int      `json:"minMileage,omitempty"`
+	MaxMileage   int      `json:"maxMileage,omitempty"`
+	// Features must all be present on the vehicle
+	Features []string `json:"features,omitempty"`
+	// AnyFeatures requires at least one of the features
+	AnyFeatures     []string `json:"anyFeatures,omitempty"`
+	ExteriorColors  []string `json:"exteriorColors,omitempty"`
+	InteriorColors  []string `json:"interiorColors,omitempty"`
+	MinDealerRating float64  `json:"minDealerRating,omitempty"`
+	DealerID        string   `json:"dealerId,omitempty"`
+	Statuses        []string `json:"statuses,omitempty"`
+	// ListedAfter and ListedBefore bound the listing date to
+	// [ListedAfter, ListedBefore)
+	ListedAfter  *time.Time `json:"listedAfter,omitempty"`
+	ListedBefore *time.Time `json:"listedBefore,omitempty"`
+	// Near and RadiusKm limit results to vehicles within RadiusKm of Near.
+	// Without a radius every vehicle with coordinates matches.
+	Near     *GeoPoint `json:"near,omitempty"`
+	RadiusKm float64   `json:"radiusKm,omitempty"`
+	// PriceDropped keeps vehicles whose price has fallen by at least
+	// MinPriceDrop percent within the last PriceDropDays days (ever when
+	// 0). Setting either of the other two implies PriceDropped.
+	PriceDropped  bool    `json:"priceDropped,omitempty"`
+	MinPriceDrop  float64 `json:"minPriceDrop,omitempty"`
+	PriceDropDays int     `json:"priceDropDays,omitempty"`
+	// PriceCurrency is the currency MinPrice, MaxPrice and price sorts are
+	// expressed in. Listing prices are converted with Rates; without
+	// either, prices are compared as listed whatever their currency.
+	PriceCurrency string         `json:"priceCurrency,omitempty"`
+	Rates         PriceConverter `json:"-"`
+	// Sort orders the results, e.g. "-listingDate,price" (see ParseVehicleSort)
+	Sort string `json:"sort,omitempty"`
+}
+
+// PriceConverter converts amounts between currencies
+type PriceConverter interface {
+	// Convert reports false when there is no rate for either currency
+	Convert(amount float64, from, to string) (float64, bool)
+}
+
+// Money is an amount in a currency
+type Money struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// PriceOf returns the price of the vehicle in the filter's PriceCurrency,
+// or the listed price when the filter does not convert prices. It reports
+// false when the price cannot be converted.
+func (f *VehicleFilter) PriceOf(v *Vehicle) (float64, bool) {
+	if !f.ConvertsPrices() {
+		return v.Price, true
+	}
+	return f.Rates.Convert(v.Price, v.Currency, f.PriceCurrency)
+}
+
+// FiltersPriceDrop reports whether the filter only keeps vehicles whose
+// price has dropped
+func (f *VehicleFilter) FiltersPriceDrop() bool {
+	return f.PriceDropped || f.MinPriceDrop > 0 || f.PriceDropDays > 0
+}
+
+// PriceDropOf returns the drop the price drop filter measures, or nil if
+// the price has not dropped in the filter's window
+func (f *VehicleFilter) PriceDropOf(v *Vehicle, now time.Time) *PriceDrop {
+	var since time.Time
+	if f.PriceDropDays > 0 {
+		since = now.AddDate(0, 0, -f.PriceDropDays)
+	}
+	return v.PriceDropSince(since)
+}
+
+// ConvertsPrices reports whether prices are converted to PriceCurrency
+func (f *VehicleFilter) ConvertsPrices() bool {
+	return f.PriceCurrency != "" && f.Rates != nil
+}
+
+// SupportedCurrencies lists the currency codes accepted on listings
+var SupportedCurrencies = []string{"USD", "GBP", "EUR", "CAD", "AUD"}
+
+// minVehicleYear is the earliest model year accepted on a listing
+const minVehicleYear = 1886
+
+// vinPattern matches a 17-character VIN; I, O and Q are never used
+var vinPattern = regexp.MustCompile(`^[A-HJ-NPR-Z0-9]{17}$`)
+
+// FieldError describes a validation failure on a single field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the field errors found while validating a model
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fe.Field, fe.Message))
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// add records a field error
+func (e *ValidationError) add(field, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+// IsSupportedCurrency reports whether code is a supported currency code
+func IsSupportedCurrency(code string) bool {
+	for _, c := range SupportedCurrencies {
+		if strings.EqualFold(c, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize canonicalises case-insensitive codes before validation
+func (v *Vehicle) Normalize() {
+	v.VIN = strings.ToUpper(strings.TrimSpace(v.VIN))
+	v.Currency = strings.ToUpper(strings.TrimSpace(v.Currency))
+	v.Country = strings.ToUpper(strings.TrimSpace(v.Country))
+}
+
+// Validate checks the listing fields and returns a *ValidationError
+// describing every problem found, or nil if the vehicle is valid
+func (v *Vehicle) Validate() error {
+	verr := &ValidationError{}
+
+	if !vinPattern.MatchString(strings.ToUpper(v.VIN)) {
+		verr.add("vin", "must be 17 characters of A-Z and 0-9, excluding I, O and Q")
+	}
+
+	maxYear := time.Now().Year() + 1
+	if v.Year < minVehicleYear || v.Year > maxYear {
+		verr.add("year", fmt.Sprintf("must be between %d and %d", minVehicleYear, maxYear))
+	}
+
+	if strings.TrimSpace(v.Make) == "" {
+		verr.add("make", "is required")
+	}
+	if strings.TrimSpace(v.Model) == "" {
+		verr.add("model", "is required")
+	}
+
+	if v.Price < 0 {
+		verr.add("price", "must not be negative")
+	}
+	if v.Mileage < 0 {
+		verr.add("mileage", "must not be negative")
+	}
+
+	if !IsSupportedCurrency(v.Currency) {
+		verr.add("currency", fmt.Sprintf("must be one of %s", strings.Join(SupportedCurrencies, ", ")))
+	}
+
+	if v.Status != "" && !IsValidStatus(v.Status) {
+		verr.add("status", fmt.Sprintf("must be one of %s", strings.Join(Statuses, ", ")))
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// CheckVIN checks the VIN against its check digit and against the make and
+// model year it decodes to. It returns a *ValidationError or nil. The model
+// year is only checked for North American VINs, where it is mandatory.
+func (v *Vehicle) CheckVIN() error {
+	verr := &ValidationError{}
+
+	info, err := vin.Decode(v.VIN)
+	if err != nil {
+		verr.add("vin", err.Error())
+		return verr
+	}
+
+	if info.CheckDigit.Required && !info.CheckDigit.Valid {
+		verr.add("vin", fmt.Sprintf("check digit is %s, expected %s", info.CheckDigit.Value, info.CheckDigit.Expected))
+	}
+	if !info.HasMake(v.Make) {
+		verr.add("make", fmt.Sprintf("VIN belongs to %s, which does not build %s", info.Manufacturer, v.Make))
+	}
+	if info.NorthAmerican && info.ModelYear != 0 && info.ModelYear != v.Year {
+		verr.add("year", fmt.Sprintf("VIN encodes model year %d", info.ModelYear))
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// CheckVINUpdate runs CheckVIN on an update of the previous version of the
+// vehicle. Its findings only fail the update when the VIN, make or model
+// year changes; otherwise they are returned as warnings, so listings loaded
+// with a VIN that fails the checks can still be edited.
+func (v *Vehicle) CheckVINUpdate(previous *Vehicle) ([]FieldError, error) {
+	err := v.CheckVIN()
+	if err == nil {
+		return nil, nil
+	}
+	var verr *ValidationError
+	changed := !strings.EqualFold(v.VIN, previous.VIN) || !strings.EqualFold(v.Make, previous.Make) || v.Year != previous.Year
+	if changed || !errors.As(err, &verr) {
+		return nil, err
+	}
+	return verr.Errors, nil
+}
+
+// Clone returns a deep copy of the vehicle
+func (v *Vehicle) Clone() *Vehicle {
+	clone := *v
+	clone.Features = append([]string(nil), v.Features...)
+	clone.Images = append([]string(nil), v.Images...)
+	clone.StatusHistory = append([]StatusTransition(nil), v.StatusHistory...)
+	clone.PriceHistory = append([]PriceChange(nil), v.PriceHistory...)
+	if v.Coordinates != nil {
+		coordinates := *v.Coordinates
+		clone.Coordinates = &coordinates
+	}
+	if v.Reservation != nil {
+		reservation := *v.Reservation
+		clone.Reservation = &reservation
+	}
+	if v.PriceDrop != nil {
+		drop := *v.PriceDrop
+		clone.PriceDrop = &drop
+	}
+	return &clone
 }
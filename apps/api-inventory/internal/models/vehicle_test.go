@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVehicleValidate(t *testing.T) {
+	valid := Vehicle{
+		VIN:      "5YJ3E1EB5KF123456",
+		Year:     2023,
+		Make:     "Tesla",
+		Model:    "Model 3",
+		Mileage:  12500,
+		Price:    45990,
+		Currency: "USD",
+	}
+
+	tests := []struct {
+		name        string
+		mutate      func(v *Vehicle)
+		expectField string
+	}{
+		{name: "Valid vehicle", mutate: func(v *Vehicle) {}},
+		{name: "VIN too short", mutate: func(v *Vehicle) { v.VIN = "5YJ3E1EB5KF" }, expectField: "vin"},
+		{name: "VIN with letter O", mutate: func(v *Vehicle) { v.VIN = "5YJ3E1EB5KF12345O" }, expectField: "vin"},
+		{name: "Year too old", mutate: func(v *Vehicle) { v.Year = 1850 }, expectField: "year"},
+		{name: "Missing make", mutate: func(v *Vehicle) { v.Make = "" }, expectField: "make"},
+		{name: "Negative price", mutate: func(v *Vehicle) { v.Price = -1 }, expectField: "price"},
+		{name: "Negative mileage", mutate: func(v *Vehicle) { v.Mileage = -10 }, expectField: "mileage"},
+		{name: "Unknown currency", mutate: func(v *Vehicle) { v.Currency = "JPY" }, expectField: "currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := valid
+			tt.mutate(&vehicle)
+
+			err := vehicle.Validate()
+			if tt.expectField == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected ValidationError, got %v", err)
+			}
+			if len(verr.Errors) != 1 || verr.Errors[0].Field != tt.expectField {
+				t.Errorf("Expected a single error on %s, got %+v", tt.expectField, verr.Errors)
+			}
+		})
+	}
+}
+
+func TestVehicleCheckVIN(t *testing.T) {
+	valid := Vehicle{VIN: "1HGCM82633A004352", Year: 2003, Make: "Honda"}
+
+	tests := []struct {
+		name        string
+		mutate      func(v *Vehicle)
+		expectField string
+	}{
+		{name: "Matching VIN", mutate: func(v *Vehicle) {}},
+		{name: "Wrong check digit", mutate: func(v *Vehicle) { v.VIN = "1HGCM82643A004352" }, expectField: "vin"},
+		{name: "Different make", mutate: func(v *Vehicle) { v.Make = "Toyota" }, expectField: "make"},
+		{name: "Different year", mutate: func(v *Vehicle) { v.Year = 2013 }, expectField: "year"},
+		// Model years are not checked outside North America
+		{name: "European VIN", mutate: func(v *Vehicle) { v.VIN, v.Make, v.Year = "WBAJE5C50JG123456", "BMW", 2022 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vehicle := valid
+			tt.mutate(&vehicle)
+
+			err := vehicle.CheckVIN()
+			if tt.expectField == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected ValidationError, got %v", err)
+			}
+			if len(verr.Errors) != 1 || verr.Errors[0].Field != tt.expectField {
+				t.Errorf("Expected a single error on %s, got %+v", tt.expectField, verr.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Package recommend ranks vehicles for a user from their stated
+// preferences and the vehicles they have favorited and viewed
+package recommend
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// Score weights. A vehicle matching the stated type and budget outranks one
+// that is only similar to the user's favorites.
+const (
+	weightType         = 3.0
+	weightBudget       = 3.0
+	weightNearBudget   = 1.0
+	weightOverBudget   = -1.0
+	weightCountry      = 1.5
+	weightCurrency     = 0.5
+	weightFavoriteMake = 2.0
+	weightFavoriteType = 1.0
+	weightViewedMake   = 1.0
+	weightViewedType   = 0.5
+	weightFreshness    = 1.0
+	weightRating       = 0.1
+	// maxJitter bounds the random boost that varies the order of close
+	// scores between requests
+	maxJitter = 0.5
+)
+
+// nearBudget is how far over the top of the budget still counts as near it
+const nearBudget = 0.10
+
+// freshFor is how long a listing earns a freshness boost, and newFor how
+// long it is called out as newly listed
+const (
+	freshFor = 90 * 24 * time.Hour
+	newFor   = 14 * 24 * time.Hour
+)
+
+// Profile is what is known about the user a ranking is for
+type Profile struct {
+	User *models.User
+	// Favorites are the vehicles the user has favorited. They are never
+	// recommended again.
+	Favorites []*models.Vehicle
+	// Viewed are the vehicles the user has recently looked at
+	Viewed []*models.Vehicle
+}
+
+// Options tunes a ranking
+type Options struct {
+	// Rates converts prices to the user's currency to compare them with
+	// the budget. Without rates only prices in that currency are compared.
+	Rates models.PriceConverter
+	// Now is the time listing ages are measured from
+	Now time.Time
+	// Deterministic ranks the same inventory the same way every time: it
+	// leaves out the jitter and measures listing ages from the newest
+	// listing rather than Now
+	Deterministic bool
+	// Rand is the source of jitter, a time-seeded one when nil
+	Rand *rand.Rand
+}
+
+// Recommendation is a ranked vehicle and why it was recommended
+type Recommendation struct {
+	Vehicle *models.Vehicle
+	Score   float64
+	Reasons []string
+}
+
+// Explanation joins the reasons into a sentence fragment such as
+// "matches your SUV preference, within budget"
+func (r *Recommendation) Explanation() string {
+	return strings.Join(r.Reasons, ", ")
+}
+
+// Recommend ranks the available vehicles for the profile, best first, and
+// returns at most limit of them (all when limit is 0). Ties are broken by
+// vehicle ID.
+func Recommend(profile Profile, vehicles []*models.Vehicle, limit int, opts Options) []*Recommendation {
+	favorited := make(map[string]bool, len(profile.Favorites))
+	for _, vehicle := range profile.Favorites {
+		favorited[vehicle.ID] = true
+	}
+
+	candidates := make([]*models.Vehicle, 0, len(vehicles))
+	for _, vehicle := range vehicles {
+		if vehicle.Status == models.StatusAvailable && !favorited[vehicle.ID] {
+			candidates = append(candidates, vehicle)
+		}
+	}
+
+	now := opts.Now
+	if opts.Deterministic {
+		now = time.Time{}
+		for _, vehicle := range candidates {
+			if vehicle.ListingDate.After(now) {
+				now = vehicle.ListingDate
+			}
+		}
+	}
+	random := opts.Rand
+	if random == nil && !opts.Deterministic {
+		random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	scorer := newScorer(profile, opts.Rates, now)
+	results := make([]*Recommendation, 0, len(candidates))
+	for _, vehicle := range candidates {
+		rec := scorer.score(vehicle)
+		if !opts.Deterministic {
+			rec.Score += random.Float64() * maxJitter
+		}
+		rec.Score = math.Round(rec.Score*100) / 100
+		results = append(results, rec)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Vehicle.ID < results[j].Vehicle.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// affinity counts the makes and types of a set of vehicles
+type affinity struct {
+	makes map[string]int
+	types map[string]int
+	ids   map[string]bool
+}
+
+func newAffinity(vehicles []*models.Vehicle) *affinity {
+	a := &affinity{
+		makes: make(map[string]int),
+		types: make(map[string]int),
+		ids:   make(map[string]bool),
+	}
+	for _, vehicle := range vehicles {
+		if a.ids[vehicle.ID] {
+			continue
+		}
+		a.ids[vehicle.ID] = true
+		a.makes[strings.ToLower(vehicle.Make)]++
+		a.types[strings.ToLower(vehicle.Type)]++
+	}
+	return a
+}
+
+// shares returns the fraction of the other vehicles in the set sharing the
+// vehicle's make and type. The vehicle itself is left out, so viewing a
+// listing does not make it look like its own best match.
+func (a *affinity) shares(vehicle *models.Vehicle) (makeShare, typeShare float64) {
+	total := len(a.ids)
+	makeCount := a.makes[strings.ToLower(vehicle.Make)]
+	typeCount := a.types[strings.ToLower(vehicle.Type)]
+	if a.ids[vehicle.ID] {
+		total--
+		makeCount--
+		typeCount--
+	}
+	if total <= 0 {
+		return 0, 0
+	}
+	return float64(makeCount) / float64(total), float64(typeCount) / float64(total)
+}
+
+// scorer scores vehicles against one profile
+type scorer struct {
+	user      *models.User
+	types     map[string]bool
+	rates     models.PriceConverter
+	now       time.Time
+	favorites *affinity
+	viewed    *affinity
+}
+
+func newScorer(profile Profile, rates models.PriceConverter, now time.Time) *scorer {
+	s := &scorer{
+		user:      profile.User,
+		types:     make(map[string]bool),
+		rates:     rates,
+		now:       now,
+		favorites: newAffinity(profile.Favorites),
+		viewed:    newAffinity(profile.Viewed),
+	}
+	if s.user.Preferences != nil {
+		for _, vehicleType := range s.user.Preferences.VehicleTypes {
+			s.types[strings.ToLower(vehicleType)] = true
+		}
+	}
+	return s
+}
+
+// score rates one vehicle and records the reasons it scored
+func (s *scorer) score(vehicle *models.Vehicle) *Recommendation {
+	rec := &Recommendation{Vehicle: vehicle, Reasons: []string{}}
+	add := func(weight float64, reason string) {
+		rec.Score += weight
+		if reason != "" {
+			rec.Reasons = append(rec.Reasons, reason)
+		}
+	}
+
+	if s.types[strings.ToLower(vehicle.Type)] {
+		add(weightType, "matches your "+typeLabel(vehicle.Type)+" preference")
+	}
+
+	if price, budget, ok := s.budget(vehicle); ok {
+		switch {
+		case price >= budget[0] && price <= budget[1]:
+			add(weightBudget, "within budget")
+		case price < budget[0]:
+			add(weightNearBudget, "below your budget")
+		case price <= budget[1]*(1+nearBudget):
+			add(weightNearBudget, "just over budget")
+		default:
+			add(weightOverBudget, "")
+		}
+	}
+
+	if s.user.Country != "" && strings.EqualFold(vehicle.Country, s.user.Country) {
+		add(weightCountry, "listed in your country")
+	}
+	if s.user.PreferredCurrency != "" && strings.EqualFold(vehicle.Currency, s.user.PreferredCurrency) {
+		add(weightCurrency, "priced in "+strings.ToUpper(vehicle.Currency))
+	}
+
+	if makeShare, typeShare := s.favorites.shares(vehicle); makeShare > 0 || typeShare > 0 {
+		add(weightFavoriteMake*makeShare+weightFavoriteType*typeShare, "similar to vehicles you favorited")
+	}
+	if makeShare, typeShare := s.viewed.shares(vehicle); makeShare > 0 || typeShare > 0 {
+		add(weightViewedMake*makeShare+weightViewedType*typeShare, "similar to vehicles you viewed")
+	}
+
+	if !s.now.IsZero() && !vehicle.ListingDate.IsZero() {
+		age := s.now.Sub(vehicle.ListingDate)
+		if age < 0 {
+			age = 0
+		}
+		if age < freshFor {
+			reason := ""
+			if age <= newFor {
+				reason = "newly listed"
+			}
+			add(weightFreshness*(1-float64(age)/float64(freshFor)), reason)
+		}
+	}
+
+	add(weightRating*vehicle.DealerRating, "")
+	return rec
+}
+
+// budget returns the vehicle's price in the user's currency along with the
+// user's price range. It reports false when the user has no budget or the
+// price cannot be compared with it.
+func (s *scorer) budget(vehicle *models.Vehicle) (float64, [2]float64, bool) {
+	var budget [2]float64
+	if s.user.Preferences == nil || len(s.user.Preferences.PriceRange) != 2 {
+		return 0, budget, false
+	}
+	budget[0] = float64(s.user.Preferences.PriceRange[0])
+	budget[1] = float64(s.user.Preferences.PriceRange[1])
+
+	currency := s.user.PreferredCurrency
+	if currency == "" || strings.EqualFold(vehicle.Currency, currency) {
+		return vehicle.Price, budget, true
+	}
+	if s.rates == nil {
+		return 0, budget, false
+	}
+	price, ok := s.rates.Convert(vehicle.Price, vehicle.Currency, currency)
+	return price, budget, ok
+}
+
+// typeLabel is how a vehicle type reads in an explanation
+func typeLabel(vehicleType string) string {
+	if strings.EqualFold(vehicleType, "suv") {
+		return "SUV"
+	}
+	return strings.ToLower(vehicleType)
+}
@@ -0,0 +1,163 @@
+package recommend
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// testRates converts at a fixed 1 GBP = 1.25 USD
+type testRates struct{}
+
+func (testRates) Convert(amount float64, from, to string) (float64, bool) {
+	switch {
+	case from == to:
+		return amount, true
+	case from == "GBP" && to == "USD":
+		return amount * 1.25, true
+	case from == "USD" && to == "GBP":
+		return amount / 1.25, true
+	}
+	return 0, false
+}
+
+func testInventory() []*models.Vehicle {
+	listed := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	vehicle := func(id, make, vehicleType string, price float64, currency, country string, age int) *models.Vehicle {
+		return &models.Vehicle{
+			ID:          id,
+			Make:        make,
+			Type:        vehicleType,
+			Price:       price,
+			Currency:    currency,
+			Country:     country,
+			Status:      models.StatusAvailable,
+			ListingDate: listed.AddDate(0, 0, -age),
+		}
+	}
+	return []*models.Vehicle{
+		vehicle("veh-1", "Toyota", "suv", 30000, "USD", "US", 0),
+		vehicle("veh-2", "Ford", "truck", 30000, "USD", "US", 0),
+		vehicle("veh-3", "Honda", "suv", 90000, "USD", "US", 200),
+		vehicle("veh-4", "BMW", "suv", 24000, "GBP", "GB", 200),
+		vehicle("veh-5", "Toyota", "sedan", 30000, "USD", "US", 200),
+		vehicle("veh-6", "Kia", "suv", 30000, "USD", "US", 200),
+	}
+}
+
+func testUser() *models.User {
+	return &models.User{
+		ID:                "user-1",
+		Country:           "US",
+		PreferredCurrency: "USD",
+		Preferences: &models.Preferences{
+			PriceRange:   []int{20000, 40000},
+			VehicleTypes: []string{"suv"},
+		},
+	}
+}
+
+func ids(recs []*Recommendation) []string {
+	out := make([]string, len(recs))
+	for i, rec := range recs {
+		out[i] = rec.Vehicle.ID
+	}
+	return out
+}
+
+func TestRecommendDeterministic(t *testing.T) {
+	vehicles := testInventory()
+	vehicles[5].Status = models.StatusSold
+	opts := Options{Rates: testRates{}, Deterministic: true}
+
+	recs := Recommend(Profile{User: testUser()}, vehicles, 0, opts)
+	// veh-4 is within budget at the test rate but not in the user's country,
+	// tying with the truck; veh-3 is over budget
+	want := []string{"veh-1", "veh-2", "veh-4", "veh-5", "veh-3"}
+	if got := ids(recs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected ranking %v, got %v", want, got)
+	}
+	if got := recs[0].Explanation(); got != "matches your SUV preference, within budget, listed in your country, priced in USD, newly listed" {
+		t.Errorf("Unexpected explanation %q", got)
+	}
+	if recs[0].Score != 9 {
+		t.Errorf("Expected a score of 9, got %v", recs[0].Score)
+	}
+
+	for i := 0; i < 5; i++ {
+		if got := ids(Recommend(Profile{User: testUser()}, vehicles, 0, opts)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected the same ranking on every run, got %v", got)
+		}
+	}
+
+	if got := ids(Recommend(Profile{User: testUser()}, vehicles, 2, opts)); len(got) != 2 {
+		t.Errorf("Expected the limit to apply, got %v", got)
+	}
+}
+
+func TestRecommendFavoritesAndViews(t *testing.T) {
+	vehicles := testInventory()
+	user := &models.User{ID: "user-1"}
+	opts := Options{Deterministic: true}
+
+	// Favorites are left out, and their makes and types lift similar vehicles
+	profile := Profile{User: user, Favorites: []*models.Vehicle{vehicles[0]}}
+	recs := Recommend(profile, vehicles, 0, opts)
+	for _, rec := range recs {
+		if rec.Vehicle.ID == "veh-1" {
+			t.Fatal("Expected the favorite to be left out")
+		}
+	}
+	if recs[0].Vehicle.ID != "veh-5" || recs[0].Reasons[0] != "similar to vehicles you favorited" {
+		t.Errorf("Expected the other Toyota first, got %s %v", recs[0].Vehicle.ID, recs[0].Reasons)
+	}
+
+	// A viewed vehicle is not its own match
+	profile = Profile{User: user, Viewed: []*models.Vehicle{vehicles[1]}}
+	for _, rec := range Recommend(profile, vehicles, 0, opts) {
+		if len(rec.Reasons) > 0 && rec.Reasons[0] == "similar to vehicles you viewed" {
+			t.Errorf("Expected no similarity from a single view, got %s %v", rec.Vehicle.ID, rec.Reasons)
+		}
+	}
+}
+
+func TestRecommendJitter(t *testing.T) {
+	vehicles := testInventory()
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	ranking := func(seed int64) []*Recommendation {
+		return Recommend(Profile{User: testUser()}, vehicles, 0, Options{Now: now, Rand: rand.New(rand.NewSource(seed))})
+	}
+
+	first := ranking(1)
+	if !reflect.DeepEqual(first, ranking(1)) {
+		t.Error("Expected the same seed to give the same ranking")
+	}
+	for _, rec := range first {
+		if rec.Vehicle.ID == "veh-1" && (rec.Score < 9 || rec.Score > 9+maxJitter) {
+			t.Errorf("Expected jitter of at most %v, got a score of %v", maxJitter, rec.Score)
+		}
+	}
+}
+
+func TestViews(t *testing.T) {
+	views := NewViews()
+	for _, id := range []string{"veh-1", "veh-2", "veh-1", "veh-3"} {
+		views.Record("user-1", id)
+	}
+	if got, want := views.Recent("user-1"), []string{"veh-3", "veh-1", "veh-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	for i := 0; i < MaxViews+10; i++ {
+		views.Record("user-2", time.Duration(i).String())
+	}
+	if got := len(views.Recent("user-2")); got != MaxViews {
+		t.Errorf("Expected history capped at %d, got %d", MaxViews, got)
+	}
+	if got := views.Recent("user-3"); len(got) != 0 {
+		t.Errorf("Expected no history, got %v", got)
+	}
+}
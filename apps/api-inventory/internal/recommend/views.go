@@ -0,0 +1,45 @@
+package recommend
+
+import "sync"
+
+// MaxViews is how many recently viewed vehicles are kept per user
+const MaxViews = 50
+
+// Views keeps each user's recently viewed vehicles in memory
+type Views struct {
+	mu    sync.Mutex
+	users map[string][]string
+}
+
+// NewViews creates an empty view history
+func NewViews() *Views {
+	return &Views{users: make(map[string][]string)}
+}
+
+// Record notes that the user viewed the vehicle, moving it to the front of
+// their history if they had viewed it before
+func (v *Views) Record(userID, vehicleID string) {
+	if userID == "" || vehicleID == "" {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	history := v.users[userID]
+	viewed := make([]string, 0, len(history)+1)
+	viewed = append(viewed, vehicleID)
+	for _, id := range history {
+		if id != vehicleID && len(viewed) < MaxViews {
+			viewed = append(viewed, id)
+		}
+	}
+	v.users[userID] = viewed
+}
+
+// Recent returns the IDs of the vehicles the user viewed, most recent first
+func (v *Views) Recent(userID string) []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]string(nil), v.users[userID]...)
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"math"
+	"sort"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// facet describes how one facet reads a vehicle and which filter fields it
+// ignores when counting
+type facet struct {
+	name  string
+	clear func(filter *models.VehicleFilter)
+}
+
+// termFacet counts the distinct values of a text field
+type termFacet struct {
+	facet
+	value func(v *models.Vehicle) string
+}
+
+// histogramFacet counts a numeric field in fixed-width buckets. A priced
+// facet counts prices in the filter's price currency.
+type histogramFacet struct {
+	facet
+	interval float64
+	value    func(v *models.Vehicle) float64
+	priced   bool
+}
+
+var termFacets = []termFacet{
+	{facet{"make", func(f *models.VehicleFilter) { f.Make = "" }}, termValues[termMake]},
+	{facet{"type", func(f *models.VehicleFilter) { f.Type, f.VehicleTypes = "", nil }}, termValues[termType]},
+	{facet{"fuelType", func(f *models.VehicleFilter) { f.FuelType = "" }}, termValues[termFuelType]},
+	{facet{"transmission", func(f *models.VehicleFilter) { f.Transmission = "" }}, termValues[termTransmission]},
+	{facet{"drivetrain", func(f *models.VehicleFilter) { f.Drivetrain = "" }}, termValues[termDrivetrain]},
+	{facet{"condition", func(f *models.VehicleFilter) { f.Condition = "" }}, termValues[termCondition]},
+	{facet{"country", func(f *models.VehicleFilter) { f.Country = "" }}, termValues[termCountry]},
+}
+
+var histogramFacets = []histogramFacet{
+	{facet{"price", func(f *models.VehicleFilter) { f.MinPrice, f.MaxPrice = 0, 0 }}, 10000, rangeValues[rangePrice], true},
+	{facet{"year", func(f *models.VehicleFilter) { f.MinYear, f.MaxYear = 0, 0 }}, 1, rangeValues[rangeYear], false},
+	{facet{"mileage", func(f *models.VehicleFilter) { f.MinMileage, f.MaxMileage = 0, 0 }}, 25000, rangeValues[rangeMileage], false},
+}
+
+// FacetBase returns the filter without any facet predicate. Every vehicle
+// counted by ComputeFacets passes it, so the vehicles it selects through the
+// store indexes are enough to compute the facets from.
+func FacetBase(filter *models.VehicleFilter) *models.VehicleFilter {
+	base := models.VehicleFilter{}
+	if filter != nil {
+		base = *filter
+	}
+	for _, f := range termFacets {
+		f.clear(&base)
+	}
+	for _, f := range histogramFacets {
+		f.clear(&base)
+	}
+	return &base
+}
+
+// termCount counts one facet value, keeping how often each spelling of it
+// was seen so the most common one is shown
+type termCount struct {
+	count     int
+	spellings map[string]int
+}
+
+// value returns the facet value with its most common spelling, the
+// alphabetically first on a tie
+func (c *termCount) value() models.FacetValue {
+	best := ""
+	for spelling, n := range c.spellings {
+		if best == "" || n > c.spellings[best] || (n == c.spellings[best] && spelling < best) {
+			best = spelling
+		}
+	}
+	return models.FacetValue{Value: best, Count: c.count}
+}
+
+// ComputeFacets counts the facets of the vehicles that pass the filter.
+// vehicles is the set being searched, before filtering; it may already be
+// narrowed to the vehicles passing FacetBase. Each facet is disjunctive: it
+// is counted over the vehicles passing every predicate of the filter except
+// its own, using the same matchesFilter semantics.
+func ComputeFacets(vehicles []*models.Vehicle, filter *models.VehicleFilter) *models.VehicleFacets {
+	if filter == nil {
+		filter = &models.VehicleFilter{}
+	}
+
+	// Each facet's filter without its own predicate, and the filter without
+	// any facet predicate that every counted vehicle must pass
+	base := FacetBase(filter)
+	termFilters := make([]models.VehicleFilter, len(termFacets))
+	for i, f := range termFacets {
+		termFilters[i] = *filter
+		f.clear(&termFilters[i])
+	}
+	histogramFilters := make([]models.VehicleFilter, len(histogramFacets))
+	for i, f := range histogramFacets {
+		histogramFilters[i] = *filter
+		f.clear(&histogramFilters[i])
+	}
+
+	terms := make([]map[string]*termCount, len(termFacets))
+	for i := range terms {
+		terms[i] = make(map[string]*termCount)
+	}
+	buckets := make([]map[float64]int, len(histogramFacets))
+	for i := range buckets {
+		buckets[i] = make(map[float64]int)
+	}
+
+	for _, vehicle := range vehicles {
+		if !matchesFilter(vehicle, base) {
+			continue
+		}
+		all := matchesFilter(vehicle, filter)
+
+		for i, f := range termFacets {
+			if !all && !matchesFilter(vehicle, &termFilters[i]) {
+				continue
+			}
+			value := f.value(vehicle)
+			if value == "" {
+				continue
+			}
+			key := foldKey(value)
+			counted := terms[i][key]
+			if counted == nil {
+				counted = &termCount{spellings: make(map[string]int)}
+				terms[i][key] = counted
+			}
+			counted.count++
+			counted.spellings[value]++
+		}
+
+		for i, f := range histogramFacets {
+			if !all && !matchesFilter(vehicle, &histogramFilters[i]) {
+				continue
+			}
+			value := f.value(vehicle)
+			if f.priced {
+				price, ok := filter.PriceOf(vehicle)
+				if !ok {
+					continue
+				}
+				value = price
+			}
+			buckets[i][math.Floor(value/f.interval)*f.interval]++
+		}
+	}
+
+	facets := &models.VehicleFacets{
+		Terms:      make(map[string][]models.FacetValue, len(termFacets)),
+		Histograms: make(map[string][]models.HistogramBucket, len(histogramFacets)),
+	}
+
+	for i, f := range termFacets {
+		values := make([]models.FacetValue, 0, len(terms[i]))
+		for _, counted := range terms[i] {
+			values = append(values, counted.value())
+		}
+		// Most common first, then alphabetical
+		sort.Slice(values, func(a, b int) bool {
+			if values[a].Count != values[b].Count {
+				return values[a].Count > values[b].Count
+			}
+			return values[a].Value < values[b].Value
+		})
+		facets.Terms[f.name] = values
+	}
+
+	for i, f := range histogramFacets {
+		histogram := make([]models.HistogramBucket, 0, len(buckets[i]))
+		for min, count := range buckets[i] {
+			histogram = append(histogram, models.HistogramBucket{Min: min, Max: min + f.interval, Count: count})
+		}
+		sort.Slice(histogram, func(a, b int) bool { return histogram[a].Min < histogram[b].Min })
+		facets.Histograms[f.name] = histogram
+	}
+
+	return facets
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestComputeFacets(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	vehicles := repo.GetAllVehicles()
+
+	filter := &models.VehicleFilter{Make: "chevrolet", Country: "US", MinYear: 2022}
+	facets := ComputeFacets(vehicles, filter)
+
+	count := func(f *models.VehicleFilter, match func(v *models.Vehicle) bool) int {
+		n := 0
+		for _, v := range vehicles {
+			if matchesFilter(v, f) && match(v) {
+				n++
+			}
+		}
+		return n
+	}
+
+	// The make facet ignores the make predicate, so other makes are counted
+	withoutMake := *filter
+	withoutMake.Make = ""
+	makes := facets.Terms["make"]
+	if len(makes) < 2 {
+		t.Fatalf("Expected counts for several makes, got %+v", makes)
+	}
+	total := 0
+	for _, value := range makes {
+		total += value.Count
+		expected := count(&withoutMake, func(v *models.Vehicle) bool { return strings.EqualFold(v.Make, value.Value) })
+		if value.Count != expected {
+			t.Errorf("make %s: expected %d, got %d", value.Value, expected, value.Count)
+		}
+	}
+	if expected := count(&withoutMake, func(v *models.Vehicle) bool { return true }); total != expected {
+		t.Errorf("Expected make counts to add up to %d, got %d", expected, total)
+	}
+
+	// Facets without a selection count the fully filtered results
+	matching := count(filter, func(v *models.Vehicle) bool { return true })
+	if matching == 0 {
+		t.Fatal("Expected the filter to match some vehicles")
+	}
+	total = 0
+	for _, value := range facets.Terms["fuelType"] {
+		total += value.Count
+	}
+	if total != matching {
+		t.Errorf("Expected fuel type counts to add up to %d, got %d", matching, total)
+	}
+
+	// The year histogram ignores the year range
+	withoutYear := *filter
+	withoutYear.MinYear = 0
+	total = 0
+	for _, bucket := range facets.Histograms["year"] {
+		if bucket.Max-bucket.Min != 1 {
+			t.Errorf("Unexpected year bucket %+v", bucket)
+		}
+		total += bucket.Count
+	}
+	if expected := count(&withoutYear, func(v *models.Vehicle) bool { return true }); total != expected {
+		t.Errorf("Expected year buckets to add up to %d, got %d", expected, total)
+	}
+
+	for _, bucket := range facets.Histograms["price"] {
+		if bucket.Count == 0 || bucket.Max <= bucket.Min {
+			t.Errorf("Unexpected price bucket %+v", bucket)
+		}
+	}
+
+	// Counting over the vehicles the store selects for FacetBase gives the
+	// same facets as counting over them all
+	if narrowed := ComputeFacets(repo.SearchVehicles(FacetBase(filter)), filter); !reflect.DeepEqual(narrowed, facets) {
+		t.Errorf("Expected the same facets from the FacetBase candidates, got %+v", narrowed)
+	}
+}
+
+func TestComputeFacetsSpelling(t *testing.T) {
+	vehicles := []*models.Vehicle{{Make: "bmw"}, {Make: "BMW"}, {Make: "Bmw"}, {Make: "BMW"}}
+	for i := 0; i < 10; i++ {
+		makes := ComputeFacets(vehicles, nil).Terms["make"]
+		if len(makes) != 1 || makes[0].Value != "BMW" || makes[0].Count != 4 {
+			t.Fatalf("Expected the most common spelling with every count, got %+v", makes)
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// termField identifies a vehicle field with an equality index
+type termField int
+
+const (
+	termMake termField = iota
+	termModel
+	termType
+	termCondition
+	termCurrency
+	termCountry
+	termFuelType
+	termTransmission
+	termDrivetrain
+	termDealer
+	// termVIN is not a filter; it finds the owner of a VIN on writes
+	termVIN
+	numTermFields
+)
+
+// termValues extracts the indexed value of each term field
+var termValues = [numTermFields]func(v *models.Vehicle) string{
+	termMake:         func(v *models.Vehicle) string { return v.Make },
+	termModel:        func(v *models.Vehicle) string { return v.Model },
+	termType:         func(v *models.Vehicle) string { return v.Type },
+	termCondition:    func(v *models.Vehicle) string { return v.Condition },
+	termCurrency:     func(v *models.Vehicle) string { return v.Currency },
+	termCountry:      func(v *models.Vehicle) string { return v.Country },
+	termFuelType:     func(v *models.Vehicle) string { return v.FuelType },
+	termTransmission: func(v *models.Vehicle) string { return v.Transmission },
+	termDrivetrain:   func(v *models.Vehicle) string { return v.Drivetrain },
+	termDealer:       func(v *models.Vehicle) string { return v.DealerID },
+	termVIN:          func(v *models.Vehicle) string { return v.VIN },
+}
+
+// rangeField identifies a numeric vehicle field with a sorted index
+type rangeField int
+
+const (
+	rangePrice rangeField = iota
+	rangeYear
+	rangeMileage
+	numRangeFields
+)
+
+// rangeValues extracts the indexed value of each range field
+var rangeValues = [numRangeFields]func(v *models.Vehicle) float64{
+	rangePrice:   func(v *models.Vehicle) float64 { return v.Price },
+	rangeYear:    func(v *models.Vehicle) float64 { return float64(v.Year) },
+	rangeMileage: func(v *models.Vehicle) float64 { return float64(v.Mileage) },
+}
+
+// verifyThreshold is the candidate count below which the remaining
+// predicates are checked directly instead of intersecting more postings
+const verifyThreshold = 64
+
+// rangeEntry is one element of a sorted numeric index
+type rangeEntry struct {
+	value float64
+	doc   uint32
+}
+
+// vehicleIndex holds secondary indexes over the vehicles. Each vehicle
+// version gets a document number; posting lists and range entries are
+// append-only and reference document numbers, so an update assigns a new
+// document and the old one is left behind as garbage until the next rebuild.
+type vehicleIndex struct {
+	docs    []*models.Vehicle
+	docOf   map[string]uint32
+	terms   [numTermFields]map[string][]uint32
+	ranges  [numRangeFields][]rangeEntry
+	garbage int
+}
+
+// newVehicleIndex builds the indexes for a set of vehicles. Documents are
+// numbered in ID order so scans over the index are deterministic.
+func newVehicleIndex(vehicles map[string]*models.Vehicle) *vehicleIndex {
+	ids := make([]string, 0, len(vehicles))
+	for id := range vehicles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ix := &vehicleIndex{
+		docs:  make([]*models.Vehicle, 0, len(ids)),
+		docOf: make(map[string]uint32, len(ids)),
+	}
+	for f := range ix.terms {
+		ix.terms[f] = make(map[string][]uint32)
+	}
+
+	for _, id := range ids {
+		vehicle := vehicles[id]
+		doc := uint32(len(ix.docs))
+		ix.docs = append(ix.docs, vehicle)
+		ix.docOf[id] = doc
+		ix.addTerms(vehicle, doc)
+		for f := range ix.ranges {
+			ix.ranges[f] = append(ix.ranges[f], rangeEntry{value: rangeValues[f](vehicle), doc: doc})
+		}
+	}
+
+	for f := range ix.ranges {
+		entries := ix.ranges[f]
+		sort.Slice(entries, func(i, k int) bool { return lessEntry(entries[i], entries[k]) })
+	}
+
+	return ix
+}
+
+// put indexes a new or updated vehicle
+func (ix *vehicleIndex) put(vehicle *models.Vehicle) {
+	ix.remove(vehicle.ID)
+
+	doc := uint32(len(ix.docs))
+	ix.docs = append(ix.docs, vehicle)
+	ix.docOf[vehicle.ID] = doc
+	ix.addTerms(vehicle, doc)
+
+	for f := range ix.ranges {
+		entry := rangeEntry{value: rangeValues[f](vehicle), doc: doc}
+		entries := ix.ranges[f]
+		i := sort.Search(len(entries), func(i int) bool { return !lessEntry(entries[i], entry) })
+		entries = append(entries, rangeEntry{})
+		copy(entries[i+1:], entries[i:])
+		entries[i] = entry
+		ix.ranges[f] = entries
+	}
+}
+
+// remove drops a vehicle from the index
+func (ix *vehicleIndex) remove(vehicleID string) {
+	doc, exists := ix.docOf[vehicleID]
+	if !exists {
+		return
+	}
+
+	ix.docs[doc] = nil
+	delete(ix.docOf, vehicleID)
+	ix.garbage++
+
+	// Rebuild once most documents are dead so postings stay compact
+	if ix.garbage > 1024 && ix.garbage > len(ix.docOf) {
+		live := make(map[string]*models.Vehicle, len(ix.docOf))
+		for id, d := range ix.docOf {
+			live[id] = ix.docs[d]
+		}
+		*ix = *newVehicleIndex(live)
+	}
+}
+
+func (ix *vehicleIndex) addTerms(vehicle *models.Vehicle, doc uint32) {
+	for f := range ix.terms {
+		key := foldKey(termValues[f](vehicle))
+		ix.terms[f][key] = append(ix.terms[f][key], doc)
+	}
+}
+
+// vinInUse reports whether a vehicle other than excludeID has the VIN,
+// ignoring case
+func (ix *vehicleIndex) vinInUse(vin, excludeID string) bool {
+	for _, doc := range ix.terms[termVIN][foldKey(vin)] {
+		if vehicle := ix.docs[doc]; vehicle != nil && vehicle.ID != excludeID {
+			return true
+		}
+	}
+	return false
+}
+
+// search returns the vehicles matching the filter. The most selective
+// indexed predicates are intersected first and every candidate is checked
+// with matchesFilter, so results are identical to a full scan. view maps
+// each candidate to the vehicle that is checked and returned, which lets
+// the caller fill in fields the index does not hold.
+func (ix *vehicleIndex) search(filter *models.VehicleFilter, view func(*models.Vehicle) *models.Vehicle) []*models.Vehicle {
+	candidates, scan := ix.plan(filter)
+	if scan {
+		return ix.scan(filter, view)
+	}
+
+	var results []*models.Vehicle
+	for _, doc := range candidates {
+		if vehicle := ix.docs[doc]; vehicle != nil {
+			if vehicle = view(vehicle); matchesFilter(vehicle, filter) {
+				results = append(results, vehicle)
+			}
+		}
+	}
+
+	return results
+}
+
+// scan checks every live vehicle against the filter
+func (ix *vehicleIndex) scan(filter *models.VehicleFilter, view func(*models.Vehicle) *models.Vehicle) []*models.Vehicle {
+	var results []*models.Vehicle
+	for _, vehicle := range ix.docs {
+		if vehicle != nil {
+			if vehicle = view(vehicle); matchesFilter(vehicle, filter) {
+				results = append(results, vehicle)
+			}
+		}
+	}
+	return results
+}
+
+// plan picks the candidate documents for a filter. It returns scan=true
+// when no predicate can use an index.
+func (ix *vehicleIndex) plan(filter *models.VehicleFilter) (candidates []uint32, scan bool) {
+	if filter == nil {
+		return nil, true
+	}
+
+	var postings [][]uint32
+	addTerm := func(f termField, value string) {
+		if value != "" {
+			postings = append(postings, ix.terms[f][foldKey(value)])
+		}
+	}
+	addTerm(termMake, filter.Make)
+	addTerm(termModel, filter.Model)
+	addTerm(termType, filter.Type)
+	addTerm(termCondition, filter.Condition)
+	addTerm(termCurrency, filter.Currency)
+	addTerm(termCountry, filter.Country)
+	addTerm(termFuelType, filter.FuelType)
+	addTerm(termTransmission, filter.Transmission)
+	addTerm(termDrivetrain, filter.Drivetrain)
+	addTerm(termDealer, filter.DealerID)
+
+	if len(filter.VehicleTypes) > 0 {
+		var union []uint32
+		for _, vtype := range filter.VehicleTypes {
+			union = unionSorted(union, ix.terms[termType][foldKey(vtype)])
+		}
+		postings = append(postings, union)
+	}
+
+	var ranges [][]rangeEntry
+	// The price index holds listed prices, so converted bounds are only
+	// checked by matchesFilter
+	if (filter.MinPrice > 0 || filter.MaxPrice > 0) && !filter.ConvertsPrices() {
+		ranges = append(ranges, ix.rangeSlice(rangePrice, filter.MinPrice > 0, filter.MinPrice, filter.MaxPrice > 0, filter.MaxPrice))
+	}
+	if filter.MinYear > 0 || filter.MaxYear > 0 {
+		ranges = append(ranges, ix.rangeSlice(rangeYear, filter.MinYear > 0, float64(filter.MinYear), filter.MaxYear > 0, float64(filter.MaxYear)))
+	}
+	if filter.MinMileage > 0 || filter.MaxMileage > 0 {
+		ranges = append(ranges, ix.rangeSlice(rangeMileage, filter.MinMileage > 0, float64(filter.MinMileage), filter.MaxMileage > 0, float64(filter.MaxMileage)))
+	}
+
+	if len(postings) == 0 && len(ranges) == 0 {
+		return nil, true
+	}
+
+	// Most selective first
+	sort.Slice(postings, func(i, k int) bool { return len(postings[i]) < len(postings[k]) })
+	sort.Slice(ranges, func(i, k int) bool { return len(ranges[i]) < len(ranges[k]) })
+
+	if len(ranges) > 0 && (len(postings) == 0 || len(ranges[0]) < len(postings[0])) {
+		candidates = make([]uint32, len(ranges[0]))
+		for i, entry := range ranges[0] {
+			candidates[i] = entry.doc
+		}
+		sort.Slice(candidates, func(i, k int) bool { return candidates[i] < candidates[k] })
+	} else {
+		candidates = postings[0]
+		postings = postings[1:]
+	}
+
+	for _, list := range postings {
+		if len(candidates) <= verifyThreshold {
+			break
+		}
+		candidates = intersectSorted(candidates, list)
+	}
+
+	return candidates, false
+}
+
+// rangeSlice returns the entries of a sorted index within the bounds
+func (ix *vehicleIndex) rangeSlice(f rangeField, hasMin bool, min float64, hasMax bool, max float64) []rangeEntry {
+	entries := ix.ranges[f]
+
+	lo, hi := 0, len(entries)
+	if hasMin {
+		lo = sort.Search(len(entries), func(i int) bool { return entries[i].value >= min })
+	}
+	if hasMax {
+		hi = sort.Search(len(entries), func(i int) bool { return entries[i].value > max })
+	}
+	if lo >= hi {
+		return nil
+	}
+
+	return entries[lo:hi]
+}
+
+func lessEntry(a, b rangeEntry) bool {
+	if a.value != b.value {
+		return a.value < b.value
+	}
+	return a.doc < b.doc
+}
+
+// intersectSorted returns the documents present in both sorted lists. When
+// one list is much shorter its elements are looked up by binary search.
+func intersectSorted(a, b []uint32) []uint32 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	var out []uint32
+	if len(b) > 8*len(a) {
+		for _, doc := range a {
+			i := sort.Search(len(b), func(i int) bool { return b[i] >= doc })
+			if i < len(b) && b[i] == doc {
+				out = append(out, doc)
+			}
+			b = b[i:]
+		}
+		return out
+	}
+
+	i, k := 0, 0
+	for i < len(a) && k < len(b) {
+		switch {
+		case a[i] < b[k]:
+			i++
+		case a[i] > b[k]:
+			k++
+		default:
+			out = append(out, a[i])
+			i++
+			k++
+		}
+	}
+	return out
+}
+
+// unionSorted merges two sorted lists without duplicates
+func unionSorted(a, b []uint32) []uint32 {
+	out := make([]uint32, 0, len(a)+len(b))
+	i, k := 0, 0
+	for i < len(a) || k < len(b) {
+		switch {
+		case k == len(b) || (i < len(a) && a[i] < b[k]):
+			out = append(out, a[i])
+			i++
+		case i == len(a) || b[k] < a[i]:
+			out = append(out, b[k])
+			k++
+		default:
+			out = append(out, a[i])
+			i++
+			k++
+		}
+	}
+	return out
+}
+
+// foldKey maps strings that are equal under strings.EqualFold to the same
+// key by replacing each rune with the smallest rune in its case-folding
+// orbit. For ASCII letters that is the upper-case letter.
+func foldKey(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return strings.ToUpper(s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		b.WriteRune(min)
+	}
+	return b.String()
+}
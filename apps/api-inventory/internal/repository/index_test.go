@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+var (
+	syntheticMakes         = []string{"Toyota", "Ford", "BMW", "Honda", "Tesla", "Audi", "Kia", "Volvo"}
+	syntheticModels        = []string{"Camry", "F-150", "X5", "Civic", "Model 3", "A4", "Sportage", "XC90", "Corolla", "Mustang", "3 Series", "Accord"}
+	syntheticTypes         = []string{"sedan", "suv", "truck", "coupe", "hatchback"}
+	syntheticConditions    = []string{"new", "used", "certified"}
+	syntheticCountries     = []string{"US", "GB", "DE", "CA", "AU"}
+	syntheticFuelTypes     = []string{"gasoline", "diesel", "electric", "hybrid"}
+	syntheticTransmissions = []string{"automatic", "manual"}
+	syntheticDrivetrains   = []string{"fwd", "rwd", "awd", "4wd"}
+)
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.Intn(len(values))]
+}
+
+// syntheticVehicles generates n vehicles with values drawn from small
+// vocabularies. Strings are shared between vehicles to keep memory down.
+func syntheticVehicles(n int, seed int64) map[string]*models.Vehicle {
+	rng := rand.New(rand.NewSource(seed))
+	vehicles := make(map[string]*models.Vehicle, n)
+	for i := 1; i <= n; i++ {
+		vehicle := syntheticVehicle(rng)
+		vehicle.ID = formatVehicleID(i)
+		vehicles[vehicle.ID] = vehicle
+	}
+	return vehicles
+}
+
+func syntheticVehicle(rng *rand.Rand) *models.Vehicle {
+	return &models.Vehicle{
+		VIN:          fmt.Sprintf("SYN%014d", rng.Int63n(1e14)),
+		Year:         1995 + rng.Intn(31),
+		Make:         pick(rng, syntheticMakes),
+		Model:        pick(rng, syntheticModels),
+		Type:         pick(rng, syntheticTypes),
+		Condition:    pick(rng, syntheticConditions),
+		Mileage:      rng.Intn(200000),
+		Price:        float64(2000 + rng.Intn(98000)),
+		Currency:     pick(rng, models.SupportedCurrencies),
+		Country:      pick(rng, syntheticCountries),
+		Status:       "available",
+		FuelType:     pick(rng, syntheticFuelTypes),
+		Transmission: pick(rng, syntheticTransmissions),
+		Drivetrain:   pick(rng, syntheticDrivetrains),
+	}
+}
+
+// randomFilter builds a filter with a random subset of predicates, mixing
+// the case of string values to exercise case-insensitive matching
+func randomFilter(rng *rand.Rand) *models.VehicleFilter {
+	value := func(values []string) string {
+		if rng.Intn(3) != 0 {
+			return ""
+		}
+		v := pick(rng, values)
+		if rng.Intn(2) == 0 {
+			v = strings.ToUpper(v)
+		}
+		return v
+	}
+
+	filter := &models.VehicleFilter{
+		Make:         value(syntheticMakes),
+		Model:        value(syntheticModels),
+		Type:         value(syntheticTypes),
+		Condition:    value(syntheticConditions),
+		Currency:     value(models.SupportedCurrencies),
+		Country:      value(syntheticCountries),
+		FuelType:     value(syntheticFuelTypes),
+		Transmission: value(syntheticTransmissions),
+		Drivetrain:   value(syntheticDrivetrains),
+	}
+	if rng.Intn(3) == 0 {
+		filter.MinPrice = float64(rng.Intn(60000))
+	}
+	if rng.Intn(3) == 0 {
+		filter.MaxPrice = filter.MinPrice + float64(rng.Intn(60000))
+	}
+	if rng.Intn(3) == 0 {
+		filter.MinYear = 1995 + rng.Intn(31)
+	}
+	if rng.Intn(3) == 0 {
+		filter.MaxYear = 1995 + rng.Intn(31)
+	}
+	if rng.Intn(4) == 0 {
+		filter.MinMileage = rng.Intn(150000)
+	}
+	if rng.Intn(4) == 0 {
+		filter.MaxMileage = filter.MinMileage + rng.Intn(100000)
+	}
+	if rng.Intn(4) == 0 {
+		filter.VehicleTypes = []string{pick(rng, syntheticTypes), pick(rng, syntheticTypes)}
+	}
+	return filter
+}
+
+// linearScan is the reference implementation the index must agree with
+func linearScan(vehicles map[string]*models.Vehicle, filter *models.VehicleFilter) []*models.Vehicle {
+	var results []*models.Vehicle
+	for _, vehicle := range vehicles {
+		if matchesFilter(vehicle, filter) {
+			results = append(results, vehicle)
+		}
+	}
+	return results
+}
+
+func sortedIDs(vehicles []*models.Vehicle) []string {
+	ids := make([]string, len(vehicles))
+	for i, vehicle := range vehicles {
+		ids[i] = vehicle.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestIndexMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vehicles := syntheticVehicles(5000, 2)
+	repo := &Repository{vehicles: vehicles, index: newVehicleIndex(vehicles), text: newTextIndex(vehicles), vehicleSeq: len(vehicles), edited: map[string]bool{}}
+
+	check := func(stage string) {
+		t.Helper()
+		for i := 0; i < 300; i++ {
+			filter := randomFilter(rng)
+			got := sortedIDs(repo.SearchVehicles(filter))
+			want := sortedIDs(linearScan(repo.vehicles, filter))
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Fatalf("%s: filter %+v returned %d vehicles, linear scan %d", stage, *filter, len(got), len(want))
+			}
+		}
+	}
+
+	check("initial")
+
+	// Mutations must keep the index in step with the map
+	for i := 0; i < 3000; i++ {
+		id := formatVehicleID(1 + rng.Intn(repo.vehicleSeq))
+		switch rng.Intn(3) {
+		case 0:
+			repo.CreateVehicle(syntheticVehicle(rng))
+		case 1:
+			if _, err := repo.GetVehicleByID(id); err == nil {
+				updated := syntheticVehicle(rng)
+				updated.ID = id
+				if err := repo.UpdateVehicle(updated); err != nil {
+					t.Fatalf("UpdateVehicle(%s): %v", id, err)
+				}
+			}
+		case 2:
+			repo.DeleteVehicle(id)
+		}
+	}
+
+	check("after mutations")
+}
+
+func TestFoldKeyMatchesEqualFold(t *testing.T) {
+	pairs := [][2]string{
+		{"Kia", "KIA"},
+		{"Kia", "Kia"}, // Kelvin sign folds to K
+		{"ſuv", "SUV"}, // long s folds to S
+		{"Škoda", "šKODA"},
+		{"Citroën", "CITROËN"},
+	}
+	for _, pair := range pairs {
+		if !strings.EqualFold(pair[0], pair[1]) {
+			t.Fatalf("%q and %q are not EqualFold", pair[0], pair[1])
+		}
+		if foldKey(pair[0]) != foldKey(pair[1]) {
+			t.Errorf("foldKey(%q) = %q, foldKey(%q) = %q", pair[0], foldKey(pair[0]), pair[1], foldKey(pair[1]))
+		}
+	}
+
+	if foldKey("Ford") == foldKey("Fiat") {
+		t.Error("Different makes share a fold key")
+	}
+}
+
+const benchmarkVehicles = 1000000
+
+var (
+	benchmarkOnce sync.Once
+	benchmarkRepo *Repository
+)
+
+func benchmarkRepository(b *testing.B) *Repository {
+	benchmarkOnce.Do(func() {
+		vehicles := syntheticVehicles(benchmarkVehicles, 42)
+		benchmarkRepo = &Repository{vehicles: vehicles, index: newVehicleIndex(vehicles)}
+	})
+	b.ResetTimer()
+	return benchmarkRepo
+}
+
+var benchmarkFilters = []struct {
+	name   string
+	filter *models.VehicleFilter
+}{
+	{"make", &models.VehicleFilter{Make: "Tesla"}},
+	{"make+model+country", &models.VehicleFilter{Make: "toyota", Model: "camry", Country: "GB"}},
+	{"type+price", &models.VehicleFilter{Type: "suv", MinPrice: 20000, MaxPrice: 21000}},
+	{"year+fuel+drivetrain", &models.VehicleFilter{MinYear: 2024, FuelType: "electric", Drivetrain: "awd"}},
+	{"vehicleTypes+condition", &models.VehicleFilter{VehicleTypes: []string{"coupe", "truck"}, Condition: "certified"}},
+}
+
+func BenchmarkSearchVehiclesIndexed(b *testing.B) {
+	repo := benchmarkRepository(b)
+	for _, bc := range benchmarkFilters {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				repo.SearchVehicles(bc.filter)
+			}
+		})
+	}
+}
+
+func BenchmarkSearchVehiclesLinearScan(b *testing.B) {
+	repo := benchmarkRepository(b)
+	for _, bc := range benchmarkFilters {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				linearScan(repo.vehicles, bc.filter)
+			}
+		})
+	}
+}
+
+func BenchmarkBuildVehicleIndex(b *testing.B) {
+	repo := benchmarkRepository(b)
+	for i := 0; i < b.N; i++ {
+		newVehicleIndex(repo.vehicles)
+	}
+}
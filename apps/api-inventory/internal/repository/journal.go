@@ -0,0 +1,479 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Journal entity kinds
+const (
+	entityUser         = "user"
+	entityVehicle      = "vehicle"
+	entityFavorite     = "favorite"
+	entityDealerReview = "dealerReview"
+)
+
+// Journal operations
+const (
+	opPut    = "put"
+	opDelete = "delete"
+	// opPutAll puts several entities in one record, so a batch is replayed
+	// all or nothing
+	opPutAll = "putAll"
+)
+
+const (
+	journalPrefix  = "journal-"
+	journalSuffix  = ".log"
+	snapshotPrefix = "snapshot-"
+	snapshotTmp    = ".tmp"
+	manifestFile   = "manifest.json"
+
+	// recordHeaderSize is the length and checksum preceding each payload
+	recordHeaderSize = 8
+	// maxRecordSize guards against reading a garbage length as a huge payload
+	maxRecordSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord reports a record that was only partly written or whose
+// checksum does not match, as left behind by a crash during an append
+var errTornRecord = errors.New("torn journal record")
+
+// journalRecord is a single mutation in the write-ahead log
+type journalRecord struct {
+	Seq    uint64          `json:"seq"`
+	Op     string          `json:"op"`
+	Entity string          `json:"entity"`
+	ID     string          `json:"id"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	At     time.Time       `json:"at"`
+}
+
+// snapshotManifest is written last into a snapshot directory and marks it
+// as complete
+type snapshotManifest struct {
+	// Seq is the last journal sequence number included in the snapshot
+	Seq        uint64    `json:"seq"`
+	VehicleSeq int       `json:"vehicleSeq"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// EditedVehicles lists the vehicles written through the API, which
+	// seed reloads keep
+	EditedVehicles []string `json:"editedVehicles,omitempty"`
+	// PostedReviews lists the keys of the reviews written through the API,
+	// which seed reloads keep
+	PostedReviews []string `json:"postedReviews,omitempty"`
+}
+
+// journal is an append-only write-ahead log split into segments named after
+// the first sequence number they hold. Each record is framed as a 4-byte
+// big-endian payload length, a 4-byte CRC-32C of the payload and the JSON
+// payload, so a record torn by a crash is detected on replay.
+type journal struct {
+	dir    string
+	file   *os.File
+	offset int64
+	seq    uint64
+	logger *logrus.Logger
+}
+
+// openJournal replays every record after afterSeq through apply, repairs a
+// torn tail in the newest segment and opens that segment for appending
+func openJournal(dir string, afterSeq uint64, apply func(journalRecord) error, logger *logrus.Logger) (*journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &journal{
+		dir:    dir,
+		seq:    afterSeq,
+		logger: logger,
+	}
+
+	replayed := 0
+	for i, start := range segments {
+		path := segmentPath(dir, start)
+		last := i == len(segments)-1
+
+		goodOffset, n, err := j.replaySegment(path, afterSeq, apply)
+		replayed += n
+		if errors.Is(err, errTornRecord) && last {
+			// Only the segment being written when the process stopped can
+			// have a torn tail; drop it and continue from the last good record
+			logger.WithFields(logrus.Fields{
+				"segment": path,
+				"offset":  goodOffset,
+			}).Warn("Truncating torn record at journal tail")
+			if err := os.Truncate(path, goodOffset); err != nil {
+				return nil, fmt.Errorf("failed to truncate journal: %w", err)
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to replay %s: %w", path, err)
+		}
+
+		if last {
+			j.offset = goodOffset
+		}
+	}
+
+	if len(segments) == 0 {
+		if err := j.openSegment(afterSeq + 1); err != nil {
+			return nil, err
+		}
+	} else {
+		path := segmentPath(dir, segments[len(segments)-1])
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open journal: %w", err)
+		}
+		j.file = file
+	}
+
+	logger.Infof("Replayed %d journal records from %s", replayed, dir)
+
+	return j, nil
+}
+
+// replaySegment applies the records of one segment. It returns the offset
+// just past the last intact record and the number of records applied.
+func (j *journal) replaySegment(path string, afterSeq uint64, apply func(journalRecord) error) (int64, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	applied := 0
+
+	for {
+		payload, err := readRecord(reader)
+		if err == io.EOF {
+			return offset, applied, nil
+		}
+		if err != nil {
+			return offset, applied, err
+		}
+
+		var rec journalRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return offset, applied, fmt.Errorf("%w: %v", errTornRecord, err)
+		}
+
+		if rec.Seq > afterSeq {
+			if err := apply(rec); err != nil {
+				return offset, applied, err
+			}
+			applied++
+		}
+		if rec.Seq > j.seq {
+			j.seq = rec.Seq
+		}
+
+		offset += int64(recordHeaderSize + len(payload))
+	}
+}
+
+// readRecord reads one framed record. It returns io.EOF at a clean record
+// boundary and errTornRecord for a partial or corrupt record.
+func readRecord(reader io.Reader) ([]byte, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, errTornRecord
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	checksum := binary.BigEndian.Uint32(header[4:8])
+	if size > maxRecordSize {
+		return nil, errTornRecord
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, errTornRecord
+	}
+	if crc32.Checksum(payload, crcTable) != checksum {
+		return nil, errTornRecord
+	}
+
+	return payload, nil
+}
+
+// append writes a record and syncs it to disk before returning. A failed
+// write is rolled back so the segment never keeps a torn record.
+func (j *journal) append(op, entity, id string, data interface{}) error {
+	rec := journalRecord{
+		Seq:    j.seq + 1,
+		Op:     op,
+		Entity: entity,
+		ID:     id,
+		At:     time.Now().UTC(),
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		rec.Data = raw
+	}
+
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[recordHeaderSize:], payload)
+
+	if _, err := j.file.Write(frame); err != nil {
+		j.file.Truncate(j.offset)
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		j.file.Truncate(j.offset)
+		return fmt.Errorf("failed to sync journal: %w", err)
+	}
+
+	j.offset += int64(len(frame))
+	j.seq = rec.Seq
+
+	return nil
+}
+
+// rotate starts a new segment and returns the last sequence number written
+// to the previous ones
+func (j *journal) rotate() (uint64, error) {
+	if err := j.file.Close(); err != nil {
+		return 0, err
+	}
+	if err := j.openSegment(j.seq + 1); err != nil {
+		return 0, err
+	}
+	return j.seq, nil
+}
+
+// openSegment creates the segment starting at seq and makes it current
+func (j *journal) openSegment(start uint64) error {
+	file, err := os.OpenFile(segmentPath(j.dir, start), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create journal segment: %w", err)
+	}
+	if err := syncDir(j.dir); err != nil {
+		file.Close()
+		return err
+	}
+
+	j.file = file
+	j.offset = 0
+	return nil
+}
+
+// compact removes segments that only hold records up to seq
+func (j *journal) compact(seq uint64) error {
+	segments, err := listSegments(j.dir)
+	if err != nil {
+		return err
+	}
+
+	for i, start := range segments {
+		// A segment ends where the next one starts
+		if i+1 < len(segments) && segments[i+1] <= seq+1 {
+			if err := os.Remove(segmentPath(j.dir, start)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// close closes the current segment
+func (j *journal) close() error {
+	return j.file.Close()
+}
+
+// listSegments returns the start sequence numbers of the journal segments
+// in ascending order
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, journalPrefix) || !strings.HasSuffix(name, journalSuffix) {
+			continue
+		}
+		start, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, journalPrefix), journalSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, start)
+	}
+
+	sort.Slice(segments, func(i, k int) bool { return segments[i] < segments[k] })
+	return segments, nil
+}
+
+func segmentPath(dir string, start uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d%s", journalPrefix, start, journalSuffix))
+}
+
+func snapshotPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%s%020d", snapshotPrefix, seq))
+}
+
+// latestSnapshot returns the directory and manifest of the newest complete
+// snapshot, or an empty path if there is none. Incomplete snapshots left by
+// a crash are removed.
+func latestSnapshot(dir string) (string, *snapshotManifest, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		bestPath     string
+		bestManifest *snapshotManifest
+	)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, snapshotPrefix) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if strings.HasSuffix(name, snapshotTmp) {
+			os.RemoveAll(path)
+			continue
+		}
+
+		var manifest snapshotManifest
+		if err := readJSONFile(filepath.Join(path, manifestFile), &manifest); err != nil {
+			continue
+		}
+		if bestManifest == nil || manifest.Seq > bestManifest.Seq {
+			bestPath = path
+			bestManifest = &manifest
+		}
+	}
+
+	return bestPath, bestManifest, nil
+}
+
+// writeSnapshot writes each file into a new snapshot directory. The manifest
+// is written last and the directory is only renamed into place once every
+// file is synced, so a crash never leaves a partial snapshot that looks
+// complete. Older snapshots are removed afterwards.
+func writeSnapshot(dir string, manifest snapshotManifest, files map[string]interface{}) error {
+	final := snapshotPath(dir, manifest.Seq)
+	tmp := final + snapshotTmp
+
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmp, 0o755); err != nil {
+		return err
+	}
+
+	for name, v := range files {
+		if err := writeJSONFileSync(filepath.Join(tmp, name), v); err != nil {
+			return err
+		}
+	}
+	if err := writeJSONFileSync(filepath.Join(tmp, manifestFile), manifest); err != nil {
+		return err
+	}
+	if err := syncDir(tmp); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(final); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		return err
+	}
+	if err := syncDir(dir); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() && strings.HasPrefix(name, snapshotPrefix) && path != final && !strings.HasSuffix(name, snapshotTmp) {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeJSONFileSync writes v as indented JSON and syncs the file
+func writeJSONFileSync(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// syncDir flushes directory entries so creates and renames are durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func openJournaledRepository(t *testing.T, journalDir string) *Repository {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := NewRepository(dataPath, logger, WithJournal(journalDir, 0))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	return repo
+}
+
+func TestJournalReplaysMutations(t *testing.T) {
+	dir := t.TempDir()
+	repo := openJournaledRepository(t, dir)
+
+	created := &models.Vehicle{VIN: "1HGCM82633A004352", Make: "Honda", Price: 24000}
+	if err := repo.CreateVehicle(created); err != nil {
+		t.Fatalf("Failed to create vehicle: %v", err)
+	}
+	updated, _ := repo.GetVehicleByID("veh-001")
+	updated = updated.Clone()
+	updated.Price = 1
+	if err := repo.UpdateVehicle(updated); err != nil {
+		t.Fatalf("Failed to update vehicle: %v", err)
+	}
+	if err := repo.DeleteVehicle("veh-002"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	repo.Close()
+
+	repo = openJournaledRepository(t, dir)
+	defer repo.Close()
+
+	if _, err := repo.GetVehicleByID(created.ID); err != nil {
+		t.Errorf("Created vehicle was not replayed: %v", err)
+	}
+	if vehicle, _ := repo.GetVehicleByID("veh-001"); vehicle == nil || vehicle.Price != 1 {
+		t.Errorf("Updated vehicle was not replayed: %+v", vehicle)
+	}
+	if _, err := repo.GetVehicleByID("veh-002"); err == nil {
+		t.Error("Deleted vehicle was replayed")
+	}
+}
+
+func TestSnapshotCompactsJournal(t *testing.T) {
+	dir := t.TempDir()
+	repo := openJournaledRepository(t, dir)
+
+	if err := repo.DeleteVehicle("veh-001"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	if err := repo.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	if err := repo.DeleteVehicle("veh-002"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	count := len(repo.GetAllVehicles())
+	repo.Close()
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		t.Fatalf("Failed to list segments: %v", err)
+	}
+	if len(segments) != 1 {
+		t.Errorf("Expected 1 journal segment after compaction, got %d", len(segments))
+	}
+
+	// The snapshot uses the seed file format
+	snapshotDir, manifest, err := latestSnapshot(dir)
+	if err != nil || manifest == nil {
+		t.Fatalf("Expected a snapshot, got %v", err)
+	}
+	var vehicles []*models.Vehicle
+	if err := readJSONFile(filepath.Join(snapshotDir, "vehicles.json"), &vehicles); err != nil {
+		t.Fatalf("Failed to read snapshot vehicles: %v", err)
+	}
+	if len(vehicles) != count+1 {
+		t.Errorf("Expected %d vehicles in snapshot, got %d", count+1, len(vehicles))
+	}
+
+	repo = openJournaledRepository(t, dir)
+	defer repo.Close()
+
+	if got := len(repo.GetAllVehicles()); got != count {
+		t.Errorf("Expected %d vehicles after restart, got %d", count, got)
+	}
+}
+
+func TestJournalTruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	repo := openJournaledRepository(t, dir)
+
+	if err := repo.DeleteVehicle("veh-001"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	repo.Close()
+
+	// Simulate a crash partway through appending the next record
+	segments, _ := listSegments(dir)
+	path := segmentPath(dir, segments[len(segments)-1])
+	info, _ := os.Stat(path)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open segment: %v", err)
+	}
+	file.Write([]byte{0, 0, 1, 0, 0xde, 0xad, 0xbe, 0xef, '{', '"'})
+	file.Close()
+
+	repo = openJournaledRepository(t, dir)
+	if _, err := repo.GetVehicleByID("veh-001"); err == nil {
+		t.Error("Intact record before the torn tail was not replayed")
+	}
+	if truncated, _ := os.Stat(path); truncated.Size() != info.Size() {
+		t.Errorf("Expected torn tail to be truncated to %d bytes, got %d", info.Size(), truncated.Size())
+	}
+
+	// Appends after recovery are replayed normally
+	if err := repo.DeleteVehicle("veh-002"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	repo.Close()
+
+	repo = openJournaledRepository(t, dir)
+	defer repo.Close()
+
+	if _, err := repo.GetVehicleByID("veh-002"); err == nil {
+		t.Error("Record appended after recovery was not replayed")
+	}
+}
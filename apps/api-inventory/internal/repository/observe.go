@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// VehicleObserver is told about every vehicle a store creates or updates
+type VehicleObserver interface {
+	// VehicleSaved is called after a successful write. previous is the
+	// stored vehicle before an update, or nil for a new vehicle.
+	VehicleSaved(vehicle, previous *models.Vehicle)
+}
+
+// vehicleNotifier is implemented by stores that change vehicles on their
+// own, such as on a seed reload, and tell an observer about them
+type vehicleNotifier interface {
+	setObserver(observer VehicleObserver)
+}
+
+var _ vehicleNotifier = (*Repository)(nil)
+
+// observedStore passes vehicle writes on to an observer
+type observedStore struct {
+	Store
+	observer VehicleObserver
+}
+
+// Observe wraps a store so the observer sees every vehicle written through
+// it, every vehicle whose dealer rating a review changes and, for stores
+// that reload their seed files, every vehicle a reload adds or changes. The
+// wrapper only exposes Store; other interfaces of the store, such as
+// Reloader, must be taken from the original.
+func Observe(store Store, observer VehicleObserver) Store {
+	if notifier, ok := store.(vehicleNotifier); ok {
+		notifier.setObserver(observer)
+	}
+	return &observedStore{Store: store, observer: observer}
+}
+
+// CreateVehicle stores the vehicle and notifies the observer
+func (s *observedStore) CreateVehicle(vehicle *models.Vehicle) error {
+	if err := s.Store.CreateVehicle(vehicle); err != nil {
+		return err
+	}
+	s.observer.VehicleSaved(vehicle, nil)
+	return nil
+}
+
+// UpdateVehicle stores the vehicle and notifies the observer
+func (s *observedStore) UpdateVehicle(vehicle *models.Vehicle) error {
+	previous, err := s.Store.GetVehicleByID(vehicle.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.UpdateVehicle(vehicle); err != nil {
+		return err
+	}
+	s.observer.VehicleSaved(vehicle, previous)
+	return nil
+}
+
+// SaveVehicles stores the vehicles and notifies the observer of each
+func (s *observedStore) SaveVehicles(vehicles []*models.Vehicle) error {
+	previous := make([]*models.Vehicle, len(vehicles))
+	for i, vehicle := range vehicles {
+		if vehicle.ID == "" {
+			continue
+		}
+		stored, err := s.Store.GetVehicleByID(vehicle.ID)
+		if err != nil {
+			return err
+		}
+		previous[i] = stored
+	}
+	if err := s.Store.SaveVehicles(vehicles); err != nil {
+		return err
+	}
+	for i, vehicle := range vehicles {
+		s.observer.VehicleSaved(vehicle, previous[i])
+	}
+	return nil
+}
+
+// PutDealerReview stores the review and notifies the observer of every
+// vehicle whose dealer rating changed. A review moves the mean all scores
+// are smoothed towards, so those can belong to any dealer.
+func (s *observedStore) PutDealerReview(review *models.DealerReview) error {
+	before := dealerScores(s.Store.GetDealers())
+	if err := s.Store.PutDealerReview(review); err != nil {
+		return err
+	}
+
+	moved := make(map[string]float64)
+	for id, score := range dealerScores(s.Store.GetDealers()) {
+		if before[id] != score {
+			moved[id] = before[id]
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	// The review is stored by now, so a failed read only loses
+	// notifications and is not reported as a failed write
+	s.Store.EachVehicle(context.Background(), nil, func(vehicle *models.Vehicle) error {
+		if score, ok := moved[vehicle.DealerID]; ok {
+			previous := *vehicle
+			previous.DealerRating = score
+			s.observer.VehicleSaved(vehicle, &previous)
+		}
+		return nil
+	})
+	return nil
+}
+
+// dealerScores maps dealer IDs to their rating scores
+func dealerScores(dealers []*models.Dealer) map[string]float64 {
+	scores := make(map[string]float64, len(dealers))
+	for _, dealer := range dealers {
+		if dealer.Rating != nil {
+			scores[dealer.ID] = dealer.Rating.Score
+		}
+	}
+	return scores
+}
+
+// MatchesFilter reports whether a vehicle passes every field of the filter,
+// exactly as SearchVehicles applies it
+func MatchesFilter(vehicle *models.Vehicle, filter *models.VehicleFilter) bool {
+	return matchesFilter(vehicle, filter)
+}
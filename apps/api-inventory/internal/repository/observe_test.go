@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// recordingObserver keeps every VehicleSaved call
+type recordingObserver struct {
+	mu    sync.Mutex
+	saved [][2]*models.Vehicle
+}
+
+func (o *recordingObserver) VehicleSaved(vehicle, previous *models.Vehicle) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.saved = append(o.saved, [2]*models.Vehicle{vehicle, previous})
+}
+
+func TestObserveReload(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := copySeedData(t)
+
+	repo, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	observer := &recordingObserver{}
+	Observe(repo, observer)
+
+	// Reprice one seed vehicle; the reload leaves the others as they were
+	var vehicles []*models.Vehicle
+	if err := readJSONFile(filepath.Join(dataPath, "vehicles.json"), &vehicles); err != nil {
+		t.Fatalf("Failed to read vehicles: %v", err)
+	}
+	before := vehicles[1].Price
+	vehicles[1].Price = before - 1000
+	data, _ := json.Marshal(vehicles)
+	if err := os.WriteFile(filepath.Join(dataPath, "vehicles.json"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write vehicles: %v", err)
+	}
+	if err := repo.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+
+	if len(observer.saved) != 1 {
+		t.Fatalf("Expected the repriced vehicle only, got %d changes", len(observer.saved))
+	}
+	vehicle, previous := observer.saved[0][0], observer.saved[0][1]
+	if vehicle.ID != vehicles[1].ID || vehicle.Price != before-1000 || previous == nil || previous.Price != before {
+		t.Errorf("Unexpected change %+v from %+v", vehicle, previous)
+	}
+}
+
+func TestObserveDealerReview(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+
+	repo, err := NewRepository(filepath.Join("..", "..", "..", "..", "data", "seed"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	observer := &recordingObserver{}
+	store := Observe(repo, observer)
+
+	review := &models.DealerReview{DealerID: "dlr-010", UserID: "user-001", VehicleID: "veh-048", Rating: 5}
+	if err := store.PutDealerReview(review); err != nil {
+		t.Fatalf("PutDealerReview failed: %v", err)
+	}
+
+	// The review moves the mean, so vehicles of other dealers change too
+	dealers := make(map[string]bool)
+	for _, change := range observer.saved {
+		vehicle, previous := change[0], change[1]
+		if previous == nil || previous.DealerRating == vehicle.DealerRating {
+			t.Fatalf("Expected %s to change dealer rating, got %+v", vehicle.ID, previous)
+		}
+		if stored, _ := repo.GetVehicleByID(vehicle.ID); stored.DealerRating != vehicle.DealerRating {
+			t.Errorf("Expected %s to be rated %v, got %v", vehicle.ID, stored.DealerRating, vehicle.DealerRating)
+		}
+		dealers[vehicle.DealerID] = true
+	}
+	if !dealers["dlr-010"] || len(dealers) < 2 {
+		t.Errorf("Expected vehicles of several dealers including dlr-010, got %v", dealers)
+	}
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+)
+
+// Option configures optional Repository behaviour
+type Option func(*repositoryOptions)
+
+type repositoryOptions struct {
+	journalDir       string
+	snapshotInterval time.Duration
+	watchInterval    time.Duration
+}
+
+// WithJournal makes the repository durable. Every mutation is appended to a
+// write-ahead journal in dir before it is applied, and a compacted snapshot
+// in the seed file format is written every snapshotInterval (0 disables
+// periodic snapshots; Snapshot can still be called directly).
+func WithJournal(dir string, snapshotInterval time.Duration) Option {
+	return func(o *repositoryOptions) {
+		o.journalDir = dir
+		o.snapshotInterval = snapshotInterval
+	}
+}
+
+// openJournal replays the journal on top of the loaded data and starts the
+// periodic snapshot loop
+func (r *Repository) openJournal(dir string, snapshotInterval time.Duration) error {
+	j, err := openJournal(dir, r.snapshotSeq, r.applyRecord, r.logger)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	r.journal = j
+	r.journalDir = dir
+
+	if snapshotInterval > 0 {
+		r.wg.Add(1)
+		go r.snapshotLoop(snapshotInterval)
+	}
+
+	return nil
+}
+
+// applyRecord applies a replayed journal record to the maps
+func (r *Repository) applyRecord(rec journalRecord) error {
+	if rec.Op == opReload {
+		// The reload is repeated with the seed files as they are now, in
+		// case the snapshot that should follow it was never written
+		seed, err := readSeedData(r.dataPath)
+		if err != nil {
+			r.logger.WithError(err).Warn("Skipping replayed seed data reload")
+			return nil
+		}
+		r.mergeSeed(seed)
+		return nil
+	}
+
+	switch rec.Entity {
+	case entityUser:
+		if rec.Op == opDelete {
+			delete(r.users, rec.ID)
+			return nil
+		}
+		var user models.User
+		if err := json.Unmarshal(rec.Data, &user); err != nil {
+			return err
+		}
+		r.users[rec.ID] = &user
+	case entityVehicle:
+		if rec.Op == opPutAll {
+			var vehicles []*models.Vehicle
+			if err := json.Unmarshal(rec.Data, &vehicles); err != nil {
+				return err
+			}
+			for _, vehicle := range vehicles {
+				if seq := vehicleIDSeq(vehicle.ID); seq > r.vehicleSeq {
+					r.vehicleSeq = seq
+				}
+				r.vehicles[vehicle.ID] = vehicle
+				r.edited[vehicle.ID] = true
+			}
+			return nil
+		}
+		if seq := vehicleIDSeq(rec.ID); seq > r.vehicleSeq {
+			r.vehicleSeq = seq
+		}
+		r.edited[rec.ID] = true
+		if rec.Op == opDelete {
+			delete(r.vehicles, rec.ID)
+			return nil
+		}
+		var vehicle models.Vehicle
+		if err := json.Unmarshal(rec.Data, &vehicle); err != nil {
+			return err
+		}
+		r.vehicles[rec.ID] = &vehicle
+	case entityFavorite:
+		if rec.Op == opDelete {
+			delete(r.favorites, rec.ID)
+			return nil
+		}
+		var favorite models.Favorite
+		if err := json.Unmarshal(rec.Data, &favorite); err != nil {
+			return err
+		}
+		r.favorites[rec.ID] = &favorite
+	case entityDealerReview:
+		var review models.DealerReview
+		if err := json.Unmarshal(rec.Data, &review); err != nil {
+			return err
+		}
+		r.reviews[rec.ID] = &review
+		r.posted[rec.ID] = true
+	default:
+		return fmt.Errorf("unknown journal entity %q", rec.Entity)
+	}
+
+	return nil
+}
+
+// record appends a mutation to the journal, if enabled. Callers must hold mu
+// and apply the mutation only when record succeeds.
+func (r *Repository) record(op, entity, id string, data interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.append(op, entity, id, data)
+}
+
+// Snapshot writes a compacted snapshot of the current data and drops the
+// journal segments it covers. It is a no-op without a journal.
+func (r *Repository) Snapshot() error {
+	if r.journal == nil {
+		return nil
+	}
+
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+
+	// Stored records are replaced rather than modified, so copying the
+	// pointers under the lock is enough to capture a consistent view
+	r.mu.Lock()
+	if r.journal.seq == r.snapshotSeq {
+		r.mu.Unlock()
+		return nil
+	}
+	seq, err := r.journal.rotate()
+	if err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("failed to rotate journal: %w", err)
+	}
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	vehicles := make([]*models.Vehicle, 0, len(r.vehicles))
+	for _, vehicle := range r.vehicles {
+		vehicles = append(vehicles, vehicle)
+	}
+	favorites := make([]*models.Favorite, 0, len(r.favorites))
+	for _, favorite := range r.favorites {
+		favorites = append(favorites, favorite)
+	}
+	dealers := make([]*models.Dealer, 0, len(r.dealers))
+	for _, dealer := range r.dealers {
+		dealers = append(dealers, dealer)
+	}
+	reviews := r.reviewList()
+	vehicleSeq := r.vehicleSeq
+	edited := make([]string, 0, len(r.edited))
+	for id := range r.edited {
+		edited = append(edited, id)
+	}
+	posted := make([]string, 0, len(r.posted))
+	for key := range r.posted {
+		posted = append(posted, key)
+	}
+	r.mu.Unlock()
+	sort.Strings(edited)
+	sort.Strings(posted)
+
+	manifest := snapshotManifest{
+		Seq:            seq,
+		VehicleSeq:     vehicleSeq,
+		CreatedAt:      time.Now().UTC(),
+		EditedVehicles: edited,
+		PostedReviews:  posted,
+	}
+	files := map[string]interface{}{
+		"users.json":          users,
+		"vehicles.json":       vehicles,
+		"favorites.json":      favorites,
+		"dealers.json":        dealers,
+		"dealer_reviews.json": reviews,
+	}
+	if err := writeSnapshot(r.journalDir, manifest, files); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	r.snapshotSeq = seq
+	if err := r.journal.compact(seq); err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+
+	r.logger.WithField("seq", seq).Info("Wrote repository snapshot")
+
+	return nil
+}
+
+// snapshotLoop takes periodic snapshots until Close is called
+func (r *Repository) snapshotLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				r.logger.WithError(err).Error("Periodic snapshot failed")
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background loops and closes the journal
+func (r *Repository) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+
+	if r.journal == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.journal.close()
+}
@@ -0,0 +1,353 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// seedFileNames are the files under the data path read by the repository
+var seedFileNames = []string{"users.json", "vehicles.json", "dealers.json", "dealer_reviews.json"}
+
+// opReload marks a seed data reload in the journal. It carries no data: on
+// replay the seed files are read and merged again, so a crash before the
+// snapshot taken after the reload does not lose it.
+const opReload = "reload"
+
+// ReloadStatus reports hot reload activity for the seed data
+type ReloadStatus struct {
+	Reloads    int64      `json:"reloads"`
+	Failures   int64      `json:"failures"`
+	LastReload *time.Time `json:"lastReload,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+}
+
+// Reloader is implemented by stores that can reload their seed files
+// without a restart
+type Reloader interface {
+	Reload() error
+	ReloadStatus() ReloadStatus
+}
+
+var _ Reloader = (*Repository)(nil)
+
+// WithWatch polls the data path every interval and reloads the seed files
+// when one of them changes
+func WithWatch(interval time.Duration) Option {
+	return func(o *repositoryOptions) {
+		o.watchInterval = interval
+	}
+}
+
+// fileVersion identifies the version of a file on disk
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reload re-reads and validates the seed files, then merges them in
+// atomically (see mergeSeed). If any file is missing or invalid the current
+// data is kept and the error is returned.
+func (r *Repository) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	seed, err := readSeedData(r.dataPath)
+	if err != nil {
+		r.mu.Lock()
+		r.reloadStatus.Failures++
+		r.reloadStatus.LastError = err.Error()
+		r.mu.Unlock()
+
+		r.logger.WithError(err).Warn("Seed data reload rejected, keeping current data")
+		return err
+	}
+	r.mu.Lock()
+	if err := r.record(opReload, "", "", nil); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	vehicles, ratings := r.vehicles, r.ratings
+	kept := r.mergeSeed(seed)
+	r.index = newVehicleIndex(r.vehicles)
+	r.text = newTextIndex(r.vehicles)
+	var changes []vehicleChange
+	if r.observer != nil {
+		changes = r.reloadChanges(vehicles, ratings)
+	}
+	observer := r.observer
+
+	now := time.Now().UTC()
+	r.reloadStatus.Reloads++
+	r.reloadStatus.LastReload = &now
+	r.reloadStatus.LastError = ""
+	r.mu.Unlock()
+
+	// The observer may read the store, so it is called without the lock
+	for _, change := range changes {
+		observer.VehicleSaved(change.vehicle, change.previous)
+	}
+
+	r.logger.Infof("Reloaded %d users, %d vehicles, %d dealers and %d dealer reviews from %s, keeping %d vehicles edited through the API",
+		len(seed.users), len(seed.vehicles), len(seed.dealers), len(seed.reviews), r.dataPath, kept)
+
+	// Persist the reloaded data so a restart does not replay older journal
+	// records on top of it
+	return r.Snapshot()
+}
+
+// mergeSeed swaps in the seed users and dealers and merges the seed reviews
+// and vehicles by key. Reviews posted and vehicles created, changed or
+// deleted through the API are kept as they are; every other review or
+// vehicle came from the seed files, so it is replaced by its new seed record
+// or removed along with it. A seed vehicle whose VIN is taken by an edited
+// vehicle is skipped. It returns the number of edited vehicles kept.
+// Callers must hold mu and rebuild the indexes.
+func (r *Repository) mergeSeed(seed *seedData) int {
+	r.users = seed.users
+	r.dealers = seed.dealers
+
+	reviews := make(map[string]*models.DealerReview, len(seed.reviews))
+	for key, review := range r.reviews {
+		if r.posted[key] {
+			reviews[key] = review
+		}
+	}
+	for key, review := range seed.reviews {
+		if !r.posted[key] {
+			reviews[key] = review
+		}
+	}
+	r.reviews = reviews
+	r.rerate()
+
+	vehicles := make(map[string]*models.Vehicle, len(seed.vehicles))
+	owners := make(map[string]string)
+	for id, vehicle := range r.vehicles {
+		if r.edited[id] {
+			vehicles[id] = vehicle
+			owners[strings.ToUpper(vehicle.VIN)] = id
+		}
+	}
+	kept := len(vehicles)
+
+	for id, vehicle := range seed.vehicles {
+		if r.edited[id] {
+			continue
+		}
+		if owner, ok := owners[strings.ToUpper(vehicle.VIN)]; ok {
+			r.logger.WithFields(logrus.Fields{
+				"vehicle_id": id,
+				"owner_id":   owner,
+			}).Warn("Seed vehicle skipped, its VIN is taken by an edited vehicle")
+			continue
+		}
+		flagInvalidVIN(vehicle, r.logger)
+		rateVehicle(vehicle, r.ratings)
+		vehicles[id] = vehicle
+		// Never hand out an ID that is used by the seed files
+		if seq := vehicleIDSeq(id); seq > r.vehicleSeq {
+			r.vehicleSeq = seq
+		}
+	}
+	r.vehicles = vehicles
+
+	return kept
+}
+
+// vehicleChange is a vehicle written by a reload, for the observer
+type vehicleChange struct {
+	vehicle, previous *models.Vehicle
+}
+
+// reloadChanges lists the vehicles that are new or differ from the vehicles
+// and ratings before the reload, which includes vehicles whose dealer score
+// moved with the reloaded reviews. Callers must hold mu.
+func (r *Repository) reloadChanges(vehicles map[string]*models.Vehicle, ratings map[string]models.DealerRating) []vehicleChange {
+	var changes []vehicleChange
+	for id, vehicle := range r.vehicles {
+		current := r.rated(vehicle)
+		stored, exists := vehicles[id]
+		if !exists {
+			changes = append(changes, vehicleChange{vehicle: current})
+			continue
+		}
+		// Seed vehicles are read afresh, so compare them by value
+		if previous := ratedVehicle(stored, ratings); !reflect.DeepEqual(previous, current) {
+			changes = append(changes, vehicleChange{vehicle: current, previous: previous})
+		}
+	}
+	sort.Slice(changes, func(i, k int) bool { return changes[i].vehicle.ID < changes[k].vehicle.ID })
+	return changes
+}
+
+// setObserver implements vehicleNotifier
+func (r *Repository) setObserver(observer VehicleObserver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.observer = observer
+}
+
+// ReloadStatus returns the reload counters
+func (r *Repository) ReloadStatus() ReloadStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.reloadStatus
+}
+
+// seedData is the content of the seed files
+type seedData struct {
+	users    map[string]*models.User
+	vehicles map[string]*models.Vehicle
+	dealers  map[string]*models.Dealer
+	reviews  map[string]*models.DealerReview
+}
+
+// readSeedData parses and validates the seed files into new maps.
+// dealers.json and dealer_reviews.json are optional.
+func readSeedData(dataPath string) (*seedData, error) {
+	var userList []*models.User
+	if err := readJSONFile(filepath.Join(dataPath, "users.json"), &userList); err != nil {
+		return nil, fmt.Errorf("users.json: %w", err)
+	}
+
+	users := make(map[string]*models.User, len(userList))
+	emails := make(map[string]bool, len(userList))
+	for i, user := range userList {
+		switch {
+		case user == nil || user.ID == "":
+			return nil, fmt.Errorf("users.json: record %d has no id", i)
+		case user.Email == "":
+			return nil, fmt.Errorf("users.json: user %s has no email", user.ID)
+		case users[user.ID] != nil:
+			return nil, fmt.Errorf("users.json: duplicate user id %s", user.ID)
+		case emails[user.Email]:
+			return nil, fmt.Errorf("users.json: duplicate email %s", user.Email)
+		}
+		users[user.ID] = user
+		emails[user.Email] = true
+	}
+
+	var vehicleList []*models.Vehicle
+	if err := readJSONFile(filepath.Join(dataPath, "vehicles.json"), &vehicleList); err != nil {
+		return nil, fmt.Errorf("vehicles.json: %w", err)
+	}
+
+	vehicles := make(map[string]*models.Vehicle, len(vehicleList))
+	for i, vehicle := range vehicleList {
+		if vehicle == nil || vehicle.ID == "" {
+			return nil, fmt.Errorf("vehicles.json: record %d has no id", i)
+		}
+		if vehicles[vehicle.ID] != nil {
+			return nil, fmt.Errorf("vehicles.json: duplicate vehicle id %s", vehicle.ID)
+		}
+		if err := vehicle.Validate(); err != nil {
+			return nil, fmt.Errorf("vehicles.json: vehicle %s: %w", vehicle.ID, err)
+		}
+		geocodeVehicle(vehicle, nil)
+		vehicles[vehicle.ID] = vehicle
+	}
+
+	var dealerList []*models.Dealer
+	if err := readJSONFile(filepath.Join(dataPath, "dealers.json"), &dealerList); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("dealers.json: %w", err)
+	}
+
+	dealers := make(map[string]*models.Dealer, len(dealerList))
+	for i, dealer := range dealerList {
+		if dealer == nil || dealer.ID == "" {
+			return nil, fmt.Errorf("dealers.json: record %d has no id", i)
+		}
+		if dealers[dealer.ID] != nil {
+			return nil, fmt.Errorf("dealers.json: duplicate dealer id %s", dealer.ID)
+		}
+		dealer.Rating = nil
+		dealers[dealer.ID] = dealer
+	}
+	for _, vehicle := range vehicles {
+		if vehicle.DealerID != "" && dealers[vehicle.DealerID] == nil {
+			return nil, fmt.Errorf("vehicles.json: vehicle %s: unknown dealer %s", vehicle.ID, vehicle.DealerID)
+		}
+	}
+
+	var reviewList []*models.DealerReview
+	if err := readJSONFile(filepath.Join(dataPath, "dealer_reviews.json"), &reviewList); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("dealer_reviews.json: %w", err)
+	}
+
+	// A later review replaces an earlier one for the same key, as on load
+	reviews := make(map[string]*models.DealerReview, len(reviewList))
+	for i, review := range reviewList {
+		if review == nil || review.DealerID == "" || review.UserID == "" {
+			return nil, fmt.Errorf("dealer_reviews.json: record %d has no dealerId or userId", i)
+		}
+		if dealers[review.DealerID] == nil {
+			return nil, fmt.Errorf("dealer_reviews.json: record %d: unknown dealer %s", i, review.DealerID)
+		}
+		if err := review.Validate(); err != nil {
+			return nil, fmt.Errorf("dealer_reviews.json: record %d: %w", i, err)
+		}
+		reviews[reviewKey(review.DealerID, review.UserID)] = review
+	}
+
+	return &seedData{users: users, vehicles: vehicles, dealers: dealers, reviews: reviews}, nil
+}
+
+// statSeedFiles returns the current version of each seed file
+func statSeedFiles(dataPath string) map[string]fileVersion {
+	versions := make(map[string]fileVersion, len(seedFileNames))
+	for _, name := range seedFileNames {
+		if info, err := os.Stat(filepath.Join(dataPath, name)); err == nil {
+			versions[name] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return versions
+}
+
+// watchLoop reloads the seed files whenever their versions change
+func (r *Repository) watchLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current := statSeedFiles(r.dataPath)
+			if sameVersions(current, r.seedFiles) {
+				continue
+			}
+			// Remember the version even if it fails to load, so a malformed
+			// file is reported once rather than on every tick
+			r.seedFiles = current
+			r.Reload()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func sameVersions(a, b map[string]fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, version := range a {
+		other, ok := b[name]
+		if !ok || !version.modTime.Equal(other.modTime) || version.size != other.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// copySeedData copies the seed files into a temporary data path
+func copySeedData(t *testing.T) string {
+	t.Helper()
+
+	src := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dst := t.TempDir()
+	for _, name := range seedFileNames {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, name), data, 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	return dst
+}
+
+// dropFirstVehicle rewrites vehicles.json without its first entry
+func dropFirstVehicle(t *testing.T, dataPath string) {
+	t.Helper()
+
+	var vehicles []*models.Vehicle
+	if err := readJSONFile(filepath.Join(dataPath, "vehicles.json"), &vehicles); err != nil {
+		t.Fatalf("Failed to read vehicles: %v", err)
+	}
+	data, _ := json.Marshal(vehicles[1:])
+	if err := os.WriteFile(filepath.Join(dataPath, "vehicles.json"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write vehicles: %v", err)
+	}
+}
+
+// dropDealerReviews rewrites dealer_reviews.json without the dealer's reviews
+func dropDealerReviews(t *testing.T, dataPath, dealerID string) {
+	t.Helper()
+
+	var reviews, kept []*models.DealerReview
+	if err := readJSONFile(filepath.Join(dataPath, "dealer_reviews.json"), &reviews); err != nil {
+		t.Fatalf("Failed to read reviews: %v", err)
+	}
+	for _, review := range reviews {
+		if review.DealerID != dealerID {
+			kept = append(kept, review)
+		}
+	}
+	if len(kept) == len(reviews) {
+		t.Fatalf("Expected seed reviews for %s", dealerID)
+	}
+	data, _ := json.Marshal(kept)
+	if err := os.WriteFile(filepath.Join(dataPath, "dealer_reviews.json"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write reviews: %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := copySeedData(t)
+
+	repo, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	count := len(repo.GetAllVehicles())
+	review := &models.DealerReview{DealerID: "dlr-003", UserID: "user-003", VehicleID: "veh-002", Rating: 4}
+	if err := repo.PutDealerReview(review); err != nil {
+		t.Fatalf("PutDealerReview failed: %v", err)
+	}
+
+	// Vehicles written through the API are kept by the reload
+	edited, _ := repo.GetVehicleByID("veh-003")
+	changed := *edited
+	changed.Price = 1234
+	if err := repo.UpdateVehicle(&changed); err != nil {
+		t.Fatalf("UpdateVehicle failed: %v", err)
+	}
+	created := &models.Vehicle{VIN: "1HGCM82633A004352", Year: 2003, Make: "Honda", Model: "Accord", Status: models.StatusAvailable}
+	if err := repo.CreateVehicle(created); err != nil {
+		t.Fatalf("CreateVehicle failed: %v", err)
+	}
+
+	dropFirstVehicle(t, dataPath)
+	dropDealerReviews(t, dataPath, "dlr-003")
+	if err := repo.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := len(repo.GetAllVehicles()); got != count {
+		t.Errorf("Expected %d vehicles after reload, got %d", count, got)
+	}
+	if _, err := repo.GetVehicleByID("veh-001"); err == nil {
+		t.Error("Expected the vehicle removed from the seed file to be removed")
+	}
+	if vehicle, _ := repo.GetVehicleByID("veh-003"); vehicle.Price != 1234 {
+		t.Errorf("Expected the edited vehicle to be kept, got price %v", vehicle.Price)
+	}
+	if _, err := repo.GetVehicleByID(created.ID); err != nil {
+		t.Errorf("Expected the created vehicle to be kept: %v", err)
+	}
+
+	// Posted reviews are kept, seed reviews follow the file, and the new
+	// ratings apply to every vehicle
+	dealer, err := repo.GetDealerByID("dlr-003")
+	if err != nil || dealer.Rating == nil || dealer.Rating.Count != 1 {
+		t.Fatalf("Expected only the posted review after the reload, got %+v %v", dealer.Rating, err)
+	}
+	if vehicle, _ := repo.GetVehicleByID("veh-002"); vehicle.DealerRating != dealer.Rating.Score {
+		t.Errorf("Expected reloaded vehicles to be rated %v, got %v", dealer.Rating.Score, vehicle.DealerRating)
+	}
+
+	status := repo.ReloadStatus()
+	if status.Reloads != 1 || status.LastReload == nil {
+		t.Errorf("Unexpected reload status: %+v", status)
+	}
+
+	// A malformed file keeps the current data
+	if err := os.WriteFile(filepath.Join(dataPath, "vehicles.json"), []byte(`[{"id": "veh-1"`), 0o644); err != nil {
+		t.Fatalf("Failed to write vehicles: %v", err)
+	}
+	if err := repo.Reload(); err == nil {
+		t.Error("Expected error reloading malformed file")
+	}
+	if got := len(repo.GetAllVehicles()); got != count {
+		t.Errorf("Expected %d vehicles to be kept, got %d", count, got)
+	}
+
+	// So does a well-formed file with invalid records
+	if err := os.WriteFile(filepath.Join(dataPath, "vehicles.json"), []byte(`[{"id": "veh-1", "vin": "bad"}]`), 0o644); err != nil {
+		t.Fatalf("Failed to write vehicles: %v", err)
+	}
+	if err := repo.Reload(); err == nil {
+		t.Error("Expected error reloading invalid vehicle")
+	}
+
+	status = repo.ReloadStatus()
+	if status.Reloads != 1 || status.Failures != 2 || status.LastError == "" {
+		t.Errorf("Unexpected reload status: %+v", status)
+	}
+}
+
+func TestWatchReloadsChangedFiles(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := copySeedData(t)
+
+	repo, err := NewRepository(dataPath, logger, WithWatch(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	defer repo.Close()
+	count := len(repo.GetAllVehicles())
+
+	// Make sure the rewritten file gets a different modification time
+	time.Sleep(20 * time.Millisecond)
+	dropFirstVehicle(t, dataPath)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(repo.GetAllVehicles()) != count-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Watcher did not reload changed vehicles.json")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadWithJournalSurvivesRestart(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := copySeedData(t)
+	journalDir := t.TempDir()
+
+	repo, err := NewRepository(dataPath, logger, WithJournal(journalDir, 0))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if err := repo.DeleteVehicle("veh-010"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	review := &models.DealerReview{DealerID: "dlr-003", UserID: "user-003", VehicleID: "veh-002", Rating: 4}
+	if err := repo.PutDealerReview(review); err != nil {
+		t.Fatalf("PutDealerReview failed: %v", err)
+	}
+	dropFirstVehicle(t, dataPath)
+	dropDealerReviews(t, dataPath, "dlr-003")
+	if err := repo.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	count := len(repo.GetAllVehicles())
+	repo.Close()
+
+	repo, err = NewRepository(dataPath, logger, WithJournal(journalDir, 0))
+	if err != nil {
+		t.Fatalf("Failed to reopen repository: %v", err)
+	}
+	defer repo.Close()
+
+	if got := len(repo.GetAllVehicles()); got != count {
+		t.Errorf("Expected %d vehicles after restart, got %d", count, got)
+	}
+
+	// The snapshot remembers which reviews were posted, so a later reload
+	// still keeps them
+	if err := repo.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if dealer, _ := repo.GetDealerByID("dlr-003"); dealer.Rating == nil || dealer.Rating.Count != 1 {
+		t.Errorf("Expected the posted review to be kept, got %+v", dealer.Rating)
+	}
+}
+
+func TestReplayedReloadRereadsSeed(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := copySeedData(t)
+	journalDir := t.TempDir()
+
+	repo, err := NewRepository(dataPath, logger, WithJournal(journalDir, 0))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if err := repo.DeleteVehicle("veh-010"); err != nil {
+		t.Fatalf("Failed to delete vehicle: %v", err)
+	}
+	if err := repo.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+
+	// Crash between journaling the reload and the snapshot that follows it
+	dropFirstVehicle(t, dataPath)
+	repo.mu.Lock()
+	err = repo.record(opReload, "", "", nil)
+	repo.mu.Unlock()
+	if err != nil {
+		t.Fatalf("Failed to record reload: %v", err)
+	}
+	repo.Close()
+
+	repo, err = NewRepository(dataPath, logger, WithJournal(journalDir, 0))
+	if err != nil {
+		t.Fatalf("Failed to reopen repository: %v", err)
+	}
+	defer repo.Close()
+
+	if _, err := repo.GetVehicleByID("veh-001"); err == nil {
+		t.Error("Expected the replayed reload to drop veh-001")
+	}
+	if _, err := repo.GetVehicleByID("veh-010"); err == nil {
+		t.Error("Expected the deleted vehicle to stay deleted")
+	}
+}
@@ -1,57 +1,171 @@
 package repository
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/geo"
 	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/search"
 	"github.com/sirupsen/logrus"
 )
 
-// Repository provides data access for users and vehicles
+// Repository is the in-memory Store implementation. It provides data access
+// for users and vehicles held in maps loaded from the JSON seed files.
 type Repository struct {
 	users    map[string]*models.User
 	vehicles map[string]*models.Vehicle
+	// favorites are keyed by favoriteKey. They are user data rather than
+	// seed data, so reloads keep them.
+	favorites map[string]*models.Favorite
+	dealers   map[string]*models.Dealer
+	// reviews are keyed by reviewKey. posted holds the keys of reviews
+	// written through the API, which reloads keep (see mergeSeed). ratings
+	// is computed from the reviews and applied to vehicles as they are read.
+	reviews map[string]*models.DealerReview
+	posted  map[string]bool
+	ratings map[string]models.DealerRating
+	// index holds the secondary indexes used by SearchVehicles
+	index *vehicleIndex
+	// text is the full-text index over the vehicles
+	text *search.Index
+	// vehicleSeq is the highest sequence number used in a vehicle ID
+	vehicleSeq int
+	// edited holds the IDs of vehicles created, changed or deleted through
+	// the API. Reloads leave them alone (see mergeSeed).
+	edited map[string]bool
+	// observer is told about the vehicles a reload adds or changes (see
+	// Observe)
+	observer VehicleObserver
 	mu       sync.RWMutex
 	logger   *logrus.Logger
+
+	// journal records mutations when durability is enabled (see WithJournal)
+	journal     *journal
+	journalDir  string
+	snapshotMu  sync.Mutex
+	snapshotSeq uint64
+
+	// dataPath is re-read by Reload; reloadMu serialises reloads
+	dataPath     string
+	reloadMu     sync.Mutex
+	reloadStatus ReloadStatus
+	seedFiles    map[string]fileVersion
+
+	// stop ends the background loops, which are tracked by wg
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
 }
 
-// NewRepository creates a new repository and loads data from JSON files
-func NewRepository(dataPath string, logger *logrus.Logger) (*Repository, error) {
+// NewRepository creates a new repository and loads data from JSON files.
+// With WithJournal the latest snapshot replaces the seed files as the
+// starting point and the journal is replayed on top of it.
+func NewRepository(dataPath string, logger *logrus.Logger, opts ...Option) (*Repository, error) {
+	options := repositoryOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	repo := &Repository{
-		users:    make(map[string]*models.User),
-		vehicles: make(map[string]*models.Vehicle),
-		logger:   logger,
+		users:     make(map[string]*models.User),
+		vehicles:  make(map[string]*models.Vehicle),
+		favorites: make(map[string]*models.Favorite),
+		dealers:   make(map[string]*models.Dealer),
+		reviews:   make(map[string]*models.DealerReview),
+		posted:    make(map[string]bool),
+		edited:    make(map[string]bool),
+		logger:    logger,
+		dataPath:  dataPath,
+		stop:      make(chan struct{}),
+	}
+
+	loadPath := dataPath
+	var manifest *snapshotManifest
+	if options.journalDir != "" {
+		snapshotDir, m, err := latestSnapshot(options.journalDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshots: %w", err)
+		}
+		if snapshotDir != "" {
+			loadPath = snapshotDir
+			manifest = m
+		}
 	}
 
 	// Load users
-	if err := repo.loadUsers(filepath.Join(dataPath, "users.json")); err != nil {
+	if err := repo.loadUsers(filepath.Join(loadPath, "users.json")); err != nil {
 		return nil, fmt.Errorf("failed to load users: %w", err)
 	}
 
 	// Load vehicles
-	if err := repo.loadVehicles(filepath.Join(dataPath, "vehicles.json")); err != nil {
+	if err := repo.loadVehicles(filepath.Join(loadPath, "vehicles.json")); err != nil {
 		return nil, fmt.Errorf("failed to load vehicles: %w", err)
 	}
 
-	logger.Infof("Loaded %d users and %d vehicles from %s", len(repo.users), len(repo.vehicles), dataPath)
+	// Favorites only exist in snapshots, not in the seed files
+	if err := repo.loadFavorites(filepath.Join(loadPath, "favorites.json")); err != nil {
+		return nil, fmt.Errorf("failed to load favorites: %w", err)
+	}
+
+	// Dealers and their reviews are optional so older data directories
+	// still load
+	if err := repo.loadDealers(filepath.Join(loadPath, "dealers.json")); err != nil {
+		return nil, fmt.Errorf("failed to load dealers: %w", err)
+	}
+	if err := repo.loadReviews(filepath.Join(loadPath, "dealer_reviews.json")); err != nil {
+		return nil, fmt.Errorf("failed to load dealer reviews: %w", err)
+	}
+
+	logger.Infof("Loaded %d users and %d vehicles from %s", len(repo.users), len(repo.vehicles), loadPath)
+
+	if options.journalDir != "" {
+		if manifest != nil {
+			repo.snapshotSeq = manifest.Seq
+			if manifest.VehicleSeq > repo.vehicleSeq {
+				repo.vehicleSeq = manifest.VehicleSeq
+			}
+			for _, id := range manifest.EditedVehicles {
+				repo.edited[id] = true
+			}
+			for _, key := range manifest.PostedReviews {
+				repo.posted[key] = true
+			}
+		}
+		if err := repo.openJournal(options.journalDir, options.snapshotInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	repo.ratings = models.RateDealers(repo.reviewList())
+	for _, vehicle := range repo.vehicles {
+		geocodeVehicle(vehicle, nil)
+		rateVehicle(vehicle, repo.ratings)
+		flagInvalidVIN(vehicle, logger)
+	}
+	repo.index = newVehicleIndex(repo.vehicles)
+	repo.text = newTextIndex(repo.vehicles)
+
+	if options.watchInterval > 0 {
+		repo.seedFiles = statSeedFiles(dataPath)
+		repo.wg.Add(1)
+		go repo.watchLoop(options.watchInterval)
+	}
 
 	return repo, nil
 }
 
 // loadUsers loads users from a JSON file
 func (r *Repository) loadUsers(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
 	var users []*models.User
-	if err := json.Unmarshal(data, &users); err != nil {
+	if err := readJSONFile(filePath, &users); err != nil {
 		return err
 	}
 
@@ -67,13 +181,8 @@ func (r *Repository) loadUsers(filePath string) error {
 
 // loadVehicles loads vehicles from a JSON file
 func (r *Repository) loadVehicles(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
 	var vehicles []*models.Vehicle
-	if err := json.Unmarshal(data, &vehicles); err != nil {
+	if err := readJSONFile(filePath, &vehicles); err != nil {
 		return err
 	}
 
@@ -82,6 +191,70 @@ func (r *Repository) loadVehicles(filePath string) error {
 
 	for _, vehicle := range vehicles {
 		r.vehicles[vehicle.ID] = vehicle
+		if seq := vehicleIDSeq(vehicle.ID); seq > r.vehicleSeq {
+			r.vehicleSeq = seq
+		}
+	}
+
+	return nil
+}
+
+// loadFavorites loads favorites from a JSON file, if it exists
+func (r *Repository) loadFavorites(filePath string) error {
+	var favorites []*models.Favorite
+	if err := readJSONFile(filePath, &favorites); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, favorite := range favorites {
+		r.favorites[favoriteKey(favorite.UserID, favorite.VehicleID)] = favorite
+	}
+
+	return nil
+}
+
+// loadDealers loads dealers from a JSON file, if it exists
+func (r *Repository) loadDealers(filePath string) error {
+	var dealers []*models.Dealer
+	if err := readJSONFile(filePath, &dealers); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, dealer := range dealers {
+		dealer.Rating = nil
+		r.dealers[dealer.ID] = dealer
+	}
+
+	return nil
+}
+
+// loadReviews loads dealer reviews from a JSON file, if it exists
+func (r *Repository) loadReviews(filePath string) error {
+	var reviews []*models.DealerReview
+	if err := readJSONFile(filePath, &reviews); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, review := range reviews {
+		r.reviews[reviewKey(review.DealerID, review.UserID)] = review
 	}
 
 	return nil
@@ -94,7 +267,7 @@ func (r *Repository) GetUserByID(userID string) (*models.User, error) {
 
 	user, exists := r.users[userID]
 	if !exists {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	return user, nil
@@ -111,7 +284,7 @@ func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("user not found")
+	return nil, ErrUserNotFound
 }
 
 // GetAllUsers returns all users
@@ -134,10 +307,10 @@ func (r *Repository) GetVehicleByID(vehicleID string) (*models.Vehicle, error) {
 
 	vehicle, exists := r.vehicles[vehicleID]
 	if !exists {
-		return nil, fmt.Errorf("vehicle not found")
+		return nil, ErrVehicleNotFound
 	}
 
-	return vehicle, nil
+	return r.rated(vehicle), nil
 }
 
 // GetAllVehicles returns all vehicles
@@ -147,30 +320,317 @@ func (r *Repository) GetAllVehicles() []*models.Vehicle {
 
 	vehicles := make([]*models.Vehicle, 0, len(r.vehicles))
 	for _, vehicle := range r.vehicles {
-		vehicles = append(vehicles, vehicle)
+		vehicles = append(vehicles, r.rated(vehicle))
 	}
 
 	return vehicles
 }
 
-// SearchVehicles searches for vehicles based on filter criteria
+// SearchVehicles searches for vehicles based on filter criteria using the
+// secondary indexes
 func (r *Repository) SearchVehicles(filter *models.VehicleFilter) []*models.Vehicle {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	var results []*models.Vehicle
+	return r.index.search(filter, r.rated)
+}
 
-	for _, vehicle := range r.vehicles {
-		if r.matchesFilter(vehicle, filter) {
-			results = append(results, vehicle)
+// EachVehicle calls fn with every vehicle passing the filter, in ID order.
+// The matches are picked under the read lock, which is released before fn
+// is called, so a slow caller does not hold up writes.
+func (r *Repository) EachVehicle(ctx context.Context, filter *models.VehicleFilter, fn func(*models.Vehicle) error) error {
+	r.mu.RLock()
+	vehicles := r.index.search(filter, r.rated)
+	r.mu.RUnlock()
+
+	sort.Slice(vehicles, func(i, k int) bool { return vehicles[i].ID < vehicles[k].ID })
+	for _, vehicle := range vehicles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(vehicle); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	return results
+// SearchText ranks the vehicles passing the filter against a full-text query
+func (r *Repository) SearchText(query string, filter *models.VehicleFilter) []TextMatch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return matchText(r.text, query, filter, func(id string) *models.Vehicle {
+		if vehicle, exists := r.vehicles[id]; exists {
+			return r.rated(vehicle)
+		}
+		return nil
+	})
+}
+
+// CreateVehicle assigns a new ID to the vehicle and stores it
+func (r *Repository) CreateVehicle(vehicle *models.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.vinInUse(vehicle.VIN, "") {
+		return ErrDuplicateVIN
+	}
+
+	geocodeVehicle(vehicle, nil)
+	rateVehicle(vehicle, r.ratings)
+	id := formatVehicleID(r.vehicleSeq + 1)
+	stored := *vehicle
+	stored.ID = id
+	if err := r.record(opPut, entityVehicle, id, &stored); err != nil {
+		return err
+	}
+
+	r.vehicleSeq++
+	vehicle.ID = id
+	r.vehicles[id] = vehicle
+	r.edited[id] = true
+	r.index.put(vehicle)
+	r.text.Put(id, vehicleTextFields(vehicle))
+
+	return nil
+}
+
+// UpdateVehicle replaces the stored vehicle with the same ID
+func (r *Repository) UpdateVehicle(vehicle *models.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous, exists := r.vehicles[vehicle.ID]
+	if !exists {
+		return ErrVehicleNotFound
+	}
+	if r.vinInUse(vehicle.VIN, vehicle.ID) {
+		return ErrDuplicateVIN
+	}
+	geocodeVehicle(vehicle, previous)
+	rateVehicle(vehicle, r.ratings)
+	if err := r.record(opPut, entityVehicle, vehicle.ID, vehicle); err != nil {
+		return err
+	}
+
+	r.vehicles[vehicle.ID] = vehicle
+	r.edited[vehicle.ID] = true
+	r.index.put(vehicle)
+	r.text.Put(vehicle.ID, vehicleTextFields(vehicle))
+
+	return nil
+}
+
+// DeleteVehicle removes a vehicle by ID
+func (r *Repository) DeleteVehicle(vehicleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.vehicles[vehicleID]; !exists {
+		return ErrVehicleNotFound
+	}
+	if err := r.record(opDelete, entityVehicle, vehicleID, nil); err != nil {
+		return err
+	}
+
+	delete(r.vehicles, vehicleID)
+	r.edited[vehicleID] = true
+	r.index.remove(vehicleID)
+	r.text.Remove(vehicleID)
+
+	return nil
+}
+
+// SaveVehicles stores the vehicles in one all-or-nothing write. Those with
+// an ID replace the stored vehicle; the others are created and assigned new
+// IDs.
+func (r *Repository) SaveVehicles(vehicles []*models.Vehicle) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	owners := make(map[string]string, len(r.vehicles))
+	for id, vehicle := range r.vehicles {
+		owners[strings.ToUpper(vehicle.VIN)] = id
+	}
+	if err := checkVehicleBatch(vehicles, owners); err != nil {
+		return err
+	}
+
+	seq := r.vehicleSeq
+	stored := make([]*models.Vehicle, len(vehicles))
+	for i, vehicle := range vehicles {
+		geocodeVehicle(vehicle, r.vehicles[vehicle.ID])
+		rateVehicle(vehicle, r.ratings)
+		copied := *vehicle
+		if copied.ID == "" {
+			seq++
+			copied.ID = formatVehicleID(seq)
+		}
+		stored[i] = &copied
+	}
+	if err := r.record(opPutAll, entityVehicle, "", stored); err != nil {
+		return err
+	}
+
+	r.vehicleSeq = seq
+	for i, vehicle := range vehicles {
+		vehicle.ID = stored[i].ID
+		r.vehicles[vehicle.ID] = vehicle
+		r.edited[vehicle.ID] = true
+		r.index.put(vehicle)
+		r.text.Put(vehicle.ID, vehicleTextFields(vehicle))
+	}
+
+	return nil
+}
+
+// GetFavorites returns the user's favorites, most recently added first
+func (r *Repository) GetFavorites(userID string) []*models.Favorite {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	favorites := []*models.Favorite{}
+	for _, favorite := range r.favorites {
+		if favorite.UserID == userID {
+			favorites = append(favorites, favorite)
+		}
+	}
+	sortFavorites(favorites)
+
+	return favorites
+}
+
+// PutFavorite stores a favorite, replacing any the user has for the same
+// vehicle
+func (r *Repository) PutFavorite(favorite *models.Favorite) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey(favorite.UserID, favorite.VehicleID)
+	if err := r.record(opPut, entityFavorite, key, favorite); err != nil {
+		return err
+	}
+	stored := *favorite
+	r.favorites[key] = &stored
+
+	return nil
+}
+
+// DeleteFavorite removes a user's favorite
+func (r *Repository) DeleteFavorite(userID, vehicleID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey(userID, vehicleID)
+	if _, exists := r.favorites[key]; !exists {
+		return ErrFavoriteNotFound
+	}
+	if err := r.record(opDelete, entityFavorite, key, nil); err != nil {
+		return err
+	}
+	delete(r.favorites, key)
+
+	return nil
+}
+
+// GetDealers returns every dealer, ordered by ID, with its rating
+func (r *Repository) GetDealers() []*models.Dealer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dealers := make([]*models.Dealer, 0, len(r.dealers))
+	for _, dealer := range r.dealers {
+		dealers = append(dealers, withRating(dealer, r.ratings))
+	}
+	sortDealers(dealers)
+
+	return dealers
+}
+
+// GetDealerByID retrieves a dealer, with its rating, by ID
+func (r *Repository) GetDealerByID(dealerID string) (*models.Dealer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dealer, exists := r.dealers[dealerID]
+	if !exists {
+		return nil, ErrDealerNotFound
+	}
+
+	return withRating(dealer, r.ratings), nil
+}
+
+// GetDealerReviews returns the dealer's reviews, newest first
+func (r *Repository) GetDealerReviews(dealerID string) []*models.DealerReview {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reviews := []*models.DealerReview{}
+	for _, review := range r.reviews {
+		if review.DealerID == dealerID {
+			reviews = append(reviews, review)
+		}
+	}
+	sortReviews(reviews)
+
+	return reviews
+}
+
+// PutDealerReview stores a review, replacing any the user has for the same
+// dealer, and recomputes the dealer ratings
+func (r *Repository) PutDealerReview(review *models.DealerReview) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.dealers[review.DealerID]; !exists {
+		return ErrDealerNotFound
+	}
+
+	key := reviewKey(review.DealerID, review.UserID)
+	if err := r.record(opPut, entityDealerReview, key, review); err != nil {
+		return err
+	}
+	stored := *review
+	r.reviews[key] = &stored
+	r.posted[key] = true
+	r.rerate()
+
+	return nil
+}
+
+// reviewList returns the stored reviews. Callers must hold mu.
+func (r *Repository) reviewList() []*models.DealerReview {
+	reviews := make([]*models.DealerReview, 0, len(r.reviews))
+	for _, review := range r.reviews {
+		reviews = append(reviews, review)
+	}
+	return reviews
+}
+
+// rerate recomputes the dealer ratings. A review moves the mean all scores
+// are smoothed towards, so any dealer's score may change; stored vehicles
+// are left alone and pick up the new scores through rated. Callers must
+// hold mu.
+func (r *Repository) rerate() {
+	r.ratings = models.RateDealers(r.reviewList())
+}
+
+// rated returns the vehicle with its dealer's current rating. A stored
+// vehicle keeps the score it was written with, so it is only copied when
+// a later review changed that score. Callers must hold mu.
+func (r *Repository) rated(vehicle *models.Vehicle) *models.Vehicle {
+	return ratedVehicle(vehicle, r.ratings)
+}
+
+// vinInUse reports whether a vehicle other than excludeID has the VIN.
+// Callers must hold mu.
+func (r *Repository) vinInUse(vin, excludeID string) bool {
+	return r.index.vinInUse(vin, excludeID)
 }
 
 // matchesFilter checks if a vehicle matches the given filter
-func (r *Repository) matchesFilter(vehicle *models.Vehicle, filter *models.VehicleFilter) bool {
+func matchesFilter(vehicle *models.Vehicle, filter *models.VehicleFilter) bool {
 	if filter == nil {
 		return true
 	}
@@ -195,12 +655,18 @@ func (r *Repository) matchesFilter(vehicle *models.Vehicle, filter *models.Vehic
 		return false
 	}
 
-	// Price range filter
-	if filter.MinPrice > 0 && vehicle.Price < filter.MinPrice {
-		return false
-	}
-	if filter.MaxPrice > 0 && vehicle.Price > filter.MaxPrice {
-		return false
+	// Price range filter, in the filter's price currency
+	if filter.MinPrice > 0 || filter.MaxPrice > 0 {
+		price, ok := filter.PriceOf(vehicle)
+		if !ok {
+			return false
+		}
+		if filter.MinPrice > 0 && price < filter.MinPrice {
+			return false
+		}
+		if filter.MaxPrice > 0 && price > filter.MaxPrice {
+			return false
+		}
 	}
 
 	// Currency filter
@@ -250,5 +716,89 @@ func (r *Repository) matchesFilter(vehicle *models.Vehicle, filter *models.Vehic
 		}
 	}
 
+	// Mileage range filter
+	if filter.MinMileage > 0 && vehicle.Mileage < filter.MinMileage {
+		return false
+	}
+	if filter.MaxMileage > 0 && vehicle.Mileage > filter.MaxMileage {
+		return false
+	}
+
+	// Feature filters
+	for _, feature := range filter.Features {
+		if !containsFold(vehicle.Features, feature) {
+			return false
+		}
+	}
+	if len(filter.AnyFeatures) > 0 {
+		found := false
+		for _, feature := range filter.AnyFeatures {
+			if containsFold(vehicle.Features, feature) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	// Color filters
+	if len(filter.ExteriorColors) > 0 && !containsFold(filter.ExteriorColors, vehicle.ExteriorColor) {
+		return false
+	}
+	if len(filter.InteriorColors) > 0 && !containsFold(filter.InteriorColors, vehicle.InteriorColor) {
+		return false
+	}
+
+	// Dealer filters
+	if filter.DealerID != "" && !strings.EqualFold(vehicle.DealerID, filter.DealerID) {
+		return false
+	}
+	if filter.MinDealerRating > 0 && vehicle.DealerRating < filter.MinDealerRating {
+		return false
+	}
+
+	// Status filter
+	if len(filter.Statuses) > 0 && !containsFold(filter.Statuses, vehicle.Status) {
+		return false
+	}
+
+	// Listing date window
+	if filter.ListedAfter != nil && vehicle.ListingDate.Before(*filter.ListedAfter) {
+		return false
+	}
+	if filter.ListedBefore != nil && !vehicle.ListingDate.Before(*filter.ListedBefore) {
+		return false
+	}
+
+	// Price drop filter
+	if filter.FiltersPriceDrop() {
+		drop := filter.PriceDropOf(vehicle, time.Now())
+		if drop == nil || drop.Percent < filter.MinPriceDrop {
+			return false
+		}
+	}
+
+	// Radius filter; vehicles whose location could not be geocoded never match
+	if filter.Near != nil {
+		if vehicle.Coordinates == nil {
+			return false
+		}
+		if filter.RadiusKm > 0 && geo.DistanceKm(*filter.Near, *vehicle.Coordinates) > filter.RadiusKm {
+			return false
+		}
+	}
+
 	return true
 }
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,11 +1,18 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 )
 
 func TestNewRepository(t *testing.T) {
@@ -39,6 +46,34 @@ func TestNewRepository(t *testing.T) {
 	t.Logf("Loaded %d vehicles", len(vehicles))
 }
 
+func TestNewRepositoryFlagsInvalidVINs(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	repo, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	if len(repo.GetAllVehicles()) != 51 {
+		t.Error("Expected vehicles with invalid VINs to be loaded")
+	}
+
+	flagged := make(map[string]bool)
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "Seed vehicle has an invalid VIN" {
+			flagged[entry.Data["vehicle_id"].(string)] = true
+		}
+	}
+	// veh-005 is a Honda with a wrong check digit; veh-008 is a Subaru
+	// built in Japan, where the check digit is not mandatory
+	if !flagged["veh-005"] {
+		t.Error("Expected veh-005 to be flagged")
+	}
+	if flagged["veh-008"] {
+		t.Error("Expected veh-008 not to be flagged")
+	}
+}
+
 func TestGetUserByEmail(t *testing.T) {
 	logger := logrus.New()
 	logger.SetOutput(os.Stdout)
@@ -164,3 +199,453 @@ func TestGetVehicleByID(t *testing.T) {
 		t.Error("Expected error for non-existent vehicle")
 	}
 }
+
+func TestVehicleMutations(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	memory, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	sqlStore, err := NewSQLStore(filepath.Join(t.TempDir(), "inventory.db"), dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	defer sqlStore.Close()
+
+	stores := map[string]Store{
+		BackendMemory: memory,
+		BackendSQLite: sqlStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			count := len(store.GetAllVehicles())
+
+			vehicle := &models.Vehicle{
+				VIN:      "1HGCM82633A004352",
+				Year:     2021,
+				Make:     "Honda",
+				Model:    "Accord",
+				Price:    24000,
+				Currency: "USD",
+			}
+			if err := store.CreateVehicle(vehicle); err != nil {
+				t.Fatalf("Failed to create vehicle: %v", err)
+			}
+			if vehicle.ID != "veh-052" {
+				t.Errorf("Expected generated ID veh-052, got %s", vehicle.ID)
+			}
+			if got := len(store.GetAllVehicles()); got != count+1 {
+				t.Errorf("Expected %d vehicles, got %d", count+1, got)
+			}
+
+			for _, vin := range []string{vehicle.VIN, strings.ToLower(vehicle.VIN)} {
+				duplicate := &models.Vehicle{VIN: vin}
+				if err := store.CreateVehicle(duplicate); !errors.Is(err, ErrDuplicateVIN) {
+					t.Errorf("Expected ErrDuplicateVIN for %s, got %v", vin, err)
+				}
+			}
+
+			updated := vehicle.Clone()
+			updated.Price = 22500
+			if err := store.UpdateVehicle(updated); err != nil {
+				t.Fatalf("Failed to update vehicle: %v", err)
+			}
+			stored, err := store.GetVehicleByID(vehicle.ID)
+			if err != nil {
+				t.Fatalf("Failed to get vehicle: %v", err)
+			}
+			if stored.Price != 22500 {
+				t.Errorf("Expected price 22500, got %v", stored.Price)
+			}
+
+			if err := store.DeleteVehicle(vehicle.ID); err != nil {
+				t.Fatalf("Failed to delete vehicle: %v", err)
+			}
+			if _, err := store.GetVehicleByID(vehicle.ID); !errors.Is(err, ErrVehicleNotFound) {
+				t.Errorf("Expected ErrVehicleNotFound, got %v", err)
+			}
+			if err := store.DeleteVehicle(vehicle.ID); !errors.Is(err, ErrVehicleNotFound) {
+				t.Errorf("Expected ErrVehicleNotFound on second delete, got %v", err)
+			}
+
+			// IDs of deleted vehicles are not reused
+			next := &models.Vehicle{VIN: "1HGCM82633A004353"}
+			if err := store.CreateVehicle(next); err != nil {
+				t.Fatalf("Failed to create vehicle: %v", err)
+			}
+			if next.ID != "veh-053" {
+				t.Errorf("Expected generated ID veh-053, got %s", next.ID)
+			}
+
+			// The VIN of a deleted vehicle, or one changed away from, is free
+			next.VIN = vehicle.VIN
+			if err := store.UpdateVehicle(next); err != nil {
+				t.Fatalf("Expected the deleted vehicle's VIN to be free, got %v", err)
+			}
+			reused := &models.Vehicle{VIN: "1HGCM82633A004353"}
+			if err := store.CreateVehicle(reused); err != nil {
+				t.Errorf("Expected the replaced VIN to be free, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMatchesFilterExtendedFields(t *testing.T) {
+	listed := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	vehicle := &models.Vehicle{
+		Mileage:       42000,
+		Features:      []string{"Heated Seats", "Glass Roof", "AWD"},
+		ExteriorColor: "Pearl White",
+		InteriorColor: "Black",
+		DealerRating:  4.5,
+		Status:        "available",
+		ListingDate:   listed,
+	}
+
+	day := func(d int) *time.Time {
+		t := listed.AddDate(0, 0, d)
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		filter models.VehicleFilter
+		expect bool
+	}{
+		{name: "Mileage in range", filter: models.VehicleFilter{MinMileage: 40000, MaxMileage: 50000}, expect: true},
+		{name: "Mileage above max", filter: models.VehicleFilter{MaxMileage: 30000}, expect: false},
+		{name: "All features present", filter: models.VehicleFilter{Features: []string{"heated seats", "awd"}}, expect: true},
+		{name: "One feature missing", filter: models.VehicleFilter{Features: []string{"Heated Seats", "Sunroof"}}, expect: false},
+		{name: "Any feature present", filter: models.VehicleFilter{AnyFeatures: []string{"Sunroof", "glass roof"}}, expect: true},
+		{name: "No feature present", filter: models.VehicleFilter{AnyFeatures: []string{"Sunroof", "Tow Package"}}, expect: false},
+		{name: "Exterior color listed", filter: models.VehicleFilter{ExteriorColors: []string{"Red", "pearl white"}}, expect: true},
+		{name: "Interior color not listed", filter: models.VehicleFilter{InteriorColors: []string{"Tan"}}, expect: false},
+		{name: "Rating above minimum", filter: models.VehicleFilter{MinDealerRating: 4.5}, expect: true},
+		{name: "Rating below minimum", filter: models.VehicleFilter{MinDealerRating: 4.6}, expect: false},
+		{name: "Status in set", filter: models.VehicleFilter{Statuses: []string{"reserved", "Available"}}, expect: true},
+		{name: "Status not in set", filter: models.VehicleFilter{Statuses: []string{"sold"}}, expect: false},
+		{name: "Listed on the after bound", filter: models.VehicleFilter{ListedAfter: day(0)}, expect: true},
+		{name: "Listed before the after bound", filter: models.VehicleFilter{ListedAfter: day(1)}, expect: false},
+		{name: "Listed on the before bound", filter: models.VehicleFilter{ListedBefore: day(0)}, expect: false},
+		{name: "Inside the window", filter: models.VehicleFilter{ListedAfter: day(-7), ListedBefore: day(7)}, expect: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesFilter(vehicle, &tt.filter); got != tt.expect {
+				t.Errorf("Expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestFavoritesPersist(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dir := t.TempDir()
+
+	backends := []struct {
+		name string
+		open func() (Store, error)
+		// snapshot compacts the journal before reopening, when supported
+		snapshot bool
+	}{
+		{"memory journal", func() (Store, error) {
+			return NewRepository(dataPath, logger, WithJournal(filepath.Join(dir, "journal"), 0))
+		}, false},
+		{"memory snapshot", func() (Store, error) {
+			return NewRepository(dataPath, logger, WithJournal(filepath.Join(dir, "snapshot"), 0))
+		}, true},
+		{"sqlite", func() (Store, error) {
+			return NewSQLStore(filepath.Join(dir, "inventory.db"), dataPath, logger)
+		}, false},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			store, err := backend.open()
+			if err != nil {
+				t.Fatalf("Failed to open store: %v", err)
+			}
+
+			first := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+			for i, vehicleID := range []string{"veh-001", "veh-002", "veh-003"} {
+				favorite := &models.Favorite{
+					UserID:    "user-003",
+					VehicleID: vehicleID,
+					AddedAt:   first.Add(time.Duration(i) * time.Hour),
+					Price:     models.Money{Amount: 1000, Currency: "USD"},
+				}
+				if err := store.PutFavorite(favorite); err != nil {
+					t.Fatalf("PutFavorite failed: %v", err)
+				}
+			}
+			store.PutFavorite(&models.Favorite{UserID: "user-004", VehicleID: "veh-001", AddedAt: first})
+			if err := store.DeleteFavorite("user-003", "veh-002"); err != nil {
+				t.Fatalf("DeleteFavorite failed: %v", err)
+			}
+			if err := store.DeleteFavorite("user-003", "veh-002"); !errors.Is(err, ErrFavoriteNotFound) {
+				t.Errorf("Expected ErrFavoriteNotFound, got %v", err)
+			}
+			if backend.snapshot {
+				if err := store.(*Repository).Snapshot(); err != nil {
+					t.Fatalf("Snapshot failed: %v", err)
+				}
+			}
+			store.Close()
+
+			store, err = backend.open()
+			if err != nil {
+				t.Fatalf("Failed to reopen store: %v", err)
+			}
+			defer store.Close()
+
+			favorites := store.GetFavorites("user-003")
+			if len(favorites) != 2 || favorites[0].VehicleID != "veh-003" || favorites[1].VehicleID != "veh-001" {
+				t.Fatalf("Expected veh-003 and veh-001, newest first, got %+v", favorites)
+			}
+			if favorites[1].Price.Amount != 1000 || !favorites[1].AddedAt.Equal(first) {
+				t.Errorf("Unexpected favorite %+v", favorites[1])
+			}
+			if got := store.GetFavorites("user-004"); len(got) != 1 {
+				t.Errorf("Expected another user's favorite to be kept, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestDealerReviewsPersist(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dir := t.TempDir()
+
+	backends := []struct {
+		name     string
+		open     func() (Store, error)
+		snapshot bool
+	}{
+		{"memory journal", func() (Store, error) {
+			return NewRepository(dataPath, logger, WithJournal(filepath.Join(dir, "journal"), 0))
+		}, false},
+		{"memory snapshot", func() (Store, error) {
+			return NewRepository(dataPath, logger, WithJournal(filepath.Join(dir, "snapshot"), 0))
+		}, true},
+		{"sqlite", func() (Store, error) {
+			return NewSQLStore(filepath.Join(dir, "inventory.db"), dataPath, logger)
+		}, false},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			store, err := backend.open()
+			if err != nil {
+				t.Fatalf("Failed to open store: %v", err)
+			}
+
+			dealer, err := store.GetDealerByID("dlr-010")
+			if err != nil {
+				t.Fatalf("GetDealerByID failed: %v", err)
+			}
+			if dealer.Rating == nil || dealer.Rating.Count != 1 || dealer.Rating.Average != 3 {
+				t.Fatalf("Expected the seed review, got %+v", dealer.Rating)
+			}
+			other, _ := store.GetVehicleByID("veh-001")
+			before := other.DealerRating
+
+			at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+			for _, rating := range []int{4, 5} {
+				review := &models.DealerReview{DealerID: "dlr-010", UserID: "user-001", VehicleID: "veh-048", Rating: rating, CreatedAt: at}
+				if err := store.PutDealerReview(review); err != nil {
+					t.Fatalf("PutDealerReview failed: %v", err)
+				}
+			}
+			if err := store.PutDealerReview(&models.DealerReview{DealerID: "dlr-999", UserID: "user-001", Rating: 5}); !errors.Is(err, ErrDealerNotFound) {
+				t.Errorf("Expected ErrDealerNotFound, got %v", err)
+			}
+			dealer, _ = store.GetDealerByID("dlr-010")
+			rated := store.SearchVehicles(&models.VehicleFilter{DealerID: "dlr-010", MinDealerRating: dealer.Rating.Score})
+			if len(rated) == 0 || rated[0].DealerRating != dealer.Rating.Score {
+				t.Errorf("Expected the minimum rating filter to use the new score %v, got %+v", dealer.Rating.Score, rated)
+			}
+			if backend.snapshot {
+				if err := store.(*Repository).Snapshot(); err != nil {
+					t.Fatalf("Snapshot failed: %v", err)
+				}
+			}
+			store.Close()
+
+			store, err = backend.open()
+			if err != nil {
+				t.Fatalf("Failed to reopen store: %v", err)
+			}
+			defer store.Close()
+
+			reviews := store.GetDealerReviews("dlr-010")
+			if len(reviews) != 2 || reviews[0].UserID != "user-001" || reviews[0].Rating != 5 {
+				t.Fatalf("Expected the replaced review first, got %+v", reviews)
+			}
+			dealer, _ = store.GetDealerByID("dlr-010")
+			if dealer.Rating == nil || dealer.Rating.Count != 2 || dealer.Rating.Average != 4 {
+				t.Fatalf("Expected the rating to include the review, got %+v", dealer.Rating)
+			}
+			for _, vehicle := range store.SearchVehicles(&models.VehicleFilter{DealerID: "dlr-010"}) {
+				if vehicle.DealerRating != dealer.Rating.Score {
+					t.Errorf("Expected %s to show the score %v, got %v", vehicle.ID, dealer.Rating.Score, vehicle.DealerRating)
+				}
+			}
+			if other, _ := store.GetVehicleByID("veh-001"); other.DealerRating == before {
+				t.Errorf("Expected other dealers' scores to follow the new mean, still %v", before)
+			}
+		})
+	}
+}
+
+func TestSaveVehicles(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dir := t.TempDir()
+
+	backends := []struct {
+		name string
+		open func() (Store, error)
+	}{
+		{"memory", func() (Store, error) {
+			return NewRepository(dataPath, logger, WithJournal(filepath.Join(dir, "journal"), 0))
+		}},
+		{"sqlite", func() (Store, error) {
+			return NewSQLStore(filepath.Join(dir, "inventory.db"), dataPath, logger)
+		}},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			store, err := backend.open()
+			if err != nil {
+				t.Fatalf("Failed to open store: %v", err)
+			}
+			count := len(store.GetAllVehicles())
+
+			first, _ := store.GetVehicleByID("veh-001")
+			second, _ := store.GetVehicleByID("veh-002")
+
+			// Nothing is written when any vehicle of the batch is rejected
+			updated := first.Clone()
+			updated.Price = 1
+			clash := &models.Vehicle{VIN: second.VIN, Make: "Honda"}
+			if err := store.SaveVehicles([]*models.Vehicle{updated, clash}); !errors.Is(err, ErrDuplicateVIN) {
+				t.Fatalf("Expected ErrDuplicateVIN, got %v", err)
+			}
+			if err := store.SaveVehicles([]*models.Vehicle{{ID: "veh-999", VIN: "1HGCM82633A004352"}}); !errors.Is(err, ErrVehicleNotFound) {
+				t.Fatalf("Expected ErrVehicleNotFound, got %v", err)
+			}
+			if got, _ := store.GetVehicleByID("veh-001"); got.Price == 1 {
+				t.Fatal("Expected a rejected batch to leave the vehicles unchanged")
+			}
+
+			// Two listings may swap VINs within a batch
+			a, b := first.Clone(), second.Clone()
+			a.VIN, b.VIN = second.VIN, first.VIN
+			created := &models.Vehicle{VIN: "1HGCM82633A004352", Make: "Honda", Model: "Accord", Year: 2003}
+			if err := store.SaveVehicles([]*models.Vehicle{a, b, created}); err != nil {
+				t.Fatalf("SaveVehicles failed: %v", err)
+			}
+			if created.ID == "" || created.ID == "veh-001" {
+				t.Fatalf("Expected a new ID, got %q", created.ID)
+			}
+			store.Close()
+
+			store, err = backend.open()
+			if err != nil {
+				t.Fatalf("Failed to reopen store: %v", err)
+			}
+			defer store.Close()
+
+			if got := len(store.GetAllVehicles()); got != count+1 {
+				t.Errorf("Expected %d vehicles, got %d", count+1, got)
+			}
+			if got, _ := store.GetVehicleByID("veh-001"); got.VIN != second.VIN {
+				t.Errorf("Expected veh-001 to have VIN %s, got %s", second.VIN, got.VIN)
+			}
+			if got, err := store.GetVehicleByID(created.ID); err != nil || got.Model != "Accord" {
+				t.Errorf("Expected the created vehicle to persist, got %+v, %v", got, err)
+			}
+			found := false
+			for _, match := range store.SearchText("accord", &models.VehicleFilter{}) {
+				found = found || match.Vehicle.ID == created.ID
+			}
+			if !found {
+				t.Error("Expected the created vehicle to be searchable")
+			}
+		})
+	}
+}
+
+func TestEachVehicle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	memory, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	sqlStore, err := NewSQLStore(filepath.Join(t.TempDir(), "inventory.db"), dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	defer sqlStore.Close()
+
+	stores := map[string]Store{
+		BackendMemory: memory,
+		BackendSQLite: sqlStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			filter := &models.VehicleFilter{Country: "US"}
+			want := len(store.SearchVehicles(filter))
+
+			var ids []string
+			err := store.EachVehicle(context.Background(), filter, func(vehicle *models.Vehicle) error {
+				if vehicle.Country != "US" {
+					t.Errorf("Expected only US vehicles, got %s in %s", vehicle.ID, vehicle.Country)
+				}
+				ids = append(ids, vehicle.ID)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("EachVehicle failed: %v", err)
+			}
+			if len(ids) != want || !sort.StringsAreSorted(ids) {
+				t.Errorf("Expected %d vehicles in ID order, got %v", want, ids)
+			}
+
+			stop := errors.New("stop")
+			calls := 0
+			err = store.EachVehicle(context.Background(), nil, func(*models.Vehicle) error {
+				calls++
+				return stop
+			})
+			if !errors.Is(err, stop) || calls != 1 {
+				t.Errorf("Expected to stop at the first error, got %v after %d calls", err, calls)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err = store.EachVehicle(ctx, nil, func(*models.Vehicle) error {
+				t.Error("Expected no vehicles once cancelled")
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
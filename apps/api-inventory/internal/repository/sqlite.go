@@ -0,0 +1,982 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/search"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables used by SQLStore. Records are stored as
+// JSON documents keyed by ID so the schema follows the model types; columns
+// that are looked up directly are duplicated alongside the document.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id    TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	data  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+CREATE TABLE IF NOT EXISTS vehicles (
+	id   TEXT PRIMARY KEY,
+	vin  TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_vehicles_vin ON vehicles(vin);
+
+CREATE TABLE IF NOT EXISTS favorites (
+	user_id    TEXT NOT NULL,
+	vehicle_id TEXT NOT NULL,
+	data       TEXT NOT NULL,
+	PRIMARY KEY (user_id, vehicle_id)
+);
+
+CREATE TABLE IF NOT EXISTS dealers (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS dealer_reviews (
+	dealer_id TEXT NOT NULL,
+	user_id   TEXT NOT NULL,
+	data      TEXT NOT NULL,
+	PRIMARY KEY (dealer_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS sequences (
+	name  TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+`
+
+// SQLStore is the Store implementation backed by an embedded SQLite file
+type SQLStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+
+	// text is the full-text index over the vehicles, kept in process
+	text *search.Index
+	// writeMu serialises vehicle and review writes so the text index and
+	// the ratings see them in commit order
+	writeMu sync.Mutex
+	// ratings are the dealer ratings computed from the reviews table. They
+	// are applied to vehicles as they are read rather than stored.
+	ratings   map[string]models.DealerRating
+	ratingsMu sync.RWMutex
+}
+
+// NewSQLStore opens (or creates) the SQLite database at dbPath. Empty tables
+// are seeded from the JSON files in dataPath, so a fresh database starts with
+// the same data as the in-memory store.
+func NewSQLStore(dbPath, dataPath string, logger *logrus.Logger) (*SQLStore, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; serialise access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	store := &SQLStore{
+		db:     db,
+		logger: logger,
+	}
+
+	if dataPath != "" {
+		if err := store.seed(dataPath); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if err := store.initVehicleSeq(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialise vehicle IDs: %w", err)
+	}
+
+	if err := store.loadRatings(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store.text = search.NewIndex()
+	for _, vehicle := range store.GetAllVehicles() {
+		store.text.Put(vehicle.ID, vehicleTextFields(vehicle))
+	}
+
+	logger.Infof("Opened SQLite store at %s", dbPath)
+
+	return store, nil
+}
+
+// Close closes the underlying database
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// seed loads the JSON seed files into any table that is still empty
+func (s *SQLStore) seed(dataPath string) error {
+	empty, err := s.isEmpty("users")
+	if err != nil {
+		return err
+	}
+	if empty {
+		var users []*models.User
+		if err := readJSONFile(filepath.Join(dataPath, "users.json"), &users); err != nil {
+			return fmt.Errorf("failed to load users: %w", err)
+		}
+		if err := s.insertUsers(users); err != nil {
+			return fmt.Errorf("failed to seed users: %w", err)
+		}
+		s.logger.Infof("Seeded %d users from %s", len(users), dataPath)
+	}
+
+	empty, err = s.isEmpty("vehicles")
+	if err != nil {
+		return err
+	}
+	if empty {
+		var vehicles []*models.Vehicle
+		if err := readJSONFile(filepath.Join(dataPath, "vehicles.json"), &vehicles); err != nil {
+			return fmt.Errorf("failed to load vehicles: %w", err)
+		}
+		for _, vehicle := range vehicles {
+			flagInvalidVIN(vehicle, s.logger)
+		}
+		if err := s.insertVehicles(vehicles); err != nil {
+			return fmt.Errorf("failed to seed vehicles: %w", err)
+		}
+		s.logger.Infof("Seeded %d vehicles from %s", len(vehicles), dataPath)
+	}
+
+	// Dealers and their reviews are optional so older data directories
+	// still seed
+	empty, err = s.isEmpty("dealers")
+	if err != nil {
+		return err
+	}
+	if empty {
+		var dealers []*models.Dealer
+		if err := readJSONFile(filepath.Join(dataPath, "dealers.json"), &dealers); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load dealers: %w", err)
+		}
+		if err := s.insertDealers(dealers); err != nil {
+			return fmt.Errorf("failed to seed dealers: %w", err)
+		}
+		s.logger.Infof("Seeded %d dealers from %s", len(dealers), dataPath)
+	}
+
+	empty, err = s.isEmpty("dealer_reviews")
+	if err != nil {
+		return err
+	}
+	if empty {
+		var reviews []*models.DealerReview
+		if err := readJSONFile(filepath.Join(dataPath, "dealer_reviews.json"), &reviews); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load dealer reviews: %w", err)
+		}
+		for _, review := range reviews {
+			if err := s.insertReview(review); err != nil {
+				return fmt.Errorf("failed to seed dealer reviews: %w", err)
+			}
+		}
+		s.logger.Infof("Seeded %d dealer reviews from %s", len(reviews), dataPath)
+	}
+
+	return nil
+}
+
+// isEmpty reports whether a table has no rows
+func (s *SQLStore) isEmpty(table string) (bool, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	return count == 0, nil
+}
+
+// insertUsers upserts users in a single transaction
+func (s *SQLStore) insertUsers(users []*models.User) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		data, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO users (id, email, data) VALUES (?, ?, ?)",
+			user.ID, user.Email, string(data),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertVehicles upserts vehicles in a single transaction
+func (s *SQLStore) insertVehicles(vehicles []*models.Vehicle) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seq := 0
+	for _, vehicle := range vehicles {
+		geocodeVehicle(vehicle, nil)
+		data, err := json.Marshal(vehicle)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO vehicles (id, vin, data) VALUES (?, ?, ?)",
+			vehicle.ID, vehicle.VIN, string(data),
+		); err != nil {
+			return err
+		}
+		if n := vehicleIDSeq(vehicle.ID); n > seq {
+			seq = n
+		}
+	}
+	if err := raiseVehicleSeqTx(tx, seq); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertDealers upserts dealers in a single transaction
+func (s *SQLStore) insertDealers(dealers []*models.Dealer) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, dealer := range dealers {
+		stored := *dealer
+		stored.Rating = nil
+		data, err := json.Marshal(&stored)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO dealers (id, data) VALUES (?, ?)",
+			stored.ID, string(data),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertReview upserts a dealer review
+func (s *SQLStore) insertReview(review *models.DealerReview) error {
+	data, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		"INSERT OR REPLACE INTO dealer_reviews (dealer_id, user_id, data) VALUES (?, ?, ?)",
+		review.DealerID, review.UserID, string(data),
+	)
+	return err
+}
+
+// queryUser returns the single user matched by the query
+func (s *SQLStore) queryUser(query string, args ...interface{}) (*models.User, error) {
+	var data string
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := json.Unmarshal([]byte(data), &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by ID
+func (s *SQLStore) GetUserByID(userID string) (*models.User, error) {
+	return s.queryUser("SELECT data FROM users WHERE id = ?", userID)
+}
+
+// GetUserByEmail retrieves a user by email address
+func (s *SQLStore) GetUserByEmail(email string) (*models.User, error) {
+	return s.queryUser("SELECT data FROM users WHERE email = ?", email)
+}
+
+// GetAllUsers returns all users
+func (s *SQLStore) GetAllUsers() []*models.User {
+	rows, err := s.db.Query("SELECT data FROM users ORDER BY id")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query users")
+		return []*models.User{}
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			s.logger.WithError(err).Error("Failed to scan user")
+			continue
+		}
+		var user models.User
+		if err := json.Unmarshal([]byte(data), &user); err != nil {
+			s.logger.WithError(err).Error("Failed to decode user")
+			continue
+		}
+		users = append(users, &user)
+	}
+
+	return users
+}
+
+// GetVehicleByID retrieves a vehicle by ID
+func (s *SQLStore) GetVehicleByID(vehicleID string) (*models.Vehicle, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM vehicles WHERE id = ?", vehicleID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVehicleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicle models.Vehicle
+	if err := json.Unmarshal([]byte(data), &vehicle); err != nil {
+		return nil, err
+	}
+	// Rows written before coordinates were stored
+	if vehicle.Coordinates == nil {
+		geocodeVehicle(&vehicle, nil)
+	}
+	s.rateVehicle(&vehicle)
+
+	return &vehicle, nil
+}
+
+// GetAllVehicles returns all vehicles
+func (s *SQLStore) GetAllVehicles() []*models.Vehicle {
+	rows, err := s.db.Query("SELECT data FROM vehicles ORDER BY id")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query vehicles")
+		return []*models.Vehicle{}
+	}
+	defer rows.Close()
+
+	vehicles := []*models.Vehicle{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			s.logger.WithError(err).Error("Failed to scan vehicle")
+			continue
+		}
+		var vehicle models.Vehicle
+		if err := json.Unmarshal([]byte(data), &vehicle); err != nil {
+			s.logger.WithError(err).Error("Failed to decode vehicle")
+			continue
+		}
+		if vehicle.Coordinates == nil {
+			geocodeVehicle(&vehicle, nil)
+		}
+		s.rateVehicle(&vehicle)
+		vehicles = append(vehicles, &vehicle)
+	}
+
+	return vehicles
+}
+
+// SearchVehicles searches for vehicles based on filter criteria. Filtering is
+// done with matchesFilter so results are identical to the in-memory store.
+func (s *SQLStore) SearchVehicles(filter *models.VehicleFilter) []*models.Vehicle {
+	var results []*models.Vehicle
+
+	for _, vehicle := range s.GetAllVehicles() {
+		if matchesFilter(vehicle, filter) {
+			results = append(results, vehicle)
+		}
+	}
+
+	return results
+}
+
+// eachVehicleBatch is how many vehicles EachVehicle reads per query
+const eachVehicleBatch = 200
+
+// EachVehicle calls fn with every vehicle passing the filter, in ID order.
+// Vehicles are read in batches, releasing the connection in between, so a
+// slow caller does not hold up other queries.
+func (s *SQLStore) EachVehicle(ctx context.Context, filter *models.VehicleFilter, fn func(*models.Vehicle) error) error {
+	after := ""
+	for {
+		rows, err := s.db.QueryContext(ctx,
+			"SELECT id, data FROM vehicles WHERE id > ? ORDER BY id LIMIT ?", after, eachVehicleBatch)
+		if err != nil {
+			return err
+		}
+
+		var batch []*models.Vehicle
+		read := 0
+		for rows.Next() {
+			var data string
+			if err := rows.Scan(&after, &data); err != nil {
+				rows.Close()
+				return err
+			}
+			read++
+			var vehicle models.Vehicle
+			if err := json.Unmarshal([]byte(data), &vehicle); err != nil {
+				s.logger.WithError(err).WithField("vehicle_id", after).Error("Failed to decode vehicle")
+				continue
+			}
+			if vehicle.Coordinates == nil {
+				geocodeVehicle(&vehicle, nil)
+			}
+			s.rateVehicle(&vehicle)
+			if matchesFilter(&vehicle, filter) {
+				batch = append(batch, &vehicle)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, vehicle := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(vehicle); err != nil {
+				return err
+			}
+		}
+		if read < eachVehicleBatch {
+			return nil
+		}
+	}
+}
+
+// SearchText ranks the vehicles passing the filter against a full-text query
+func (s *SQLStore) SearchText(query string, filter *models.VehicleFilter) []TextMatch {
+	vehicles := make(map[string]*models.Vehicle)
+	for _, vehicle := range s.GetAllVehicles() {
+		vehicles[vehicle.ID] = vehicle
+	}
+
+	return matchText(s.text, query, filter, func(id string) *models.Vehicle {
+		return vehicles[id]
+	})
+}
+
+// CreateVehicle assigns a new ID to the vehicle and stores it
+func (s *SQLStore) CreateVehicle(vehicle *models.Vehicle) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if inUse, err := vinInUseTx(tx, vehicle.VIN, ""); err != nil {
+		return err
+	} else if inUse {
+		return ErrDuplicateVIN
+	}
+
+	seq, err := nextVehicleSeqTx(tx)
+	if err != nil {
+		return err
+	}
+
+	geocodeVehicle(vehicle, nil)
+	s.rateVehicle(vehicle)
+	stored := *vehicle
+	stored.ID = formatVehicleID(seq)
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO vehicles (id, vin, data) VALUES (?, ?, ?)",
+		stored.ID, stored.VIN, string(data),
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	vehicle.ID = stored.ID
+	s.text.Put(stored.ID, vehicleTextFields(&stored))
+	return nil
+}
+
+// UpdateVehicle replaces the stored vehicle with the same ID
+func (s *SQLStore) UpdateVehicle(vehicle *models.Vehicle) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if inUse, err := vinInUseTx(tx, vehicle.VIN, vehicle.ID); err != nil {
+		return err
+	} else if inUse {
+		return ErrDuplicateVIN
+	}
+
+	previous, err := storedVehicleTx(tx, vehicle.ID)
+	if err != nil {
+		return err
+	}
+	geocodeVehicle(vehicle, previous)
+	s.rateVehicle(vehicle)
+	data, err := json.Marshal(vehicle)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec(
+		"UPDATE vehicles SET vin = ?, data = ? WHERE id = ?",
+		vehicle.VIN, string(data), vehicle.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrVehicleNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.text.Put(vehicle.ID, vehicleTextFields(vehicle))
+	return nil
+}
+
+// DeleteVehicle removes a vehicle by ID
+func (s *SQLStore) DeleteVehicle(vehicleID string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	result, err := s.db.Exec("DELETE FROM vehicles WHERE id = ?", vehicleID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrVehicleNotFound
+	}
+
+	s.text.Remove(vehicleID)
+	return nil
+}
+
+// SaveVehicles stores the vehicles in one transaction. Those with an ID
+// replace the stored vehicle; the others are created and assigned new IDs.
+func (s *SQLStore) SaveVehicles(vehicles []*models.Vehicle) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, vin FROM vehicles")
+	if err != nil {
+		return err
+	}
+	owners := make(map[string]string)
+	for rows.Next() {
+		var id, vin string
+		if err := rows.Scan(&id, &vin); err != nil {
+			rows.Close()
+			return err
+		}
+		owners[strings.ToUpper(vin)] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := checkVehicleBatch(vehicles, owners); err != nil {
+		return err
+	}
+
+	stored := make([]*models.Vehicle, len(vehicles))
+	for i, vehicle := range vehicles {
+		var previous *models.Vehicle
+		if vehicle.ID != "" {
+			if previous, err = storedVehicleTx(tx, vehicle.ID); err != nil {
+				return err
+			}
+		}
+		geocodeVehicle(vehicle, previous)
+		s.rateVehicle(vehicle)
+		copied := *vehicle
+		query := "UPDATE vehicles SET vin = ?, data = ? WHERE id = ?"
+		if copied.ID == "" {
+			seq, err := nextVehicleSeqTx(tx)
+			if err != nil {
+				return err
+			}
+			copied.ID = formatVehicleID(seq)
+			query = "INSERT INTO vehicles (vin, data, id) VALUES (?, ?, ?)"
+		}
+		data, err := json.Marshal(&copied)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, copied.VIN, string(data), copied.ID); err != nil {
+			return err
+		}
+		stored[i] = &copied
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, vehicle := range vehicles {
+		vehicle.ID = stored[i].ID
+		s.text.Put(vehicle.ID, vehicleTextFields(vehicle))
+	}
+	return nil
+}
+
+// GetFavorites returns the user's favorites, most recently added first
+func (s *SQLStore) GetFavorites(userID string) []*models.Favorite {
+	rows, err := s.db.Query("SELECT data FROM favorites WHERE user_id = ?", userID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query favorites")
+		return []*models.Favorite{}
+	}
+	defer rows.Close()
+
+	favorites := []*models.Favorite{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			s.logger.WithError(err).Error("Failed to scan favorite")
+			continue
+		}
+		var favorite models.Favorite
+		if err := json.Unmarshal([]byte(data), &favorite); err != nil {
+			s.logger.WithError(err).Error("Failed to decode favorite")
+			continue
+		}
+		favorites = append(favorites, &favorite)
+	}
+	sortFavorites(favorites)
+
+	return favorites
+}
+
+// PutFavorite stores a favorite, replacing any the user has for the same
+// vehicle
+func (s *SQLStore) PutFavorite(favorite *models.Favorite) error {
+	data, err := json.Marshal(favorite)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(
+		"INSERT OR REPLACE INTO favorites (user_id, vehicle_id, data) VALUES (?, ?, ?)",
+		favorite.UserID, favorite.VehicleID, string(data),
+	)
+	return err
+}
+
+// DeleteFavorite removes a user's favorite
+func (s *SQLStore) DeleteFavorite(userID, vehicleID string) error {
+	result, err := s.db.Exec("DELETE FROM favorites WHERE user_id = ? AND vehicle_id = ?", userID, vehicleID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrFavoriteNotFound
+	}
+	return nil
+}
+
+// GetDealers returns every dealer, ordered by ID, with its rating
+func (s *SQLStore) GetDealers() []*models.Dealer {
+	rows, err := s.db.Query("SELECT data FROM dealers ORDER BY id")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query dealers")
+		return []*models.Dealer{}
+	}
+	defer rows.Close()
+
+	ratings := s.currentRatings()
+	dealers := []*models.Dealer{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			s.logger.WithError(err).Error("Failed to scan dealer")
+			continue
+		}
+		var dealer models.Dealer
+		if err := json.Unmarshal([]byte(data), &dealer); err != nil {
+			s.logger.WithError(err).Error("Failed to decode dealer")
+			continue
+		}
+		dealers = append(dealers, withRating(&dealer, ratings))
+	}
+
+	return dealers
+}
+
+// GetDealerByID retrieves a dealer, with its rating, by ID
+func (s *SQLStore) GetDealerByID(dealerID string) (*models.Dealer, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM dealers WHERE id = ?", dealerID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDealerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dealer models.Dealer
+	if err := json.Unmarshal([]byte(data), &dealer); err != nil {
+		return nil, err
+	}
+
+	return withRating(&dealer, s.currentRatings()), nil
+}
+
+// GetDealerReviews returns the dealer's reviews, newest first
+func (s *SQLStore) GetDealerReviews(dealerID string) []*models.DealerReview {
+	return s.queryReviews("SELECT data FROM dealer_reviews WHERE dealer_id = ?", dealerID)
+}
+
+// PutDealerReview stores a review, replacing any the user has for the same
+// dealer, and recomputes the dealer ratings
+func (s *SQLStore) PutDealerReview(review *models.DealerReview) error {
+	if _, err := s.GetDealerByID(review.DealerID); err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if err := s.insertReview(review); err != nil {
+		return err
+	}
+	return s.loadRatings()
+}
+
+// queryReviews returns the reviews matched by the query, newest first
+func (s *SQLStore) queryReviews(query string, args ...interface{}) []*models.DealerReview {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query dealer reviews")
+		return []*models.DealerReview{}
+	}
+	defer rows.Close()
+
+	reviews := []*models.DealerReview{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			s.logger.WithError(err).Error("Failed to scan dealer review")
+			continue
+		}
+		var review models.DealerReview
+		if err := json.Unmarshal([]byte(data), &review); err != nil {
+			s.logger.WithError(err).Error("Failed to decode dealer review")
+			continue
+		}
+		reviews = append(reviews, &review)
+	}
+	sortReviews(reviews)
+
+	return reviews
+}
+
+// loadRatings recomputes the dealer ratings from every stored review
+func (s *SQLStore) loadRatings() error {
+	rows, err := s.db.Query("SELECT data FROM dealer_reviews")
+	if err != nil {
+		return fmt.Errorf("failed to query dealer reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []*models.DealerReview
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("failed to scan dealer review: %w", err)
+		}
+		var review models.DealerReview
+		if err := json.Unmarshal([]byte(data), &review); err != nil {
+			return fmt.Errorf("failed to decode dealer review: %w", err)
+		}
+		reviews = append(reviews, &review)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ratings := models.RateDealers(reviews)
+	s.ratingsMu.Lock()
+	s.ratings = ratings
+	s.ratingsMu.Unlock()
+
+	return nil
+}
+
+// currentRatings returns the dealer ratings. The map is replaced, never
+// modified, so it can be read without the lock.
+func (s *SQLStore) currentRatings() map[string]models.DealerRating {
+	s.ratingsMu.RLock()
+	defer s.ratingsMu.RUnlock()
+
+	return s.ratings
+}
+
+// rateVehicle sets the dealer rating of a vehicle read or written
+func (s *SQLStore) rateVehicle(vehicle *models.Vehicle) {
+	rateVehicle(vehicle, s.currentRatings())
+}
+
+// vinInUseTx reports whether a vehicle other than excludeID has the VIN
+func vinInUseTx(tx *sql.Tx, vin, excludeID string) (bool, error) {
+	var count int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM vehicles WHERE vin = ? COLLATE NOCASE AND id != ?",
+		vin, excludeID,
+	).Scan(&count)
+	return count > 0, err
+}
+
+// storedVehicleTx reads the stored version of a vehicle within a transaction
+func storedVehicleTx(tx *sql.Tx, vehicleID string) (*models.Vehicle, error) {
+	var data string
+	err := tx.QueryRow("SELECT data FROM vehicles WHERE id = ?", vehicleID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVehicleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicle models.Vehicle
+	if err := json.Unmarshal([]byte(data), &vehicle); err != nil {
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// nextVehicleSeqTx reserves the next vehicle ID sequence number. The counter
+// is kept in the sequences table so IDs of deleted vehicles are never reused.
+func nextVehicleSeqTx(tx *sql.Tx) (int, error) {
+	var seq int
+	err := tx.QueryRow("SELECT value FROM sequences WHERE name = 'vehicles'").Scan(&seq)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	seq++
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO sequences (name, value) VALUES ('vehicles', ?)", seq,
+	); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// raiseVehicleSeqTx moves the vehicle sequence up to at least seq, for
+// vehicles inserted with their own IDs
+func raiseVehicleSeqTx(tx *sql.Tx, seq int) error {
+	_, err := tx.Exec(`
+		INSERT INTO sequences (name, value) VALUES ('vehicles', ?)
+		ON CONFLICT (name) DO UPDATE SET value = MAX(value, excluded.value)`, seq)
+	return err
+}
+
+// initVehicleSeq starts the vehicle sequence after the highest stored ID
+// when the database has none yet, as those written before the sequence was
+// maintained on seeding do not
+func (s *SQLStore) initVehicleSeq() error {
+	var seq int
+	err := s.db.QueryRow("SELECT value FROM sequences WHERE name = 'vehicles'").Scan(&seq)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	rows, err := s.db.Query("SELECT id FROM vehicles")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if n := vehicleIDSeq(id); n > seq {
+			seq = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := raiseVehicleSeqTx(tx, seq); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
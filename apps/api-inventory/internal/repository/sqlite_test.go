@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSQLStoreMatchesMemoryStore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	memory, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	store, err := NewSQLStore(filepath.Join(t.TempDir(), "inventory.db"), dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	defer store.Close()
+
+	if got, want := len(store.GetAllUsers()), len(memory.GetAllUsers()); got != want {
+		t.Errorf("Expected %d users, got %d", want, got)
+	}
+	if got, want := len(store.GetAllVehicles()), len(memory.GetAllVehicles()); got != want {
+		t.Errorf("Expected %d vehicles, got %d", want, got)
+	}
+
+	user, err := store.GetUserByEmail("demo@autostack.com")
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if _, err := store.GetUserByID(user.ID); err != nil {
+		t.Errorf("Failed to get user by ID: %v", err)
+	}
+	if _, err := store.GetUserByEmail("nonexistent@autostack.com"); err == nil {
+		t.Error("Expected error for non-existent user")
+	}
+
+	vehicle, err := store.GetVehicleByID("veh-001")
+	if err != nil {
+		t.Fatalf("Failed to get vehicle: %v", err)
+	}
+	if vehicle.Make != "Tesla" {
+		t.Errorf("Expected make Tesla, got %s", vehicle.Make)
+	}
+	if _, err := store.GetVehicleByID("nonexistent-id"); err == nil {
+		t.Error("Expected error for non-existent vehicle")
+	}
+
+	filter := &models.VehicleFilter{Type: "suv", Currency: "GBP"}
+	if got, want := len(store.SearchVehicles(filter)), len(memory.SearchVehicles(filter)); got != want {
+		t.Errorf("Expected %d search results, got %d", want, got)
+	}
+}
+
+func TestSQLStoreSeedsOnlyOnce(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dbPath := filepath.Join(t.TempDir(), "inventory.db")
+
+	store, err := NewSQLStore(dbPath, dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	count := len(store.GetAllVehicles())
+	store.Close()
+
+	// Reopening without seed data keeps the persisted rows
+	store, err = NewSQLStore(dbPath, "", logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen SQL store: %v", err)
+	}
+	defer store.Close()
+
+	if got := len(store.GetAllVehicles()); got != count {
+		t.Errorf("Expected %d persisted vehicles, got %d", count, got)
+	}
+}
+
+func TestSQLStoreStartsSequenceAfterStoredIDs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dbPath := filepath.Join(t.TempDir(), "inventory.db")
+
+	store, err := NewSQLStore(dbPath, dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	// A database written before seeding maintained the sequence
+	if _, err := store.db.Exec("DELETE FROM sequences"); err != nil {
+		t.Fatalf("Failed to clear sequences: %v", err)
+	}
+	store.Close()
+
+	store, err = NewSQLStore(dbPath, "", logger)
+	if err != nil {
+		t.Fatalf("Failed to reopen SQL store: %v", err)
+	}
+	defer store.Close()
+
+	vehicle := &models.Vehicle{VIN: "1HGCM82633A004352", Year: 2003, Make: "Honda", Model: "Accord", Currency: "USD"}
+	if err := store.CreateVehicle(vehicle); err != nil {
+		t.Fatalf("CreateVehicle failed: %v", err)
+	}
+	if vehicle.ID != "veh-052" {
+		t.Errorf("Expected generated ID veh-052, got %s", vehicle.ID)
+	}
+}
+
+func TestNewStoreUnknownBackend(t *testing.T) {
+	logger := logrus.New()
+
+	if _, err := NewStore(Config{Backend: "postgres"}, logger); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/geo"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Storage backends supported by NewStore
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+)
+
+var (
+	// ErrUserNotFound is returned when no user matches the lookup
+	ErrUserNotFound = errors.New("user not found")
+	// ErrVehicleNotFound is returned when no vehicle matches the lookup
+	ErrVehicleNotFound = errors.New("vehicle not found")
+	// ErrDuplicateVIN is returned when a write would reuse another listing's VIN
+	ErrDuplicateVIN = errors.New("a vehicle with this VIN already exists")
+	// ErrFavoriteNotFound is returned when the user has not favorited the
+	// vehicle
+	ErrFavoriteNotFound = errors.New("favorite not found")
+	// ErrDealerNotFound is returned when no dealer matches the lookup
+	ErrDealerNotFound = errors.New("dealer not found")
+)
+
+// Store provides data access for users and vehicles. Handlers depend on this
+// interface rather than a concrete backend.
+type Store interface {
+	GetUserByID(userID string) (*models.User, error)
+	GetUserByEmail(email string) (*models.User, error)
+	GetAllUsers() []*models.User
+	GetVehicleByID(vehicleID string) (*models.Vehicle, error)
+	GetAllVehicles() []*models.Vehicle
+	SearchVehicles(filter *models.VehicleFilter) []*models.Vehicle
+	// SearchText ranks the vehicles passing the filter against a full-text
+	// query, best match first
+	SearchText(query string, filter *models.VehicleFilter) []TextMatch
+	// EachVehicle calls fn with every vehicle passing the filter, in ID
+	// order, without collecting them all first. It stops at the first error
+	// from fn or when ctx is done, and returns that error.
+	EachVehicle(ctx context.Context, filter *models.VehicleFilter, fn func(*models.Vehicle) error) error
+
+	// CreateVehicle assigns a new ID to the vehicle and stores it
+	CreateVehicle(vehicle *models.Vehicle) error
+	// UpdateVehicle replaces the stored vehicle with the same ID
+	UpdateVehicle(vehicle *models.Vehicle) error
+	// DeleteVehicle removes a vehicle by ID
+	DeleteVehicle(vehicleID string) error
+	// SaveVehicles stores the vehicles in one all-or-nothing write. Those
+	// with an ID replace the stored vehicle; the others are created and
+	// assigned new IDs.
+	SaveVehicles(vehicles []*models.Vehicle) error
+
+	// GetFavorites returns the user's favorites, most recently added first.
+	// Favorites outlive the vehicles they refer to.
+	GetFavorites(userID string) []*models.Favorite
+	// PutFavorite stores a favorite, replacing any the user has for the
+	// same vehicle
+	PutFavorite(favorite *models.Favorite) error
+	// DeleteFavorite removes a user's favorite
+	DeleteFavorite(userID, vehicleID string) error
+
+	// GetDealers returns every dealer, ordered by ID, with its rating
+	GetDealers() []*models.Dealer
+	// GetDealerByID retrieves a dealer, with its rating, by ID
+	GetDealerByID(dealerID string) (*models.Dealer, error)
+	// GetDealerReviews returns the dealer's reviews, newest first
+	GetDealerReviews(dealerID string) []*models.DealerReview
+	// PutDealerReview stores a review, replacing any the user has for the
+	// same dealer, and recomputes the dealer ratings of every vehicle.
+	// Whether the user may review the dealer is up to the caller.
+	PutDealerReview(review *models.DealerReview) error
+
+	// Close releases files and connections held by the store
+	Close() error
+}
+
+// Config selects and configures the storage backend
+type Config struct {
+	// Backend is either BackendMemory (default) or BackendSQLite
+	Backend string
+	// DataPath is the directory holding the JSON seed files
+	DataPath string
+	// SQLitePath is the database file used by the SQLite backend
+	SQLitePath string
+	// JournalDir enables the write-ahead journal and snapshots of the
+	// memory backend when set
+	JournalDir string
+	// SnapshotInterval is how often the memory backend compacts its journal
+	SnapshotInterval time.Duration
+	// WatchInterval is how often the memory backend checks the seed files
+	// for changes to reload (0 disables watching)
+	WatchInterval time.Duration
+}
+
+var (
+	_ Store = (*Repository)(nil)
+	_ Store = (*SQLStore)(nil)
+)
+
+// NewStore creates the store selected by the configuration
+func NewStore(cfg Config, logger *logrus.Logger) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendMemory:
+		var opts []Option
+		if cfg.JournalDir != "" {
+			opts = append(opts, WithJournal(cfg.JournalDir, cfg.SnapshotInterval))
+		}
+		if cfg.WatchInterval > 0 {
+			opts = append(opts, WithWatch(cfg.WatchInterval))
+		}
+		return NewRepository(cfg.DataPath, logger, opts...)
+	case BackendSQLite:
+		return NewSQLStore(cfg.SQLitePath, cfg.DataPath, logger)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// readJSONFile decodes a JSON seed file into v
+func readJSONFile(filePath string, v interface{}) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// checkVehicleBatch checks that every vehicle of a batch with an ID is
+// stored and that no two vehicles would share a VIN once the batch is
+// written. owners maps the upper-case VIN of every stored vehicle to its ID.
+func checkVehicleBatch(vehicles []*models.Vehicle, owners map[string]string) error {
+	stored := make(map[string]bool, len(owners))
+	for _, id := range owners {
+		stored[id] = true
+	}
+	replaced := make(map[string]bool)
+	for _, vehicle := range vehicles {
+		if vehicle.ID == "" {
+			continue
+		}
+		if !stored[vehicle.ID] || replaced[vehicle.ID] {
+			return fmt.Errorf("%w: %s", ErrVehicleNotFound, vehicle.ID)
+		}
+		replaced[vehicle.ID] = true
+	}
+
+	vins := make(map[string]bool, len(vehicles))
+	for _, vehicle := range vehicles {
+		vin := strings.ToUpper(vehicle.VIN)
+		if vins[vin] {
+			return fmt.Errorf("%w: %s", ErrDuplicateVIN, vehicle.VIN)
+		}
+		vins[vin] = true
+		if owner, ok := owners[vin]; ok && owner != vehicle.ID && !replaced[owner] {
+			return fmt.Errorf("%w: %s", ErrDuplicateVIN, vehicle.VIN)
+		}
+	}
+	return nil
+}
+
+// vehicleIDPrefix is the prefix of generated vehicle IDs (veh-001, veh-002, ...)
+const vehicleIDPrefix = "veh-"
+
+// vehicleIDSeq returns the numeric part of a generated vehicle ID, or 0 if
+// the ID was not generated by formatVehicleID
+func vehicleIDSeq(id string) int {
+	if !strings.HasPrefix(id, vehicleIDPrefix) {
+		return 0
+	}
+	seq, err := strconv.Atoi(strings.TrimPrefix(id, vehicleIDPrefix))
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+// formatVehicleID formats a sequence number as a vehicle ID
+func formatVehicleID(seq int) string {
+	return fmt.Sprintf("%s%03d", vehicleIDPrefix, seq)
+}
+
+// favoriteKey identifies a favorite by user and vehicle, e.g. in journal
+// records
+func favoriteKey(userID, vehicleID string) string {
+	return userID + "/" + vehicleID
+}
+
+// sortFavorites orders favorites most recently added first
+func sortFavorites(favorites []*models.Favorite) {
+	sort.Slice(favorites, func(i, j int) bool {
+		if !favorites[i].AddedAt.Equal(favorites[j].AddedAt) {
+			return favorites[i].AddedAt.After(favorites[j].AddedAt)
+		}
+		return favorites[i].VehicleID < favorites[j].VehicleID
+	})
+}
+
+// reviewKey identifies a dealer review by dealer and reviewer
+func reviewKey(dealerID, userID string) string {
+	return dealerID + "/" + userID
+}
+
+// sortReviews orders reviews newest first
+func sortReviews(reviews []*models.DealerReview) {
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].UserID < reviews[j].UserID
+	})
+}
+
+// sortDealers orders dealers by ID
+func sortDealers(dealers []*models.Dealer) {
+	sort.Slice(dealers, func(i, j int) bool { return dealers[i].ID < dealers[j].ID })
+}
+
+// withRating returns a copy of the dealer carrying its rating, or no rating
+// when the dealer has no reviews
+func withRating(dealer *models.Dealer, ratings map[string]models.DealerRating) *models.Dealer {
+	rated := *dealer
+	rated.Rating = nil
+	if rating, ok := ratings[dealer.ID]; ok {
+		rated.Rating = &rating
+	}
+	return &rated
+}
+
+// rateVehicle sets the dealer rating of a vehicle from the dealer ratings.
+// Vehicles without a dealer, or whose dealer has no reviews, rate 0.
+func rateVehicle(vehicle *models.Vehicle, ratings map[string]models.DealerRating) {
+	vehicle.DealerRating = ratings[vehicle.DealerID].Score
+}
+
+// ratedVehicle returns the vehicle with its dealer rating from the ratings,
+// copying it only when the rating differs
+func ratedVehicle(vehicle *models.Vehicle, ratings map[string]models.DealerRating) *models.Vehicle {
+	score := ratings[vehicle.DealerID].Score
+	if vehicle.DealerRating == score {
+		return vehicle
+	}
+	rated := *vehicle
+	rated.DealerRating = score
+	return &rated
+}
+
+// geocodeVehicle sets the coordinates of a vehicle from its location when
+// the gazetteer knows the place. Otherwise coordinates given by the caller
+// are kept, but those carried over from previous, the stored version of the
+// vehicle (nil for new vehicles), are cleared when the vehicle has moved.
+func geocodeVehicle(vehicle, previous *models.Vehicle) {
+	if point, ok := geo.Geocode(vehicle.Location, vehicle.Country); ok {
+		vehicle.Coordinates = &point
+		return
+	}
+	if previous == nil || vehicle.Coordinates == nil || previous.Coordinates == nil {
+		return
+	}
+	moved := vehicle.Location != previous.Location || vehicle.Country != previous.Country
+	if moved && *vehicle.Coordinates == *previous.Coordinates {
+		vehicle.Coordinates = nil
+	}
+}
+
+// flagInvalidVIN logs a warning when a seed vehicle fails CheckVIN. Seed
+// records are loaded regardless so existing listings stay available; only
+// API writes are rejected.
+func flagInvalidVIN(vehicle *models.Vehicle, logger *logrus.Logger) {
+	if err := vehicle.CheckVIN(); err != nil {
+		logger.WithFields(logrus.Fields{
+			"vehicle_id": vehicle.ID,
+			"vin":        vehicle.VIN,
+		}).WithError(err).Warn("Seed vehicle has an invalid VIN")
+	}
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/search"
+)
+
+// TextMatch is a vehicle matched by a full-text query
+type TextMatch struct {
+	Vehicle *models.Vehicle
+	Score   float64
+}
+
+// Weights of the vehicle fields in the full-text index. A match on the make
+// or model counts for more than one in the feature list.
+const (
+	textWeightMake   = 3
+	textWeightModel  = 3
+	textWeightTrim   = 2
+	textWeightDetail = 1
+)
+
+// vehicleTextFields returns the text of a vehicle indexed for full-text search
+func vehicleTextFields(vehicle *models.Vehicle) []search.Field {
+	fields := []search.Field{
+		{Text: vehicle.Make, Weight: textWeightMake},
+		{Text: vehicle.Model, Weight: textWeightModel},
+		{Text: vehicle.Trim, Weight: textWeightTrim},
+		{Text: vehicle.ExteriorColor, Weight: textWeightDetail},
+		{Text: vehicle.InteriorColor, Weight: textWeightDetail},
+		{Text: vehicle.Location, Weight: textWeightDetail},
+	}
+	for _, feature := range vehicle.Features {
+		fields = append(fields, search.Field{Text: feature, Weight: textWeightDetail})
+	}
+	return fields
+}
+
+// newTextIndex builds the full-text index for a set of vehicles
+func newTextIndex(vehicles map[string]*models.Vehicle) *search.Index {
+	text := search.NewIndex()
+	for id, vehicle := range vehicles {
+		text.Put(id, vehicleTextFields(vehicle))
+	}
+	return text
+}
+
+// matchText runs a full-text query and keeps the hits that pass the filter,
+// in relevance order
+func matchText(text *search.Index, query string, filter *models.VehicleFilter, lookup func(id string) *models.Vehicle) []TextMatch {
+	var matches []TextMatch
+	for _, hit := range text.Search(query) {
+		vehicle := lookup(hit.ID)
+		if vehicle != nil && matchesFilter(vehicle, filter) {
+			matches = append(matches, TextMatch{Vehicle: vehicle, Score: hit.Score})
+		}
+	}
+	return matches
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSearchText(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	memory, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	sqlStore, err := NewSQLStore(filepath.Join(t.TempDir(), "inventory.db"), dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	defer sqlStore.Close()
+
+	stores := map[string]Store{
+		BackendMemory: memory,
+		BackendSQLite: sqlStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			matches := store.SearchText("tesla awd glass roof", nil)
+			if len(matches) == 0 {
+				t.Fatal("Expected matches")
+			}
+			if matches[0].Vehicle.ID != "veh-001" {
+				t.Errorf("Expected veh-001 to rank first, got %s", matches[0].Vehicle.ID)
+			}
+			for i := 1; i < len(matches); i++ {
+				if matches[i].Score > matches[i-1].Score {
+					t.Fatalf("Matches not in relevance order at %d", i)
+				}
+			}
+
+			// The filter still applies
+			for _, match := range store.SearchText("awd", &models.VehicleFilter{Country: "DE"}) {
+				if match.Vehicle.Country != "DE" {
+					t.Errorf("Vehicle %s does not pass the filter", match.Vehicle.ID)
+				}
+			}
+
+			// Mutations update the index
+			vehicle := &models.Vehicle{
+				VIN:      "1HGCM82633A004352",
+				Make:     "Honda",
+				Model:    "Accord",
+				Features: []string{"Ventilated Seats"},
+			}
+			if err := store.CreateVehicle(vehicle); err != nil {
+				t.Fatalf("Failed to create vehicle: %v", err)
+			}
+			if matches := store.SearchText("ventilated", nil); len(matches) != 1 || matches[0].Vehicle.ID != vehicle.ID {
+				t.Errorf("Expected the new vehicle to match, got %d matches", len(matches))
+			}
+
+			updated := vehicle.Clone()
+			updated.Features = []string{"Massage Seats"}
+			if err := store.UpdateVehicle(updated); err != nil {
+				t.Fatalf("Failed to update vehicle: %v", err)
+			}
+			if matches := store.SearchText("ventilated", nil); len(matches) != 0 {
+				t.Errorf("Expected no matches for a removed feature, got %d", len(matches))
+			}
+
+			if err := store.DeleteVehicle(vehicle.ID); err != nil {
+				t.Fatalf("Failed to delete vehicle: %v", err)
+			}
+			if matches := store.SearchText("massage", nil); len(matches) != 0 {
+				t.Errorf("Expected no matches after delete, got %d", len(matches))
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Package reservations lets buyers hold a vehicle for a limited time and
+// releases holds once they expire.
+package reservations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// SweeperID is recorded as the user releasing expired reservations
+const SweeperID = "reservation-sweeper"
+
+var (
+	// ErrNotAvailable is returned when the vehicle cannot be reserved
+	// because it is not available, including when another buyer holds it
+	ErrNotAvailable = errors.New("vehicle is not available for reservation")
+	// ErrReservationNotFound is returned when the user holds no reservation
+	// on the vehicle
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrInvalidHold is returned for a hold outside (0, MaxHold]
+	ErrInvalidHold = errors.New("invalid reservation hold")
+)
+
+// Manager creates, cancels and expires reservations. Reservations are kept
+// on the vehicles themselves, so they are stored by whichever backend holds
+// the inventory.
+type Manager struct {
+	repo repository.Store
+	// lock is held while a vehicle is read, changed and written back; it
+	// is shared with the other vehicle writers so a reservation cannot
+	// interleave with an edit or status transition
+	lock    sync.Locker
+	maxHold time.Duration
+	logger  *logrus.Logger
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewManager creates a manager whose reservations last at most maxHold
+func NewManager(repo repository.Store, lock sync.Locker, maxHold time.Duration, logger *logrus.Logger) *Manager {
+	return &Manager{
+		repo:    repo,
+		lock:    lock,
+		maxHold: maxHold,
+		logger:  logger,
+		stop:    make(chan struct{}),
+	}
+}
+
+// MaxHold returns the longest a vehicle can be reserved for
+func (m *Manager) MaxHold() time.Duration {
+	return m.maxHold
+}
+
+// Reserve holds an available vehicle for the user. A zero hold reserves it
+// for MaxHold.
+func (m *Manager) Reserve(vehicleID, userID string, hold time.Duration, at time.Time) (*models.Reservation, error) {
+	if hold == 0 {
+		hold = m.maxHold
+	}
+	if hold < 0 || hold > m.maxHold {
+		return nil, fmt.Errorf("%w: must be at most %s", ErrInvalidHold, m.maxHold)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status != models.StatusAvailable {
+		return nil, ErrNotAvailable
+	}
+
+	vehicle := existing.Clone()
+	reservation := &models.Reservation{
+		VehicleID:  vehicleID,
+		UserID:     userID,
+		ReservedAt: at,
+		ExpiresAt:  at.Add(hold),
+	}
+	note := "reserved until " + reservation.ExpiresAt.Format(time.RFC3339)
+	if err := vehicle.Transition(models.StatusReserved, userID, note, at); err != nil {
+		return nil, err
+	}
+	vehicle.Reservation = reservation
+
+	if err := m.repo.UpdateVehicle(vehicle); err != nil {
+		return nil, err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"vehicle_id": vehicleID,
+		"user_id":    userID,
+		"expires_at": reservation.ExpiresAt,
+	}).Info("Vehicle reserved")
+
+	return reservation, nil
+}
+
+// Cancel ends the user's reservation of a vehicle and makes it available
+// again
+func (m *Manager) Cancel(vehicleID, userID string, at time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	existing, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil {
+		return err
+	}
+	if existing.Reservation == nil || existing.Reservation.UserID != userID {
+		return ErrReservationNotFound
+	}
+
+	if err := m.release(existing, userID, "reservation cancelled", at); err != nil {
+		return err
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"vehicle_id": vehicleID,
+		"user_id":    userID,
+	}).Info("Reservation cancelled")
+
+	return nil
+}
+
+// ForUser returns the user's reservations, soonest to expire first
+func (m *Manager) ForUser(userID string) []*models.Reservation {
+	reservations := []*models.Reservation{}
+	for _, vehicle := range m.repo.GetAllVehicles() {
+		if vehicle.Reservation != nil && vehicle.Reservation.UserID == userID {
+			reservation := *vehicle.Reservation
+			reservations = append(reservations, &reservation)
+		}
+	}
+
+	sort.Slice(reservations, func(i, j int) bool {
+		if !reservations[i].ExpiresAt.Equal(reservations[j].ExpiresAt) {
+			return reservations[i].ExpiresAt.Before(reservations[j].ExpiresAt)
+		}
+		return reservations[i].VehicleID < reservations[j].VehicleID
+	})
+	return reservations
+}
+
+// Sweep releases every reservation that has expired at the given time and
+// returns how many were released
+func (m *Manager) Sweep(at time.Time) int {
+	released := 0
+	for _, vehicle := range m.repo.GetAllVehicles() {
+		if vehicle.Reservation == nil || !vehicle.Reservation.Expired(at) {
+			continue
+		}
+		if m.expire(vehicle.ID, at) {
+			released++
+		}
+	}
+	return released
+}
+
+// expire releases a vehicle's reservation if it is still the expired one
+// the sweep found
+func (m *Manager) expire(vehicleID string, at time.Time) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// The vehicle may have been cancelled, sold or deleted since the scan
+	existing, err := m.repo.GetVehicleByID(vehicleID)
+	if err != nil || existing.Reservation == nil || !existing.Reservation.Expired(at) {
+		return false
+	}
+
+	if err := m.release(existing, SweeperID, "reservation expired", at); err != nil {
+		m.logger.WithError(err).WithField("vehicle_id", vehicleID).Error("Failed to release expired reservation")
+		return false
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"vehicle_id": vehicleID,
+		"user_id":    existing.Reservation.UserID,
+	}).Info("Reservation expired")
+
+	return true
+}
+
+// release makes a reserved vehicle available again. Callers must hold lock.
+func (m *Manager) release(existing *models.Vehicle, by, note string, at time.Time) error {
+	vehicle := existing.Clone()
+	if vehicle.Status == models.StatusReserved {
+		if err := vehicle.Transition(models.StatusAvailable, by, note, at); err != nil {
+			return err
+		}
+	}
+	// Transition clears the reservation; a vehicle that already left
+	// reserved only needs its stale hold dropped
+	vehicle.Reservation = nil
+
+	return m.repo.UpdateVehicle(vehicle)
+}
+
+// Start runs the sweeper every interval until Close is called
+func (m *Manager) Start(interval time.Duration) {
+	m.wg.Add(1)
+	go m.sweepLoop(interval)
+}
+
+// sweepLoop releases expired reservations on every tick
+func (m *Manager) sweepLoop(interval time.Duration) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if released := m.Sweep(time.Now().UTC()); released > 0 {
+				m.logger.WithField("released", released).Info("Released expired reservations")
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Close stops the sweeper
+func (m *Manager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.wg.Wait()
+	return nil
+}
@@ -0,0 +1,142 @@
+package reservations
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-inventory/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestManager(t *testing.T) (*Manager, repository.Store) {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	repo, err := repository.NewRepository(filepath.Join("..", "..", "..", "..", "data", "seed"), logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	return NewManager(repo, &sync.Mutex{}, 48*time.Hour, logger), repo
+}
+
+func TestReserveConcurrently(t *testing.T) {
+	m, repo := newTestManager(t)
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	const buyers = 20
+	var wg sync.WaitGroup
+	errs := make([]error, buyers)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = m.Reserve("veh-002", fmt.Sprintf("user-%03d", i), 0, at)
+		}(i)
+	}
+	wg.Wait()
+
+	held := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			held++
+		case !errors.Is(err, ErrNotAvailable):
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if held != 1 {
+		t.Fatalf("Expected exactly one reservation, got %d", held)
+	}
+
+	vehicle, _ := repo.GetVehicleByID("veh-002")
+	if vehicle.Status != models.StatusReserved || vehicle.Reservation == nil {
+		t.Fatalf("Expected a reserved vehicle, got %+v", vehicle)
+	}
+	if !vehicle.Reservation.ExpiresAt.Equal(at.Add(48 * time.Hour)) {
+		t.Errorf("Expected the maximum hold, got expiry %s", vehicle.Reservation.ExpiresAt)
+	}
+	if len(vehicle.StatusHistory) != 1 || vehicle.StatusHistory[0].By != vehicle.Reservation.UserID {
+		t.Errorf("Expected the reservation in the status history, got %+v", vehicle.StatusHistory)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	m, repo := newTestManager(t)
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	if _, err := m.Reserve("veh-001", "user-003", time.Hour, at); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if _, err := m.Reserve("veh-002", "user-003", 2*time.Hour, at); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if got := m.ForUser("user-003"); len(got) != 2 || got[0].VehicleID != "veh-001" {
+		t.Fatalf("Expected both reservations, soonest first, got %+v", got)
+	}
+
+	if released := m.Sweep(at.Add(30 * time.Minute)); released != 0 {
+		t.Errorf("Expected nothing to expire yet, released %d", released)
+	}
+	if released := m.Sweep(at.Add(90 * time.Minute)); released != 1 {
+		t.Errorf("Expected one expired reservation, released %d", released)
+	}
+
+	vehicle, _ := repo.GetVehicleByID("veh-001")
+	if vehicle.Status != models.StatusAvailable || vehicle.Reservation != nil {
+		t.Errorf("Expected the expired hold to be released, got %+v", vehicle)
+	}
+	last := vehicle.StatusHistory[len(vehicle.StatusHistory)-1]
+	if last.By != SweeperID || last.To != models.StatusAvailable {
+		t.Errorf("Unexpected release record %+v", last)
+	}
+	if got := m.ForUser("user-003"); len(got) != 1 || got[0].VehicleID != "veh-002" {
+		t.Errorf("Expected only the unexpired reservation, got %+v", got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	m, repo := newTestManager(t)
+	at := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	if _, err := m.Reserve("veh-001", "user-003", 0, at); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if err := m.Cancel("veh-001", "user-004", at); !errors.Is(err, ErrReservationNotFound) {
+		t.Errorf("Expected ErrReservationNotFound for another user, got %v", err)
+	}
+	if err := m.Cancel("veh-001", "user-003", at); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	vehicle, _ := repo.GetVehicleByID("veh-001")
+	if vehicle.Status != models.StatusAvailable || vehicle.Reservation != nil {
+		t.Errorf("Expected the vehicle to be available, got %+v", vehicle)
+	}
+
+	// Selling a reserved vehicle ends the hold, so the sweeper leaves it
+	if _, err := m.Reserve("veh-001", "user-003", time.Hour, at); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	reserved, _ := repo.GetVehicleByID("veh-001")
+	sold := reserved.Clone()
+	if err := sold.Transition(models.StatusSold, "user-002", "", at); err != nil {
+		t.Fatalf("Transition failed: %v", err)
+	}
+	if err := repo.UpdateVehicle(sold); err != nil {
+		t.Fatalf("UpdateVehicle failed: %v", err)
+	}
+	if released := m.Sweep(at.Add(2 * time.Hour)); released != 0 {
+		t.Errorf("Expected a sold vehicle to be left alone, released %d", released)
+	}
+
+	if _, err := m.Reserve("veh-001", "user-003", 72*time.Hour, at); !errors.Is(err, ErrInvalidHold) {
+		t.Errorf("Expected ErrInvalidHold, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Token is a word of the analyzed text
+type Token struct {
+	// Term is the normalized, stemmed form used for matching
+	Term string
+	// Start and End are the byte offsets of the word in the original text
+	Start, End int
+}
+
+// stopWords are dropped from documents and queries
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+	"with": true,
+}
+
+// Analyze splits text into lower-case words of letters and digits, drops
+// stop words and stems what remains
+func Analyze(text string) []Token {
+	var tokens []Token
+
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+
+	return tokens
+}
+
+func appendToken(tokens []Token, text string, start, end int) []Token {
+	word := strings.ToLower(text[start:end])
+	if stopWords[word] {
+		return tokens
+	}
+	return append(tokens, Token{Term: Stem(word), Start: start, End: end})
+}
+
+// Terms returns the distinct terms of text in order of first appearance
+func Terms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, token := range Analyze(text) {
+		if !seen[token.Term] {
+			seen[token.Term] = true
+			terms = append(terms, token.Term)
+		}
+	}
+	return terms
+}
+
+// Highlight wraps the words of text whose terms are in terms with <em> tags.
+// The rest of the text is HTML-escaped. It reports whether any word matched.
+func Highlight(text string, terms []string) (string, bool) {
+	want := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		want[term] = true
+	}
+
+	var b strings.Builder
+	matched := false
+	last := 0
+	for _, token := range Analyze(text) {
+		if !want[token.Term] {
+			continue
+		}
+		matched = true
+		b.WriteString(html.EscapeString(text[last:token.Start]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[token.Start:token.End]))
+		b.WriteString("</em>")
+		last = token.End
+	}
+	if !matched {
+		return text, false
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+
+	return b.String(), true
+}
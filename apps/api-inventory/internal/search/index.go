@@ -0,0 +1,164 @@
+// Package search provides full-text search over short documents: an
+// English analyzer with Porter stemming and an inverted index ranked with
+// BM25.
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// BM25 parameters: k1 controls term frequency saturation and b how much
+// scores are normalized by document length
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Field is a piece of document text. Occurrences of a term in the field
+// count Weight times towards the term frequency of the document.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index is an inverted index over documents made of weighted fields. It is
+// safe for concurrent use.
+type Index struct {
+	mu sync.RWMutex
+	// postings maps each term to the weighted frequency of the term in
+	// every document containing it
+	postings map[string]map[string]float64
+	// docTerms lists the distinct terms of each document
+	docTerms map[string][]string
+	// docLen is the weighted length of each document
+	docLen   map[string]float64
+	totalLen float64
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]float64),
+		docTerms: make(map[string][]string),
+		docLen:   make(map[string]float64),
+	}
+}
+
+// Put adds a document or replaces the document with the same ID
+func (ix *Index) Put(id string, fields []Field) {
+	freqs := make(map[string]float64)
+	length := 0.0
+	for _, field := range fields {
+		for _, token := range Analyze(field.Text) {
+			freqs[token.Term] += field.Weight
+			length += field.Weight
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	terms := make([]string, 0, len(freqs))
+	for term, freq := range freqs {
+		docs := ix.postings[term]
+		if docs == nil {
+			docs = make(map[string]float64)
+			ix.postings[term] = docs
+		}
+		docs[id] = freq
+		terms = append(terms, term)
+	}
+	ix.docTerms[id] = terms
+	ix.docLen[id] = length
+	ix.totalLen += length
+}
+
+// Remove deletes a document from the index
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	terms, exists := ix.docTerms[id]
+	if !exists {
+		return
+	}
+
+	for _, term := range terms {
+		docs := ix.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLen -= ix.docLen[id]
+	delete(ix.docTerms, id)
+	delete(ix.docLen, id)
+}
+
+// Len returns the number of documents in the index
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.docTerms)
+}
+
+// Search returns the documents containing any of the query terms, best
+// match first. Documents with equal scores are ordered by ID.
+func (ix *Index) Search(query string) []Hit {
+	terms := Terms(query)
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	n := float64(len(ix.docTerms))
+	if n == 0 || len(terms) == 0 {
+		return nil
+	}
+	avgLen := ix.totalLen / n
+	if avgLen <= 0 {
+		avgLen = 1
+	}
+
+	scores := make(map[string]float64)
+	for _, term := range terms {
+		docs := ix.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range docs {
+			norm := bm25K1 * (1 - bm25B + bm25B*ix.docLen[id]/avgLen)
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, k int) bool {
+		if hits[i].Score != hits[k].Score {
+			return hits[i].Score > hits[k].Score
+		}
+		return hits[i].ID < hits[k].ID
+	})
+
+	return hits
+}
@@ -0,0 +1,102 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	tests := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"cats":           "cat",
+		"feed":           "feed",
+		"agreed":         "agre",
+		"plastered":      "plaster",
+		"motoring":       "motor",
+		"sing":           "sing",
+		"conflated":      "conflat",
+		"troubled":       "troubl",
+		"sized":          "size",
+		"hopping":        "hop",
+		"falling":        "fall",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"conditional":    "condit",
+		"generalization": "gener",
+		"electrical":     "electr",
+		"adjustment":     "adjust",
+		"heated":         "heat",
+		"seats":          "seat",
+		"awd":            "awd",
+		"4x4":            "4x4",
+	}
+	for word, expected := range tests {
+		if got := Stem(word); got != expected {
+			t.Errorf("Stem(%q) = %q, expected %q", word, got, expected)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	tokens := Analyze("Heated Seats, and a Panoramic Glass-Roof")
+
+	var terms []string
+	for _, token := range tokens {
+		terms = append(terms, token.Term)
+	}
+	if got := strings.Join(terms, " "); got != "heat seat panoram glass roof" {
+		t.Errorf("Unexpected terms %q", got)
+	}
+	if tokens[0].Start != 0 || tokens[0].End != len("Heated") {
+		t.Errorf("Unexpected offsets for the first token: %+v", tokens[0])
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	text, ok := Highlight("Panoramic <Glass> Roof", Terms("glass roofs"))
+	if !ok {
+		t.Fatal("Expected a match")
+	}
+	if text != "Panoramic &lt;<em>Glass</em>&gt; <em>Roof</em>" {
+		t.Errorf("Unexpected highlight %q", text)
+	}
+
+	if _, ok := Highlight("Leather Seats", Terms("roof")); ok {
+		t.Error("Expected no match")
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex()
+	ix.Put("veh-001", []Field{{Text: "Tesla", Weight: 3}, {Text: "Model 3", Weight: 3}, {Text: "Glass Roof", Weight: 1}})
+	ix.Put("veh-002", []Field{{Text: "Tesla", Weight: 3}, {Text: "Model S", Weight: 3}, {Text: "Heated Seats", Weight: 1}})
+	ix.Put("veh-003", []Field{{Text: "Ford", Weight: 3}, {Text: "Mustang", Weight: 3}, {Text: "Glass Roof", Weight: 1}})
+
+	hits := ix.Search("tesla glass roof")
+	if len(hits) != 3 {
+		t.Fatalf("Expected 3 hits, got %d", len(hits))
+	}
+	if hits[0].ID != "veh-001" {
+		t.Errorf("Expected veh-001 to rank first, got %+v", hits)
+	}
+
+	// Replacing a document drops its old terms
+	ix.Put("veh-003", []Field{{Text: "Ford", Weight: 3}, {Text: "Mustang", Weight: 3}})
+	if hits := ix.Search("roof"); len(hits) != 1 || hits[0].ID != "veh-001" {
+		t.Errorf("Expected only veh-001 to match roof, got %+v", hits)
+	}
+
+	ix.Remove("veh-001")
+	if hits := ix.Search("roof"); len(hits) != 0 {
+		t.Errorf("Expected no hits after removal, got %+v", hits)
+	}
+	if ix.Len() != 2 {
+		t.Errorf("Expected 2 documents, got %d", ix.Len())
+	}
+
+	if hits := ix.Search("the and"); hits != nil {
+		t.Errorf("Expected no hits for stop words, got %+v", hits)
+	}
+}
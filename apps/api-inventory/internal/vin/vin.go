@@ -0,0 +1,197 @@
+// Package vin validates and decodes 17-character vehicle identification
+// numbers (ISO 3779, with the North American check digit of 49 CFR 565).
+package vin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Length is the number of characters in a VIN
+const Length = 17
+
+var (
+	// ErrLength is returned for VINs that are not 17 characters long
+	ErrLength = errors.New("VIN must be 17 characters")
+	// ErrCharacter is returned for characters outside A-Z and 0-9, and for
+	// I, O and Q, which are never used
+	ErrCharacter = errors.New("VIN may only contain A-Z and 0-9, excluding I, O and Q")
+	// ErrCheckDigit is returned when the ninth character of a North
+	// American VIN does not match the computed check digit
+	ErrCheckDigit = errors.New("VIN check digit does not match")
+)
+
+// Info is a decoded VIN
+type Info struct {
+	VIN string `json:"vin"`
+	// Valid reports whether the VIN passes Validate
+	Valid bool `json:"valid"`
+	// WMI is the world manufacturer identifier, VDS the vehicle descriptor
+	// section and VIS the vehicle identifier section
+	WMI string `json:"wmi"`
+	VDS string `json:"vds"`
+	VIS string `json:"vis"`
+	// Manufacturer and Makes are empty when the WMI is not known
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	Makes        []string `json:"makes,omitempty"`
+	// Country is the ISO 3166 code of the country the WMI is assigned to
+	Country string `json:"country,omitempty"`
+	// NorthAmerican is set for VINs assigned to the United States, Canada
+	// and Mexico, where the check digit and model year are mandatory
+	NorthAmerican bool `json:"northAmerican"`
+	// ModelYear is decoded from the tenth character; 0 when it is not a
+	// year code
+	ModelYear  int        `json:"modelYear,omitempty"`
+	CheckDigit CheckDigit `json:"checkDigit"`
+	// SerialNumber is the production sequence number
+	SerialNumber string `json:"serialNumber"`
+}
+
+// CheckDigit describes the ninth character of a VIN
+type CheckDigit struct {
+	Value    string `json:"value"`
+	Expected string `json:"expected"`
+	Valid    bool   `json:"valid"`
+	// Required is set when the check digit must be valid, i.e. for North
+	// American VINs
+	Required bool `json:"required"`
+}
+
+// Normalize upper-cases a VIN and trims surrounding space
+func Normalize(vin string) string {
+	return strings.ToUpper(strings.TrimSpace(vin))
+}
+
+// Validate checks the structure of a VIN and, for North American VINs, its
+// check digit
+func Validate(vin string) error {
+	info, err := Decode(vin)
+	if err != nil {
+		return err
+	}
+	if !info.Valid {
+		return fmt.Errorf("%w: expected %s, got %s", ErrCheckDigit, info.CheckDigit.Expected, info.CheckDigit.Value)
+	}
+	return nil
+}
+
+// Decode splits a VIN into its sections and decodes the manufacturer,
+// country and model year. It only fails when the structure is invalid; an
+// incorrect check digit is reported in the result.
+func Decode(vin string) (*Info, error) {
+	vin = Normalize(vin)
+	if len(vin) != Length {
+		return nil, ErrLength
+	}
+	for i := 0; i < Length; i++ {
+		if _, ok := transliterate(vin[i]); !ok {
+			return nil, fmt.Errorf("%w: %q at position %d", ErrCharacter, vin[i], i+1)
+		}
+	}
+
+	info := &Info{
+		VIN:          vin,
+		WMI:          vin[:3],
+		VDS:          vin[3:9],
+		VIS:          vin[9:],
+		Country:      countryOf(vin),
+		SerialNumber: vin[11:],
+	}
+	info.NorthAmerican = info.Country == "US" || info.Country == "CA" || info.Country == "MX"
+
+	if m, ok := manufacturers[info.WMI]; ok {
+		info.Manufacturer = m.name
+		info.Makes = m.makes
+	}
+
+	expected := checkDigit(vin)
+	info.CheckDigit = CheckDigit{
+		Value:    vin[8:9],
+		Expected: string(expected),
+		Valid:    vin[8] == expected,
+		Required: info.NorthAmerican,
+	}
+	info.Valid = info.CheckDigit.Valid || !info.CheckDigit.Required
+
+	info.ModelYear = modelYear(vin, info.NorthAmerican, time.Now().Year()+1)
+
+	return info, nil
+}
+
+// HasMake reports whether make is one of the makes built under the WMI.
+// It also reports true when the WMI is not known, since the make cannot be
+// contradicted.
+func (i *Info) HasMake(make string) bool {
+	if len(i.Makes) == 0 {
+		return true
+	}
+	for _, m := range i.Makes {
+		if strings.EqualFold(m, strings.TrimSpace(make)) {
+			return true
+		}
+	}
+	return false
+}
+
+// weights are the position weights of the check digit calculation
+var weights = [Length]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// transliterate returns the numeric value of a VIN character
+func transliterate(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'H':
+		return int(c-'A') + 1, true
+	case c >= 'J' && c <= 'N':
+		return int(c-'J') + 1, true
+	case c == 'P':
+		return 7, true
+	case c == 'R':
+		return 9, true
+	case c >= 'S' && c <= 'Z':
+		return int(c-'S') + 2, true
+	}
+	return 0, false
+}
+
+// checkDigit computes the check digit of a structurally valid VIN
+func checkDigit(vin string) byte {
+	sum := 0
+	for i := 0; i < Length; i++ {
+		value, _ := transliterate(vin[i])
+		sum += value * weights[i]
+	}
+	if r := sum % 11; r < 10 {
+		return byte('0' + r)
+	}
+	return 'X'
+}
+
+// yearCodes lists the model year characters from 1980 (A) to 2009 (9). The
+// cycle repeats every 30 years.
+const yearCodes = "ABCDEFGHJKLMNPRSTVWXY123456789"
+
+// modelYear decodes the tenth character. North American passenger VINs
+// use a letter or digit in position 7 to tell the 2010-2039 cycle from
+// 1980-2009; otherwise the latest year no later than maxYear is taken.
+func modelYear(vin string, northAmerican bool, maxYear int) int {
+	i := strings.IndexByte(yearCodes, vin[9])
+	if i < 0 {
+		return 0
+	}
+	year := 1980 + i
+
+	if northAmerican {
+		if vin[6] < '0' || vin[6] > '9' {
+			year += 30
+		}
+		return year
+	}
+	for year+30 <= maxYear {
+		year += 30
+	}
+	return year
+}
@@ -0,0 +1,72 @@
+package vin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		vin      string
+		expected error
+	}{
+		{"1HGCM82633A004352", nil},
+		{" 1hgcm82633a004352 ", nil},
+		{"5YJ3E1EB5KF123456", nil},
+		// X is the check digit for a remainder of 10
+		{"1M8GDM9AXKP042788", nil},
+		{"1HGCM82643A004352", ErrCheckDigit},
+		// The check digit is not mandatory outside North America
+		{"WBAJE5C50JG123456", nil},
+		{"1HGCM82633A00435", ErrLength},
+		{"1HGCM82633A00435O", ErrCharacter},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.vin)
+		if !errors.Is(err, tt.expected) || (tt.expected == nil && err != nil) {
+			t.Errorf("Validate(%q) = %v, expected %v", tt.vin, err, tt.expected)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	info, err := Decode("1HGCM82633A004352")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if info.WMI != "1HG" || info.VDS != "CM8263" || info.VIS != "3A004352" || info.SerialNumber != "004352" {
+		t.Errorf("Unexpected sections %+v", info)
+	}
+	if info.Manufacturer != "Honda of America" || info.Country != "US" || !info.NorthAmerican {
+		t.Errorf("Unexpected manufacturer %+v", info)
+	}
+	if info.ModelYear != 2003 {
+		t.Errorf("Expected model year 2003, got %d", info.ModelYear)
+	}
+	if !info.HasMake("honda") || info.HasMake("Toyota") {
+		t.Error("Expected the VIN to belong to Honda only")
+	}
+
+	// A letter in position 7 selects the 2010-2039 cycle
+	info, _ = Decode("5YJ3E1EB5KF123456")
+	if info.ModelYear != 2019 || info.Manufacturer != "Tesla" {
+		t.Errorf("Expected a 2019 Tesla, got %+v", info)
+	}
+
+	// Unknown manufacturers cannot contradict a make
+	info, _ = Decode("UAAGA813XNA123456")
+	if info.Manufacturer != "" || info.Country != "" || !info.HasMake("Ford") {
+		t.Errorf("Expected an unknown manufacturer, got %+v", info)
+	}
+
+	info, _ = Decode("SCFRMFAW5NGF12345")
+	if info.Country != "GB" || info.NorthAmerican || !info.HasMake("Aston Martin") {
+		t.Errorf("Expected a British Aston Martin, got %+v", info)
+	}
+
+	info, _ = Decode("1HGCM82643A004352")
+	if info.Valid || info.CheckDigit.Expected != "3" || info.CheckDigit.Value != "4" {
+		t.Errorf("Expected an invalid check digit, got %+v", info.CheckDigit)
+	}
+}
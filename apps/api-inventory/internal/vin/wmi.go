@@ -0,0 +1,132 @@
+package vin
+
+import "strings"
+
+// wmiOrder is the order of VIN characters in the WMI country ranges
+const wmiOrder = "ABCDEFGHJKLMNPRSTUVWXYZ1234567890"
+
+// countryRange assigns the WMIs whose first character is first and whose
+// second character lies between from and to (in wmiOrder) to a country
+type countryRange struct {
+	first    byte
+	from, to byte
+	country  string
+}
+
+// countryRanges covers the major vehicle producing countries
+var countryRanges = []countryRange{
+	{'1', 'A', '0', "US"},
+	{'4', 'A', '0', "US"},
+	{'5', 'A', '0', "US"},
+	{'2', 'A', '0', "CA"},
+	{'3', 'A', 'W', "MX"},
+	{'6', 'A', 'W', "AU"},
+	{'7', 'A', 'E', "NZ"},
+	{'9', 'A', 'E', "BR"},
+	{'9', '3', '9', "BR"},
+	{'J', 'A', '0', "JP"},
+	{'K', 'L', 'R', "KR"},
+	{'L', 'A', '0', "CN"},
+	{'M', 'A', 'E', "IN"},
+	{'S', 'A', 'M', "GB"},
+	{'S', 'N', 'T', "DE"},
+	{'S', 'U', 'Z', "PL"},
+	{'T', 'A', 'H', "CH"},
+	{'T', 'J', 'P', "CZ"},
+	{'T', 'R', 'V', "HU"},
+	{'V', 'A', 'E', "AT"},
+	{'V', 'F', 'R', "FR"},
+	{'V', 'S', 'W', "ES"},
+	{'W', 'A', '0', "DE"},
+	{'X', 'L', 'R', "NL"},
+	{'Y', 'A', 'E', "BE"},
+	{'Y', 'F', 'K', "FI"},
+	{'Y', 'S', 'W', "SE"},
+	{'Z', 'A', 'R', "IT"},
+}
+
+// countryOf returns the country the WMI of a VIN is assigned to, or "" when
+// it is not in countryRanges
+func countryOf(vin string) string {
+	second := strings.IndexByte(wmiOrder, vin[1])
+	for _, r := range countryRanges {
+		if vin[0] != r.first {
+			continue
+		}
+		if second >= strings.IndexByte(wmiOrder, r.from) && second <= strings.IndexByte(wmiOrder, r.to) {
+			return r.country
+		}
+	}
+	return ""
+}
+
+// manufacturer is the holder of a WMI and the makes it builds under it
+type manufacturer struct {
+	name  string
+	makes []string
+}
+
+// manufacturers maps known WMIs to their manufacturer
+var manufacturers = map[string]manufacturer{
+	"1C3": {"Chrysler", []string{"Chrysler", "Dodge"}},
+	"1C4": {"Chrysler", []string{"Chrysler", "Dodge", "Jeep"}},
+	"1C6": {"Chrysler", []string{"Ram", "Dodge"}},
+	"1FA": {"Ford Motor Company", []string{"Ford"}},
+	"1FM": {"Ford Motor Company", []string{"Ford"}},
+	"1FT": {"Ford Motor Company", []string{"Ford"}},
+	"1G1": {"General Motors", []string{"Chevrolet"}},
+	"1GC": {"General Motors", []string{"Chevrolet"}},
+	"1GT": {"General Motors", []string{"GMC"}},
+	"1GY": {"General Motors", []string{"Cadillac"}},
+	"1HG": {"Honda of America", []string{"Honda"}},
+	"1N4": {"Nissan North America", []string{"Nissan"}},
+	"2C3": {"Chrysler Canada", []string{"Chrysler", "Dodge"}},
+	"2HG": {"Honda Canada", []string{"Honda"}},
+	"2T3": {"Toyota Canada", []string{"Toyota"}},
+	"3C6": {"Chrysler de Mexico", []string{"Ram", "Dodge"}},
+	"3CZ": {"Honda de Mexico", []string{"Honda"}},
+	"3FA": {"Ford de Mexico", []string{"Ford"}},
+	"4S3": {"Subaru of Indiana", []string{"Subaru"}},
+	"4S4": {"Subaru of Indiana", []string{"Subaru"}},
+	"4T1": {"Toyota Motor Manufacturing Kentucky", []string{"Toyota"}},
+	"5FN": {"Honda Manufacturing of Alabama", []string{"Honda"}},
+	"5N1": {"Nissan North America", []string{"Nissan"}},
+	"5TD": {"Toyota Motor Manufacturing Indiana", []string{"Toyota"}},
+	"5TF": {"Toyota Motor Manufacturing Texas", []string{"Toyota"}},
+	"5XY": {"Kia Georgia", []string{"Kia", "Hyundai"}},
+	"5YJ": {"Tesla", []string{"Tesla"}},
+	"6FP": {"Ford Australia", []string{"Ford"}},
+	"6G1": {"Holden", []string{"Holden"}},
+	"6G2": {"Holden", []string{"Holden", "Pontiac"}},
+	"6T1": {"Toyota Australia", []string{"Toyota"}},
+	"JA3": {"Mitsubishi", []string{"Mitsubishi"}},
+	"JA4": {"Mitsubishi", []string{"Mitsubishi"}},
+	"JF1": {"Subaru", []string{"Subaru"}},
+	"JF2": {"Subaru", []string{"Subaru"}},
+	"JHM": {"Honda", []string{"Honda"}},
+	"JM1": {"Mazda", []string{"Mazda"}},
+	"JM3": {"Mazda", []string{"Mazda"}},
+	"JN1": {"Nissan", []string{"Nissan"}},
+	"JT2": {"Toyota", []string{"Toyota"}},
+	"JTD": {"Toyota", []string{"Toyota"}},
+	"KMH": {"Hyundai", []string{"Hyundai"}},
+	"KNA": {"Kia", []string{"Kia"}},
+	"SAJ": {"Jaguar", []string{"Jaguar"}},
+	"SAL": {"Land Rover", []string{"Land Rover"}},
+	"SB1": {"Toyota Motor Manufacturing UK", []string{"Toyota"}},
+	"SBM": {"McLaren", []string{"McLaren"}},
+	"SCB": {"Bentley", []string{"Bentley"}},
+	"SCF": {"Aston Martin", []string{"Aston Martin"}},
+	"VF3": {"Peugeot", []string{"Peugeot"}},
+	"WAU": {"Audi", []string{"Audi"}},
+	"WBA": {"BMW", []string{"BMW"}},
+	"WBS": {"BMW M", []string{"BMW"}},
+	"WDB": {"Mercedes-Benz", []string{"Mercedes-Benz"}},
+	"WDD": {"Mercedes-Benz", []string{"Mercedes-Benz"}},
+	"WMW": {"MINI", []string{"Mini"}},
+	"WP0": {"Porsche", []string{"Porsche"}},
+	"WP1": {"Porsche SUV", []string{"Porsche"}},
+	"WVW": {"Volkswagen", []string{"Volkswagen"}},
+	"WVG": {"Volkswagen SUV", []string{"Volkswagen"}},
+	"ZFF": {"Ferrari", []string{"Ferrari"}},
+}
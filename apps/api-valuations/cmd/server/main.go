@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/auth"
@@ -21,19 +22,53 @@ func main() {
 	logger.SetFormatter(&logrus.JSONFormatter{})
 	logger.SetLevel(logrus.InfoLevel)
 
+	// Subcommands run offline against the seed data
+	if len(os.Args) > 1 && os.Args[1] == "validate-data" {
+		os.Exit(runValidateData(os.Args[2:], logger))
+	}
+
 	// Get configuration from environment
 	dataPath := getEnv("DATA_PATH", "/app/data/seed")
 	jwtSecret := getEnv("JWT_SECRET", "dev-jwt-secret-change-in-production")
 	port := getEnv("PORT", "8002")
+	storageBackend := getEnv("STORAGE_BACKEND", repository.BackendMemory)
+	sqlitePath := getEnv("SQLITE_PATH", "/app/data/valuations.db")
+	journalDir := getEnv("JOURNAL_DIR", "")
+	snapshotInterval, err := time.ParseDuration(getEnv("SNAPSHOT_INTERVAL", "5m"))
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid SNAPSHOT_INTERVAL")
+	}
+	watchInterval, err := time.ParseDuration(getEnv("WATCH_INTERVAL", "0"))
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid WATCH_INTERVAL")
+	}
+	strictData, err := strconv.ParseBool(getEnv("STRICT_DATA", "false"))
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid STRICT_DATA")
+	}
 
 	logger.Info("Starting API Valuations service...")
 	logger.WithFields(logrus.Fields{
-		"data_path": dataPath,
-		"port":      port,
+		"data_path":       dataPath,
+		"port":            port,
+		"storage_backend": storageBackend,
+		"journal_dir":     journalDir,
 	}).Info("Configuration loaded")
 
+	// In strict mode, refuse to start on seed data validate-data rejects
+	if strictData {
+		checkDataStrict(dataPath, logger)
+	}
+
 	// Initialize repository
-	repo, err := repository.NewRepository(dataPath, logger)
+	repo, err := repository.NewStore(repository.Config{
+		Backend:          storageBackend,
+		DataPath:         dataPath,
+		SQLitePath:       sqlitePath,
+		JournalDir:       journalDir,
+		SnapshotInterval: snapshotInterval,
+		WatchInterval:    watchInterval,
+	}, logger)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize repository")
 	}
@@ -45,6 +80,8 @@ func main() {
 	healthHandler := handlers.NewHealthHandler(logger)
 	authHandler := handlers.NewAuthHandler(repo, jwtManager, logger)
 	valuationHandler := handlers.NewValuationHandler(repo, logger)
+	reloader, _ := repo.(repository.Reloader)
+	adminHandler := handlers.NewAdminHandler(reloader, logger)
 
 	// Setup router
 	r := mux.NewRouter()
@@ -58,10 +95,20 @@ func main() {
 	api.Use(middleware.AuthMiddleware(jwtManager, logger))
 
 	api.HandleFunc("/valuations", valuationHandler.HandleListValuations).Methods("GET")
+	api.HandleFunc("/valuations", valuationHandler.HandleCreateValuation).Methods("POST")
+	api.HandleFunc("/valuations/export", valuationHandler.HandleExportValuations).Methods("GET")
 	api.HandleFunc("/valuations/{id}", valuationHandler.HandleGetValuation).Methods("GET")
 	api.HandleFunc("/valuations/estimate", valuationHandler.HandleEstimateValuation).Methods("POST")
 	api.HandleFunc("/valuations/summary", valuationHandler.HandleGetValuationSummary).Methods("GET")
 
+	// Admin routes
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AuthMiddleware(jwtManager, logger))
+	admin.Use(middleware.RequireRole(repo, "admin", logger))
+
+	admin.HandleFunc("/reload", adminHandler.HandleReload).Methods("POST")
+	admin.HandleFunc("/reload", adminHandler.HandleReloadStatus).Methods("GET")
+
 	// Add logging middleware to all routes
 	r.Use(middleware.LoggingMiddleware(logger))
 
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/datacheck"
+	"github.com/sirupsen/logrus"
+)
+
+// runValidateData checks the seed files and prints the report as JSON. It
+// returns the exit status: 1 when the data has errors, 2 for bad usage.
+func runValidateData(args []string, logger *logrus.Logger) int {
+	flags := flag.NewFlagSet("validate-data", flag.ContinueOnError)
+	dataPath := flags.String("data", getEnv("DATA_PATH", "/app/data/seed"), "directory holding the seed files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: server validate-data [flags]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+
+	report := datacheck.Check(*dataPath)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		logger.WithError(err).Error("Failed to write the report")
+		return 1
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// checkDataStrict runs the validate-data checks before the server starts
+// and stops it when the seed files have errors
+func checkDataStrict(dataPath string, logger *logrus.Logger) {
+	report := datacheck.Check(dataPath)
+	for _, issue := range report.Issues {
+		entry := logger.WithFields(logrus.Fields{
+			"file":   issue.File,
+			"line":   issue.Line,
+			"record": issue.Record,
+			"field":  issue.Field,
+		})
+		if issue.Severity == datacheck.SeverityError {
+			entry.Error(issue.Message)
+		} else {
+			entry.Warn(issue.Message)
+		}
+	}
+	if !report.OK() {
+		logger.Fatal("Seed data is invalid: " + report.Summary())
+	}
+	logger.Info("Seed data checked: " + report.Summary())
+}
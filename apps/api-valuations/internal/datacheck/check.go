@@ -0,0 +1,152 @@
+package datacheck
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Seed files checked by Check
+const (
+	UsersFile      = "users.json"
+	ValuationsFile = "valuations.json"
+)
+
+// KnownRoles are the roles the services grant access by
+var KnownRoles = []string{"user", "admin"}
+
+// minValuationYear is the earliest model year a valuation can be for
+const minValuationYear = 1886
+
+// countryPattern matches an ISO 3166-1 alpha-2 country code
+var countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// seedUser is a user as stored in users.json. The search preferences are
+// only used by the inventory service.
+type seedUser struct {
+	models.User
+	Preferences json.RawMessage `json:"preferences,omitempty"`
+}
+
+// Check loads the seed files in dataPath and reports every problem found.
+// Unreadable files are reported too, so the report is always complete.
+func Check(dataPath string) *Report {
+	report := &Report{
+		DataPath: dataPath,
+		Records:  make(map[string]int),
+		Issues:   []Issue{},
+	}
+
+	checkUsers(report, dataPath)
+	checkValuations(report, dataPath, time.Now())
+
+	report.sortIssues()
+	return report
+}
+
+// checkUsers checks users.json
+func checkUsers(report *Report, dataPath string) {
+	records, ok := readRecords[seedUser](report, dataPath, UsersFile, true)
+	if !ok {
+		return
+	}
+
+	c := &fileChecker{report: report, file: UsersFile}
+	uniqueID := newUniqueIDs(c, "id")
+	uniqueEmail := newUniqueIDs(c, "email")
+	for _, rec := range records {
+		user, line := rec.value, rec.line
+		uniqueID.check(line, user.ID, user.ID, user.ID)
+
+		uniqueEmail.check(line, user.ID, user.Email, strings.ToLower(strings.TrimSpace(user.Email)))
+		if user.Email != "" && !strings.Contains(user.Email, "@") {
+			c.errorf(line, user.ID, "email", "%q is not an email address", user.Email)
+		}
+
+		if _, err := bcrypt.Cost([]byte(user.Password)); err != nil {
+			c.errorf(line, user.ID, "password", "is not a bcrypt hash: %v", err)
+		}
+
+		if len(user.Roles) == 0 {
+			c.errorf(line, user.ID, "roles", "user has no roles")
+		}
+		for _, role := range user.Roles {
+			if !contains(KnownRoles, role) {
+				c.warnf(line, user.ID, "roles", "unknown role %q", role)
+			}
+		}
+
+		if !contains(models.SupportedCurrencies, strings.ToUpper(user.PreferredCurrency)) {
+			c.errorf(line, user.ID, "preferredCurrency", "%q must be one of %s",
+				user.PreferredCurrency, strings.Join(models.SupportedCurrencies, ", "))
+		}
+		if !countryPattern.MatchString(user.Country) {
+			c.warnf(line, user.ID, "country", "%q is not a two-letter country code", user.Country)
+		}
+		if user.CreatedAt.IsZero() {
+			c.warnf(line, user.ID, "createdAt", "is missing")
+		}
+	}
+}
+
+// checkValuations checks valuations.json. Model years may run one year
+// ahead of now, as new models go on sale early.
+func checkValuations(report *Report, dataPath string, now time.Time) {
+	records, ok := readRecords[models.Valuation](report, dataPath, ValuationsFile, true)
+	if !ok {
+		return
+	}
+
+	c := &fileChecker{report: report, file: ValuationsFile}
+	uniqueID := newUniqueIDs(c, "id")
+	maxYear := now.Year() + 1
+	for _, rec := range records {
+		valuation, line := rec.value, rec.line
+		id := valuation.ID
+		uniqueID.check(line, id, id, id)
+
+		if valuation.Year < minValuationYear || valuation.Year > maxYear {
+			c.errorf(line, id, "year", "must be between %d and %d", minValuationYear, maxYear)
+		}
+		if strings.TrimSpace(valuation.Make) == "" {
+			c.errorf(line, id, "make", "is required")
+		}
+		if strings.TrimSpace(valuation.Model) == "" {
+			c.errorf(line, id, "model", "is required")
+		}
+		if valuation.Mileage < 0 {
+			c.errorf(line, id, "mileage", "must not be negative")
+		}
+		if !contains(models.Conditions, valuation.Condition) {
+			c.errorf(line, id, "condition", "%q must be one of %s",
+				valuation.Condition, strings.Join(models.Conditions, ", "))
+		}
+		if valuation.EstimatedValue < 0 {
+			c.errorf(line, id, "estimatedValue", "must not be negative")
+		}
+		if valuation.MarketValue < 0 {
+			c.errorf(line, id, "marketValue", "must not be negative")
+		}
+		if valuation.DepreciationRate < 0 || valuation.DepreciationRate > 1 {
+			c.errorf(line, id, "depreciationRate", "must be between 0 and 1")
+		}
+		if valuation.CalculatedAt.IsZero() {
+			c.warnf(line, id, "calculatedAt", "is missing")
+		} else if valuation.CalculatedAt.After(now) {
+			c.warnf(line, id, "calculatedAt", "is in the future")
+		}
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
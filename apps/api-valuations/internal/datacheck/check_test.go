@@ -0,0 +1,103 @@
+package datacheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// validHash is a bcrypt hash of "password"
+const validHash = "$2a$10$eLTE.2EIttvwXEG3cNc9c.fZmt5km2cO5JmYvUAll.DuobqcS.hQW"
+
+func writeSeed(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestCheckShippedSeed(t *testing.T) {
+	report := Check(filepath.Join("..", "..", "..", "..", "data", "seed"))
+
+	if !report.OK() || report.Warnings != 0 {
+		t.Errorf("Expected the shipped seed data to pass cleanly, got %+v", report.Issues)
+	}
+	if report.Records[UsersFile] == 0 || report.Records[ValuationsFile] == 0 {
+		t.Errorf("Expected records to be counted, got %v", report.Records)
+	}
+}
+
+func TestCheckFindsProblems(t *testing.T) {
+	dir := writeSeed(t, map[string]string{
+		UsersFile: `[
+  {"id": "user-001", "email": "a@example.com", "password": "` + validHash + `", "country": "US",
+   "preferredCurrency": "USD", "roles": ["user"], "createdAt": "2024-01-01T00:00:00Z",
+   "preferences": {"makes": ["Honda"]}},
+  {"id": "user-002", "email": "b@example.com", "password": "secret", "country": "USA",
+   "preferredCurrency": "JPY", "roles": ["owner"], "createdAt": "2024-01-01T00:00:00Z"}
+]`,
+		ValuationsFile: `[
+  {"id": "val-001", "year": 2020, "make": "Honda", "model": "Civic", "mileage": 45000,
+   "condition": "good", "estimatedValue": 18500, "marketValue": 22000, "depreciationRate": 0.16,
+   "calculatedAt": "2024-01-15T10:30:00Z"},
+  {"id": "val-001", "year": 1700, "make": "", "model": "Civic", "mileage": -1,
+   "condition": "mint", "estimatedValue": -5, "marketValue": 22000, "depreciationRate": 1.5},
+  {"id": "val-003", "year": 2020, "make": "Honda", "model": "Civic", "mileage": "lots"}
+]`,
+	})
+
+	report := Check(dir)
+	if report.OK() {
+		t.Fatal("Expected the check to fail")
+	}
+
+	type key struct {
+		file     string
+		line     int
+		field    string
+		severity string
+	}
+	found := make(map[key]bool)
+	for _, issue := range report.Issues {
+		found[key{issue.File, issue.Line, issue.Field, issue.Severity}] = true
+	}
+
+	want := []key{
+		{UsersFile, 5, "password", SeverityError},
+		{UsersFile, 5, "preferredCurrency", SeverityError},
+		{UsersFile, 5, "roles", SeverityWarning},
+		{UsersFile, 5, "country", SeverityWarning},
+		{ValuationsFile, 5, "id", SeverityError},
+		{ValuationsFile, 5, "year", SeverityError},
+		{ValuationsFile, 5, "make", SeverityError},
+		{ValuationsFile, 5, "mileage", SeverityError},
+		{ValuationsFile, 5, "condition", SeverityError},
+		{ValuationsFile, 5, "estimatedValue", SeverityError},
+		{ValuationsFile, 5, "depreciationRate", SeverityError},
+		{ValuationsFile, 5, "calculatedAt", SeverityWarning},
+		{ValuationsFile, 7, "mileage", SeverityError},
+	}
+	for _, k := range want {
+		if !found[k] {
+			t.Errorf("Expected a %s on %s line %d field %q, got %+v", k.severity, k.file, k.line, k.field, report.Issues)
+		}
+	}
+	for _, issue := range report.Issues {
+		if issue.Line == 2 {
+			t.Errorf("Expected the first records to pass, got %+v", issue)
+		}
+	}
+}
+
+func TestCheckReportsMissingFiles(t *testing.T) {
+	report := Check(t.TempDir())
+
+	if report.Errors != 2 {
+		t.Errorf("Expected both files to be reported missing, got %+v", report.Issues)
+	}
+}
@@ -12,13 +12,13 @@ import (
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	repo       *repository.Repository
+	repo       repository.Store
 	jwtManager *auth.JWTManager
 	logger     *logrus.Logger
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(repo *repository.Repository, jwtManager *auth.JWTManager, logger *logrus.Logger) *AuthHandler {
+func NewAuthHandler(repo repository.Store, jwtManager *auth.JWTManager, logger *logrus.Logger) *AuthHandler {
 	return &AuthHandler{
 		repo:       repo,
 		jwtManager: jwtManager,
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// Export formats
+const (
+	exportNDJSON = "ndjson"
+	exportCSV    = "csv"
+	exportJSON   = "json"
+)
+
+// exportFlushEvery is how many records are written between flushes
+const exportFlushEvery = 100
+
+var errExportFormat = errors.New("format must be ndjson, csv or json")
+
+// exportContentTypes maps each export format to its media type
+var exportContentTypes = map[string]string{
+	exportNDJSON: "application/x-ndjson",
+	exportCSV:    "text/csv; charset=utf-8",
+	exportJSON:   "application/json",
+}
+
+// valuationColumns are the CSV columns of a valuation export
+var valuationColumns = []string{
+	"id", "year", "make", "model", "mileage", "condition",
+	"estimatedValue", "marketValue", "depreciationRate", "calculatedAt",
+}
+
+// exportFormat picks the export format from the format parameter, else the
+// Accept header, defaulting to NDJSON
+func exportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		format = strings.ToLower(format)
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", errExportFormat
+		}
+		return format, nil
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := mime.ParseMediaType(strings.TrimSpace(accept))
+		switch mediaType {
+		case "application/x-ndjson":
+			return exportNDJSON, nil
+		case "text/csv":
+			return exportCSV, nil
+		case "application/json":
+			return exportJSON, nil
+		}
+	}
+	return exportNDJSON, nil
+}
+
+// exportWriter streams records as a file download. NDJSON writes one JSON
+// object per line, JSON a single array and CSV a header row followed by the
+// cells row returns for each record.
+type exportWriter[T any] struct {
+	format     string
+	w          io.Writer
+	controller *http.ResponseController
+	csv        *csv.Writer
+	row        func(T) []string
+	count      int
+}
+
+// newExportWriter sets the download headers and starts the response. name
+// is the file name without its extension.
+func newExportWriter[T any](w http.ResponseWriter, format, name string, header []string, row func(T) []string) (*exportWriter[T], error) {
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	w.WriteHeader(http.StatusOK)
+
+	ew := &exportWriter[T]{
+		format:     format,
+		w:          w,
+		controller: http.NewResponseController(w),
+		row:        row,
+	}
+	switch format {
+	case exportCSV:
+		ew.csv = csv.NewWriter(w)
+		return ew, ew.csv.Write(header)
+	case exportJSON:
+		_, err := io.WriteString(w, "[")
+		return ew, err
+	}
+	return ew, nil
+}
+
+// Write writes one record, flushing the response every exportFlushEvery
+// records
+func (ew *exportWriter[T]) Write(record T) error {
+	var err error
+	if ew.csv != nil {
+		err = ew.csv.Write(ew.row(record))
+	} else {
+		var data []byte
+		if data, err = json.Marshal(record); err != nil {
+			return err
+		}
+		switch {
+		case ew.format == exportNDJSON:
+			data = append(data, '\n')
+		case ew.count > 0:
+			data = append([]byte(",\n"), data...)
+		default:
+			data = append([]byte("\n"), data...)
+		}
+		_, err = ew.w.Write(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	ew.count++
+	if ew.count%exportFlushEvery == 0 {
+		return ew.flush()
+	}
+	return nil
+}
+
+// Close ends the file and flushes the rest of it
+func (ew *exportWriter[T]) Close() error {
+	if ew.format == exportJSON {
+		end := "]\n"
+		if ew.count > 0 {
+			end = "\n]\n"
+		}
+		if _, err := io.WriteString(ew.w, end); err != nil {
+			return err
+		}
+	}
+	return ew.flush()
+}
+
+// flush sends what has been written so far to the client
+func (ew *exportWriter[T]) flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := ew.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// valuationFilter selects the valuations to export
+type valuationFilter struct {
+	make      string
+	model     string
+	condition string
+	from      time.Time
+	to        time.Time
+}
+
+// matches reports whether the valuation passes the filter. from is
+// inclusive and to exclusive.
+func (f *valuationFilter) matches(v *models.Valuation) bool {
+	switch {
+	case f.make != "" && !strings.EqualFold(v.Make, f.make):
+		return false
+	case f.model != "" && !strings.EqualFold(v.Model, f.model):
+		return false
+	case f.condition != "" && !strings.EqualFold(v.Condition, f.condition):
+		return false
+	case !f.from.IsZero() && v.CalculatedAt.Before(f.from):
+		return false
+	case !f.to.IsZero() && !v.CalculatedAt.Before(f.to):
+		return false
+	}
+	return true
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (UTC)
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// HandleExportValuations streams the valuation history as a download in
+// NDJSON, CSV or JSON, in ID order. make, model and condition narrow it
+// down, and from and to bound the calculation time. The export stops when
+// the client goes away.
+func (h *ValuationHandler) HandleExportValuations(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := valuationFilter{
+		make:      query.Get("make"),
+		model:     query.Get("model"),
+		condition: query.Get("condition"),
+	}
+	for name, bound := range map[string]*time.Time{"from": &filter.from, "to": &filter.to} {
+		if value := query.Get(name); value != "" {
+			if *bound, err = parseTimeParam(value); err != nil {
+				http.Error(w, name+" must be a date (2024-01-31) or an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	name := "valuations-" + time.Now().UTC().Format("20060102")
+	out, err := newExportWriter(w, format, name, valuationColumns, valuationCells)
+	if err == nil {
+		err = h.repo.EachValuation(r.Context(), func(valuation *models.Valuation) error {
+			if !filter.matches(valuation) {
+				return nil
+			}
+			return out.Write(valuation)
+		})
+	}
+	if err == nil {
+		err = out.Close()
+	}
+
+	fields := logrus.Fields{"format": format, "count": out.count}
+	switch {
+	case r.Context().Err() != nil:
+		h.logger.WithFields(fields).Info("Valuation export cancelled by the client")
+	case err != nil:
+		// The status has been sent, so the client only sees a truncated file
+		h.logger.WithError(err).WithFields(fields).Error("Valuation export failed")
+	default:
+		h.logger.WithFields(fields).Info("Valuations exported")
+	}
+}
+
+// valuationCells formats a valuation as the cells of valuationColumns
+func valuationCells(v *models.Valuation) []string {
+	return []string{
+		v.ID,
+		strconv.Itoa(v.Year),
+		v.Make,
+		v.Model,
+		strconv.Itoa(v.Mileage),
+		v.Condition,
+		strconv.FormatFloat(v.EstimatedValue, 'f', -1, 64),
+		strconv.FormatFloat(v.MarketValue, 'f', -1, 64),
+		strconv.FormatFloat(v.DepreciationRate, 'f', -1, 64),
+		v.CalculatedAt.Format(time.RFC3339),
+	}
+}
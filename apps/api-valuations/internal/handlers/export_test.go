@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestExportHandler(t *testing.T) *ValuationHandler {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := repository.NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return NewValuationHandler(repo, logger)
+}
+
+func TestExportValuations(t *testing.T) {
+	handler := newTestExportHandler(t)
+
+	export := func(query, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/valuations/export"+query, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.HandleExportValuations(rec, req)
+		return rec
+	}
+
+	rec := export("", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected NDJSON by default, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="valuations-`) {
+		t.Errorf("Expected a download file name, got %q", cd)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var valuation struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &valuation); err != nil {
+			t.Fatalf("Expected one JSON object per line, got %q", scanner.Text())
+		}
+		ids = append(ids, valuation.ID)
+	}
+	if strings.Join(ids, ",") != "val-001,val-002,val-003" {
+		t.Errorf("Expected every valuation in ID order, got %v", ids)
+	}
+
+	rec = export("?make=honda", "text/csv")
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV: %v", err)
+	}
+	if len(records) != 2 || records[0][0] != "id" || records[1][0] != "val-001" || records[1][2] != "Honda" {
+		t.Errorf("Expected a header and the Honda valuation, got %v", records)
+	}
+
+	rec = export("?format=json&from=2000-01-01&to=2000-01-02", "")
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Errorf("Expected an empty array, got %q", body)
+	}
+
+	for _, query := range []string{"?format=xml", "?from=yesterday"} {
+		if rec := export(query, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
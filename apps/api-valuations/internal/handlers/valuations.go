@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
@@ -14,26 +15,33 @@ import (
 
 // ValuationHandler handles valuation-related requests
 type ValuationHandler struct {
-	repo   *repository.Repository
+	repo   repository.Store
 	logger *logrus.Logger
 }
 
 // NewValuationHandler creates a new valuation handler
-func NewValuationHandler(repo *repository.Repository, logger *logrus.Logger) *ValuationHandler {
+func NewValuationHandler(repo repository.Store, logger *logrus.Logger) *ValuationHandler {
 	return &ValuationHandler{
 		repo:   repo,
 		logger: logger,
 	}
 }
 
-// HandleListValuations returns all historical valuations
+// HandleListValuations returns a page of historical valuations
 func (h *ValuationHandler) HandleListValuations(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	valuations := h.repo.GetAllValuations()
 
-	response := map[string]interface{}{
-		"data":  valuations,
-		"count": len(valuations),
-	}
+	sort.Slice(valuations, func(i, k int) bool { return valuations[i].ID < valuations[k].ID })
+	valuations, nextCursor, hasMore := paginate(valuations, page,
+		func(v *models.Valuation) bool { return v.ID > page.cursor.After },
+		func(v *models.Valuation) pageCursor { return pageCursor{After: v.ID} })
+	response := pageResponse(valuations, len(valuations), nextCursor, hasMore)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -93,6 +101,48 @@ func (h *ValuationHandler) HandleEstimateValuation(w http.ResponseWriter, r *htt
 	}).Info("Valuation calculated")
 }
 
+// HandleCreateValuation values a vehicle like HandleEstimateValuation and
+// records the result in the valuation history
+func (h *ValuationHandler) HandleCreateValuation(w http.ResponseWriter, r *http.Request) {
+	var req models.ValuationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.WithError(err).Warn("Invalid valuation request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Year == 0 || req.Make == "" || req.Model == "" {
+		http.Error(w, "Year, make, and model are required", http.StatusBadRequest)
+		return
+	}
+
+	estimate := h.calculateValuation(&req)
+	valuation := &models.Valuation{
+		Year:             req.Year,
+		Make:             req.Make,
+		Model:            req.Model,
+		Mileage:          req.Mileage,
+		Condition:        req.Condition,
+		EstimatedValue:   estimate.EstimatedValue,
+		MarketValue:      estimate.MarketValue,
+		DepreciationRate: estimate.DepreciationRate,
+		CalculatedAt:     time.Now().UTC(),
+	}
+	if err := h.repo.CreateValuation(valuation); err != nil {
+		h.logger.WithError(err).Error("Failed to record valuation")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": valuation,
+	})
+
+	h.logger.WithField("valuation_id", valuation.ID).Info("Valuation recorded")
+}
+
 // calculateValuation calculates a vehicle valuation based on the request
 // This is a simplified algorithm for demo purposes
 func (h *ValuationHandler) calculateValuation(req *models.ValuationRequest) *models.ValuationResponse {
@@ -188,10 +238,10 @@ func (h *ValuationHandler) HandleGetValuationSummary(w http.ResponseWriter, r *h
 	}
 
 	response := map[string]interface{}{
-		"totalValuations":      count,
-		"totalValue":           fmt.Sprintf("%.2f", totalValue),
-		"averageDepreciation":  fmt.Sprintf("%.2f%%", avgDepreciation*100),
-		"calculatedAt":         time.Now().Format(time.RFC3339),
+		"totalValuations":     count,
+		"totalValue":          fmt.Sprintf("%.2f", totalValue),
+		"averageDepreciation": fmt.Sprintf("%.2f%%", avgDepreciation*100),
+		"calculatedAt":        time.Now().Format(time.RFC3339),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
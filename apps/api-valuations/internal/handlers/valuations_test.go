@@ -1,9 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/repository"
 	"github.com/sirupsen/logrus"
 )
 
@@ -208,3 +215,95 @@ func TestCalculateValuationMileageImpact(t *testing.T) {
 		})
 	}
 }
+
+func TestListValuationsPagination(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := repository.NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	handler := NewValuationHandler(repo, logger)
+
+	type page struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		HasMore    bool    `json:"hasMore"`
+		NextCursor *string `json:"nextCursor"`
+	}
+	list := func(query string) (int, page) {
+		rec := httptest.NewRecorder()
+		handler.HandleListValuations(rec, httptest.NewRequest("GET", "/valuations"+query, nil))
+		var p page
+		json.NewDecoder(rec.Body).Decode(&p)
+		return rec.Code, p
+	}
+
+	_, first := list("?limit=2")
+	if len(first.Data) != 2 || !first.HasMore || first.NextCursor == nil {
+		t.Fatalf("Unexpected first page: %+v", first)
+	}
+	if first.Data[0].ID != "val-001" || first.Data[1].ID != "val-002" {
+		t.Errorf("Expected valuations in ID order, got %+v", first.Data)
+	}
+
+	_, second := list("?limit=2&cursor=" + *first.NextCursor)
+	if len(second.Data) != 1 || second.Data[0].ID != "val-003" || second.HasMore {
+		t.Errorf("Unexpected last page: %+v", second)
+	}
+
+	if code, _ := list("?cursor=bogus"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid cursor, got %d", code)
+	}
+}
+
+func TestCreateValuationIsJournaled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dir := t.TempDir()
+
+	repo, err := repository.NewRepository(dataPath, logger, repository.WithJournal(dir, 0))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	handler := NewValuationHandler(repo, logger)
+
+	body := `{"year": 2020, "make": "Honda", "model": "Civic", "mileage": 30000, "condition": "good"}`
+	rec := httptest.NewRecorder()
+	handler.HandleCreateValuation(rec, httptest.NewRequest("POST", "/valuations", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Data models.Valuation `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Data.ID == "" || created.Data.EstimatedValue == 0 {
+		t.Fatalf("Expected a stored valuation, got %+v", created.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.HandleCreateValuation(rec, httptest.NewRequest("POST", "/valuations", strings.NewReader(`{"make": "Honda"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an incomplete request, got %d", rec.Code)
+	}
+
+	// The valuation survives a restart through the journal
+	repo.Close()
+	repo, err = repository.NewRepository(dataPath, logger, repository.WithJournal(dir, 0))
+	if err != nil {
+		t.Fatalf("Failed to reopen repository: %v", err)
+	}
+	defer repo.Close()
+	stored, err := repo.GetValuationByID(created.Data.ID)
+	if err != nil {
+		t.Fatalf("Expected the valuation to be replayed, got %v", err)
+	}
+	if stored.Make != "Honda" || stored.EstimatedValue != created.Data.EstimatedValue {
+		t.Errorf("Expected the recorded valuation, got %+v", stored)
+	}
+}
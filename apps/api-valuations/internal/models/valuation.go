@@ -34,3 +34,9 @@ type ValuationResponse struct {
 	Currency         string  `json:"currency"`
 	Confidence       string  `json:"confidence"`
 }
+
+// Conditions lists the vehicle conditions a valuation accepts
+var Conditions = []string{"excellent", "good", "fair", "poor"}
+
+// SupportedCurrencies lists the currency codes the services price in
+var SupportedCurrencies = []string{"USD", "GBP", "EUR", "CAD", "AUD"}
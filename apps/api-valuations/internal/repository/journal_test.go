@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func openJournaledRepository(t *testing.T, journalDir string) *Repository {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	repo, err := NewRepository(dataPath, logger, WithJournal(journalDir, 0))
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	return repo
+}
+
+func TestJournalReplaysValuations(t *testing.T) {
+	dir := t.TempDir()
+	repo := openJournaledRepository(t, dir)
+
+	first := &models.Valuation{Year: 2020, Make: "Honda", Model: "Civic", EstimatedValue: 18000}
+	if err := repo.CreateValuation(first); err != nil {
+		t.Fatalf("Failed to create valuation: %v", err)
+	}
+	if first.ID != "val-004" {
+		t.Errorf("Expected generated ID val-004, got %s", first.ID)
+	}
+	if err := repo.Snapshot(); err != nil {
+		t.Fatalf("Failed to snapshot: %v", err)
+	}
+	second := &models.Valuation{Year: 2018, Make: "Ford", Model: "Focus", EstimatedValue: 9000}
+	if err := repo.CreateValuation(second); err != nil {
+		t.Fatalf("Failed to create valuation: %v", err)
+	}
+	repo.Close()
+
+	// Simulate a crash partway through appending the next record
+	segments, _ := listSegments(dir)
+	file, err := os.OpenFile(segmentPath(dir, segments[len(segments)-1]), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open segment: %v", err)
+	}
+	file.Write([]byte{0, 0, 0, 9, 1, 2})
+	file.Close()
+
+	repo = openJournaledRepository(t, dir)
+	defer repo.Close()
+
+	for _, id := range []string{first.ID, second.ID} {
+		if _, err := repo.GetValuationByID(id); err != nil {
+			t.Errorf("Valuation %s was not recovered: %v", id, err)
+		}
+	}
+
+	third := &models.Valuation{Year: 2015, Make: "Mazda", Model: "3"}
+	if err := repo.CreateValuation(third); err != nil {
+		t.Fatalf("Failed to create valuation: %v", err)
+	}
+	if third.ID != "val-006" {
+		t.Errorf("Expected generated ID val-006, got %s", third.ID)
+	}
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+)
+
+// Option configures optional Repository behaviour
+type Option func(*repositoryOptions)
+
+type repositoryOptions struct {
+	journalDir       string
+	snapshotInterval time.Duration
+	watchInterval    time.Duration
+}
+
+// WithJournal makes the repository durable. Every mutation is appended to a
+// write-ahead journal in dir before it is applied, and a compacted snapshot
+// in the seed file format is written every snapshotInterval (0 disables
+// periodic snapshots; Snapshot can still be called directly).
+func WithJournal(dir string, snapshotInterval time.Duration) Option {
+	return func(o *repositoryOptions) {
+		o.journalDir = dir
+		o.snapshotInterval = snapshotInterval
+	}
+}
+
+// openJournal replays the journal on top of the loaded data and starts the
+// periodic snapshot loop
+func (r *Repository) openJournal(dir string, snapshotInterval time.Duration) error {
+	j, err := openJournal(dir, r.snapshotSeq, r.applyRecord, r.logger)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	r.journal = j
+	r.journalDir = dir
+
+	if snapshotInterval > 0 {
+		r.wg.Add(1)
+		go r.snapshotLoop(snapshotInterval)
+	}
+
+	return nil
+}
+
+// applyRecord applies a replayed journal record to the maps
+func (r *Repository) applyRecord(rec journalRecord) error {
+	if rec.Op == opReload {
+		// The reload is repeated with the seed files as they are now, in
+		// case the snapshot that should follow it was never written
+		users, valuations, err := readSeedData(r.dataPath)
+		if err != nil {
+			r.logger.WithError(err).Warn("Skipping replayed seed data reload")
+			return nil
+		}
+		r.mergeSeed(users, valuations)
+		return nil
+	}
+
+	switch rec.Entity {
+	case entityUser:
+		if rec.Op == opDelete {
+			delete(r.users, rec.ID)
+			return nil
+		}
+		var user models.User
+		if err := json.Unmarshal(rec.Data, &user); err != nil {
+			return err
+		}
+		r.users[rec.ID] = &user
+	case entityValuation:
+		if seq := valuationIDSeq(rec.ID); seq > r.valuationSeq {
+			r.valuationSeq = seq
+		}
+		r.edited[rec.ID] = true
+		if rec.Op == opDelete {
+			delete(r.valuations, rec.ID)
+			return nil
+		}
+		var valuation models.Valuation
+		if err := json.Unmarshal(rec.Data, &valuation); err != nil {
+			return err
+		}
+		r.valuations[rec.ID] = &valuation
+	default:
+		return fmt.Errorf("unknown journal entity %q", rec.Entity)
+	}
+
+	return nil
+}
+
+// record appends a mutation to the journal, if enabled. Callers must hold mu
+// and apply the mutation only when record succeeds.
+func (r *Repository) record(op, entity, id string, data interface{}) error {
+	if r.journal == nil {
+		return nil
+	}
+	return r.journal.append(op, entity, id, data)
+}
+
+// Snapshot writes a compacted snapshot of the current data and drops the
+// journal segments it covers. It is a no-op without a journal.
+func (r *Repository) Snapshot() error {
+	if r.journal == nil {
+		return nil
+	}
+
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+
+	// Stored records are replaced rather than modified, so copying the
+	// pointers under the lock is enough to capture a consistent view
+	r.mu.Lock()
+	if r.journal.seq == r.snapshotSeq {
+		r.mu.Unlock()
+		return nil
+	}
+	seq, err := r.journal.rotate()
+	if err != nil {
+		r.mu.Unlock()
+		return fmt.Errorf("failed to rotate journal: %w", err)
+	}
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	valuations := make([]*models.Valuation, 0, len(r.valuations))
+	for _, valuation := range r.valuations {
+		valuations = append(valuations, valuation)
+	}
+	valuationSeq := r.valuationSeq
+	edited := make([]string, 0, len(r.edited))
+	for id := range r.edited {
+		edited = append(edited, id)
+	}
+	r.mu.Unlock()
+	sort.Strings(edited)
+
+	manifest := snapshotManifest{
+		Seq:              seq,
+		ValuationSeq:     valuationSeq,
+		CreatedAt:        time.Now().UTC(),
+		EditedValuations: edited,
+	}
+	files := map[string]interface{}{
+		"users.json":      users,
+		"valuations.json": valuations,
+	}
+	if err := writeSnapshot(r.journalDir, manifest, files); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	r.snapshotSeq = seq
+	if err := r.journal.compact(seq); err != nil {
+		return fmt.Errorf("failed to compact journal: %w", err)
+	}
+
+	r.logger.WithField("seq", seq).Info("Wrote repository snapshot")
+
+	return nil
+}
+
+// snapshotLoop takes periodic snapshots until Close is called
+func (r *Repository) snapshotLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				r.logger.WithError(err).Error("Periodic snapshot failed")
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close stops the background loops and closes the journal
+func (r *Repository) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+
+	if r.journal == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.journal.close()
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+)
+
+// seedFileNames are the files under the data path read by the repository
+var seedFileNames = []string{"users.json", "valuations.json"}
+
+// opReload marks a seed data reload in the journal. It carries no data: on
+// replay the seed files are read and merged again, so a crash before the
+// snapshot taken after the reload does not lose it.
+const opReload = "reload"
+
+// ReloadStatus reports hot reload activity for the seed data
+type ReloadStatus struct {
+	Reloads    int64      `json:"reloads"`
+	Failures   int64      `json:"failures"`
+	LastReload *time.Time `json:"lastReload,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+}
+
+// Reloader is implemented by stores that can reload their seed files
+// without a restart
+type Reloader interface {
+	Reload() error
+	ReloadStatus() ReloadStatus
+}
+
+var _ Reloader = (*Repository)(nil)
+
+// WithWatch polls the data path every interval and reloads the seed files
+// when one of them changes
+func WithWatch(interval time.Duration) Option {
+	return func(o *repositoryOptions) {
+		o.watchInterval = interval
+	}
+}
+
+// fileVersion identifies the version of a file on disk
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reload re-reads and validates the seed files, then merges them in
+// atomically (see mergeSeed). If any file is missing or invalid the current
+// data is kept and the error is returned.
+func (r *Repository) Reload() error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	users, valuations, err := readSeedData(r.dataPath)
+	if err != nil {
+		r.mu.Lock()
+		r.reloadStatus.Failures++
+		r.reloadStatus.LastError = err.Error()
+		r.mu.Unlock()
+
+		r.logger.WithError(err).Warn("Seed data reload rejected, keeping current data")
+		return err
+	}
+
+	r.mu.Lock()
+	if err := r.record(opReload, "", "", nil); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	kept := r.mergeSeed(users, valuations)
+
+	now := time.Now().UTC()
+	r.reloadStatus.Reloads++
+	r.reloadStatus.LastReload = &now
+	r.reloadStatus.LastError = ""
+	r.mu.Unlock()
+
+	r.logger.Infof("Reloaded %d users and %d valuations from %s, keeping %d valuations recorded through the API",
+		len(users), len(valuations), r.dataPath, kept)
+
+	// Persist the reloaded data so a restart does not replay older journal
+	// records on top of it
+	return r.Snapshot()
+}
+
+// mergeSeed swaps in the seed users and merges the seed valuations by ID.
+// Valuations written through the API are kept as they are; every other
+// valuation came from the seed files, so it is replaced by its new seed
+// record or removed along with it. It returns the number of API valuations
+// kept. Callers must hold mu.
+func (r *Repository) mergeSeed(users map[string]*models.User, seed map[string]*models.Valuation) int {
+	r.users = users
+
+	valuations := make(map[string]*models.Valuation, len(seed))
+	for id, valuation := range r.valuations {
+		if r.edited[id] {
+			valuations[id] = valuation
+		}
+	}
+	kept := len(valuations)
+
+	for id, valuation := range seed {
+		if r.edited[id] {
+			continue
+		}
+		valuations[id] = valuation
+		// Never hand out an ID that is used by the seed files
+		if seq := valuationIDSeq(id); seq > r.valuationSeq {
+			r.valuationSeq = seq
+		}
+	}
+	r.valuations = valuations
+
+	return kept
+}
+
+// ReloadStatus returns the reload counters
+func (r *Repository) ReloadStatus() ReloadStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.reloadStatus
+}
+
+// readSeedData parses and validates the seed files into new maps
+func readSeedData(dataPath string) (map[string]*models.User, map[string]*models.Valuation, error) {
+	var userList []*models.User
+	if err := readJSONFile(filepath.Join(dataPath, "users.json"), &userList); err != nil {
+		return nil, nil, fmt.Errorf("users.json: %w", err)
+	}
+
+	users := make(map[string]*models.User, len(userList))
+	emails := make(map[string]bool, len(userList))
+	for i, user := range userList {
+		switch {
+		case user == nil || user.ID == "":
+			return nil, nil, fmt.Errorf("users.json: record %d has no id", i)
+		case user.Email == "":
+			return nil, nil, fmt.Errorf("users.json: user %s has no email", user.ID)
+		case users[user.ID] != nil:
+			return nil, nil, fmt.Errorf("users.json: duplicate user id %s", user.ID)
+		case emails[user.Email]:
+			return nil, nil, fmt.Errorf("users.json: duplicate email %s", user.Email)
+		}
+		users[user.ID] = user
+		emails[user.Email] = true
+	}
+
+	var valuationList []*models.Valuation
+	if err := readJSONFile(filepath.Join(dataPath, "valuations.json"), &valuationList); err != nil {
+		return nil, nil, fmt.Errorf("valuations.json: %w", err)
+	}
+
+	valuations := make(map[string]*models.Valuation, len(valuationList))
+	for i, valuation := range valuationList {
+		switch {
+		case valuation == nil || valuation.ID == "":
+			return nil, nil, fmt.Errorf("valuations.json: record %d has no id", i)
+		case valuations[valuation.ID] != nil:
+			return nil, nil, fmt.Errorf("valuations.json: duplicate valuation id %s", valuation.ID)
+		case valuation.Year <= 0 || valuation.Make == "" || valuation.Model == "":
+			return nil, nil, fmt.Errorf("valuations.json: valuation %s needs year, make and model", valuation.ID)
+		case valuation.Mileage < 0 || valuation.EstimatedValue < 0 || valuation.MarketValue < 0:
+			return nil, nil, fmt.Errorf("valuations.json: valuation %s has negative values", valuation.ID)
+		}
+		valuations[valuation.ID] = valuation
+	}
+
+	return users, valuations, nil
+}
+
+// statSeedFiles returns the current version of each seed file
+func statSeedFiles(dataPath string) map[string]fileVersion {
+	versions := make(map[string]fileVersion, len(seedFileNames))
+	for _, name := range seedFileNames {
+		if info, err := os.Stat(filepath.Join(dataPath, name)); err == nil {
+			versions[name] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return versions
+}
+
+// watchLoop reloads the seed files whenever their versions change
+func (r *Repository) watchLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			current := statSeedFiles(r.dataPath)
+			if sameVersions(current, r.seedFiles) {
+				continue
+			}
+			// Remember the version even if it fails to load, so a malformed
+			// file is reported once rather than on every tick
+			r.seedFiles = current
+			r.Reload()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func sameVersions(a, b map[string]fileVersion) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, version := range a {
+		other, ok := b[name]
+		if !ok || !version.modTime.Equal(other.modTime) || version.size != other.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReload(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+
+	src := filepath.Join("..", "..", "..", "..", "data", "seed")
+	dataPath := t.TempDir()
+	for _, name := range seedFileNames {
+		data, err := os.ReadFile(filepath.Join(src, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		os.WriteFile(filepath.Join(dataPath, name), data, 0o644)
+	}
+
+	repo, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	// Valuations recorded through the API are kept by the reload
+	recorded := &models.Valuation{Year: 2020, Make: "Kia", Model: "Niro", Condition: "good", EstimatedValue: 18000}
+	if err := repo.CreateValuation(recorded); err != nil {
+		t.Fatalf("Failed to create valuation: %v", err)
+	}
+
+	refreshed := `[{"id": "val-100", "year": 2019, "make": "Mazda", "model": "CX-5", "mileage": 40000,
+		"condition": "good", "estimatedValue": 21000, "marketValue": 23000, "depreciationRate": 0.2}]`
+	if err := os.WriteFile(filepath.Join(dataPath, "valuations.json"), []byte(refreshed), 0o644); err != nil {
+		t.Fatalf("Failed to write valuations: %v", err)
+	}
+	if err := repo.Reload(); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	if got := len(repo.GetAllValuations()); got != 2 {
+		t.Errorf("Expected 2 valuations after reload, got %d", got)
+	}
+	if _, err := repo.GetValuationByID(recorded.ID); err != nil {
+		t.Errorf("Expected the recorded valuation to be kept: %v", err)
+	}
+
+	// A malformed file keeps the current data
+	os.WriteFile(filepath.Join(dataPath, "valuations.json"), []byte(`[{"id": `), 0o644)
+	if err := repo.Reload(); err == nil {
+		t.Error("Expected error reloading malformed file")
+	}
+	if _, err := repo.GetValuationByID("val-100"); err != nil {
+		t.Errorf("Expected reloaded valuation to be kept: %v", err)
+	}
+
+	status := repo.ReloadStatus()
+	if status.Reloads != 1 || status.Failures != 1 || status.LastReload == nil {
+		t.Errorf("Unexpected reload status: %+v", status)
+	}
+
+	// New IDs continue after the reloaded ones
+	valuation := repo.valuations["val-100"]
+	created := *valuation
+	if err := repo.CreateValuation(&created); err != nil {
+		t.Fatalf("Failed to create valuation: %v", err)
+	}
+	if created.ID != "val-101" {
+		t.Errorf("Expected generated ID val-101, got %s", created.ID)
+	}
+}
@@ -1,56 +1,118 @@
 package repository
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
-// Repository provides data access for users and valuations
+// Repository is the in-memory Store implementation. It provides data access
+// for users and valuations held in maps loaded from the JSON seed files.
 type Repository struct {
 	users      map[string]*models.User
 	valuations map[string]*models.Valuation
-	mu         sync.RWMutex
-	logger     *logrus.Logger
+	// valuationSeq is the highest sequence number used in a valuation ID
+	valuationSeq int
+	// edited holds the IDs of valuations written through the API. Reloads
+	// leave them alone (see mergeSeed).
+	edited map[string]bool
+	mu     sync.RWMutex
+	logger *logrus.Logger
+
+	// journal records mutations when durability is enabled (see WithJournal)
+	journal     *journal
+	journalDir  string
+	snapshotMu  sync.Mutex
+	snapshotSeq uint64
+
+	// dataPath is re-read by Reload; reloadMu serialises reloads
+	dataPath     string
+	reloadMu     sync.Mutex
+	reloadStatus ReloadStatus
+	seedFiles    map[string]fileVersion
+
+	// stop ends the background loops, which are tracked by wg
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
 }
 
-// NewRepository creates a new repository and loads data from JSON files
-func NewRepository(dataPath string, logger *logrus.Logger) (*Repository, error) {
+// NewRepository creates a new repository and loads data from JSON files.
+// With WithJournal the latest snapshot replaces the seed files as the
+// starting point and the journal is replayed on top of it.
+func NewRepository(dataPath string, logger *logrus.Logger, opts ...Option) (*Repository, error) {
+	options := repositoryOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	repo := &Repository{
 		users:      make(map[string]*models.User),
 		valuations: make(map[string]*models.Valuation),
+		edited:     make(map[string]bool),
 		logger:     logger,
+		dataPath:   dataPath,
+		stop:       make(chan struct{}),
+	}
+
+	loadPath := dataPath
+	var manifest *snapshotManifest
+	if options.journalDir != "" {
+		snapshotDir, m, err := latestSnapshot(options.journalDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshots: %w", err)
+		}
+		if snapshotDir != "" {
+			loadPath = snapshotDir
+			manifest = m
+		}
 	}
 
 	// Load users
-	if err := repo.loadUsers(filepath.Join(dataPath, "users.json")); err != nil {
+	if err := repo.loadUsers(filepath.Join(loadPath, "users.json")); err != nil {
 		return nil, fmt.Errorf("failed to load users: %w", err)
 	}
 
 	// Load valuations
-	if err := repo.loadValuations(filepath.Join(dataPath, "valuations.json")); err != nil {
+	if err := repo.loadValuations(filepath.Join(loadPath, "valuations.json")); err != nil {
 		return nil, fmt.Errorf("failed to load valuations: %w", err)
 	}
 
-	logger.Infof("Loaded %d users and %d valuations from %s", len(repo.users), len(repo.valuations), dataPath)
+	logger.Infof("Loaded %d users and %d valuations from %s", len(repo.users), len(repo.valuations), loadPath)
+
+	if options.journalDir != "" {
+		if manifest != nil {
+			repo.snapshotSeq = manifest.Seq
+			if manifest.ValuationSeq > repo.valuationSeq {
+				repo.valuationSeq = manifest.ValuationSeq
+			}
+			for _, id := range manifest.EditedValuations {
+				repo.edited[id] = true
+			}
+		}
+		if err := repo.openJournal(options.journalDir, options.snapshotInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.watchInterval > 0 {
+		repo.seedFiles = statSeedFiles(dataPath)
+		repo.wg.Add(1)
+		go repo.watchLoop(options.watchInterval)
+	}
 
 	return repo, nil
 }
 
 // loadUsers loads users from a JSON file
 func (r *Repository) loadUsers(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
 	var users []*models.User
-	if err := json.Unmarshal(data, &users); err != nil {
+	if err := readJSONFile(filePath, &users); err != nil {
 		return err
 	}
 
@@ -66,13 +128,8 @@ func (r *Repository) loadUsers(filePath string) error {
 
 // loadValuations loads valuations from a JSON file
 func (r *Repository) loadValuations(filePath string) error {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
-	}
-
 	var valuations []*models.Valuation
-	if err := json.Unmarshal(data, &valuations); err != nil {
+	if err := readJSONFile(filePath, &valuations); err != nil {
 		return err
 	}
 
@@ -81,6 +138,9 @@ func (r *Repository) loadValuations(filePath string) error {
 
 	for _, valuation := range valuations {
 		r.valuations[valuation.ID] = valuation
+		if seq := valuationIDSeq(valuation.ID); seq > r.valuationSeq {
+			r.valuationSeq = seq
+		}
 	}
 
 	return nil
@@ -93,7 +153,7 @@ func (r *Repository) GetUserByID(userID string) (*models.User, error) {
 
 	user, exists := r.users[userID]
 	if !exists {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	return user, nil
@@ -110,7 +170,7 @@ func (r *Repository) GetUserByEmail(email string) (*models.User, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("user not found")
+	return nil, ErrUserNotFound
 }
 
 // GetAllValuations returns all valuations
@@ -126,6 +186,23 @@ func (r *Repository) GetAllValuations() []*models.Valuation {
 	return valuations
 }
 
+// EachValuation calls fn with every valuation, in ID order. The read lock is
+// released before fn is called, so a slow caller does not hold up writes.
+func (r *Repository) EachValuation(ctx context.Context, fn func(*models.Valuation) error) error {
+	valuations := r.GetAllValuations()
+	sort.Slice(valuations, func(i, k int) bool { return valuations[i].ID < valuations[k].ID })
+
+	for _, valuation := range valuations {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(valuation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetValuationByID retrieves a valuation by ID
 func (r *Repository) GetValuationByID(valuationID string) (*models.Valuation, error) {
 	r.mu.RLock()
@@ -133,8 +210,28 @@ func (r *Repository) GetValuationByID(valuationID string) (*models.Valuation, er
 
 	valuation, exists := r.valuations[valuationID]
 	if !exists {
-		return nil, fmt.Errorf("valuation not found")
+		return nil, ErrValuationNotFound
 	}
 
 	return valuation, nil
 }
+
+// CreateValuation assigns a new ID to the valuation and stores it
+func (r *Repository) CreateValuation(valuation *models.Valuation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := formatValuationID(r.valuationSeq + 1)
+	stored := *valuation
+	stored.ID = id
+	if err := r.record(opPut, entityValuation, id, &stored); err != nil {
+		return err
+	}
+
+	r.valuationSeq++
+	valuation.ID = id
+	r.valuations[id] = valuation
+	r.edited[id] = true
+
+	return nil
+}
@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables used by SQLStore. Records are stored as
+// JSON documents keyed by ID so the schema follows the model types; columns
+// that are looked up directly are duplicated alongside the document.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id    TEXT PRIMARY KEY,
+	email TEXT NOT NULL,
+	data  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+CREATE TABLE IF NOT EXISTS valuations (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sequences (
+	name  TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
+`
+
+// SQLStore is the Store implementation backed by an embedded SQLite file
+type SQLStore struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+// NewSQLStore opens (or creates) the SQLite database at dbPath. Empty tables
+// are seeded from the JSON files in dataPath, so a fresh database starts with
+// the same data as the in-memory store.
+func NewSQLStore(dbPath, dataPath string, logger *logrus.Logger) (*SQLStore, error) {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; serialise access through one connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	store := &SQLStore{
+		db:     db,
+		logger: logger,
+	}
+
+	if dataPath != "" {
+		if err := store.seed(dataPath); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	logger.Infof("Opened SQLite store at %s", dbPath)
+
+	return store, nil
+}
+
+// Close closes the underlying database
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// seed loads the JSON seed files into any table that is still empty
+func (s *SQLStore) seed(dataPath string) error {
+	empty, err := s.isEmpty("users")
+	if err != nil {
+		return err
+	}
+	if empty {
+		var users []*models.User
+		if err := readJSONFile(filepath.Join(dataPath, "users.json"), &users); err != nil {
+			return fmt.Errorf("failed to load users: %w", err)
+		}
+		if err := s.insertUsers(users); err != nil {
+			return fmt.Errorf("failed to seed users: %w", err)
+		}
+		s.logger.Infof("Seeded %d users from %s", len(users), dataPath)
+	}
+
+	empty, err = s.isEmpty("valuations")
+	if err != nil {
+		return err
+	}
+	if empty {
+		var valuations []*models.Valuation
+		if err := readJSONFile(filepath.Join(dataPath, "valuations.json"), &valuations); err != nil {
+			return fmt.Errorf("failed to load valuations: %w", err)
+		}
+		if err := s.insertValuations(valuations); err != nil {
+			return fmt.Errorf("failed to seed valuations: %w", err)
+		}
+		s.logger.Infof("Seeded %d valuations from %s", len(valuations), dataPath)
+	}
+
+	return nil
+}
+
+// isEmpty reports whether a table has no rows
+func (s *SQLStore) isEmpty(table string) (bool, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	return count == 0, nil
+}
+
+// insertUsers upserts users in a single transaction
+func (s *SQLStore) insertUsers(users []*models.User) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		data, err := json.Marshal(user)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO users (id, email, data) VALUES (?, ?, ?)",
+			user.ID, user.Email, string(data),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertValuations upserts valuations in a single transaction
+func (s *SQLStore) insertValuations(valuations []*models.Valuation) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, valuation := range valuations {
+		data, err := json.Marshal(valuation)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT OR REPLACE INTO valuations (id, data) VALUES (?, ?)",
+			valuation.ID, string(data),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryUser returns the single user matched by the query
+func (s *SQLStore) queryUser(query string, args ...interface{}) (*models.User, error) {
+	var data string
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := json.Unmarshal([]byte(data), &user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by ID
+func (s *SQLStore) GetUserByID(userID string) (*models.User, error) {
+	return s.queryUser("SELECT data FROM users WHERE id = ?", userID)
+}
+
+// GetUserByEmail retrieves a user by email address
+func (s *SQLStore) GetUserByEmail(email string) (*models.User, error) {
+	return s.queryUser("SELECT data FROM users WHERE email = ?", email)
+}
+
+// GetAllValuations returns all valuations
+func (s *SQLStore) GetAllValuations() []*models.Valuation {
+	rows, err := s.db.Query("SELECT data FROM valuations ORDER BY id")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query valuations")
+		return []*models.Valuation{}
+	}
+	defer rows.Close()
+
+	valuations := []*models.Valuation{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			s.logger.WithError(err).Error("Failed to scan valuation")
+			continue
+		}
+		var valuation models.Valuation
+		if err := json.Unmarshal([]byte(data), &valuation); err != nil {
+			s.logger.WithError(err).Error("Failed to decode valuation")
+			continue
+		}
+		valuations = append(valuations, &valuation)
+	}
+
+	return valuations
+}
+
+// eachValuationBatch is how many valuations EachValuation reads per query
+const eachValuationBatch = 200
+
+// EachValuation calls fn with every valuation, in ID order. Valuations are
+// read in batches, releasing the connection in between, so a slow caller
+// does not hold up other queries.
+func (s *SQLStore) EachValuation(ctx context.Context, fn func(*models.Valuation) error) error {
+	after := ""
+	for {
+		rows, err := s.db.QueryContext(ctx,
+			"SELECT id, data FROM valuations WHERE id > ? ORDER BY id LIMIT ?", after, eachValuationBatch)
+		if err != nil {
+			return err
+		}
+
+		var batch []*models.Valuation
+		read := 0
+		for rows.Next() {
+			var data string
+			if err := rows.Scan(&after, &data); err != nil {
+				rows.Close()
+				return err
+			}
+			read++
+			var valuation models.Valuation
+			if err := json.Unmarshal([]byte(data), &valuation); err != nil {
+				s.logger.WithError(err).WithField("valuation_id", after).Error("Failed to decode valuation")
+				continue
+			}
+			batch = append(batch, &valuation)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, valuation := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(valuation); err != nil {
+				return err
+			}
+		}
+		if read < eachValuationBatch {
+			return nil
+		}
+	}
+}
+
+// GetValuationByID retrieves a valuation by ID
+func (s *SQLStore) GetValuationByID(valuationID string) (*models.Valuation, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM valuations WHERE id = ?", valuationID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrValuationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var valuation models.Valuation
+	if err := json.Unmarshal([]byte(data), &valuation); err != nil {
+		return nil, err
+	}
+
+	return &valuation, nil
+}
+
+// CreateValuation assigns a new ID to the valuation and stores it
+func (s *SQLStore) CreateValuation(valuation *models.Valuation) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seq, err := nextValuationSeqTx(tx)
+	if err != nil {
+		return err
+	}
+
+	stored := *valuation
+	stored.ID = formatValuationID(seq)
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO valuations (id, data) VALUES (?, ?)",
+		stored.ID, string(data),
+	); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	valuation.ID = stored.ID
+	return nil
+}
+
+// nextValuationSeqTx reserves the next valuation ID sequence number. The
+// counter is kept in the sequences table so IDs are never reused.
+func nextValuationSeqTx(tx *sql.Tx) (int, error) {
+	var seq int
+	err := tx.QueryRow("SELECT value FROM sequences WHERE name = 'valuations'").Scan(&seq)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+
+	// Seeded rows are inserted with their own IDs, so include them as well
+	rows, err := tx.Query("SELECT id FROM valuations")
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if n := valuationIDSeq(id); n > seq {
+			seq = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	seq++
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO sequences (name, value) VALUES ('valuations', ?)", seq,
+	); err != nil {
+		return 0, err
+	}
+
+	return seq, nil
+}
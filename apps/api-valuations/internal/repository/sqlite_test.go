@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/CB-AutoStack/AutoStack/apps/api-valuations/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSQLStoreMatchesMemoryStore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	memory, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	store, err := NewSQLStore(filepath.Join(t.TempDir(), "valuations.db"), dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	defer store.Close()
+
+	if got, want := len(store.GetAllValuations()), len(memory.GetAllValuations()); got != want {
+		t.Errorf("Expected %d valuations, got %d", want, got)
+	}
+
+	user, err := store.GetUserByEmail("demo@autostack.com")
+	if err != nil {
+		t.Fatalf("Failed to get user: %v", err)
+	}
+	if _, err := store.GetUserByID(user.ID); err != nil {
+		t.Errorf("Failed to get user by ID: %v", err)
+	}
+
+	valuation, err := store.GetValuationByID("val-001")
+	if err != nil {
+		t.Fatalf("Failed to get valuation: %v", err)
+	}
+	if valuation.Make != "Honda" {
+		t.Errorf("Expected make Honda, got %s", valuation.Make)
+	}
+	if _, err := store.GetValuationByID("nonexistent-id"); err == nil {
+		t.Error("Expected error for non-existent valuation")
+	}
+}
+
+func TestNewStoreUnknownBackend(t *testing.T) {
+	logger := logrus.New()
+
+	if _, err := NewStore(Config{Backend: "postgres"}, logger); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
+
+func TestEachValuation(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(os.Stdout)
+	dataPath := filepath.Join("..", "..", "..", "..", "data", "seed")
+
+	memory, err := NewRepository(dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	sqlStore, err := NewSQLStore(filepath.Join(t.TempDir(), "valuations.db"), dataPath, logger)
+	if err != nil {
+		t.Fatalf("Failed to create SQL store: %v", err)
+	}
+	defer sqlStore.Close()
+
+	for name, store := range map[string]Store{BackendMemory: memory, BackendSQLite: sqlStore} {
+		t.Run(name, func(t *testing.T) {
+			var ids []string
+			err := store.EachValuation(context.Background(), func(valuation *models.Valuation) error {
+				ids = append(ids, valuation.ID)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("EachValuation failed: %v", err)
+			}
+			if len(ids) != len(store.GetAllValuations()) || !sort.StringsAreSorted(ids) {
+				t.Errorf("Expected every valuation in ID order, got %v", ids)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err = store.EachValuation(ctx, func(*models.Valuation) error {
+				t.Error("Expected no valuations once cancelled")
+				return nil
+			})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
@@ -28,6 +28,7 @@ export interface Vehicle {
   interiorColor: string;
   features: string[];
   images: string[];
+  dealerId?: string;
   dealerRating: number;
   location: string;
   listingDate: string;